@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/danielleontiev/neojhat/internal/cmd"
+	heapobjects "github.com/danielleontiev/neojhat/internal/objects"
 )
 
 func main() {
@@ -22,6 +23,36 @@ func main() {
 	case cmd.Objects:
 		cmd.ObjectsCommand.Parse(args)
 		objects()
+	case cmd.Referrers:
+		cmd.ReferrersCommand.Parse(args)
+		referrers()
+	case cmd.PathToRoot:
+		cmd.PathToRootCommand.Parse(args)
+		pathToRoot()
+	case cmd.Leaks:
+		cmd.LeaksCommand.Parse(args)
+		leaks()
+	case cmd.Query:
+		cmd.QueryCommand.Parse(args)
+		query()
+	case cmd.Collection:
+		cmd.CollectionCommand.Parse(args)
+		collection()
+	case cmd.Collections:
+		cmd.CollectionsCommand.Parse(args)
+		collections()
+	case cmd.Strings:
+		cmd.StringsCommand.Parse(args)
+		duplicateStrings()
+	case cmd.DupArrays:
+		cmd.DupArraysCommand.Parse(args)
+		dupArrays()
+	case cmd.Diff:
+		cmd.DiffCommand.Parse(args)
+		diff()
+	case cmd.Serve:
+		cmd.ServeCommand.Parse(args)
+		serve()
 	default:
 		cmd.PrintHelp()
 	}
@@ -32,7 +63,7 @@ func threads() {
 		cmd.PrintUsage(cmd.ThreadsCommand)
 	}
 	flags := cmd.ThreadFlags
-	if err := cmd.ParseHprof(flags.Hprof, flags.NonInteractive); err != nil {
+	if err := cmd.ParseHprof(flags.Hprof, flags.NonInteractive, flags.Jobs, cmd.BasicIndex); err != nil {
 		onError(err)
 	}
 	if err := cmd.GetThreads(flags.Hprof, flags.NoColor, flags.LocalVars, flags.Output); err != nil {
@@ -45,23 +76,163 @@ func summary() {
 		cmd.PrintUsage(cmd.SummaryCommand)
 	}
 	flags := cmd.SummaryFlags
-	if err := cmd.ParseHprof(flags.Hprof, flags.NonInteractive); err != nil {
+	if err := cmd.ParseHprof(flags.Hprof, flags.NonInteractive, flags.Jobs, cmd.BasicIndex); err != nil {
 		onError(err)
 	}
-	if err := cmd.GetSummary(flags.Hprof, flags.NoColor, flags.AllProps, flags.Output); err != nil {
+	if err := cmd.GetSummary(flags.Hprof, flags.NoColor, flags.AllProps, flags.Layout, flags.Output); err != nil {
 		onError(err)
 	}
 }
 
 func objects() {
-	if cmd.ObjectsFlags.Hprof == "" {
+	if cmd.ObjectsFlags.Hprof == "" || cmd.ObjectsFlags.PackageDepth <= 0 || cmd.ObjectsFlags.Top < 0 {
 		cmd.PrintUsage(cmd.ObjectsCommand)
 	}
 	flags := cmd.ObjectsFlags
-	if err := cmd.ParseHprof(flags.Hprof, flags.NonInteractive); err != nil {
+	if flags.Layout != "" && cmd.ObjectsIndex(flags.SortBy) == cmd.DominatorsIndex {
+		// retained sizes are stored in the index for the detected layout
+		onError(heapobjects.ErrLayoutWithRetained)
+	}
+	if err := cmd.ParseHprof(flags.Hprof, flags.NonInteractive, flags.Jobs, cmd.ObjectsIndex(flags.SortBy)); err != nil {
+		onError(err)
+	}
+	if err := cmd.GetObjects(flags.Hprof, flags.NoColor, flags.SortBy, flags.GroupBy, flags.PackageDepth, flags.Filter, flags.Top, flags.Heap, flags.Layout, flags.Output); err != nil {
+		onError(err)
+	}
+}
+
+func referrers() {
+	if cmd.ReferrersFlags.Hprof == "" || cmd.ReferrersFlags.Id == 0 {
+		cmd.PrintUsage(cmd.ReferrersCommand)
+	}
+	flags := cmd.ReferrersFlags
+	if err := cmd.ParseHprof(flags.Hprof, flags.NonInteractive, flags.Jobs, cmd.ReferrersIndex); err != nil {
+		onError(err)
+	}
+	if err := cmd.GetReferrers(flags.Hprof, flags.NoColor, flags.Id, flags.Output); err != nil {
+		onError(err)
+	}
+}
+
+func pathToRoot() {
+	if cmd.PathToRootFlags.Hprof == "" || cmd.PathToRootFlags.Id == 0 || cmd.PathToRootFlags.MaxPaths <= 0 {
+		cmd.PrintUsage(cmd.PathToRootCommand)
+	}
+	flags := cmd.PathToRootFlags
+	if err := cmd.ParseHprof(flags.Hprof, flags.NonInteractive, flags.Jobs, cmd.ReferrersIndex); err != nil {
+		onError(err)
+	}
+	if err := cmd.GetPathsToRoot(flags.Hprof, flags.NoColor, flags.Id, flags.ExcludeWeak, flags.MaxPaths, flags.Output); err != nil {
+		onError(err)
+	}
+}
+
+func leaks() {
+	if cmd.LeaksFlags.Hprof == "" || cmd.LeaksFlags.Threshold <= 0 || cmd.LeaksFlags.Threshold > 100 {
+		cmd.PrintUsage(cmd.LeaksCommand)
+	}
+	flags := cmd.LeaksFlags
+	if err := cmd.ParseHprof(flags.Hprof, flags.NonInteractive, flags.Jobs, cmd.DominatorsIndex); err != nil {
+		onError(err)
+	}
+	if err := cmd.GetLeaks(flags.Hprof, flags.NoColor, flags.Threshold, flags.Output); err != nil {
+		onError(err)
+	}
+}
+
+func query() {
+	if cmd.QueryFlags.Hprof == "" || cmd.QueryFlags.Query == "" {
+		cmd.PrintUsage(cmd.QueryCommand)
+	}
+	flags := cmd.QueryFlags
+	if err := cmd.ParseHprof(flags.Hprof, flags.NonInteractive, flags.Jobs, cmd.BasicIndex); err != nil {
+		onError(err)
+	}
+	if err := cmd.GetQuery(flags.Hprof, flags.NoColor, flags.Query, flags.Output); err != nil {
+		onError(err)
+	}
+}
+
+func collection() {
+	if cmd.CollectionFlags.Hprof == "" || cmd.CollectionFlags.Id == 0 {
+		cmd.PrintUsage(cmd.CollectionCommand)
+	}
+	flags := cmd.CollectionFlags
+	if err := cmd.ParseHprof(flags.Hprof, flags.NonInteractive, flags.Jobs, cmd.BasicIndex); err != nil {
+		onError(err)
+	}
+	if err := cmd.GetCollection(flags.Hprof, flags.NoColor, flags.Id, flags.Output); err != nil {
+		onError(err)
+	}
+}
+
+func collections() {
+	if cmd.CollectionsFlags.Hprof == "" {
+		cmd.PrintUsage(cmd.CollectionsCommand)
+	}
+	flags := cmd.CollectionsFlags
+	if err := cmd.ParseHprof(flags.Hprof, flags.NonInteractive, flags.Jobs, cmd.BasicIndex); err != nil {
+		onError(err)
+	}
+	if err := cmd.GetCollections(flags.Hprof, flags.NoColor, flags.Output); err != nil {
+		onError(err)
+	}
+}
+
+func duplicateStrings() {
+	if cmd.StringsFlags.Hprof == "" || cmd.StringsFlags.Top <= 0 {
+		cmd.PrintUsage(cmd.StringsCommand)
+	}
+	flags := cmd.StringsFlags
+	if err := cmd.ParseHprof(flags.Hprof, flags.NonInteractive, flags.Jobs, cmd.ReferrersIndex); err != nil {
+		onError(err)
+	}
+	if err := cmd.GetStrings(flags.Hprof, flags.NoColor, flags.Top, flags.Output); err != nil {
+		onError(err)
+	}
+}
+
+func dupArrays() {
+	if cmd.DupArraysFlags.Hprof == "" || cmd.DupArraysFlags.Top <= 0 {
+		cmd.PrintUsage(cmd.DupArraysCommand)
+	}
+	flags := cmd.DupArraysFlags
+	if err := cmd.ParseHprof(flags.Hprof, flags.NonInteractive, flags.Jobs, cmd.BasicIndex); err != nil {
+		onError(err)
+	}
+	if err := cmd.GetDupArrays(flags.Hprof, flags.NoColor, flags.Top, flags.Output); err != nil {
+		onError(err)
+	}
+}
+
+func diff() {
+	if cmd.DiffFlags.Base == "" || cmd.DiffFlags.Hprof == "" || cmd.DiffFlags.Top < 0 {
+		cmd.PrintUsage(cmd.DiffCommand)
+	}
+	flags := cmd.DiffFlags
+	index := cmd.BasicIndex
+	if flags.Retained {
+		index = cmd.DominatorsIndex
+	}
+	for _, hprof := range []string{flags.Base, flags.Hprof} {
+		if err := cmd.ParseHprof(hprof, flags.NonInteractive, flags.Jobs, index); err != nil {
+			onError(err)
+		}
+	}
+	if err := cmd.GetDiff(flags.Base, flags.Hprof, flags.NoColor, flags.Filter, flags.Top, flags.ById, flags.Output); err != nil {
+		onError(err)
+	}
+}
+
+func serve() {
+	if cmd.ServeFlags.Hprof == "" || cmd.ServeFlags.Listen == "" {
+		cmd.PrintUsage(cmd.ServeCommand)
+	}
+	flags := cmd.ServeFlags
+	if err := cmd.ParseHprof(flags.Hprof, flags.NonInteractive, flags.Jobs, cmd.ReferrersIndex); err != nil {
 		onError(err)
 	}
-	if err := cmd.GetObjects(flags.Hprof, flags.NoColor, flags.SortBy, flags.Output); err != nil {
+	if err := cmd.StartServer(flags.Hprof, flags.Listen); err != nil {
 		onError(err)
 	}
 }
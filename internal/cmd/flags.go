@@ -4,7 +4,9 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
 
+	"github.com/danielleontiev/neojhat/internal/core"
 	"github.com/danielleontiev/neojhat/internal/objects"
 )
 
@@ -13,49 +15,184 @@ const (
 )
 
 const (
-	Threads = "threads"
-	Summary = "summary"
-	Objects = "objects"
+	Threads     = "threads"
+	Summary     = "summary"
+	Objects     = "objects"
+	Referrers   = "referrers"
+	PathToRoot  = "path-to-root"
+	Leaks       = "leaks"
+	Query       = "query"
+	Collection  = "collection"
+	Collections = "collections"
+	Strings     = "strings"
+	DupArrays   = "dup-arrays"
+	Diff        = "diff"
+	Serve       = "serve"
 )
 
 var (
-	ThreadsCommand = flag.NewFlagSet(Threads, flag.ExitOnError)
-	SummaryCommand = flag.NewFlagSet(Summary, flag.ExitOnError)
-	ObjectsCommand = flag.NewFlagSet(Objects, flag.ExitOnError)
+	ThreadsCommand     = flag.NewFlagSet(Threads, flag.ExitOnError)
+	SummaryCommand     = flag.NewFlagSet(Summary, flag.ExitOnError)
+	ObjectsCommand     = flag.NewFlagSet(Objects, flag.ExitOnError)
+	ReferrersCommand   = flag.NewFlagSet(Referrers, flag.ExitOnError)
+	PathToRootCommand  = flag.NewFlagSet(PathToRoot, flag.ExitOnError)
+	LeaksCommand       = flag.NewFlagSet(Leaks, flag.ExitOnError)
+	QueryCommand       = flag.NewFlagSet(Query, flag.ExitOnError)
+	CollectionCommand  = flag.NewFlagSet(Collection, flag.ExitOnError)
+	CollectionsCommand = flag.NewFlagSet(Collections, flag.ExitOnError)
+	StringsCommand     = flag.NewFlagSet(Strings, flag.ExitOnError)
+	DupArraysCommand   = flag.NewFlagSet(DupArrays, flag.ExitOnError)
+	DiffCommand        = flag.NewFlagSet(Diff, flag.ExitOnError)
+	ServeCommand       = flag.NewFlagSet(Serve, flag.ExitOnError)
 )
 
 func init() {
 	ThreadsCommand.SetOutput(os.Stdout)
 	SummaryCommand.SetOutput(os.Stdout)
 	ObjectsCommand.SetOutput(os.Stdout)
+	ReferrersCommand.SetOutput(os.Stdout)
+	PathToRootCommand.SetOutput(os.Stdout)
+	LeaksCommand.SetOutput(os.Stdout)
+	QueryCommand.SetOutput(os.Stdout)
+	CollectionCommand.SetOutput(os.Stdout)
+	CollectionsCommand.SetOutput(os.Stdout)
+	StringsCommand.SetOutput(os.Stdout)
+	DupArraysCommand.SetOutput(os.Stdout)
+	DiffCommand.SetOutput(os.Stdout)
+	ServeCommand.SetOutput(os.Stdout)
 
 	ThreadsCommand.StringVar(&ThreadFlags.Hprof, hprofName, hprofDefault, hprofDesc)
 	ThreadsCommand.BoolVar(&ThreadFlags.NoColor, noColorName, noColorDefault, noColorDesc)
 	ThreadsCommand.BoolVar(&ThreadFlags.NonInteractive, nonInteractiveName, nonInteractiveDefault, nonInteractiveDesc)
+	ThreadsCommand.IntVar(&ThreadFlags.Jobs, jobsName, jobsDefault, jobsDesc)
 	ThreadsCommand.BoolVar(&ThreadFlags.LocalVars, localVarsName, localVarsDefault, localVarsDesc)
 	ThreadsCommand.Var(&ThreadFlags.Output, outputName, outputDesc)
 
 	SummaryCommand.StringVar(&SummaryFlags.Hprof, hprofName, hprofDefault, hprofDesc)
 	SummaryCommand.BoolVar(&SummaryFlags.NoColor, noColorName, noColorDefault, noColorDesc)
 	SummaryCommand.BoolVar(&SummaryFlags.NonInteractive, nonInteractiveName, nonInteractiveDefault, nonInteractiveDesc)
+	SummaryCommand.IntVar(&SummaryFlags.Jobs, jobsName, jobsDefault, jobsDesc)
 	SummaryCommand.BoolVar(&SummaryFlags.AllProps, allPropsName, allPropsDefault, allPropsDesc)
+	SummaryCommand.Var(&SummaryFlags.Layout, layoutName, layoutDesc)
 	SummaryCommand.Var(&SummaryFlags.Output, outputName, outputDesc)
 
 	ObjectsCommand.StringVar(&ObjectsFlags.Hprof, hprofName, hprofDefault, hprofDesc)
 	ObjectsCommand.BoolVar(&ObjectsFlags.NoColor, noColorName, noColorDefault, noColorDesc)
 	ObjectsCommand.BoolVar(&ObjectsFlags.NonInteractive, nonInteractiveName, nonInteractiveDefault, nonInteractiveDesc)
+	ObjectsCommand.IntVar(&ObjectsFlags.Jobs, jobsName, jobsDefault, jobsDesc)
 	ObjectsCommand.Var(&ObjectsFlags.SortBy, sortByName, sortByDesc)
+	ObjectsCommand.Var(&ObjectsFlags.GroupBy, groupByName, groupByDesc)
+	ObjectsCommand.IntVar(&ObjectsFlags.PackageDepth, packageDepthName, packageDepthDefault, packageDepthDesc)
+	ObjectsCommand.Var(&ObjectsFlags.Filter.Include, includeName, includeDesc)
+	ObjectsCommand.Var(&ObjectsFlags.Filter.Exclude, excludeName, excludeDesc)
+	ObjectsCommand.IntVar(&ObjectsFlags.Top, topName, objectsTopDefault, objectsTopDesc)
+	ObjectsCommand.StringVar(&ObjectsFlags.Heap, heapName, heapDefault, heapDesc)
+	ObjectsCommand.Var(&ObjectsFlags.Layout, layoutName, layoutDesc)
 	ObjectsCommand.Var(&ObjectsFlags.Output, outputName, outputDesc)
+
+	ReferrersCommand.StringVar(&ReferrersFlags.Hprof, hprofName, hprofDefault, hprofDesc)
+	ReferrersCommand.BoolVar(&ReferrersFlags.NoColor, noColorName, noColorDefault, noColorDesc)
+	ReferrersCommand.BoolVar(&ReferrersFlags.NonInteractive, nonInteractiveName, nonInteractiveDefault, nonInteractiveDesc)
+	ReferrersCommand.IntVar(&ReferrersFlags.Jobs, jobsName, jobsDefault, jobsDesc)
+	ReferrersCommand.Var(&ReferrersFlags.Id, idName, idDesc)
+	ReferrersCommand.Var(&ReferrersFlags.Output, outputName, outputDesc)
+
+	PathToRootCommand.StringVar(&PathToRootFlags.Hprof, hprofName, hprofDefault, hprofDesc)
+	PathToRootCommand.BoolVar(&PathToRootFlags.NoColor, noColorName, noColorDefault, noColorDesc)
+	PathToRootCommand.BoolVar(&PathToRootFlags.NonInteractive, nonInteractiveName, nonInteractiveDefault, nonInteractiveDesc)
+	PathToRootCommand.IntVar(&PathToRootFlags.Jobs, jobsName, jobsDefault, jobsDesc)
+	PathToRootCommand.Var(&PathToRootFlags.Id, idName, idDesc)
+	PathToRootCommand.BoolVar(&PathToRootFlags.ExcludeWeak, excludeWeakName, excludeWeakDefault, excludeWeakDesc)
+	PathToRootCommand.IntVar(&PathToRootFlags.MaxPaths, maxPathsName, maxPathsDefault, maxPathsDesc)
+	PathToRootCommand.Var(&PathToRootFlags.Output, outputName, outputDesc)
+
+	LeaksCommand.StringVar(&LeaksFlags.Hprof, hprofName, hprofDefault, hprofDesc)
+	LeaksCommand.BoolVar(&LeaksFlags.NoColor, noColorName, noColorDefault, noColorDesc)
+	LeaksCommand.BoolVar(&LeaksFlags.NonInteractive, nonInteractiveName, nonInteractiveDefault, nonInteractiveDesc)
+	LeaksCommand.IntVar(&LeaksFlags.Jobs, jobsName, jobsDefault, jobsDesc)
+	LeaksCommand.IntVar(&LeaksFlags.Threshold, thresholdName, thresholdDefault, thresholdDesc)
+	LeaksCommand.Var(&LeaksFlags.Output, outputName, outputDesc)
+
+	QueryCommand.StringVar(&QueryFlags.Hprof, hprofName, hprofDefault, hprofDesc)
+	QueryCommand.BoolVar(&QueryFlags.NoColor, noColorName, noColorDefault, noColorDesc)
+	QueryCommand.BoolVar(&QueryFlags.NonInteractive, nonInteractiveName, nonInteractiveDefault, nonInteractiveDesc)
+	QueryCommand.IntVar(&QueryFlags.Jobs, jobsName, jobsDefault, jobsDesc)
+	QueryCommand.StringVar(&QueryFlags.Query, queryName, queryDefault, queryDesc)
+	QueryCommand.Var(&QueryFlags.Output, outputName, outputDesc)
+
+	CollectionCommand.StringVar(&CollectionFlags.Hprof, hprofName, hprofDefault, hprofDesc)
+	CollectionCommand.BoolVar(&CollectionFlags.NoColor, noColorName, noColorDefault, noColorDesc)
+	CollectionCommand.BoolVar(&CollectionFlags.NonInteractive, nonInteractiveName, nonInteractiveDefault, nonInteractiveDesc)
+	CollectionCommand.IntVar(&CollectionFlags.Jobs, jobsName, jobsDefault, jobsDesc)
+	CollectionCommand.Var(&CollectionFlags.Id, idName, idDesc)
+	CollectionCommand.Var(&CollectionFlags.Output, outputName, outputDesc)
+
+	CollectionsCommand.StringVar(&CollectionsFlags.Hprof, hprofName, hprofDefault, hprofDesc)
+	CollectionsCommand.BoolVar(&CollectionsFlags.NoColor, noColorName, noColorDefault, noColorDesc)
+	CollectionsCommand.BoolVar(&CollectionsFlags.NonInteractive, nonInteractiveName, nonInteractiveDefault, nonInteractiveDesc)
+	CollectionsCommand.IntVar(&CollectionsFlags.Jobs, jobsName, jobsDefault, jobsDesc)
+	CollectionsCommand.Var(&CollectionsFlags.Output, outputName, outputDesc)
+
+	StringsCommand.StringVar(&StringsFlags.Hprof, hprofName, hprofDefault, hprofDesc)
+	StringsCommand.BoolVar(&StringsFlags.NoColor, noColorName, noColorDefault, noColorDesc)
+	StringsCommand.BoolVar(&StringsFlags.NonInteractive, nonInteractiveName, nonInteractiveDefault, nonInteractiveDesc)
+	StringsCommand.IntVar(&StringsFlags.Jobs, jobsName, jobsDefault, jobsDesc)
+	StringsCommand.IntVar(&StringsFlags.Top, topName, topDefault, topDesc)
+	StringsCommand.Var(&StringsFlags.Output, outputName, outputDesc)
+
+	DupArraysCommand.StringVar(&DupArraysFlags.Hprof, hprofName, hprofDefault, hprofDesc)
+	DupArraysCommand.BoolVar(&DupArraysFlags.NoColor, noColorName, noColorDefault, noColorDesc)
+	DupArraysCommand.BoolVar(&DupArraysFlags.NonInteractive, nonInteractiveName, nonInteractiveDefault, nonInteractiveDesc)
+	DupArraysCommand.IntVar(&DupArraysFlags.Jobs, jobsName, jobsDefault, jobsDesc)
+	DupArraysCommand.IntVar(&DupArraysFlags.Top, topName, topDefault, topDesc)
+	DupArraysCommand.Var(&DupArraysFlags.Output, outputName, outputDesc)
+
+	DiffCommand.StringVar(&DiffFlags.Base, baseName, baseDefault, baseDesc)
+	DiffCommand.StringVar(&DiffFlags.Hprof, hprofName, hprofDefault, diffHprofDesc)
+	DiffCommand.BoolVar(&DiffFlags.NoColor, noColorName, noColorDefault, noColorDesc)
+	DiffCommand.BoolVar(&DiffFlags.NonInteractive, nonInteractiveName, nonInteractiveDefault, nonInteractiveDesc)
+	DiffCommand.IntVar(&DiffFlags.Jobs, jobsName, jobsDefault, jobsDesc)
+	DiffCommand.BoolVar(&DiffFlags.Retained, retainedName, retainedDefault, retainedDesc)
+	DiffCommand.BoolVar(&DiffFlags.ById, byIdName, byIdDefault, byIdDesc)
+	DiffCommand.Var(&DiffFlags.Filter.Include, includeName, includeDesc)
+	DiffCommand.Var(&DiffFlags.Filter.Exclude, excludeName, excludeDesc)
+	DiffCommand.IntVar(&DiffFlags.Top, topName, objectsTopDefault, diffTopDesc)
+	DiffCommand.Var(&DiffFlags.Output, outputName, outputDesc)
+
+	ServeCommand.StringVar(&ServeFlags.Hprof, hprofName, hprofDefault, hprofDesc)
+	ServeCommand.BoolVar(&ServeFlags.NonInteractive, nonInteractiveName, nonInteractiveDefault, nonInteractiveDesc)
+	ServeCommand.IntVar(&ServeFlags.Jobs, jobsName, jobsDefault, jobsDesc)
+	ServeCommand.StringVar(&ServeFlags.Listen, listenName, listenDefault, listenDesc)
 }
 
 func PrintHelp() {
 	fmt.Printf("neojhat %s\n", version)
-	fmt.Printf("neojhat (%s|%s|%s)\n\n", Threads, Summary, Objects)
+	fmt.Printf("neojhat (%s|%s|%s|%s|%s|%s|%s|%s|%s|%s|%s|%s|%s)\n\n", Threads, Summary, Objects, Referrers, PathToRoot, Leaks, Query, Collection, Collections, Strings, DupArrays, Diff, Serve)
 	ThreadsCommand.Usage()
 	fmt.Println()
 	SummaryCommand.Usage()
 	fmt.Println()
 	ObjectsCommand.Usage()
+	fmt.Println()
+	ReferrersCommand.Usage()
+	fmt.Println()
+	PathToRootCommand.Usage()
+	fmt.Println()
+	LeaksCommand.Usage()
+	fmt.Println()
+	QueryCommand.Usage()
+	fmt.Println()
+	CollectionCommand.Usage()
+	fmt.Println()
+	CollectionsCommand.Usage()
+	fmt.Println()
+	StringsCommand.Usage()
+	fmt.Println()
+	DupArraysCommand.Usage()
+	fmt.Println()
+	DiffCommand.Usage()
+	fmt.Println()
+	ServeCommand.Usage()
 	os.Exit(0)
 }
 
@@ -77,6 +214,10 @@ const (
 	nonInteractiveDefault = false
 	nonInteractiveDesc    = "disable interactive output"
 
+	jobsName    = "jobs"
+	jobsDefault = 0
+	jobsDesc    = "number of heap dump segments parsed at once while indexing, the number of CPUs by default"
+
 	allPropsName    = "all-props"
 	allPropsDefault = false
 	allPropsDesc    = "print all available properties from java.lang.System"
@@ -86,10 +227,76 @@ const (
 	localVarsDesc    = "show local variables"
 
 	sortByName = "sort-by"
-	sortByDesc = "Sort output by 'size' or 'count' (default)"
+	sortByDesc = "Sort output by 'size', 'retained' or 'count' (default)"
+
+	groupByName = "group-by"
+	groupByDesc = "Group classes by 'package', 'classloader' or 'superclass'"
+
+	packageDepthName    = "package-depth"
+	packageDepthDefault = 2
+	packageDepthDesc    = "number of leading parts of the package name to group by, e.g. 2 for 'java.util'"
+
+	includeName = "include"
+	includeDesc = "show only classes matching the pattern, glob (e.g. 'java.util.*') or regular expression in slashes (e.g. '/Map$/'), could be repeated"
+
+	excludeName = "exclude"
+	excludeDesc = "hide classes matching the pattern, glob or regular expression in slashes, could be repeated"
+
+	objectsTopDefault = 0
+	objectsTopDesc    = "number of the first classes (or groups) to show, all by default"
+
+	heapName    = "heap"
+	heapDefault = ""
+	heapDesc    = "show only objects of the given heap of Android heap dump, e.g. 'app'"
+
+	layoutName = "layout"
+	layoutDesc = "JVM options to correct the detected heap layout, e.g. '-XX:-UseCompressedOops,-XX:ObjectAlignmentInBytes=16'"
+
+	idName = "id"
+	idDesc = "object identifier, hex (0x...) or decimal (required)"
+
+	excludeWeakName    = "exclude-weak"
+	excludeWeakDefault = false
+	excludeWeakDesc    = "do not follow referent fields of weak, soft and phantom references"
+
+	maxPathsName    = "max-paths"
+	maxPathsDefault = 10
+	maxPathsDesc    = "maximum number of paths to show"
+
+	thresholdName    = "threshold"
+	thresholdDefault = 10
+	thresholdDesc    = "share of the heap in percents the suspect retains at least"
+
+	topName    = "top"
+	topDefault = 20
+	topDesc    = "number of the biggest groups to show"
+
+	queryName    = "query"
+	queryDefault = ""
+	queryDesc    = "query to run, e.g. \"SELECT @id, size FROM java.util.HashMap WHERE size > 10000\" (required)"
+
+	baseName    = "base"
+	baseDefault = ""
+	baseDesc    = "path to .hprof file to compare with (required)"
+
+	diffHprofDesc = "path to .hprof file to compare (required)"
+
+	retainedName    = "retained"
+	retainedDefault = false
+	retainedDesc    = "build dominator trees to compare retained sizes too, the trees built before are used anyway"
+
+	byIdName    = "by-id"
+	byIdDefault = false
+	byIdDesc    = "match objects by identifiers too to count new and gone ones, makes sense only if the objects are not moved between the dumps"
+
+	diffTopDesc = "number of the fastest growing classes to show, all by default"
+
+	listenName    = "listen"
+	listenDefault = "127.0.0.1:7000"
+	listenDesc    = "address to serve the web UI on"
 
 	outputName = "output"
-	outputDesc = "Output type. 'plain' (default) or 'html'"
+	outputDesc = "Output type. 'plain' (default), 'html', 'json', 'csv' or 'tsv'"
 )
 
 type OutputType int
@@ -97,6 +304,9 @@ type OutputType int
 const (
 	Plain OutputType = iota
 	Html
+	Json
+	Csv
+	Tsv
 )
 
 func (o *OutputType) String() string {
@@ -105,6 +315,12 @@ func (o *OutputType) String() string {
 		return "plain"
 	case Html:
 		return "html"
+	case Json:
+		return "json"
+	case Csv:
+		return "csv"
+	case Tsv:
+		return "tsv"
 	}
 	return "unknown"
 }
@@ -117,17 +333,69 @@ func (o *OutputType) Set(value string) error {
 	case "html":
 		*o = Html
 		return nil
+	case "json":
+		*o = Json
+		return nil
+	case "csv":
+		*o = Csv
+		return nil
+	case "tsv":
+		*o = Tsv
+		return nil
 	case "":
 		*o = Plain
 		return nil
 	}
-	return fmt.Errorf("Use \"plain\" or \"html\" instead")
+	return fmt.Errorf("Use \"plain\", \"html\", \"json\", \"csv\" or \"tsv\" instead")
+}
+
+// comma is the delimiter of the values for delimiter-separated outputs
+func (o *OutputType) comma() rune {
+	if *o == Tsv {
+		return '\t'
+	}
+	return ','
+}
+
+// ObjectId is the identifier of the object in the heap.
+// It's accepted both in hex with 0x prefix and decimal.
+type ObjectId uint64
+
+func (id *ObjectId) String() string {
+	return fmt.Sprintf("0x%x", uint64(*id))
+}
+
+func (id *ObjectId) Set(value string) error {
+	parsed, err := strconv.ParseUint(value, 0, 64)
+	if err != nil {
+		return fmt.Errorf("Use hex (0x7ff0012a8) or decimal number instead")
+	}
+	*id = ObjectId(parsed)
+	return nil
+}
+
+// LayoutOptions are comma-separated JVM options which change
+// the detected heap layout, see core.HeapLayout.Apply.
+type LayoutOptions string
+
+func (l *LayoutOptions) String() string {
+	return string(*l)
+}
+
+func (l *LayoutOptions) Set(value string) error {
+	layout := core.DefaultHeapLayout(8)
+	if err := layout.Apply(value); err != nil {
+		return err
+	}
+	*l = LayoutOptions(value)
+	return nil
 }
 
 type threadFlags struct {
 	Hprof          string
 	NoColor        bool
 	NonInteractive bool
+	Jobs           int
 	LocalVars      bool
 	Output         OutputType
 }
@@ -136,7 +404,9 @@ type summaryFlags struct {
 	Hprof          string
 	NoColor        bool
 	NonInteractive bool
+	Jobs           int
 	AllProps       bool
+	Layout         LayoutOptions
 	Output         OutputType
 }
 
@@ -144,12 +414,122 @@ type objectsFlags struct {
 	Hprof          string
 	NoColor        bool
 	NonInteractive bool
+	Jobs           int
 	SortBy         objects.SortBy
+	GroupBy        objects.GroupBy
+	PackageDepth   int
+	Filter         objects.Filter
+	Top            int
+	Heap           string
+	Layout         LayoutOptions
 	Output         OutputType
 }
 
+type referrersFlags struct {
+	Hprof          string
+	NoColor        bool
+	NonInteractive bool
+	Jobs           int
+	Id             ObjectId
+	Output         OutputType
+}
+
+type pathToRootFlags struct {
+	Hprof          string
+	NoColor        bool
+	NonInteractive bool
+	Jobs           int
+	Id             ObjectId
+	ExcludeWeak    bool
+	MaxPaths       int
+	Output         OutputType
+}
+
+type leaksFlags struct {
+	Hprof          string
+	NoColor        bool
+	NonInteractive bool
+	Jobs           int
+	Threshold      int
+	Output         OutputType
+}
+
+type queryFlags struct {
+	Hprof          string
+	NoColor        bool
+	NonInteractive bool
+	Jobs           int
+	Query          string
+	Output         OutputType
+}
+
+type collectionFlags struct {
+	Hprof          string
+	NoColor        bool
+	NonInteractive bool
+	Jobs           int
+	Id             ObjectId
+	Output         OutputType
+}
+
+type collectionsFlags struct {
+	Hprof          string
+	NoColor        bool
+	NonInteractive bool
+	Jobs           int
+	Output         OutputType
+}
+
+type stringsFlags struct {
+	Hprof          string
+	NoColor        bool
+	NonInteractive bool
+	Jobs           int
+	Top            int
+	Output         OutputType
+}
+
+type dupArraysFlags struct {
+	Hprof          string
+	NoColor        bool
+	NonInteractive bool
+	Jobs           int
+	Top            int
+	Output         OutputType
+}
+
+type diffFlags struct {
+	Base           string
+	Hprof          string
+	NoColor        bool
+	NonInteractive bool
+	Jobs           int
+	Retained       bool
+	ById           bool
+	Filter         objects.Filter
+	Top            int
+	Output         OutputType
+}
+
+type serveFlags struct {
+	Hprof          string
+	NonInteractive bool
+	Jobs           int
+	Listen         string
+}
+
 var (
-	ThreadFlags  threadFlags
-	SummaryFlags summaryFlags
-	ObjectsFlags objectsFlags
+	ThreadFlags      threadFlags
+	SummaryFlags     summaryFlags
+	ObjectsFlags     objectsFlags
+	ReferrersFlags   referrersFlags
+	PathToRootFlags  pathToRootFlags
+	LeaksFlags       leaksFlags
+	QueryFlags       queryFlags
+	CollectionFlags  collectionFlags
+	CollectionsFlags collectionsFlags
+	StringsFlags     stringsFlags
+	DupArraysFlags   dupArraysFlags
+	DiffFlags        diffFlags
+	ServeFlags       serveFlags
 )
@@ -1,12 +1,28 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
 
+	"github.com/danielleontiev/neojhat/internal/collection"
+	"github.com/danielleontiev/neojhat/internal/collections"
+	"github.com/danielleontiev/neojhat/internal/core"
+	"github.com/danielleontiev/neojhat/internal/dominator"
 	"github.com/danielleontiev/neojhat/internal/dump"
+	"github.com/danielleontiev/neojhat/internal/duplicates"
+	"github.com/danielleontiev/neojhat/internal/leaks"
 	"github.com/danielleontiev/neojhat/internal/objects"
 	"github.com/danielleontiev/neojhat/internal/output"
+	"github.com/danielleontiev/neojhat/internal/paths"
+	"github.com/danielleontiev/neojhat/internal/query"
+	"github.com/danielleontiev/neojhat/internal/referrers"
+	"github.com/danielleontiev/neojhat/internal/server"
 	"github.com/danielleontiev/neojhat/internal/storage"
 	"github.com/danielleontiev/neojhat/internal/summary"
 	"github.com/danielleontiev/neojhat/internal/threads"
@@ -23,170 +39,492 @@ const (
 	primArrayDumpIndexFileName = "prim-array-dump.idx.bin"
 	smallRecordsFileName       = "small-records.bin"
 	metaFileName               = "meta.bin"
+	referrersIndexFileName     = "referrers.idx.bin"
+	dominatorsIndexFileName    = "dominators.idx.bin"
+	retainedIndexFileName      = "retained.idx.bin"
+	dominatedIndexFileName     = "dominated.idx.bin"
+	retainedSizesFileName      = "retained-sizes.bin"
+	temporaryFilePattern       = "*.tmp"
+)
+
+// Index is the part of the index required by the command. Every next
+// level includes the previous one and takes more time to build.
+type Index int
+
+const (
+	// BasicIndex is enough for threads, summary and objects
+	BasicIndex Index = iota
+	// ReferrersIndex adds references between objects
+	ReferrersIndex
+	// DominatorsIndex adds the dominator tree and retained sizes
+	DominatorsIndex
 )
 
 func GetThreads(hprofFileName string, noColor, localVars bool, outputType OutputType) error {
-	hprof, err := os.Open(hprofFileName)
+	parsedAccessor, closeAll, err := openParsedAccessor(hprofFileName)
 	if err != nil {
-		return fmt.Errorf("can't open file [%s]: %w", hprofFileName, err)
+		return err
 	}
-	defer hprof.Close()
+	defer closeAll()
+	threadDump, err := threads.GetThreadDump(parsedAccessor)
+	if err != nil {
+		return fmt.Errorf("can't parse thread dump: %w", err)
+	}
+	if outputType == Plain {
+		if noColor {
+			output.ThreadsPlain(threadDump, localVars, os.Stdout)
+			return nil
+		}
+		output.ThreadsPlainColor(threadDump, localVars)
+		return nil
+	}
+	if outputType == Html {
+		return output.ThreadsHtml(threadDump, localVars, os.Stdout)
+	}
+	if outputType == Json {
+		return output.ThreadsJson(threadDump, localVars, os.Stdout)
+	}
+	if outputType == Csv || outputType == Tsv {
+		return output.ThreadsCsv(threadDump, outputType.comma(), os.Stdout)
+	}
+	return fmt.Errorf("unknown output type '%s'", &outputType)
+}
 
-	smallRecordsDumpFile, err := os.Open(hprofFileName + storageDirSuffix + smallRecordsFileName)
+func GetSummary(hprofFileName string, noColor, allProps bool, layout LayoutOptions, outputType OutputType) error {
+	parsedAccessor, closeAll, err := openParsedAccessor(hprofFileName)
 	if err != nil {
 		return err
 	}
-	defer smallRecordsDumpFile.Close()
+	defer closeAll()
+	s, err := summary.GetSummary(parsedAccessor, allProps, string(layout))
+	if err != nil {
+		return fmt.Errorf("can't parse summary: %w", err)
+	}
+	if outputType == Plain {
+		if noColor {
+			output.SummaryPlain(s, os.Stdout)
+			return nil
+		}
+		output.SummaryPlainColor(s)
+		return nil
+	}
+	if outputType == Html {
+		return output.SummaryHtml(s, os.Stdout)
+	}
+	if outputType == Json {
+		return output.SummaryJson(s, os.Stdout)
+	}
+	if outputType == Csv || outputType == Tsv {
+		return output.SummaryCsv(s, outputType.comma(), os.Stdout)
+	}
+	return fmt.Errorf("unknown output type '%s'", &outputType)
+}
 
-	metaDumpFile, err := os.Open(hprofFileName + storageDirSuffix + metaFileName)
+func GetPathsToRoot(hprofFileName string, noColor bool, objectId ObjectId, excludeWeak bool, maxPaths int, outputType OutputType) error {
+	parsedAccessor, closeAll, err := openParsedAccessor(hprofFileName)
 	if err != nil {
 		return err
 	}
-	defer metaDumpFile.Close()
+	defer closeAll()
+	p, err := paths.GetPaths(parsedAccessor, core.Identifier(objectId), excludeWeak, maxPaths)
+	if err != nil {
+		return fmt.Errorf("can't find paths to GC roots: %w", err)
+	}
+	if outputType == Plain {
+		if noColor {
+			output.PathsPlain(p, os.Stdout)
+			return nil
+		}
+		output.PathsPlainColor(p)
+		return nil
+	}
+	if outputType == Html {
+		return output.PathsHtml(p, os.Stdout)
+	}
+	if outputType == Json {
+		return output.PathsJson(p, os.Stdout)
+	}
+	if outputType == Csv || outputType == Tsv {
+		return output.PathsCsv(p, outputType.comma(), os.Stdout)
+	}
+	return fmt.Errorf("unknown output type '%s'", &outputType)
+}
 
-	bigReader, err := createBigReader(hprofFileName)
+func GetLeaks(hprofFileName string, noColor bool, threshold int, outputType OutputType) error {
+	parsedAccessor, closeAll, err := openParsedAccessor(hprofFileName)
 	if err != nil {
 		return err
 	}
-	smallReader, err := createSmallReader(smallRecordsDumpFile)
+	defer closeAll()
+	l, err := leaks.GetLeaks(parsedAccessor, threshold)
 	if err != nil {
-		return err
+		return fmt.Errorf("can't find leak suspects: %w", err)
 	}
-	metaReader, err := createMetaReader(metaDumpFile)
+	if outputType == Plain {
+		if noColor {
+			output.LeaksPlain(l, os.Stdout)
+			return nil
+		}
+		output.LeaksPlainColor(l)
+		return nil
+	}
+	if outputType == Html {
+		return output.LeaksHtml(l, os.Stdout)
+	}
+	if outputType == Json {
+		return output.LeaksJson(l, os.Stdout)
+	}
+	if outputType == Csv || outputType == Tsv {
+		return output.LeaksCsv(l, outputType.comma(), os.Stdout)
+	}
+	return fmt.Errorf("unknown output type '%s'", &outputType)
+}
+
+func GetQuery(hprofFileName string, noColor bool, q string, outputType OutputType) error {
+	parsedAccessor, closeAll, err := openParsedAccessor(hprofFileName)
 	if err != nil {
 		return err
 	}
-	parsedAccessor := dump.NewParsedAccessor(hprof, bigReader, smallReader, metaReader)
-	threadDump, err := threads.GetThreadDump(parsedAccessor)
+	defer closeAll()
+	r, err := query.Run(parsedAccessor, q)
 	if err != nil {
-		return fmt.Errorf("can't parse thread dump: %w", err)
+		return fmt.Errorf("can't run query: %w", err)
 	}
 	if outputType == Plain {
 		if noColor {
-			output.ThreadsPlain(threadDump, localVars, os.Stdout)
+			output.QueryPlain(r, os.Stdout)
 			return nil
 		}
-		output.ThreadsPlainColor(threadDump, localVars)
+		output.QueryPlainColor(r)
 		return nil
 	}
 	if outputType == Html {
-		return output.ThreadsHtml(threadDump, localVars, os.Stdout)
+		return output.QueryHtml(r, os.Stdout)
+	}
+	if outputType == Json {
+		return output.QueryJson(r, os.Stdout)
+	}
+	if outputType == Csv || outputType == Tsv {
+		return output.QueryCsv(r, outputType.comma(), os.Stdout)
 	}
 	return fmt.Errorf("unknown output type '%s'", &outputType)
 }
 
-func GetSummary(hprofFileName string, noColor, allProps bool, outputType OutputType) error {
-	hprof, err := os.Open(hprofFileName)
-	if err != nil {
-		return fmt.Errorf("can't open file [%s]: %w", hprofFileName, err)
+// ObjectsIndex returns the index required by objects command,
+// sorting by retained size needs the dominator tree.
+func ObjectsIndex(sortBy objects.SortBy) Index {
+	if sortBy == objects.Retained {
+		return DominatorsIndex
 	}
-	defer hprof.Close()
+	return BasicIndex
+}
 
-	smallRecordsDumpFile, err := os.Open(hprofFileName + storageDirSuffix + smallRecordsFileName)
+func GetObjects(hprofFileName string, noColor bool, sortBy objects.SortBy, groupBy objects.GroupBy, packageDepth int, filter objects.Filter, top int, heap string, layout LayoutOptions, outputType OutputType) error {
+	parsedAccessor, closeAll, err := openParsedAccessor(hprofFileName)
 	if err != nil {
 		return err
 	}
-	defer smallRecordsDumpFile.Close()
-
-	metaDumpFile, err := os.Open(hprofFileName + storageDirSuffix + metaFileName)
+	defer closeAll()
+	obj, err := objects.GetObjects(parsedAccessor, sortBy, groupBy, packageDepth, filter, top, heap, string(layout))
 	if err != nil {
-		return err
+		return fmt.Errorf("can't parse objects: %w", err)
+	}
+	if outputType == Plain {
+		if noColor {
+			output.ObjectsPlain(obj, os.Stdout)
+			return nil
+		}
+		output.ObjectsPlainColor(obj)
+		return nil
 	}
-	defer metaDumpFile.Close()
+	if outputType == Html {
+		return output.ObjectsHtml(obj, os.Stdout)
+	}
+	if outputType == Json {
+		return output.ObjectsJson(obj, os.Stdout)
+	}
+	if outputType == Csv || outputType == Tsv {
+		return output.ObjectsCsv(obj, outputType.comma(), os.Stdout)
+	}
+	return fmt.Errorf("unknown output type '%s'", &outputType)
+}
 
-	bigReader, err := createBigReader(hprofFileName)
+func GetReferrers(hprofFileName string, noColor bool, objectId ObjectId, outputType OutputType) error {
+	parsedAccessor, closeAll, err := openParsedAccessor(hprofFileName)
 	if err != nil {
 		return err
 	}
-	smallReader, err := createSmallReader(smallRecordsDumpFile)
+	defer closeAll()
+	r, err := referrers.GetReferrers(parsedAccessor, core.Identifier(objectId))
 	if err != nil {
-		return err
+		return fmt.Errorf("can't get referrers: %w", err)
 	}
-	metaReader, err := createMetaReader(metaDumpFile)
+	if outputType == Plain {
+		if noColor {
+			output.ReferrersPlain(r, os.Stdout)
+			return nil
+		}
+		output.ReferrersPlainColor(r)
+		return nil
+	}
+	if outputType == Html {
+		return output.ReferrersHtml(r, os.Stdout)
+	}
+	if outputType == Json {
+		return output.ReferrersJson(r, os.Stdout)
+	}
+	if outputType == Csv || outputType == Tsv {
+		return output.ReferrersCsv(r, outputType.comma(), os.Stdout)
+	}
+	return fmt.Errorf("unknown output type '%s'", &outputType)
+}
+
+func GetCollection(hprofFileName string, noColor bool, objectId ObjectId, outputType OutputType) error {
+	parsedAccessor, closeAll, err := openParsedAccessor(hprofFileName)
 	if err != nil {
 		return err
 	}
-	parsedAccessor := dump.NewParsedAccessor(hprof, bigReader, smallReader, metaReader)
-	s, err := summary.GetSummary(parsedAccessor, allProps)
+	defer closeAll()
+	c, err := collection.GetCollection(parsedAccessor, core.Identifier(objectId))
 	if err != nil {
-		return fmt.Errorf("can't parse summary: %w", err)
+		return fmt.Errorf("can't get collection: %w", err)
 	}
 	if outputType == Plain {
 		if noColor {
-			output.SummaryPlain(s, os.Stdout)
+			output.CollectionPlain(c, os.Stdout)
 			return nil
 		}
-		output.SummaryPlainColor(s)
+		output.CollectionPlainColor(c)
 		return nil
 	}
 	if outputType == Html {
-		return output.SummaryHtml(s, os.Stdout)
+		return output.CollectionHtml(c, os.Stdout)
+	}
+	if outputType == Json {
+		return output.CollectionJson(c, os.Stdout)
+	}
+	if outputType == Csv || outputType == Tsv {
+		return output.CollectionCsv(c, outputType.comma(), os.Stdout)
 	}
 	return fmt.Errorf("unknown output type '%s'", &outputType)
 }
 
-func GetObjects(hprofFileName string, noColor bool, sortBy objects.SortBy, outputType OutputType) error {
-	hprof, err := os.Open(hprofFileName)
+// GetCollections sorts the maps backing HashSets in temporary
+// files next to the index, they are removed afterwards.
+func GetCollections(hprofFileName string, noColor bool, outputType OutputType) error {
+	parsedAccessor, closeAll, err := openParsedAccessor(hprofFileName)
 	if err != nil {
-		return fmt.Errorf("can't open file [%s]: %w", hprofFileName, err)
+		return err
 	}
-	defer hprof.Close()
-
-	smallRecordsDumpFile, err := os.Open(hprofFileName + storageDirSuffix + smallRecordsFileName)
+	defer closeAll()
+	newRun := func() (storage.RunVolume, error) {
+		return createTemporaryFile(hprofFileName + storageDirSuffix)
+	}
+	c, err := collections.GetCollections(parsedAccessor, newRun)
 	if err != nil {
-		return err
+		return fmt.Errorf("can't get collections: %w", err)
+	}
+	if outputType == Plain {
+		if noColor {
+			output.CollectionsPlain(c, os.Stdout)
+			return nil
+		}
+		output.CollectionsPlainColor(c)
+		return nil
+	}
+	if outputType == Html {
+		return output.CollectionsHtml(c, os.Stdout)
 	}
-	defer smallRecordsDumpFile.Close()
+	if outputType == Json {
+		return output.CollectionsJson(c, os.Stdout)
+	}
+	if outputType == Csv || outputType == Tsv {
+		return output.CollectionsCsv(c, outputType.comma(), os.Stdout)
+	}
+	return fmt.Errorf("unknown output type '%s'", &outputType)
+}
 
-	metaDumpFile, err := os.Open(hprofFileName + storageDirSuffix + metaFileName)
+// GetStrings sorts the hashes of strings in temporary
+// files next to the index, they are removed afterwards.
+func GetStrings(hprofFileName string, noColor bool, top int, outputType OutputType) error {
+	parsedAccessor, closeAll, err := openParsedAccessor(hprofFileName)
 	if err != nil {
 		return err
 	}
-	defer metaDumpFile.Close()
+	defer closeAll()
+	newRun := func() (storage.RunVolume, error) {
+		return createTemporaryFile(hprofFileName + storageDirSuffix)
+	}
+	s, err := duplicates.GetDuplicateStrings(parsedAccessor, top, newRun)
+	if err != nil {
+		return fmt.Errorf("can't get duplicate strings: %w", err)
+	}
+	if outputType == Plain {
+		if noColor {
+			output.StringsPlain(s, os.Stdout)
+			return nil
+		}
+		output.StringsPlainColor(s)
+		return nil
+	}
+	if outputType == Html {
+		return output.StringsHtml(s, os.Stdout)
+	}
+	if outputType == Json {
+		return output.StringsJson(s, os.Stdout)
+	}
+	if outputType == Csv || outputType == Tsv {
+		return output.StringsCsv(s, outputType.comma(), os.Stdout)
+	}
+	return fmt.Errorf("unknown output type '%s'", &outputType)
+}
 
-	bigReader, err := createBigReader(hprofFileName)
+// GetDupArrays sorts the hashes of arrays in temporary
+// files next to the index, they are removed afterwards.
+func GetDupArrays(hprofFileName string, noColor bool, top int, outputType OutputType) error {
+	parsedAccessor, closeAll, err := openParsedAccessor(hprofFileName)
 	if err != nil {
 		return err
 	}
-	smallReader, err := createSmallReader(smallRecordsDumpFile)
+	defer closeAll()
+	newRun := func() (storage.RunVolume, error) {
+		return createTemporaryFile(hprofFileName + storageDirSuffix)
+	}
+	a, err := duplicates.GetDuplicateArrays(parsedAccessor, top, newRun)
+	if err != nil {
+		return fmt.Errorf("can't get duplicate arrays: %w", err)
+	}
+	if outputType == Plain {
+		if noColor {
+			output.DupArraysPlain(a, os.Stdout)
+			return nil
+		}
+		output.DupArraysPlainColor(a)
+		return nil
+	}
+	if outputType == Html {
+		return output.DupArraysHtml(a, os.Stdout)
+	}
+	if outputType == Json {
+		return output.DupArraysJson(a, os.Stdout)
+	}
+	if outputType == Csv || outputType == Tsv {
+		return output.DupArraysCsv(a, outputType.comma(), os.Stdout)
+	}
+	return fmt.Errorf("unknown output type '%s'", &outputType)
+}
+
+// GetDiff compares the objects of hprofFileName with the objects of
+// baseFileName, both dumps should be parsed before.
+func GetDiff(baseFileName, hprofFileName string, noColor bool, filter objects.Filter, top int, byId bool, outputType OutputType) error {
+	base, closeBase, err := openParsedAccessor(baseFileName)
 	if err != nil {
 		return err
 	}
-	metaReader, err := createMetaReader(metaDumpFile)
+	defer closeBase()
+	current, closeCurrent, err := openParsedAccessor(hprofFileName)
 	if err != nil {
 		return err
 	}
-	parsedAccessor := dump.NewParsedAccessor(hprof, bigReader, smallReader, metaReader)
-	obj, err := objects.GetObjects(parsedAccessor, sortBy)
+	defer closeCurrent()
+	d, err := objects.GetDiff(base, current, filter, top, byId)
 	if err != nil {
-		return fmt.Errorf("can't parse objects: %w", err)
+		return fmt.Errorf("can't compare heap dumps: %w", err)
 	}
 	if outputType == Plain {
 		if noColor {
-			output.ObjectsPlain(obj, os.Stdout)
+			output.DiffPlain(d, os.Stdout)
 			return nil
 		}
-		output.ObjectsPlainColor(obj)
+		output.DiffPlainColor(d)
 		return nil
 	}
 	if outputType == Html {
-		return output.ObjectsHtml(obj, os.Stdout)
+		return output.DiffHtml(d, os.Stdout)
+	}
+	if outputType == Json {
+		return output.DiffJson(d, os.Stdout)
+	}
+	if outputType == Csv || outputType == Tsv {
+		return output.DiffCsv(d, outputType.comma(), os.Stdout)
 	}
 	return fmt.Errorf("unknown output type '%s'", &outputType)
 }
 
-func ParseHprof(hprofFileName string, nonInteractive bool) error {
+// StartServer keeps the heap dump open and serves the web UI to browse it
+// on the given address until the process is interrupted. The objects
+// sorted by class are kept in temporary files next to the index, they
+// are removed on interrupt.
+func StartServer(hprofFileName, listen string) error {
+	parsedAccessor, closeAll, err := openParsedAccessor(hprofFileName)
+	if err != nil {
+		return err
+	}
+	defer closeAll()
+	newRun := func() (storage.RunVolume, error) {
+		return createTemporaryFile(hprofFileName + storageDirSuffix)
+	}
+	handler, err := server.New(parsedAccessor, newRun)
+	if err != nil {
+		return fmt.Errorf("can't start server: %w", err)
+	}
+	defer handler.Close()
+	httpServer := &http.Server{Addr: listen, Handler: handler}
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupted)
+	go func() {
+		<-interrupted
+		httpServer.Close()
+	}()
+	fmt.Printf("Serving %s on http://%s\n", hprofFileName, listen)
+	if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// ParseHprof creates the index of the .hprof file if it does not exist yet.
+// Index of references between objects is built only when it is required
+// because it makes parsing noticeably slower. If existing index lacks
+// references but they are requested, the index is rebuilt from scratch.
+// The dominator tree is built on top of the existing index when needed.
+// Segments of the dump are parsed by the given number of goroutines, the
+// number of CPUs is used when jobs is not positive.
+func ParseHprof(hprofFileName string, nonInteractive bool, jobs int, index Index) error {
+	if jobs <= 0 {
+		jobs = runtime.NumCPU()
+	}
+	if err := parseHprof(hprofFileName, nonInteractive, jobs, index >= ReferrersIndex); err != nil {
+		return err
+	}
+	if index < DominatorsIndex || fileExists(hprofFileName+storageDirSuffix+retainedSizesFileName) {
+		return nil
+	}
+	return buildDominators(hprofFileName, nonInteractive)
+}
+
+func parseHprof(hprofFileName string, nonInteractive bool, jobs int, withReferrers bool) error {
 	hprof, err := os.Open(hprofFileName)
 	if err != nil {
 		return fmt.Errorf("can't open file [%s]: %w", hprofFileName, err)
 	}
 	defer hprof.Close()
 
-	if err = os.Mkdir(hprofFileName+storageDirSuffix, os.ModePerm); err != nil {
-		if os.IsExist(err) {
+	storageDir := hprofFileName + storageDirSuffix
+	if err = os.Mkdir(storageDir, os.ModePerm); err != nil {
+		if !os.IsExist(err) {
+			return fmt.Errorf("can't create index: %w", err)
+		}
+		if !withReferrers || fileExists(storageDir+referrersIndexFileName) {
 			return nil
 		}
-		return fmt.Errorf("can't create index: %w", err)
+		if err := os.RemoveAll(storageDir); err != nil {
+			return fmt.Errorf("can't remove index without referrers: %w", err)
+		}
+		if err := os.Mkdir(storageDir, os.ModePerm); err != nil {
+			return fmt.Errorf("can't create index: %w", err)
+		}
 	}
 
 	stat, err := hprof.Stat()
@@ -209,7 +547,17 @@ func ParseHprof(hprofFileName string, nonInteractive bool) error {
 	}
 	bigWriter := storage.NewBigRecordsWriteStorage(instanceDumpIndexFile, objArrayDumpIndexFile, primArrayDumpIndexFile)
 	metaWriter := storage.NewMetaWriteStorage()
-	parser := dump.NewParser(hprof, smallWriter, bigWriter, metaWriter)
+	newRun := func() (storage.RunVolume, error) {
+		return createTemporaryFile(storageDir)
+	}
+	parser := dump.NewParallelParser(hprof, stat.Size(), jobs, newRun, smallWriter, bigWriter, metaWriter)
+	if withReferrers {
+		referrersIndexFile, err := os.OpenFile(storageDir+referrersIndexFileName, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
+		if err != nil {
+			return err
+		}
+		parser.WithReferences(storage.NewReferencesWriteStorage(referrersIndexFile, newRun, storage.DefaultBatchSize))
+	}
 	cancel := interactive(progressBar(int(stat.Size()), parser.GetPosition, "Parsing"), nonInteractive)
 	if err := parser.ParseHeapDump(); err != nil {
 		return fmt.Errorf("can't create index: %w", err)
@@ -235,6 +583,131 @@ func ParseHprof(hprofFileName string, nonInteractive bool) error {
 	return nil
 }
 
+// buildDominators computes the dominator tree from the existing index.
+// Retained sizes by classes are written last, so their presence marks
+// the complete dominator tree. Partially written files are removed on error.
+func buildDominators(hprofFileName string, nonInteractive bool) error {
+	storageDir := hprofFileName + storageDirSuffix
+	fileNames := []string{dominatorsIndexFileName, retainedIndexFileName, dominatedIndexFileName, retainedSizesFileName}
+	if err := writeDominators(hprofFileName, nonInteractive); err != nil {
+		for _, name := range fileNames {
+			os.Remove(storageDir + name)
+		}
+		return fmt.Errorf("can't build dominator tree: %w", err)
+	}
+	return nil
+}
+
+func writeDominators(hprofFileName string, nonInteractive bool) error {
+	storageDir := hprofFileName + storageDirSuffix
+	parsedAccessor, closeAll, err := openParsedAccessor(hprofFileName)
+	if err != nil {
+		return err
+	}
+	defer closeAll()
+	var files []*os.File
+	for _, name := range []string{dominatorsIndexFileName, retainedIndexFileName, dominatedIndexFileName} {
+		file, err := os.OpenFile(storageDir+name, os.O_TRUNC|os.O_WRONLY|os.O_CREATE, 0600)
+		if err != nil {
+			for _, f := range files {
+				f.Close()
+			}
+			return err
+		}
+		files = append(files, file)
+	}
+	newRun := func() (storage.RunVolume, error) {
+		return createTemporaryFile(storageDir)
+	}
+	newArray := func() (storage.ArrayVolume, error) {
+		return createTemporaryFile(storageDir)
+	}
+	dominatorsWriter := storage.NewDominatorsWriteStorage(files[0], files[1], files[2], newRun)
+	builder := dominator.NewBuilder(parsedAccessor, newRun, newArray)
+	cancel := interactive(progressBar(1000, builder.GetPosition, "Dominators"), nonInteractive)
+	if err := builder.Build(dominatorsWriter); err != nil {
+		return err
+	}
+	cancel()
+
+	retainedSizesFile, err := os.OpenFile(storageDir+retainedSizesFileName, os.O_TRUNC|os.O_WRONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	if err := dominatorsWriter.SerializeTo(retainedSizesFile); err != nil {
+		retainedSizesFile.Close()
+		return fmt.Errorf("can't write retained sizes: %w", err)
+	}
+	return retainedSizesFile.Close()
+}
+
+// openParsedAccessor opens all the files of the index. Returned function
+// closes them and should be called when parsed accessor is not needed.
+func openParsedAccessor(hprofFileName string) (*dump.ParsedAccessor, func(), error) {
+	var closers []io.Closer
+	closeAll := func() {
+		for _, c := range closers {
+			c.Close()
+		}
+	}
+	hprof, err := os.Open(hprofFileName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't open file [%s]: %w", hprofFileName, err)
+	}
+	closers = append(closers, hprof)
+
+	smallRecordsDumpFile, err := os.Open(hprofFileName + storageDirSuffix + smallRecordsFileName)
+	if err != nil {
+		closeAll()
+		return nil, nil, err
+	}
+	closers = append(closers, smallRecordsDumpFile)
+
+	metaDumpFile, err := os.Open(hprofFileName + storageDirSuffix + metaFileName)
+	if err != nil {
+		closeAll()
+		return nil, nil, err
+	}
+	closers = append(closers, metaDumpFile)
+
+	bigReader, err := createBigReader(hprofFileName)
+	if err != nil {
+		closeAll()
+		return nil, nil, err
+	}
+	closers = append(closers, bigReader)
+	smallReader, err := createSmallReader(smallRecordsDumpFile)
+	if err != nil {
+		closeAll()
+		return nil, nil, err
+	}
+	metaReader, err := createMetaReader(metaDumpFile)
+	if err != nil {
+		closeAll()
+		return nil, nil, err
+	}
+	parsedAccessor := dump.NewParsedAccessor(hprof, bigReader, smallReader, metaReader)
+	if fileExists(hprofFileName + storageDirSuffix + referrersIndexFileName) {
+		referencesReader, err := createReferencesReader(hprofFileName)
+		if err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+		closers = append(closers, referencesReader)
+		parsedAccessor.WithReferences(referencesReader)
+	}
+	if fileExists(hprofFileName + storageDirSuffix + retainedSizesFileName) {
+		dominatorsReader, err := createDominatorsReader(hprofFileName)
+		if err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+		closers = append(closers, dominatorsReader)
+		parsedAccessor.WithDominators(dominatorsReader)
+	}
+	return parsedAccessor, closeAll, nil
+}
+
 func createBigReader(hprofFileName string) (*storage.BigRecordsReadStorage, error) {
 	instanceDumpFile, err := os.Open(hprofFileName + storageDirSuffix + instanceDumpIndexFileName)
 	if err != nil {
@@ -286,3 +759,75 @@ func createMetaReader(metaReadStorageFile *os.File) (*storage.MetaReadStorage, e
 	}
 	return metaReader, nil
 }
+
+func createReferencesReader(hprofFileName string) (*storage.ReferencesReadStorage, error) {
+	referrersIndexFile, err := os.Open(hprofFileName + storageDirSuffix + referrersIndexFileName)
+	if err != nil {
+		return nil, err
+	}
+	referrersIndexFileStat, err := referrersIndexFile.Stat()
+	if err != nil {
+		return nil, err
+	}
+	referencesReader, err := storage.NewReferencesReadStorage(referrersIndexFile, int(referrersIndexFileStat.Size()))
+	if err != nil {
+		return nil, fmt.Errorf("can't create references reader: %w", err)
+	}
+	return referencesReader, nil
+}
+
+func createDominatorsReader(hprofFileName string) (*storage.DominatorsReadStorage, error) {
+	var files []*os.File
+	var sizes []int
+	for _, name := range []string{dominatorsIndexFileName, retainedIndexFileName, dominatedIndexFileName} {
+		file, err := os.Open(hprofFileName + storageDirSuffix + name)
+		if err != nil {
+			return nil, err
+		}
+		stat, err := file.Stat()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+		sizes = append(sizes, int(stat.Size()))
+	}
+	dominatorsReader, err := storage.NewDominatorsReadStorage(files[0], sizes[0], files[1], sizes[1], files[2], sizes[2])
+	if err != nil {
+		return nil, fmt.Errorf("can't create dominators reader: %w", err)
+	}
+	retainedSizesFile, err := os.Open(hprofFileName + storageDirSuffix + retainedSizesFileName)
+	if err != nil {
+		return nil, err
+	}
+	defer retainedSizesFile.Close()
+	if err := dominatorsReader.RestoreFrom(retainedSizesFile); err != nil {
+		return nil, err
+	}
+	return dominatorsReader, nil
+}
+
+// temporaryFile is the scratch space for sorted
+// runs and paged arrays which is removed when closed.
+type temporaryFile struct {
+	*os.File
+}
+
+func createTemporaryFile(dir string) (*temporaryFile, error) {
+	file, err := os.CreateTemp(dir, temporaryFilePattern)
+	if err != nil {
+		return nil, fmt.Errorf("can't create temporary file: %w", err)
+	}
+	return &temporaryFile{File: file}, nil
+}
+
+func (f *temporaryFile) Close() error {
+	if err := f.File.Close(); err != nil {
+		return err
+	}
+	return os.Remove(f.Name())
+}
+
+func fileExists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
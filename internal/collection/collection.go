@@ -0,0 +1,103 @@
+// collection shows the content of JDK collections and maps. Elements
+// are read with java.Heap.ParseCollection, strings and boxed primitives
+// are shown as values, other objects - as the class name and identifier
+// to inspect them further.
+package collection
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/danielleontiev/neojhat/internal/core"
+	"github.com/danielleontiev/neojhat/internal/dump"
+	"github.com/danielleontiev/neojhat/internal/format"
+	"github.com/danielleontiev/neojhat/internal/java"
+)
+
+var boxedClasses = map[string]bool{
+	"java/lang/Boolean":   true,
+	"java/lang/Byte":      true,
+	"java/lang/Character": true,
+	"java/lang/Short":     true,
+	"java/lang/Integer":   true,
+	"java/lang/Long":      true,
+	"java/lang/Float":     true,
+	"java/lang/Double":    true,
+}
+
+func GetCollection(parsedAccessor *dump.ParsedAccessor, objectId core.Identifier) (Collection, error) {
+	heap := java.NewHeap(parsedAccessor)
+	c, err := heap.ParseCollection(objectId)
+	if err != nil {
+		return Collection{}, err
+	}
+	var elements []Element
+	for _, entry := range c.Entries {
+		var key string
+		if c.Kind == java.MapCollection {
+			if key, err = render(heap, entry.Key); err != nil {
+				return Collection{}, err
+			}
+		}
+		value, err := render(heap, entry.Value)
+		if err != nil {
+			return Collection{}, err
+		}
+		elements = append(elements, Element{Key: key, Value: value})
+	}
+	return Collection{
+		ObjectId:  objectId,
+		ClassName: c.ClassName,
+		Kind:      c.Kind,
+		Elements:  elements,
+	}, nil
+}
+
+// render shows strings in quotes to tell them from
+// other values, null and primitives are shown as is.
+func render(heap *java.Heap, value core.JavaValue) (string, error) {
+	if value.Type != core.Object {
+		return renderPrimitive(value), nil
+	}
+	id, err := value.ToObject()
+	if err != nil {
+		return "", err
+	}
+	if id == 0 {
+		return "null", nil
+	}
+	object, err := heap.ParseNormalObject(id)
+	if err != nil {
+		// arrays and objects missing in the dump
+		className, err := heap.ObjectTypeName(id)
+		if err != nil {
+			return format.ObjectId(uint64(id)), nil
+		}
+		return format.ClassName(className) + " " + format.ObjectId(uint64(id)), nil
+	}
+	switch {
+	case object.Class.Name == "java/lang/String":
+		s, err := heap.ParseJavaString(value)
+		if err != nil {
+			return "", err
+		}
+		return strconv.Quote(s), nil
+	case boxedClasses[object.Class.Name]:
+		boxed, err := object.GetFieldValueByName("value")
+		if err != nil {
+			return "", fmt.Errorf("error reading value of %s: %w", format.ClassName(object.Class.Name), err)
+		}
+		return renderPrimitive(boxed.Value), nil
+	}
+	return format.ClassName(object.Class.Name) + " " + format.ObjectId(uint64(id)), nil
+}
+
+func renderPrimitive(value core.JavaValue) string {
+	if value.Type == core.Char {
+		// char is kept as two raw bytes of UTF-16 code unit
+		if c, ok := value.Value.(string); ok && len(c) == 2 {
+			return strconv.QuoteRune(rune(c[0])<<8 | rune(c[1]))
+		}
+	}
+	return fmt.Sprint(value.Value)
+}
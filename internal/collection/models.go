@@ -0,0 +1,20 @@
+package collection
+
+import (
+	"github.com/danielleontiev/neojhat/internal/core"
+	"github.com/danielleontiev/neojhat/internal/java"
+)
+
+// Element is the entry of the collection with the key and the value
+// rendered to strings. Key is empty for lists and sets.
+type Element struct {
+	Key   string `json:"key,omitempty"`
+	Value string `json:"value"`
+}
+
+type Collection struct {
+	ObjectId  core.Identifier     `json:"objectId"`
+	ClassName string              `json:"className"`
+	Kind      java.CollectionKind `json:"kind"`
+	Elements  []Element           `json:"elements"`
+}
@@ -0,0 +1,173 @@
+// collections reports how well the most used JDK collections fill their
+// backing arrays. Hash maps and array lists grow their arrays ahead of
+// time and never shrink them, so a lot of memory can be held by empty
+// slots of oversized or emptied collections. Every slot is a reference,
+// so unused slots waste the size of the reference of the heap layout each.
+//
+// HashSet is backed by HashMap which is the instance of the reported class
+// too. Such maps are not counted as HashMap, so the waste is not counted
+// twice and the HashMap row shows only the maps used by the application.
+// Dumps can have millions of sets, so the identifiers of their maps are
+// sorted on disk and looked up there instead of being kept in memory.
+package collections
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/danielleontiev/neojhat/internal/core"
+	"github.com/danielleontiev/neojhat/internal/dump"
+	"github.com/danielleontiev/neojhat/internal/java"
+	"github.com/danielleontiev/neojhat/internal/storage"
+)
+
+// reportedClasses are the collection classes in the report, instances
+// of their subclasses (e.g. LinkedHashMap) are not counted.
+var reportedClasses = []string{
+	"java/util/HashMap",
+	"java/util/ArrayList",
+	"java/util/HashSet",
+	"java/util/concurrent/ConcurrentHashMap",
+	"java/util/ArrayDeque",
+}
+
+// GetCollections scans the instances of reportedClasses and returns
+// the statistics ordered by wasted size. HashSets are scanned first
+// to find the maps backing them, newRun provides temporary storage
+// for their identifiers.
+func GetCollections(parsedAccessor *dump.ParsedAccessor, newRun func() (storage.RunVolume, error)) (Collections, error) {
+	heap := java.NewHeap(parsedAccessor)
+	reported := make(map[string]bool)
+	for _, className := range reportedClasses {
+		reported[className] = true
+	}
+	classes := make(map[core.Identifier]bool)
+	hashSetClasses := make(map[core.Identifier]bool)
+	for _, loadClass := range parsedAccessor.ListHprofLoadClass() {
+		class, err := heap.ParseClass(loadClass.ClassObjectId)
+		if err != nil {
+			// loaded classes without class dump have no instances
+			continue
+		}
+		if reported[class.Name] {
+			classes[loadClass.ClassObjectId] = true
+		}
+		if class.Name == "java/util/HashSet" {
+			hashSetClasses[loadClass.ClassObjectId] = true
+		}
+	}
+
+	setMaps, err := sortSetMaps(heap, hashSetClasses, newRun)
+	if err != nil {
+		return Collections{}, err
+	}
+	defer setMaps.Close()
+
+	type stats struct {
+		ClassStats
+		filled    int
+		fillRatio float64
+	}
+	byClass := make(map[string]*stats)
+	size, err := heap.SizeInfo("")
+	if err != nil {
+		return Collections{}, err
+	}
+	slotSize := size.OfReference()
+	accept := func(classId core.Identifier) bool { return classes[classId] }
+	err = heap.ScanInstances(accept, func(objectId core.Identifier, object java.NormalObject) error {
+		backsSet, err := contains(setMaps, objectId)
+		if err != nil {
+			return err
+		}
+		if backsSet {
+			return nil
+		}
+		fill, err := heap.CollectionFill(object)
+		if err != nil {
+			return fmt.Errorf("cannot read size of collection %v: %w", objectId, err)
+		}
+		s, ok := byClass[object.Class.Name]
+		if !ok {
+			s = &stats{ClassStats: ClassStats{ClassName: object.Class.Name, Histogram: make([]int, len(SizeRanges))}}
+			byClass[object.Class.Name] = s
+		}
+		s.Instances++
+		if fill.Size <= 0 {
+			s.Empty++
+		}
+		for i, r := range SizeRanges {
+			if fill.Size >= r.Min && (r.Max == 0 || fill.Size <= r.Max) {
+				s.Histogram[i]++
+				break
+			}
+		}
+		if fill.Capacity > 0 {
+			s.filled++
+			s.fillRatio += float64(min(max(fill.Size, 0), fill.Capacity)) / float64(fill.Capacity)
+			s.WastedSize += max(fill.Capacity-fill.Size, 0) * slotSize
+		}
+		return nil
+	})
+	if err != nil {
+		return Collections{}, err
+	}
+
+	var result Collections
+	for _, s := range byClass {
+		if s.filled > 0 {
+			s.FillRatio = s.fillRatio / float64(s.filled)
+		}
+		result.Classes = append(result.Classes, s.ClassStats)
+		result.WastedSize += s.WastedSize
+	}
+	sort.Slice(result.Classes, func(i, j int) bool {
+		if result.Classes[i].WastedSize != result.Classes[j].WastedSize {
+			return result.Classes[i].WastedSize > result.Classes[j].WastedSize
+		}
+		return result.Classes[i].ClassName < result.Classes[j].ClassName
+	})
+	return result, nil
+}
+
+// sortSetMaps returns the index of the maps backing HashSets,
+// the values are the identifiers of the sets.
+func sortSetMaps(heap *java.Heap, hashSetClasses map[core.Identifier]bool, newRun func() (storage.RunVolume, error)) (*storage.IndexRecordsReadStorage, error) {
+	setMaps := storage.NewTempIndex(newRun)
+	writer := storage.NewSortingIndexWriteStorage(setMaps, newRun, storage.DefaultBatchSize)
+	accept := func(classId core.Identifier) bool { return hashSetClasses[classId] }
+	err := heap.ScanInstances(accept, func(objectId core.Identifier, object java.NormalObject) error {
+		m, err := object.GetFieldValueByName("map")
+		if err != nil {
+			return fmt.Errorf("cannot read map of HashSet %v: %w", objectId, err)
+		}
+		id, err := m.Value.ToObject()
+		if err != nil {
+			return err
+		}
+		return writer.Put(uint64(id), uint64(objectId))
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("cannot sort maps of HashSets: %w", err)
+	}
+	return setMaps.Reader()
+}
+
+// contains reports whether the index has the record with the given key.
+func contains(index *storage.IndexRecordsReadStorage, objectId core.Identifier) (bool, error) {
+	position, err := index.Find(uint64(objectId))
+	if err != nil {
+		return false, err
+	}
+	if position == index.Len() {
+		return false, nil
+	}
+	key, _, err := index.At(position)
+	if err != nil {
+		return false, err
+	}
+	return key == uint64(objectId), nil
+}
@@ -0,0 +1,59 @@
+package collections
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/danielleontiev/neojhat/internal/core"
+	td "github.com/danielleontiev/neojhat/internal/testdump"
+)
+
+// Slots are compressed references of 4 bytes.
+var collectionsSample = td.Dump(
+	[][]byte{
+		td.Utf8(1, "java/util/HashMap"),
+		td.Utf8(2, "java/util/HashSet"),
+		td.Utf8(3, "[Ljava/lang/Object;"),
+		td.Utf8(4, "table"),
+		td.Utf8(5, "size"),
+		td.Utf8(6, "map"),
+		td.LoadClass(1, 100, 1),
+		td.LoadClass(2, 101, 2),
+		td.LoadClass(3, 102, 3),
+	},
+	td.ClassDump(100, 0, 8, nil, []td.Field{{NameId: 4, Type: core.Object}, {NameId: 5, Type: core.Int}}),
+	td.ClassDump(101, 0, 8, nil, []td.Field{{NameId: 6, Type: core.Object}}),
+	td.ClassDump(102, 0, 0, nil, nil),
+	td.InstanceDump(200, 101, td.Id(301)),
+	td.InstanceDump(300, 100, td.Id(400), td.U4(3)),
+	td.InstanceDump(301, 100, td.Id(401), td.U4(1)), // backs the set 200
+	td.ObjArrayDump(400, 102, make([]uint64, 16)...),
+	td.ObjArrayDump(401, 102, make([]uint64, 4)...),
+)
+
+func TestGetCollections(t *testing.T) {
+	parsedAccessor, err := td.NewParsedAccessor(collectionsSample, false)
+	if err != nil {
+		t.Fatalf("cannot parse sample: %v", err)
+	}
+	got, err := GetCollections(parsedAccessor, td.NewRun)
+	if err != nil {
+		t.Fatalf("GetCollections() error = %v", err)
+	}
+	want := Collections{
+		Classes: []ClassStats{
+			{
+				ClassName: "java/util/HashMap", Instances: 1, Histogram: []int{0, 1, 0, 0, 0, 0},
+				FillRatio: 3.0 / 16, WastedSize: 13 * 4,
+			},
+			{
+				ClassName: "java/util/HashSet", Instances: 1, Histogram: []int{1, 0, 0, 0, 0, 0},
+				FillRatio: 1.0 / 4, WastedSize: 3 * 4,
+			},
+		},
+		WastedSize: 16 * 4,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetCollections() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,53 @@
+package collections
+
+import "fmt"
+
+// SizeRange is the bucket of the histogram of collection
+// sizes, Max is 0 for the last unbounded bucket.
+type SizeRange struct {
+	Min int
+	Max int
+}
+
+func (r SizeRange) String() string {
+	switch {
+	case r.Max == 0:
+		return fmt.Sprintf("%d+", r.Min)
+	case r.Min == r.Max:
+		return fmt.Sprintf("%d", r.Min)
+	}
+	return fmt.Sprintf("%d-%d", r.Min, r.Max)
+}
+
+func (r SizeRange) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// SizeRanges are the buckets of the histogram, empty
+// collections are counted separately.
+var SizeRanges = []SizeRange{
+	{Min: 1, Max: 1},
+	{Min: 2, Max: 4},
+	{Min: 5, Max: 16},
+	{Min: 17, Max: 64},
+	{Min: 65, Max: 256},
+	{Min: 257},
+}
+
+// ClassStats is the report for the instances of one collection class.
+// Histogram has the number of collections for each of SizeRanges.
+// FillRatio is the average share of used slots of the backing arrays,
+// collections that have not allocated the array yet are not counted.
+type ClassStats struct {
+	ClassName  string  `json:"className"`
+	Instances  int     `json:"instances"`
+	Empty      int     `json:"empty"`
+	Histogram  []int   `json:"histogram"`
+	FillRatio  float64 `json:"fillRatio"`
+	WastedSize int     `json:"wastedSize"`
+}
+
+type Collections struct {
+	Classes    []ClassStats `json:"classes"`
+	WastedSize int          `json:"wastedSize"`
+}
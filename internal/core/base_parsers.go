@@ -3,6 +3,7 @@ package core
 import (
 	"fmt"
 	"io"
+	"math"
 )
 
 func NewRecordParser(heapDump io.Reader, idSize uint32) *RecordParser {
@@ -160,6 +161,255 @@ func (parser *RecordParser) ParseHprofTrace() (HprofTrace, error) {
 	}, nil
 }
 
+// ParseHprofUnloadClass reads HPROF_UNLOAD_CLASS record.
+func (parser *RecordParser) ParseHprofUnloadClass() (HprofUnloadClass, error) {
+	classSerialNumber, err := parser.primitiveParser.ParseUint32()
+	if err != nil {
+		return HprofUnloadClass{}, fmt.Errorf("error in ParseHprofUnloadClass: %w", err)
+	}
+	return HprofUnloadClass{
+		ClassSerialNumber: classSerialNumber,
+	}, nil
+}
+
+// ParseHprofAllocSites reads HPROF_ALLOC_SITES record.
+func (parser *RecordParser) ParseHprofAllocSites() (HprofAllocSites, error) {
+	flags, err := parser.primitiveParser.ParseUint16()
+	if err != nil {
+		return HprofAllocSites{}, fmt.Errorf("error in ParseHprofAllocSites: %w", err)
+	}
+
+	cutoffRatio, err := parser.primitiveParser.ParseUint32()
+	if err != nil {
+		return HprofAllocSites{}, fmt.Errorf("error in ParseHprofAllocSites: %w", err)
+	}
+
+	totalLiveBytes, err := parser.primitiveParser.ParseUint32()
+	if err != nil {
+		return HprofAllocSites{}, fmt.Errorf("error in ParseHprofAllocSites: %w", err)
+	}
+
+	totalLiveInstances, err := parser.primitiveParser.ParseUint32()
+	if err != nil {
+		return HprofAllocSites{}, fmt.Errorf("error in ParseHprofAllocSites: %w", err)
+	}
+
+	totalBytesAllocated, err := parser.primitiveParser.ParseUint64()
+	if err != nil {
+		return HprofAllocSites{}, fmt.Errorf("error in ParseHprofAllocSites: %w", err)
+	}
+
+	totalInstancesAllocated, err := parser.primitiveParser.ParseUint64()
+	if err != nil {
+		return HprofAllocSites{}, fmt.Errorf("error in ParseHprofAllocSites: %w", err)
+	}
+
+	numberOfSites, err := parser.primitiveParser.ParseUint32()
+	if err != nil {
+		return HprofAllocSites{}, fmt.Errorf("error in ParseHprofAllocSites: %w", err)
+	}
+
+	var sites []HprofAllocSite
+	for i := numberOfSites; i > 0; i-- {
+		site, err := parser.parseHprofAllocSite()
+		if err != nil {
+			return HprofAllocSites{}, fmt.Errorf("error in ParseHprofAllocSites: %w", err)
+		}
+		sites = append(sites, site)
+	}
+
+	return HprofAllocSites{
+		Flags:                   flags,
+		CutoffRatio:             math.Float32frombits(cutoffRatio),
+		TotalLiveBytes:          totalLiveBytes,
+		TotalLiveInstances:      totalLiveInstances,
+		TotalBytesAllocated:     totalBytesAllocated,
+		TotalInstancesAllocated: totalInstancesAllocated,
+		NumberOfSites:           numberOfSites,
+		Sites:                   sites,
+	}, nil
+}
+
+// parseHprofAllocSite reads single site of HPROF_ALLOC_SITES record.
+func (parser *RecordParser) parseHprofAllocSite() (HprofAllocSite, error) {
+	arrayIndicator, err := parser.primitiveParser.ParseUint8()
+	if err != nil {
+		return HprofAllocSite{}, fmt.Errorf("error in parseHprofAllocSite: %w", err)
+	}
+
+	classSerialNumber, err := parser.primitiveParser.ParseUint32()
+	if err != nil {
+		return HprofAllocSite{}, fmt.Errorf("error in parseHprofAllocSite: %w", err)
+	}
+
+	stackTraceSerialNumber, err := parser.primitiveParser.ParseUint32()
+	if err != nil {
+		return HprofAllocSite{}, fmt.Errorf("error in parseHprofAllocSite: %w", err)
+	}
+
+	bytesAlive, err := parser.primitiveParser.ParseUint32()
+	if err != nil {
+		return HprofAllocSite{}, fmt.Errorf("error in parseHprofAllocSite: %w", err)
+	}
+
+	instancesAlive, err := parser.primitiveParser.ParseUint32()
+	if err != nil {
+		return HprofAllocSite{}, fmt.Errorf("error in parseHprofAllocSite: %w", err)
+	}
+
+	bytesAllocated, err := parser.primitiveParser.ParseUint32()
+	if err != nil {
+		return HprofAllocSite{}, fmt.Errorf("error in parseHprofAllocSite: %w", err)
+	}
+
+	instancesAllocated, err := parser.primitiveParser.ParseUint32()
+	if err != nil {
+		return HprofAllocSite{}, fmt.Errorf("error in parseHprofAllocSite: %w", err)
+	}
+	return HprofAllocSite{
+		ArrayIndicator:         arrayIndicator,
+		ClassSerialNumber:      classSerialNumber,
+		StackTraceSerialNumber: stackTraceSerialNumber,
+		BytesAlive:             bytesAlive,
+		InstancesAlive:         instancesAlive,
+		BytesAllocated:         bytesAllocated,
+		InstancesAllocated:     instancesAllocated,
+	}, nil
+}
+
+// ParseHprofHeapSummary reads HPROF_HEAP_SUMMARY record.
+func (parser *RecordParser) ParseHprofHeapSummary() (HprofHeapSummary, error) {
+	totalLiveBytes, err := parser.primitiveParser.ParseUint32()
+	if err != nil {
+		return HprofHeapSummary{}, fmt.Errorf("error in ParseHprofHeapSummary: %w", err)
+	}
+
+	totalLiveInstances, err := parser.primitiveParser.ParseUint32()
+	if err != nil {
+		return HprofHeapSummary{}, fmt.Errorf("error in ParseHprofHeapSummary: %w", err)
+	}
+
+	totalBytesAllocated, err := parser.primitiveParser.ParseUint64()
+	if err != nil {
+		return HprofHeapSummary{}, fmt.Errorf("error in ParseHprofHeapSummary: %w", err)
+	}
+
+	totalInstancesAllocated, err := parser.primitiveParser.ParseUint64()
+	if err != nil {
+		return HprofHeapSummary{}, fmt.Errorf("error in ParseHprofHeapSummary: %w", err)
+	}
+	return HprofHeapSummary{
+		TotalLiveBytes:          totalLiveBytes,
+		TotalLiveInstances:      totalLiveInstances,
+		TotalBytesAllocated:     totalBytesAllocated,
+		TotalInstancesAllocated: totalInstancesAllocated,
+	}, nil
+}
+
+// ParseHprofStartThread reads HPROF_START_THREAD record.
+func (parser *RecordParser) ParseHprofStartThread() (HprofStartThread, error) {
+	threadSerialNumber, err := parser.primitiveParser.ParseUint32()
+	if err != nil {
+		return HprofStartThread{}, fmt.Errorf("error in ParseHprofStartThread: %w", err)
+	}
+
+	threadObjectId, err := parser.primitiveParser.ParseIdentifier()
+	if err != nil {
+		return HprofStartThread{}, fmt.Errorf("error in ParseHprofStartThread: %w", err)
+	}
+
+	stackTraceSerialNumber, err := parser.primitiveParser.ParseUint32()
+	if err != nil {
+		return HprofStartThread{}, fmt.Errorf("error in ParseHprofStartThread: %w", err)
+	}
+
+	threadNameId, err := parser.primitiveParser.ParseIdentifier()
+	if err != nil {
+		return HprofStartThread{}, fmt.Errorf("error in ParseHprofStartThread: %w", err)
+	}
+
+	threadGroupNameId, err := parser.primitiveParser.ParseIdentifier()
+	if err != nil {
+		return HprofStartThread{}, fmt.Errorf("error in ParseHprofStartThread: %w", err)
+	}
+
+	threadParentGroupNameId, err := parser.primitiveParser.ParseIdentifier()
+	if err != nil {
+		return HprofStartThread{}, fmt.Errorf("error in ParseHprofStartThread: %w", err)
+	}
+	return HprofStartThread{
+		ThreadSerialNumber:      threadSerialNumber,
+		ThreadObjectId:          threadObjectId,
+		StackTraceSerialNumber:  stackTraceSerialNumber,
+		ThreadNameId:            threadNameId,
+		ThreadGroupNameId:       threadGroupNameId,
+		ThreadParentGroupNameId: threadParentGroupNameId,
+	}, nil
+}
+
+// ParseHprofEndThread reads HPROF_END_THREAD record.
+func (parser *RecordParser) ParseHprofEndThread() (HprofEndThread, error) {
+	threadSerialNumber, err := parser.primitiveParser.ParseUint32()
+	if err != nil {
+		return HprofEndThread{}, fmt.Errorf("error in ParseHprofEndThread: %w", err)
+	}
+	return HprofEndThread{
+		ThreadSerialNumber: threadSerialNumber,
+	}, nil
+}
+
+// ParseHprofCpuSamples reads HPROF_CPU_SAMPLES record.
+func (parser *RecordParser) ParseHprofCpuSamples() (HprofCpuSamples, error) {
+	totalNumberOfSamples, err := parser.primitiveParser.ParseUint32()
+	if err != nil {
+		return HprofCpuSamples{}, fmt.Errorf("error in ParseHprofCpuSamples: %w", err)
+	}
+
+	numberOfTraces, err := parser.primitiveParser.ParseUint32()
+	if err != nil {
+		return HprofCpuSamples{}, fmt.Errorf("error in ParseHprofCpuSamples: %w", err)
+	}
+
+	var samples []HprofCpuSample
+	for i := numberOfTraces; i > 0; i-- {
+		numberOfSamples, err := parser.primitiveParser.ParseUint32()
+		if err != nil {
+			return HprofCpuSamples{}, fmt.Errorf("error in ParseHprofCpuSamples: %w", err)
+		}
+		stackTraceSerialNumber, err := parser.primitiveParser.ParseUint32()
+		if err != nil {
+			return HprofCpuSamples{}, fmt.Errorf("error in ParseHprofCpuSamples: %w", err)
+		}
+		samples = append(samples, HprofCpuSample{
+			NumberOfSamples:        numberOfSamples,
+			StackTraceSerialNumber: stackTraceSerialNumber,
+		})
+	}
+
+	return HprofCpuSamples{
+		TotalNumberOfSamples: totalNumberOfSamples,
+		NumberOfTraces:       numberOfTraces,
+		Samples:              samples,
+	}, nil
+}
+
+// ParseHprofControlSettings reads HPROF_CONTROL_SETTINGS record.
+func (parser *RecordParser) ParseHprofControlSettings() (HprofControlSettings, error) {
+	flags, err := parser.primitiveParser.ParseUint32()
+	if err != nil {
+		return HprofControlSettings{}, fmt.Errorf("error in ParseHprofControlSettings: %w", err)
+	}
+
+	stackTraceDepth, err := parser.primitiveParser.ParseUint16()
+	if err != nil {
+		return HprofControlSettings{}, fmt.Errorf("error in ParseHprofControlSettings: %w", err)
+	}
+	return HprofControlSettings{
+		Flags:           flags,
+		StackTraceDepth: stackTraceDepth,
+	}, nil
+}
+
 // ParseSubRecordHeader reads the type of the sub-record inside HPROF_HEAP_DUMP_SEGMENT.
 func (parser *RecordParser) ParseSubRecordHeader() (SubRecordHeader, error) {
 	subRecordType, err := parser.primitiveParser.ParseUint8()
@@ -264,6 +514,168 @@ func (parser *RecordParser) ParseHprofGcRootStickyClass() (HprofGcRootStickyClas
 	return HprofGcRootStickyClass{ObjectId: objectId}, nil
 }
 
+// ParseHprofGcRootUnknown reads HPROF_GC_ROOT_UNKNOWN sub-record.
+func (parser *RecordParser) ParseHprofGcRootUnknown() (HprofGcRootUnknown, error) {
+	objectId, err := parser.primitiveParser.ParseIdentifier()
+	if err != nil {
+		return HprofGcRootUnknown{}, fmt.Errorf("error in ParseHprofGcRootUnknown: %w", err)
+	}
+	return HprofGcRootUnknown{
+		ObjectId: objectId,
+	}, nil
+}
+
+// ParseHprofGcRootNativeStack reads HPROF_GC_ROOT_NATIVE_STACK sub-record.
+func (parser *RecordParser) ParseHprofGcRootNativeStack() (HprofGcRootNativeStack, error) {
+	objectId, err := parser.primitiveParser.ParseIdentifier()
+	if err != nil {
+		return HprofGcRootNativeStack{}, fmt.Errorf("error in ParseHprofGcRootNativeStack: %w", err)
+	}
+
+	threadSerialNumber, err := parser.primitiveParser.ParseUint32()
+	if err != nil {
+		return HprofGcRootNativeStack{}, fmt.Errorf("error in ParseHprofGcRootNativeStack: %w", err)
+	}
+	return HprofGcRootNativeStack{
+		ObjectId:           objectId,
+		ThreadSerialNumber: threadSerialNumber,
+	}, nil
+}
+
+// ParseHprofGcRootThreadBlock reads HPROF_GC_ROOT_THREAD_BLOCK sub-record.
+func (parser *RecordParser) ParseHprofGcRootThreadBlock() (HprofGcRootThreadBlock, error) {
+	objectId, err := parser.primitiveParser.ParseIdentifier()
+	if err != nil {
+		return HprofGcRootThreadBlock{}, fmt.Errorf("error in ParseHprofGcRootThreadBlock: %w", err)
+	}
+
+	threadSerialNumber, err := parser.primitiveParser.ParseUint32()
+	if err != nil {
+		return HprofGcRootThreadBlock{}, fmt.Errorf("error in ParseHprofGcRootThreadBlock: %w", err)
+	}
+	return HprofGcRootThreadBlock{
+		ObjectId:           objectId,
+		ThreadSerialNumber: threadSerialNumber,
+	}, nil
+}
+
+// ParseHprofGcRootMonitorUsed reads HPROF_GC_ROOT_MONITOR_USED sub-record.
+func (parser *RecordParser) ParseHprofGcRootMonitorUsed() (HprofGcRootMonitorUsed, error) {
+	objectId, err := parser.primitiveParser.ParseIdentifier()
+	if err != nil {
+		return HprofGcRootMonitorUsed{}, fmt.Errorf("error in ParseHprofGcRootMonitorUsed: %w", err)
+	}
+	return HprofGcRootMonitorUsed{
+		ObjectId: objectId,
+	}, nil
+}
+
+// ParseHprofGcRootInternedString reads HPROF_GC_ROOT_INTERNED_STRING sub-record.
+func (parser *RecordParser) ParseHprofGcRootInternedString() (HprofGcRootInternedString, error) {
+	objectId, err := parser.primitiveParser.ParseIdentifier()
+	if err != nil {
+		return HprofGcRootInternedString{}, fmt.Errorf("error in ParseHprofGcRootInternedString: %w", err)
+	}
+	return HprofGcRootInternedString{
+		ObjectId: objectId,
+	}, nil
+}
+
+// ParseHprofGcRootFinalizing reads HPROF_GC_ROOT_FINALIZING sub-record.
+func (parser *RecordParser) ParseHprofGcRootFinalizing() (HprofGcRootFinalizing, error) {
+	objectId, err := parser.primitiveParser.ParseIdentifier()
+	if err != nil {
+		return HprofGcRootFinalizing{}, fmt.Errorf("error in ParseHprofGcRootFinalizing: %w", err)
+	}
+	return HprofGcRootFinalizing{
+		ObjectId: objectId,
+	}, nil
+}
+
+// ParseHprofGcRootDebugger reads HPROF_GC_ROOT_DEBUGGER sub-record.
+func (parser *RecordParser) ParseHprofGcRootDebugger() (HprofGcRootDebugger, error) {
+	objectId, err := parser.primitiveParser.ParseIdentifier()
+	if err != nil {
+		return HprofGcRootDebugger{}, fmt.Errorf("error in ParseHprofGcRootDebugger: %w", err)
+	}
+	return HprofGcRootDebugger{
+		ObjectId: objectId,
+	}, nil
+}
+
+// ParseHprofGcRootReferenceCleanup reads HPROF_GC_ROOT_REFERENCE_CLEANUP sub-record.
+func (parser *RecordParser) ParseHprofGcRootReferenceCleanup() (HprofGcRootReferenceCleanup, error) {
+	objectId, err := parser.primitiveParser.ParseIdentifier()
+	if err != nil {
+		return HprofGcRootReferenceCleanup{}, fmt.Errorf("error in ParseHprofGcRootReferenceCleanup: %w", err)
+	}
+	return HprofGcRootReferenceCleanup{
+		ObjectId: objectId,
+	}, nil
+}
+
+// ParseHprofGcRootVmInternal reads HPROF_GC_ROOT_VM_INTERNAL sub-record.
+func (parser *RecordParser) ParseHprofGcRootVmInternal() (HprofGcRootVmInternal, error) {
+	objectId, err := parser.primitiveParser.ParseIdentifier()
+	if err != nil {
+		return HprofGcRootVmInternal{}, fmt.Errorf("error in ParseHprofGcRootVmInternal: %w", err)
+	}
+	return HprofGcRootVmInternal{
+		ObjectId: objectId,
+	}, nil
+}
+
+// ParseHprofGcRootJniMonitor reads HPROF_GC_ROOT_JNI_MONITOR sub-record.
+func (parser *RecordParser) ParseHprofGcRootJniMonitor() (HprofGcRootJniMonitor, error) {
+	objectId, err := parser.primitiveParser.ParseIdentifier()
+	if err != nil {
+		return HprofGcRootJniMonitor{}, fmt.Errorf("error in ParseHprofGcRootJniMonitor: %w", err)
+	}
+
+	threadSerialNumber, err := parser.primitiveParser.ParseUint32()
+	if err != nil {
+		return HprofGcRootJniMonitor{}, fmt.Errorf("error in ParseHprofGcRootJniMonitor: %w", err)
+	}
+
+	stackTraceDepth, err := parser.primitiveParser.ParseUint32()
+	if err != nil {
+		return HprofGcRootJniMonitor{}, fmt.Errorf("error in ParseHprofGcRootJniMonitor: %w", err)
+	}
+	return HprofGcRootJniMonitor{
+		ObjectId:           objectId,
+		ThreadSerialNumber: threadSerialNumber,
+		StackTraceDepth:    stackTraceDepth,
+	}, nil
+}
+
+// ParseHprofGcRootUnreachable reads HPROF_GC_ROOT_UNREACHABLE sub-record.
+func (parser *RecordParser) ParseHprofGcRootUnreachable() (HprofGcRootUnreachable, error) {
+	objectId, err := parser.primitiveParser.ParseIdentifier()
+	if err != nil {
+		return HprofGcRootUnreachable{}, fmt.Errorf("error in ParseHprofGcRootUnreachable: %w", err)
+	}
+	return HprofGcRootUnreachable{
+		ObjectId: objectId,
+	}, nil
+}
+
+// ParseHprofHeapDumpInfo reads HPROF_HEAP_DUMP_INFO sub-record.
+func (parser *RecordParser) ParseHprofHeapDumpInfo() (HprofHeapDumpInfo, error) {
+	heapType, err := parser.primitiveParser.ParseUint32()
+	if err != nil {
+		return HprofHeapDumpInfo{}, fmt.Errorf("error in ParseHprofHeapDumpInfo: %w", err)
+	}
+
+	heapNameId, err := parser.primitiveParser.ParseIdentifier()
+	if err != nil {
+		return HprofHeapDumpInfo{}, fmt.Errorf("error in ParseHprofHeapDumpInfo: %w", err)
+	}
+	return HprofHeapDumpInfo{
+		HeapType:   heapType,
+		HeapNameId: heapNameId,
+	}, nil
+}
+
 // ParseHprofGcClassDump reads HPROF_GC_CLASS_DUMP sub-record.
 func (parser *RecordParser) ParseHprofGcClassDump() (HprofGcClassDump, error) {
 	classObjectId, err := parser.primitiveParser.ParseIdentifier()
@@ -184,6 +184,177 @@ func TestRecordParser_ParseHprofTrace(t *testing.T) {
 	}
 }
 
+func TestRecordParser_ParseHprofAllocSites(t *testing.T) {
+	tests := []struct {
+		name    string
+		parser  RecordParser
+		want    HprofAllocSites
+		wantErr bool
+	}{
+		{
+			name: "success",
+			parser: createRecordParser(concat(
+				one2,                           // flags
+				[]byte{0x3f, 0x00, 0x00, 0x00}, // cutoff ratio, 0.5
+				one4, one4,                     // total live bytes and instances
+				one8, one8, // total allocated bytes and instances
+				one4,         // number of sites
+				[]byte{0x02}, // array indicator
+				one4, one4, one4, one4, one4, one4,
+			), CreateOpts{idSize: 4}),
+			want: HprofAllocSites{
+				Flags:                   1,
+				CutoffRatio:             0.5,
+				TotalLiveBytes:          1,
+				TotalLiveInstances:      1,
+				TotalBytesAllocated:     1,
+				TotalInstancesAllocated: 1,
+				NumberOfSites:           1,
+				Sites: []HprofAllocSite{
+					{
+						ArrayIndicator:         2,
+						ClassSerialNumber:      1,
+						StackTraceSerialNumber: 1,
+						BytesAlive:             1,
+						InstancesAlive:         1,
+						BytesAllocated:         1,
+						InstancesAllocated:     1,
+					},
+				},
+			},
+		},
+		{
+			name:    "error",
+			parser:  createRecordParser(empty, CreateOpts{}),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.parser.ParseHprofAllocSites()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RecordParser.ParseHprofAllocSites() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RecordParser.ParseHprofAllocSites() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordParser_ParseHprofHeapSummary(t *testing.T) {
+	tests := []struct {
+		name    string
+		parser  RecordParser
+		want    HprofHeapSummary
+		wantErr bool
+	}{
+		{
+			name:   "success",
+			parser: createRecordParser(concat(one4, one4, one8, one8), CreateOpts{idSize: 4}),
+			want: HprofHeapSummary{
+				TotalLiveBytes:          1,
+				TotalLiveInstances:      1,
+				TotalBytesAllocated:     1,
+				TotalInstancesAllocated: 1,
+			},
+		},
+		{
+			name:    "error",
+			parser:  createRecordParser(empty, CreateOpts{}),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.parser.ParseHprofHeapSummary()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RecordParser.ParseHprofHeapSummary() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RecordParser.ParseHprofHeapSummary() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordParser_ParseHprofStartThread(t *testing.T) {
+	tests := []struct {
+		name    string
+		parser  RecordParser
+		want    HprofStartThread
+		wantErr bool
+	}{
+		{
+			name:   "success",
+			parser: createRecordParser(concat(one4, one4, one4, one4, one4, one4), CreateOpts{idSize: 4}),
+			want: HprofStartThread{
+				ThreadSerialNumber:      1,
+				ThreadObjectId:          1,
+				StackTraceSerialNumber:  1,
+				ThreadNameId:            1,
+				ThreadGroupNameId:       1,
+				ThreadParentGroupNameId: 1,
+			},
+		},
+		{
+			name:    "error",
+			parser:  createRecordParser(empty, CreateOpts{}),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.parser.ParseHprofStartThread()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RecordParser.ParseHprofStartThread() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RecordParser.ParseHprofStartThread() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordParser_ParseHprofCpuSamples(t *testing.T) {
+	tests := []struct {
+		name    string
+		parser  RecordParser
+		want    HprofCpuSamples
+		wantErr bool
+	}{
+		{
+			name:   "success",
+			parser: createRecordParser(concat(one4, one4, one4, one4), CreateOpts{idSize: 4}),
+			want: HprofCpuSamples{
+				TotalNumberOfSamples: 1,
+				NumberOfTraces:       1,
+				Samples:              []HprofCpuSample{{NumberOfSamples: 1, StackTraceSerialNumber: 1}},
+			},
+		},
+		{
+			name:    "error",
+			parser:  createRecordParser(empty, CreateOpts{}),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.parser.ParseHprofCpuSamples()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RecordParser.ParseHprofCpuSamples() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RecordParser.ParseHprofCpuSamples() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestRecordParser_parseHprofSubRecordHeader(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -393,6 +564,230 @@ func TestRecordParser_ParseHprofGcRootStickyClass(t *testing.T) {
 	}
 }
 
+func TestRecordParser_ParseHprofGcRootUnknown(t *testing.T) {
+	tests := []struct {
+		name    string
+		parser  RecordParser
+		want    HprofGcRootUnknown
+		wantErr bool
+	}{
+		{
+			name:   "success",
+			parser: createRecordParser(one8, CreateOpts{idSize: 8}),
+			want:   HprofGcRootUnknown{ObjectId: 1},
+		},
+		{
+			name:    "error",
+			parser:  createRecordParser(empty, CreateOpts{}),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.parser.ParseHprofGcRootUnknown()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RecordParser.ParseHprofGcRootUnknown() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("RecordParser.ParseHprofGcRootUnknown() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordParser_ParseHprofGcRootNativeStack(t *testing.T) {
+	tests := []struct {
+		name    string
+		parser  RecordParser
+		want    HprofGcRootNativeStack
+		wantErr bool
+	}{
+		{
+			name:   "success",
+			parser: createRecordParser(concat(one8, one4), CreateOpts{idSize: 8}),
+			want:   HprofGcRootNativeStack{ObjectId: 1, ThreadSerialNumber: 1},
+		},
+		{
+			name:    "error",
+			parser:  createRecordParser(empty, CreateOpts{}),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.parser.ParseHprofGcRootNativeStack()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RecordParser.ParseHprofGcRootNativeStack() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("RecordParser.ParseHprofGcRootNativeStack() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordParser_ParseHprofGcRootThreadBlock(t *testing.T) {
+	tests := []struct {
+		name    string
+		parser  RecordParser
+		want    HprofGcRootThreadBlock
+		wantErr bool
+	}{
+		{
+			name:   "success",
+			parser: createRecordParser(concat(one8, one4), CreateOpts{idSize: 8}),
+			want:   HprofGcRootThreadBlock{ObjectId: 1, ThreadSerialNumber: 1},
+		},
+		{
+			name:    "error",
+			parser:  createRecordParser(empty, CreateOpts{}),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.parser.ParseHprofGcRootThreadBlock()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RecordParser.ParseHprofGcRootThreadBlock() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("RecordParser.ParseHprofGcRootThreadBlock() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordParser_ParseHprofGcRootMonitorUsed(t *testing.T) {
+	tests := []struct {
+		name    string
+		parser  RecordParser
+		want    HprofGcRootMonitorUsed
+		wantErr bool
+	}{
+		{
+			name:   "success",
+			parser: createRecordParser(one8, CreateOpts{idSize: 8}),
+			want:   HprofGcRootMonitorUsed{ObjectId: 1},
+		},
+		{
+			name:    "error",
+			parser:  createRecordParser(empty, CreateOpts{}),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.parser.ParseHprofGcRootMonitorUsed()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RecordParser.ParseHprofGcRootMonitorUsed() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("RecordParser.ParseHprofGcRootMonitorUsed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordParser_ParseHprofGcRootInternedString(t *testing.T) {
+	tests := []struct {
+		name    string
+		parser  RecordParser
+		want    HprofGcRootInternedString
+		wantErr bool
+	}{
+		{
+			name:   "success",
+			parser: createRecordParser(one8, CreateOpts{idSize: 8}),
+			want:   HprofGcRootInternedString{ObjectId: 1},
+		},
+		{
+			name:    "error",
+			parser:  createRecordParser(empty, CreateOpts{}),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.parser.ParseHprofGcRootInternedString()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RecordParser.ParseHprofGcRootInternedString() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("RecordParser.ParseHprofGcRootInternedString() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordParser_ParseHprofGcRootJniMonitor(t *testing.T) {
+	tests := []struct {
+		name    string
+		parser  RecordParser
+		want    HprofGcRootJniMonitor
+		wantErr bool
+	}{
+		{
+			name:   "success",
+			parser: createRecordParser(concat(one8, one4, one4), CreateOpts{idSize: 8}),
+			want:   HprofGcRootJniMonitor{ObjectId: 1, ThreadSerialNumber: 1, StackTraceDepth: 1},
+		},
+		{
+			name:    "error",
+			parser:  createRecordParser(empty, CreateOpts{}),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.parser.ParseHprofGcRootJniMonitor()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RecordParser.ParseHprofGcRootJniMonitor() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("RecordParser.ParseHprofGcRootJniMonitor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordParser_ParseHprofHeapDumpInfo(t *testing.T) {
+	tests := []struct {
+		name    string
+		parser  RecordParser
+		want    HprofHeapDumpInfo
+		wantErr bool
+	}{
+		{
+			name:   "success",
+			parser: createRecordParser(concat(one4, one8), CreateOpts{idSize: 8}),
+			want:   HprofHeapDumpInfo{HeapType: 1, HeapNameId: 1},
+		},
+		{
+			name:    "error",
+			parser:  createRecordParser(empty, CreateOpts{}),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.parser.ParseHprofHeapDumpInfo()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RecordParser.ParseHprofHeapDumpInfo() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("RecordParser.ParseHprofHeapDumpInfo() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestRecordParser_ParseHprofGcClassDumpHeader(t *testing.T) {
 	record := concat(
 		one4, one4, one4, one4, one4, one4, one4, one4, one4, // header
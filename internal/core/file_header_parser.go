@@ -8,19 +8,27 @@ import (
 )
 
 const (
-	ValidProfileVersion = "JAVA PROFILE 1.0.2"
+	ProfileVersion101 = "JAVA PROFILE 1.0.1"
+	ProfileVersion102 = "JAVA PROFILE 1.0.2"
+	ProfileVersion103 = "JAVA PROFILE 1.0.3"
 )
 
-// ParseFileHeader reads the header of .hprof file.
+// ParseFileHeader reads the header of .hprof file. Versions 1.0.1,
+// 1.0.2 and 1.0.3 are supported: 1.0.1 writes the heap dump as single
+// HPROF_HEAP_DUMP record, 1.0.2 as HPROF_HEAP_DUMP_SEGMENT records
+// and 1.0.3 is written by Android Runtime, it's 1.0.2 with extra
+// sub-records.
 func ParseFileHeader(heapDump io.Reader) (FileHeader, error) {
-	str := make([]byte, len(ValidProfileVersion)+1)
+	// all versions have the same length
+	str := make([]byte, len(ProfileVersion102)+1)
 	_, err := io.ReadFull(heapDump, str)
 	if err != nil {
 		return FileHeader{}, fmt.Errorf("error in ParseFileHeader: %w", err)
 	}
 	header := string(str[:len(str)-1])
-	if header != ValidProfileVersion {
-		return FileHeader{}, fmt.Errorf("unsupported profile %v, only %v is supported", header, ValidProfileVersion)
+	if header != ProfileVersion101 && header != ProfileVersion102 && header != ProfileVersion103 {
+		return FileHeader{}, fmt.Errorf("unsupported profile %v, only %v, %v and %v are supported",
+			header, ProfileVersion101, ProfileVersion102, ProfileVersion103)
 	}
 	identifiersSize, err := parserUint32(heapDump)
 	if err != nil {
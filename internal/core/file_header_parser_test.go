@@ -17,6 +17,9 @@ func TestParseFileHeader(t *testing.T) {
 		0x00, 0x00, 0x01, 0x7b, // high word
 		0xf1, 0x0c, 0xa9, 0xd3, // low word
 	}
+	header101 := append([]byte("JAVA PROFILE 1.0.1\x00"), header[19:]...)
+	header103 := append([]byte("JAVA PROFILE 1.0.3\x00"), header[19:]...)
+	unsupported := append([]byte("JAVA PROFILE 1.0.4\x00"), header[19:]...)
 	tests := []struct {
 		name    string
 		input   []byte
@@ -32,6 +35,29 @@ func TestParseFileHeader(t *testing.T) {
 				Timestamp:      sampleTime,
 			},
 		},
+		{
+			name:  "success parse 1.0.1 file header",
+			input: header101,
+			want: FileHeader{
+				Header:         "JAVA PROFILE 1.0.1",
+				IdentifierSize: 8,
+				Timestamp:      sampleTime,
+			},
+		},
+		{
+			name:  "success parse 1.0.3 file header",
+			input: header103,
+			want: FileHeader{
+				Header:         "JAVA PROFILE 1.0.3",
+				IdentifierSize: 8,
+				Timestamp:      sampleTime,
+			},
+		},
+		{
+			name:    "error unsupported profile version",
+			input:   unsupported,
+			wantErr: true,
+		},
 		{
 			name:    "error parse file header",
 			input:   empty,
@@ -48,6 +48,16 @@ func (parser *PrimitiveParser) ParseUint32() (uint32, error) {
 	return result, nil
 }
 
+// ParseUint64 reads 64-bit unsigned (u8) number.
+func (parser *PrimitiveParser) ParseUint64() (uint64, error) {
+	var result uint64
+	err := binary.Read(parser.heapDump, binary.BigEndian, &result)
+	if err != nil {
+		return 0, fmt.Errorf("error in ParseUint64: %w", err)
+	}
+	return result, nil
+}
+
 // ParseInt32 reads 32-bit signed (i4) number.
 func (parser *PrimitiveParser) ParseInt32() (int32, error) {
 	var result int32
@@ -53,6 +53,70 @@ type HprofTrace struct {
 	StackFrameIds          []Identifier
 }
 
+type HprofUnloadClass struct {
+	ClassSerialNumber uint32
+}
+
+type HprofAllocSites struct {
+	Flags                   uint16
+	CutoffRatio             float32
+	TotalLiveBytes          uint32
+	TotalLiveInstances      uint32
+	TotalBytesAllocated     uint64
+	TotalInstancesAllocated uint64
+	NumberOfSites           uint32
+	Sites                   []HprofAllocSite
+}
+
+// HprofAllocSite is the single site of HPROF_ALLOC_SITES record.
+// ArrayIndicator is 0 for normal objects, 2 for object arrays
+// and the element type for primitive arrays.
+type HprofAllocSite struct {
+	ArrayIndicator         uint8
+	ClassSerialNumber      uint32
+	StackTraceSerialNumber uint32
+	BytesAlive             uint32
+	InstancesAlive         uint32
+	BytesAllocated         uint32
+	InstancesAllocated     uint32
+}
+
+type HprofHeapSummary struct {
+	TotalLiveBytes          uint32
+	TotalLiveInstances      uint32
+	TotalBytesAllocated     uint64
+	TotalInstancesAllocated uint64
+}
+
+type HprofStartThread struct {
+	ThreadSerialNumber      uint32
+	ThreadObjectId          Identifier
+	StackTraceSerialNumber  uint32
+	ThreadNameId            Identifier
+	ThreadGroupNameId       Identifier
+	ThreadParentGroupNameId Identifier
+}
+
+type HprofEndThread struct {
+	ThreadSerialNumber uint32
+}
+
+type HprofCpuSamples struct {
+	TotalNumberOfSamples uint32
+	NumberOfTraces       uint32
+	Samples              []HprofCpuSample
+}
+
+type HprofCpuSample struct {
+	NumberOfSamples        uint32
+	StackTraceSerialNumber uint32
+}
+
+type HprofControlSettings struct {
+	Flags           uint32
+	StackTraceDepth uint16
+}
+
 type SubRecordHeader struct {
 	SubRecordType SubRecordType
 }
@@ -84,6 +148,66 @@ type HprofGcRootStickyClass struct {
 	ObjectId Identifier
 }
 
+type HprofGcRootUnknown struct {
+	ObjectId Identifier
+}
+
+type HprofGcRootNativeStack struct {
+	ObjectId           Identifier
+	ThreadSerialNumber uint32
+}
+
+type HprofGcRootThreadBlock struct {
+	ObjectId           Identifier
+	ThreadSerialNumber uint32
+}
+
+type HprofGcRootMonitorUsed struct {
+	ObjectId Identifier
+}
+
+// HprofGcRootInternedString and the roots below
+// are written by Android Runtime (ART) only.
+type HprofGcRootInternedString struct {
+	ObjectId Identifier
+}
+
+type HprofGcRootFinalizing struct {
+	ObjectId Identifier
+}
+
+type HprofGcRootDebugger struct {
+	ObjectId Identifier
+}
+
+type HprofGcRootReferenceCleanup struct {
+	ObjectId Identifier
+}
+
+type HprofGcRootVmInternal struct {
+	ObjectId Identifier
+}
+
+type HprofGcRootJniMonitor struct {
+	ObjectId           Identifier
+	ThreadSerialNumber uint32
+	StackTraceDepth    uint32
+}
+
+// HprofGcRootUnreachable marks the object that is not
+// reachable from any root, it's not a root itself.
+type HprofGcRootUnreachable struct {
+	ObjectId Identifier
+}
+
+// HprofHeapDumpInfo is written by ART before the objects of
+// every heap (app, image, zygote), all the following objects
+// belong to the heap until the next HprofHeapDumpInfo.
+type HprofHeapDumpInfo struct {
+	HeapType   uint32
+	HeapNameId Identifier
+}
+
 type HprofGcClassDump struct {
 	ClassObjectId            Identifier
 	StackTraceSerialNumber   uint32
@@ -1,13 +1,36 @@
 package core
 
-import "fmt"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
 
+// SizeInfo knows the sizes of HPROF records (Of, OfType, OfObject)
+// which are needed to walk the dump, and the sizes of objects in the
+// JVM heap (OfInstance, OfArray, OfArrays) which depend on the heap
+// layout.
 type SizeInfo struct {
 	idSize int
+	layout HeapLayout
 }
 
+// NewSizeInfo creates SizeInfo with the default heap layout
+// for the identifier size, see DefaultHeapLayout.
 func NewSizeInfo(idSize uint32) *SizeInfo {
-	return &SizeInfo{idSize: int(idSize)}
+	return &SizeInfo{idSize: int(idSize), layout: DefaultHeapLayout(idSize)}
+}
+
+// WithLayout is the copy of SizeInfo that calculates
+// shallow sizes of objects for the given heap layout.
+func (s SizeInfo) WithLayout(layout HeapLayout) *SizeInfo {
+	s.layout = layout
+	return &s
+}
+
+// Layout is the heap layout shallow sizes are calculated for.
+func (s SizeInfo) Layout() HeapLayout {
+	return s.layout
 }
 
 func (s SizeInfo) Of(record any) int {
@@ -22,6 +45,30 @@ func (s SizeInfo) Of(record any) int {
 		return s.idSize
 	case HprofGcRootThreadObj:
 		return s.idSize + 2*4
+	case HprofGcRootUnknown:
+		return s.idSize
+	case HprofGcRootNativeStack:
+		return s.idSize + 4
+	case HprofGcRootThreadBlock:
+		return s.idSize + 4
+	case HprofGcRootMonitorUsed:
+		return s.idSize
+	case HprofGcRootInternedString:
+		return s.idSize
+	case HprofGcRootFinalizing:
+		return s.idSize
+	case HprofGcRootDebugger:
+		return s.idSize
+	case HprofGcRootReferenceCleanup:
+		return s.idSize
+	case HprofGcRootVmInternal:
+		return s.idSize
+	case HprofGcRootJniMonitor:
+		return s.idSize + 2*4
+	case HprofGcRootUnreachable:
+		return s.idSize
+	case HprofHeapDumpInfo:
+		return 4 + s.idSize
 	case HprofGcClassDump:
 		fieldsSize := 7*s.idSize + 2*4 + 3*2
 		var constantPoolSize int
@@ -81,3 +128,181 @@ func (s SizeInfo) OfObject(record any) (size, recordsSize int) {
 	}
 	return
 }
+
+// HeapLayout describes how the JVM lays out objects in memory. HPROF
+// records have only the values of fields and elements, so the shallow
+// size of the object is calculated from the layout: the object header
+// (mark word and class pointer), the length of arrays, the size of
+// references and the padding up to the object alignment.
+type HeapLayout struct {
+	Is64Bit                 bool
+	CompressedOops          bool
+	CompressedClassPointers bool
+	ObjectAlignment         int
+}
+
+// DefaultHeapLayout is the layout of the JVM with default options.
+// 4-byte identifiers are written by 32-bit JVMs (and by ART, which
+// has the same layout), 8-byte ones by 64-bit JVMs that compress
+// oops and class pointers unless the heap is larger than 32 GB.
+func DefaultHeapLayout(idSize uint32) HeapLayout {
+	if idSize == 4 {
+		return HeapLayout{ObjectAlignment: 8}
+	}
+	return HeapLayout{
+		Is64Bit:                 true,
+		CompressedOops:          true,
+		CompressedClassPointers: true,
+		ObjectAlignment:         8,
+	}
+}
+
+// Apply changes the layout according to the comma-separated list of
+// JVM options, e.g. "-XX:-UseCompressedOops,-XX:ObjectAlignmentInBytes=16".
+// The "-XX:" prefix is optional. UseCompressedOops,
+// UseCompressedClassPointers and ObjectAlignmentInBytes are supported.
+func (l *HeapLayout) Apply(options string) error {
+	for _, option := range strings.Split(options, ",") {
+		option = strings.TrimPrefix(strings.TrimSpace(option), "-XX:")
+		if option == "" {
+			continue
+		}
+		if err := l.applyOption(option); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *HeapLayout) applyOption(option string) error {
+	if value, ok := strings.CutPrefix(option, "ObjectAlignmentInBytes="); ok {
+		alignment, err := strconv.Atoi(value)
+		if err != nil || alignment < 8 || alignment > 256 || alignment&(alignment-1) != 0 {
+			return fmt.Errorf("ObjectAlignmentInBytes should be the power of two between 8 and 256, got '%s'", value)
+		}
+		l.ObjectAlignment = alignment
+		return nil
+	}
+	if len(option) < 2 || (option[0] != '+' && option[0] != '-') {
+		return fmt.Errorf("unknown heap layout option '%s'", option)
+	}
+	enabled := option[0] == '+'
+	switch option[1:] {
+	case "UseCompressedOops":
+		l.CompressedOops = enabled
+	case "UseCompressedClassPointers":
+		l.CompressedClassPointers = enabled
+	default:
+		return fmt.Errorf("unknown heap layout option '%s'", option)
+	}
+	return nil
+}
+
+func (l HeapLayout) String() string {
+	if !l.Is64Bit {
+		return fmt.Sprintf("32-bit, %d-byte alignment", l.ObjectAlignment)
+	}
+	parts := []string{"64-bit"}
+	if l.CompressedOops {
+		parts = append(parts, "compressed oops")
+	}
+	if l.CompressedClassPointers {
+		parts = append(parts, "compressed class pointers")
+	}
+	parts = append(parts, fmt.Sprintf("%d-byte alignment", l.ObjectAlignment))
+	return strings.Join(parts, ", ")
+}
+
+func (l HeapLayout) referenceSize() int {
+	if l.Is64Bit && !l.CompressedOops {
+		return 8
+	}
+	return 4
+}
+
+// headerSize is the size of the mark word and the class pointer
+func (l HeapLayout) headerSize() int {
+	if !l.Is64Bit {
+		return 8
+	}
+	if l.CompressedClassPointers {
+		return 12
+	}
+	return 16
+}
+
+func (l HeapLayout) alignment() int {
+	if l.ObjectAlignment <= 0 {
+		return 8
+	}
+	return l.ObjectAlignment
+}
+
+// elementSize is the size of the field or
+// the array element of the type in the heap
+func (s SizeInfo) elementSize(javaType JavaType) int {
+	if javaType == Object {
+		return s.layout.referenceSize()
+	}
+	return s.OfType(javaType)
+}
+
+// arrayBase is the offset of the first element of the array,
+// elements follow the length which follows the header. The
+// base is word aligned on 64-bit JVM, on 32-bit JVM only the
+// arrays of 8-byte elements are aligned.
+func (s SizeInfo) arrayBase(elementType JavaType) int {
+	base := s.layout.headerSize() + 4
+	if s.layout.Is64Bit || s.elementSize(elementType) == 8 {
+		base = alignUp(base, 8)
+	}
+	return base
+}
+
+// OfReference is the size of the reference in the heap.
+func (s SizeInfo) OfReference() int {
+	return s.layout.referenceSize()
+}
+
+// OfInstance is the shallow size of the instance which has the fields
+// of the given types. The fields of all superclasses should be given.
+func (s SizeInfo) OfInstance(fieldTypes []JavaType) int {
+	size := s.layout.headerSize()
+	for _, fieldType := range fieldTypes {
+		size += s.elementSize(fieldType)
+	}
+	return alignUp(size, s.layout.alignment())
+}
+
+// OfArray is the shallow size of the array of the given length.
+func (s SizeInfo) OfArray(elementType JavaType, length int) int {
+	return alignUp(s.arrayBase(elementType)+length*s.elementSize(elementType), s.layout.alignment())
+}
+
+// OfArrays is the total shallow size of count arrays with the elements
+// of the given type. The padding of every array is restored from the
+// lengths, it's not taken into account for the arrays that are not
+// counted in the lengths.
+func (s SizeInfo) OfArrays(elementType JavaType, count, elements int, lengths LengthResidues) int {
+	base, element := s.arrayBase(elementType), s.elementSize(elementType)
+	size := count*base + elements*element
+	for residue, arrays := range lengths {
+		unaligned := base + int(residue)*element
+		size += arrays * (alignUp(unaligned, s.layout.alignment()) - unaligned)
+	}
+	return size
+}
+
+// LengthResidues counts the arrays by the remainder of the division of
+// their lengths by 256. Object alignment is the power of two up to 256,
+// so the padding of the array depends only on the remainder and the
+// total size of arrays is known without reading every array.
+type LengthResidues map[uint8]int
+
+func (r LengthResidues) Add(length int) {
+	r[uint8(length)]++
+}
+
+func alignUp(size, alignment int) int {
+	return (size + alignment - 1) / alignment * alignment
+}
@@ -53,6 +53,71 @@ func TestHprofGcRootJavaFrame_Size(t *testing.T) {
 	}
 }
 
+func TestHprofGcRootUnknown_Size(t *testing.T) {
+	r := HprofGcRootUnknown{
+		ObjectId: 1,
+	}
+	var want int = 8
+	if got := size.Of(r); got != want {
+		t.Errorf("HprofGcRootUnknown.Size() = %v, want %v", got, want)
+	}
+}
+
+func TestHprofGcRootNativeStack_Size(t *testing.T) {
+	r := HprofGcRootNativeStack{
+		ObjectId:           1,
+		ThreadSerialNumber: 1,
+	}
+	var want int = 12
+	if got := size.Of(r); got != want {
+		t.Errorf("HprofGcRootNativeStack.Size() = %v, want %v", got, want)
+	}
+}
+
+func TestHprofGcRootThreadBlock_Size(t *testing.T) {
+	r := HprofGcRootThreadBlock{
+		ObjectId:           1,
+		ThreadSerialNumber: 1,
+	}
+	var want int = 12
+	if got := size.Of(r); got != want {
+		t.Errorf("HprofGcRootThreadBlock.Size() = %v, want %v", got, want)
+	}
+}
+
+func TestHprofGcRootMonitorUsed_Size(t *testing.T) {
+	r := HprofGcRootMonitorUsed{
+		ObjectId: 1,
+	}
+	var want int = 8
+	if got := size.Of(r); got != want {
+		t.Errorf("HprofGcRootMonitorUsed.Size() = %v, want %v", got, want)
+	}
+}
+
+func TestHprofGcRootJniMonitor_Size(t *testing.T) {
+	r := HprofGcRootJniMonitor{
+		ObjectId:           1,
+		ThreadSerialNumber: 1,
+		StackTraceDepth:    1,
+	}
+	var want int = 16
+	if got := size.Of(r); got != want {
+		t.Errorf("HprofGcRootJniMonitor.Size() = %v, want %v", got, want)
+	}
+}
+
+func TestHprofHeapDumpInfo_Size(t *testing.T) {
+	r := HprofHeapDumpInfo{
+		HeapType:   1,
+		HeapNameId: 1,
+	}
+	var want int = 12
+	if got := size.Of(r); got != want {
+		t.Errorf("HprofHeapDumpInfo.Size() = %v, want %v", got, want)
+	}
+}
+
 func TestHprofGcRootStickyClass_Size(t *testing.T) {
 	r := HprofGcRootStickyClass{
 		ObjectId: 1,
@@ -193,3 +258,95 @@ func TestJavaType_Size(t *testing.T) {
 		})
 	}
 }
+
+func heapLayout(t *testing.T, idSize uint32, options string) *SizeInfo {
+	t.Helper()
+	layout := DefaultHeapLayout(idSize)
+	if err := layout.Apply(options); err != nil {
+		t.Fatalf("Apply(%q) error = %v", options, err)
+	}
+	return NewSizeInfo(idSize).WithLayout(layout)
+}
+
+func TestSizeInfo_OfInstance(t *testing.T) {
+	fields := []JavaType{Object, Int, Byte}
+	tests := []struct {
+		name    string
+		idSize  uint32
+		options string
+		fields  []JavaType
+		want    int
+	}{
+		{name: "compressed", idSize: 8, fields: fields, want: 24},
+		{name: "no fields", idSize: 8, want: 16},
+		{name: "uncompressed", idSize: 8, options: "-XX:-UseCompressedOops,-XX:-UseCompressedClassPointers", fields: fields, want: 32},
+		{name: "compressed class pointers only", idSize: 8, options: "-UseCompressedOops", fields: fields, want: 32},
+		{name: "32-bit", idSize: 4, fields: fields, want: 24},
+		{name: "alignment", idSize: 8, options: "-XX:ObjectAlignmentInBytes=16", fields: fields, want: 32},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := heapLayout(t, tt.idSize, tt.options).OfInstance(tt.fields); got != tt.want {
+				t.Errorf("OfInstance() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSizeInfo_OfArray(t *testing.T) {
+	tests := []struct {
+		name        string
+		idSize      uint32
+		options     string
+		elementType JavaType
+		length      int
+		want        int
+	}{
+		{name: "byte[3] compressed", idSize: 8, elementType: Byte, length: 3, want: 24},
+		{name: "byte[3] uncompressed", idSize: 8, options: "-UseCompressedOops,-UseCompressedClassPointers", elementType: Byte, length: 3, want: 32},
+		{name: "byte[3] 32-bit", idSize: 4, elementType: Byte, length: 3, want: 16},
+		{name: "empty char[]", idSize: 8, elementType: Char, length: 0, want: 16},
+		{name: "long[1] 32-bit", idSize: 4, elementType: Long, length: 1, want: 24},
+		{name: "Object[3] compressed", idSize: 8, elementType: Object, length: 3, want: 32},
+		{name: "Object[3] uncompressed", idSize: 8, options: "-UseCompressedOops,-UseCompressedClassPointers", elementType: Object, length: 3, want: 48},
+		{name: "Object[3] 32-bit", idSize: 4, elementType: Object, length: 3, want: 24},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := heapLayout(t, tt.idSize, tt.options).OfArray(tt.elementType, tt.length); got != tt.want {
+				t.Errorf("OfArray() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSizeInfo_OfArrays(t *testing.T) {
+	for _, options := range []string{"", "ObjectAlignmentInBytes=32", "-UseCompressedClassPointers"} {
+		size := heapLayout(t, 8, options)
+		lengths := make(LengthResidues)
+		var want, elements int
+		for _, length := range []int{3, 3, 8, 259} {
+			lengths.Add(length)
+			elements += length
+			want += size.OfArray(Byte, length)
+		}
+		if got := size.OfArrays(Byte, 4, elements, lengths); got != want {
+			t.Errorf("OfArrays() with %q = %v, want %v", options, got, want)
+		}
+	}
+}
+
+func TestHeapLayout_Apply(t *testing.T) {
+	layout := DefaultHeapLayout(8)
+	if err := layout.Apply("-XX:-UseCompressedOops, -XX:ObjectAlignmentInBytes=16"); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if want := "64-bit, compressed class pointers, 16-byte alignment"; layout.String() != want {
+		t.Errorf("String() = %v, want %v", layout.String(), want)
+	}
+	for _, options := range []string{"ObjectAlignmentInBytes=12", "ObjectAlignmentInBytes=512", "+UseG1GC", "UseCompressedOops"} {
+		if err := layout.Apply(options); err == nil {
+			t.Errorf("Apply(%q) error expected", options)
+		}
+	}
+}
@@ -7,8 +7,16 @@ type Tag byte
 const (
 	HprofUtf8Tag            Tag = 0x01
 	HprofLoadClassTag       Tag = 0x02
+	HprofUnloadClassTag     Tag = 0x03
 	HprofFrameTag           Tag = 0x04
 	HprofTraceTag           Tag = 0x05
+	HprofAllocSitesTag      Tag = 0x06
+	HprofHeapSummaryTag     Tag = 0x07
+	HprofStartThreadTag     Tag = 0x0a
+	HprofEndThreadTag       Tag = 0x0b
+	HprofHeapDumpTag        Tag = 0x0c
+	HprofCpuSamplesTag      Tag = 0x0d
+	HprofControlSettingsTag Tag = 0x0e
 	HprofHeapDumpSegmentTag Tag = 0x1c
 	HprofHeapDumpEndTag     Tag = 0x2c
 )
@@ -16,8 +24,16 @@ const (
 var tagMap = map[Tag]string{
 	HprofUtf8Tag:            "HPROF_UTF8",
 	HprofLoadClassTag:       "HPROF_LOAD_CLASS",
+	HprofUnloadClassTag:     "HPROF_UNLOAD_CLASS",
 	HprofFrameTag:           "HPROF_FRAME",
 	HprofTraceTag:           "HPROF_TRACE",
+	HprofAllocSitesTag:      "HPROF_ALLOC_SITES",
+	HprofHeapSummaryTag:     "HPROF_HEAP_SUMMARY",
+	HprofStartThreadTag:     "HPROF_START_THREAD",
+	HprofEndThreadTag:       "HPROF_END_THREAD",
+	HprofHeapDumpTag:        "HPROF_HEAP_DUMP",
+	HprofCpuSamplesTag:      "HPROF_CPU_SAMPLES",
+	HprofControlSettingsTag: "HPROF_CONTROL_SETTINGS",
 	HprofHeapDumpSegmentTag: "HPROF_HEAP_DUMP_SEGMENT",
 	HprofHeapDumpEndTag:     "HPROF_HEAP_DUMP_END",
 }
@@ -37,17 +53,35 @@ const (
 	HprofGcRootJniLocalType    SubRecordType = 0x02
 	HprofGcRootJavaFrameType   SubRecordType = 0x03
 	HprofGcRootStickyClassType SubRecordType = 0x05
+	HprofGcRootNativeStackType SubRecordType = 0x04
+	HprofGcRootThreadBlockType SubRecordType = 0x06
+	HprofGcRootMonitorUsedType SubRecordType = 0x07
 	HprofGcRootThreadObjType   SubRecordType = 0x08
 	HprofGcClassDumpType       SubRecordType = 0x20
 	HprofGcInstanceDumpType    SubRecordType = 0x21
 	HprofGcObjArrayDumpType    SubRecordType = 0x22
 	HprofGcPrimArrayDumpType   SubRecordType = 0x23
+	HprofGcRootUnknownType     SubRecordType = 0xff
+)
+
+// sub-records of Android Runtime (ART) heap dumps.
+const (
+	HprofGcRootInternedStringType   SubRecordType = 0x89
+	HprofGcRootFinalizingType       SubRecordType = 0x8a
+	HprofGcRootDebuggerType         SubRecordType = 0x8b
+	HprofGcRootReferenceCleanupType SubRecordType = 0x8c
+	HprofGcRootVmInternalType       SubRecordType = 0x8d
+	HprofGcRootJniMonitorType       SubRecordType = 0x8e
+	HprofGcRootUnreachableType      SubRecordType = 0x90
+	HprofGcPrimArrayNoDataDumpType  SubRecordType = 0xc3
+	HprofHeapDumpInfoType           SubRecordType = 0xfe
 )
 
 // these duplicated tags from Tags to be able to exit parse subrecords loop.
 const (
 	HprofHeapDumpEndSubRecord     SubRecordType = 0x2c
 	HprofHeapDumpSegmentSubRecord SubRecordType = 0x1c
+	HprofHeapDumpSubRecord        SubRecordType = 0x0c
 )
 
 var subRecordTypeMap = map[SubRecordType]string{
@@ -55,11 +89,25 @@ var subRecordTypeMap = map[SubRecordType]string{
 	HprofGcRootJniLocalType:    "HPROF_GC_ROOT_JNI_LOCAL",
 	HprofGcRootJavaFrameType:   "HPROF_GC_ROOT_JAVA_FRAME",
 	HprofGcRootStickyClassType: "HPROF_GC_ROOT_STICKY_CLASS",
+	HprofGcRootNativeStackType: "HPROF_GC_ROOT_NATIVE_STACK",
+	HprofGcRootThreadBlockType: "HPROF_GC_ROOT_THREAD_BLOCK",
+	HprofGcRootMonitorUsedType: "HPROF_GC_ROOT_MONITOR_USED",
 	HprofGcRootThreadObjType:   "HPROF_GC_ROOT_THREAD_OBJ",
 	HprofGcClassDumpType:       "HPROF_GC_CLASS_DUMP",
 	HprofGcInstanceDumpType:    "HPROF_GC_INSTANCE_DUMP",
 	HprofGcObjArrayDumpType:    "HPROF_GC_OBJ_ARRAY_DUMP",
 	HprofGcPrimArrayDumpType:   "HPROF_GC_PRIM_ARRAY_DUMP",
+	HprofGcRootUnknownType:     "HPROF_GC_ROOT_UNKNOWN",
+
+	HprofGcRootInternedStringType:   "HPROF_GC_ROOT_INTERNED_STRING",
+	HprofGcRootFinalizingType:       "HPROF_GC_ROOT_FINALIZING",
+	HprofGcRootDebuggerType:         "HPROF_GC_ROOT_DEBUGGER",
+	HprofGcRootReferenceCleanupType: "HPROF_GC_ROOT_REFERENCE_CLEANUP",
+	HprofGcRootVmInternalType:       "HPROF_GC_ROOT_VM_INTERNAL",
+	HprofGcRootJniMonitorType:       "HPROF_GC_ROOT_JNI_MONITOR",
+	HprofGcRootUnreachableType:      "HPROF_GC_ROOT_UNREACHABLE",
+	HprofGcPrimArrayNoDataDumpType:  "HPROF_GC_PRIM_ARRAY_NODATA_DUMP",
+	HprofHeapDumpInfoType:           "HPROF_HEAP_DUMP_INFO",
 }
 
 func (s SubRecordType) String() string {
@@ -28,6 +28,11 @@ func TestTag_String(t *testing.T) {
 			tag:  HprofTraceTag,
 			want: "HPROF_TRACE",
 		},
+		{
+			name: "HprofHeapDumpTag",
+			tag:  HprofHeapDumpTag,
+			want: "HPROF_HEAP_DUMP",
+		},
 		{
 			name: "HprofHeapDumpSegmentTag",
 			tag:  HprofHeapDumpSegmentTag,
@@ -38,6 +43,41 @@ func TestTag_String(t *testing.T) {
 			tag:  HprofHeapDumpEndTag,
 			want: "HPROF_HEAP_DUMP_END",
 		},
+		{
+			name: "HprofUnloadClassTag",
+			tag:  HprofUnloadClassTag,
+			want: "HPROF_UNLOAD_CLASS",
+		},
+		{
+			name: "HprofAllocSitesTag",
+			tag:  HprofAllocSitesTag,
+			want: "HPROF_ALLOC_SITES",
+		},
+		{
+			name: "HprofHeapSummaryTag",
+			tag:  HprofHeapSummaryTag,
+			want: "HPROF_HEAP_SUMMARY",
+		},
+		{
+			name: "HprofStartThreadTag",
+			tag:  HprofStartThreadTag,
+			want: "HPROF_START_THREAD",
+		},
+		{
+			name: "HprofEndThreadTag",
+			tag:  HprofEndThreadTag,
+			want: "HPROF_END_THREAD",
+		},
+		{
+			name: "HprofCpuSamplesTag",
+			tag:  HprofCpuSamplesTag,
+			want: "HPROF_CPU_SAMPLES",
+		},
+		{
+			name: "HprofControlSettingsTag",
+			tag:  HprofControlSettingsTag,
+			want: "HPROF_CONTROL_SETTINGS",
+		},
 		{
 			name: "UnknownTag",
 			tag:  142,
@@ -84,6 +124,26 @@ func TestSubRecordType_String(t *testing.T) {
 			s:    HprofGcRootThreadObjType,
 			want: "HPROF_GC_ROOT_THREAD_OBJ",
 		},
+		{
+			name: "HprofGcRootNativeStackType",
+			s:    HprofGcRootNativeStackType,
+			want: "HPROF_GC_ROOT_NATIVE_STACK",
+		},
+		{
+			name: "HprofGcRootThreadBlockType",
+			s:    HprofGcRootThreadBlockType,
+			want: "HPROF_GC_ROOT_THREAD_BLOCK",
+		},
+		{
+			name: "HprofGcRootMonitorUsedType",
+			s:    HprofGcRootMonitorUsedType,
+			want: "HPROF_GC_ROOT_MONITOR_USED",
+		},
+		{
+			name: "HprofGcRootUnknownType",
+			s:    HprofGcRootUnknownType,
+			want: "HPROF_GC_ROOT_UNKNOWN",
+		},
 		{
 			name: "HprofGcClassDumpType",
 			s:    HprofGcClassDumpType,
@@ -104,6 +164,51 @@ func TestSubRecordType_String(t *testing.T) {
 			s:    HprofGcPrimArrayDumpType,
 			want: "HPROF_GC_PRIM_ARRAY_DUMP",
 		},
+		{
+			name: "HprofGcRootInternedStringType",
+			s:    HprofGcRootInternedStringType,
+			want: "HPROF_GC_ROOT_INTERNED_STRING",
+		},
+		{
+			name: "HprofGcRootFinalizingType",
+			s:    HprofGcRootFinalizingType,
+			want: "HPROF_GC_ROOT_FINALIZING",
+		},
+		{
+			name: "HprofGcRootDebuggerType",
+			s:    HprofGcRootDebuggerType,
+			want: "HPROF_GC_ROOT_DEBUGGER",
+		},
+		{
+			name: "HprofGcRootReferenceCleanupType",
+			s:    HprofGcRootReferenceCleanupType,
+			want: "HPROF_GC_ROOT_REFERENCE_CLEANUP",
+		},
+		{
+			name: "HprofGcRootVmInternalType",
+			s:    HprofGcRootVmInternalType,
+			want: "HPROF_GC_ROOT_VM_INTERNAL",
+		},
+		{
+			name: "HprofGcRootJniMonitorType",
+			s:    HprofGcRootJniMonitorType,
+			want: "HPROF_GC_ROOT_JNI_MONITOR",
+		},
+		{
+			name: "HprofGcRootUnreachableType",
+			s:    HprofGcRootUnreachableType,
+			want: "HPROF_GC_ROOT_UNREACHABLE",
+		},
+		{
+			name: "HprofGcPrimArrayNoDataDumpType",
+			s:    HprofGcPrimArrayNoDataDumpType,
+			want: "HPROF_GC_PRIM_ARRAY_NODATA_DUMP",
+		},
+		{
+			name: "HprofHeapDumpInfoType",
+			s:    HprofHeapDumpInfoType,
+			want: "HPROF_HEAP_DUMP_INFO",
+		},
 		{
 			name: "Unknown",
 			s:    42,
@@ -6,6 +6,12 @@ import "fmt"
 // used to store it because actual size can be 4 or 8 bytes.
 type Identifier uint64
 
+// MarshalText encodes the identifier as hex string, e.g. 0x7ff0012a8,
+// since JSON numbers can't hold 64-bit identifiers precisely.
+func (i Identifier) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("0x%x", uint64(i))), nil
+}
+
 // LineNumber is the wrapper for int32 with verbose values
 // for some constants (Unknown, CompiledMethod, NativeMethod).
 // It's stringified integer if > 0.
@@ -70,6 +76,10 @@ func (j JavaType) String() string {
 	return "unknown"
 }
 
+func (j JavaType) MarshalText() ([]byte, error) {
+	return []byte(j.String()), nil
+}
+
 // JavaValue wraps type of the
 // constant and the value converted
 // to corresponding Go type.
@@ -89,6 +99,17 @@ func (jv JavaValue) ToBool() (bool, error) {
 	return false, fmt.Errorf("given value %v is not bool", jv)
 }
 
+func (jv JavaValue) ToByte() (int8, error) {
+	if jv.Type == Byte {
+		b, ok := jv.Value.(int8)
+		if ok {
+			return b, nil
+		}
+		return 0, fmt.Errorf("malformed byte value %v (%T)", jv.Value, jv.Value)
+	}
+	return 0, fmt.Errorf("given value %v is not byte", jv)
+}
+
 func (jv JavaValue) ToInt() (int, error) {
 	if jv.Type == Int {
 		i, ok := jv.Value.(int32)
@@ -148,6 +148,43 @@ func TestJavaValue_ToBool(t *testing.T) {
 	}
 }
 
+func TestJavaValue_ToByte(t *testing.T) {
+	tests := []struct {
+		name    string
+		jv      JavaValue
+		want    int8
+		wantErr bool
+	}{
+		{
+			name: "success",
+			jv:   JavaValue{Type: Byte, Value: int8(1)},
+			want: 1,
+		},
+		{
+			name:    "wrong type",
+			jv:      JavaValue{Type: Int, Value: int32(1)},
+			wantErr: true,
+		},
+		{
+			name:    "wrong value",
+			jv:      JavaValue{Type: Byte, Value: true},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.jv.ToByte()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("JavaValue.ToByte() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("JavaValue.ToByte() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestJavaValue_ToInt(t *testing.T) {
 	tests := []struct {
 		name    string
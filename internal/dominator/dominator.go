@@ -0,0 +1,599 @@
+// dominator builds the dominator tree of the heap and computes retained
+// sizes. Object d dominates object x if every path from GC roots to x goes
+// through d, so x is garbage collected as soon as d is. The retained size of
+// the object is the sum of shallow sizes of all objects it dominates.
+//
+// The tree is rooted at the virtual root which references all GC roots.
+// It's built with the semi-NCA algorithm:
+//
+//  1. All objects are numbered in the order of identifiers, the numbering
+//     is the sorted list of identifiers kept on disk.
+//  2. DFS from the virtual root over outbound references (which are the
+//     references index sorted by referrers) assigns preorder numbers and
+//     builds DFS spanning tree. Unreachable objects are not numbered and
+//     are not presented in the tree.
+//  3. Semidominators are computed in decreasing preorder using inbound
+//     references (the references index itself) and link-eval forest with
+//     path compression.
+//  4. Immediate dominator of every object is the nearest common ancestor
+//     of its DFS parent and semidominator in the tree built so far.
+//  5. Retained sizes are accumulated bottom-up in decreasing preorder since
+//     the dominator always precedes the object in preorder.
+//
+// All the arrays of the algorithm are indexed by object number and are
+// backed by storage.PagedArray, so the memory consumption does not depend
+// on the size of the heap.
+package dominator
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/danielleontiev/neojhat/internal/core"
+	"github.com/danielleontiev/neojhat/internal/dump"
+	"github.com/danielleontiev/neojhat/internal/java"
+	"github.com/danielleontiev/neojhat/internal/storage"
+)
+
+// primArrayClass marks primitive arrays in the list of objects
+// since they do not have class objects. The lowest byte is the type.
+const primArrayClass = 1 << 63
+
+const phases = 6
+
+// Builder builds the dominator tree using temporary storage
+// provided by newRun and newArray.
+type Builder struct {
+	parsedAccessor *dump.ParsedAccessor
+	heap           *java.Heap
+	newRun         func() (storage.RunVolume, error)
+	newArray       func() (storage.ArrayVolume, error)
+	pageSize       int
+	maxPages       int
+	progress       atomic.Int64
+
+	arrays []*storage.PagedArray
+
+	// sorted list of all objects, object number is the position in the list + 1,
+	// 0 is the virtual root. Values are shallow sizes and classes of objects.
+	objectSizes   *storage.IndexRecordsReadStorage
+	objectClasses *storage.IndexRecordsReadStorage
+	// references index sorted by referrers
+	outbound *storage.IndexRecordsReadStorage
+	roots    []core.Identifier
+	isRoot   map[core.Identifier]bool
+}
+
+func NewBuilder(
+	parsedAccessor *dump.ParsedAccessor,
+	newRun func() (storage.RunVolume, error),
+	newArray func() (storage.ArrayVolume, error),
+) *Builder {
+	return &Builder{
+		parsedAccessor: parsedAccessor,
+		heap:           java.NewHeap(parsedAccessor),
+		newRun:         newRun,
+		newArray:       newArray,
+		pageSize:       storage.DefaultPageSize,
+		maxPages:       storage.DefaultMaxPages,
+	}
+}
+
+// GetPosition returns the progress of the
+// build in per mille. Safe for concurrent use.
+func (b *Builder) GetPosition() int {
+	return int(b.progress.Load())
+}
+
+func (b *Builder) step(phase, done, total int) {
+	if total == 0 {
+		total = 1
+	}
+	b.progress.Store(int64((1000*phase + 1000*done/total) / phases))
+}
+
+// Build computes the dominator tree and writes it
+// to the destination which is closed afterwards.
+func (b *Builder) Build(destination *storage.DominatorsWriteStorage) error {
+	defer b.release()
+	if err := b.listObjects(); err != nil {
+		return fmt.Errorf("cannot list objects: %w", err)
+	}
+	if err := b.sortOutbound(); err != nil {
+		return fmt.Errorf("cannot sort references: %w", err)
+	}
+	b.roots = b.heap.GcRootIds()
+	b.isRoot = make(map[core.Identifier]bool, len(b.roots))
+	for _, r := range b.roots {
+		b.isRoot[r] = true
+	}
+	t, err := b.newTree()
+	if err != nil {
+		return err
+	}
+	if err := b.dfs(t); err != nil {
+		return fmt.Errorf("cannot traverse heap: %w", err)
+	}
+	if err := b.dominators(t); err != nil {
+		return fmt.Errorf("cannot compute dominators: %w", err)
+	}
+	b.retainedSizes(t)
+	if err := b.write(t, destination); err != nil {
+		return fmt.Errorf("cannot write dominators: %w", err)
+	}
+	if err := b.classRetainedSizes(t, destination.RetainedSizes); err != nil {
+		return fmt.Errorf("cannot compute retained sizes of classes: %w", err)
+	}
+	b.step(phases, 0, 1)
+	return destination.Close()
+}
+
+// tree holds the arrays of the algorithm, all of them
+// except preorder are indexed by preorder number.
+type tree struct {
+	size       int64 // number of reachable objects + virtual root
+	preorder   *storage.PagedArray
+	vertex     *storage.PagedArray
+	parent     *storage.PagedArray
+	semi       *storage.PagedArray
+	label      *storage.PagedArray
+	ancestor   *storage.PagedArray
+	idom       *storage.PagedArray
+	retained   *storage.PagedArray
+	stack      *storage.PagedArray
+	stackState *storage.PagedArray
+}
+
+func (b *Builder) newTree() (*tree, error) {
+	var arrays [10]*storage.PagedArray
+	for i := range arrays {
+		volume, err := b.newArray()
+		if err != nil {
+			return nil, fmt.Errorf("cannot create temporary array: %w", err)
+		}
+		arrays[i] = storage.NewPagedArray(volume, b.pageSize, b.maxPages)
+		b.arrays = append(b.arrays, arrays[i])
+	}
+	return &tree{
+		preorder:   arrays[0],
+		vertex:     arrays[1],
+		parent:     arrays[2],
+		semi:       arrays[3],
+		label:      arrays[4],
+		ancestor:   arrays[5],
+		idom:       arrays[6],
+		retained:   arrays[7],
+		stack:      arrays[8],
+		stackState: arrays[9],
+	}, nil
+}
+
+func (t *tree) err() error {
+	for _, a := range []*storage.PagedArray{t.preorder, t.vertex, t.parent, t.semi, t.label, t.ancestor, t.idom, t.retained, t.stack, t.stackState} {
+		if err := a.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listObjects writes all objects sorted by identifiers together with
+// their shallow sizes and classes to the temporary indexes.
+func (b *Builder) listObjects() error {
+	sizes := storage.NewTempIndex(b.newRun)
+	classes := storage.NewTempIndex(b.newRun)
+	sizesWriter := storage.NewSortingIndexWriteStorage(sizes, b.newRun, storage.DefaultBatchSize)
+	classesWriter := storage.NewSortingIndexWriteStorage(classes, b.newRun, storage.DefaultBatchSize)
+	size, err := b.heap.SizeInfo("")
+	if err != nil {
+		return err
+	}
+	total := b.objectsCount()
+	var done int
+	put := func(objectId core.Identifier, shallowSize int, class uint64) error {
+		done++
+		if done%10_000 == 0 {
+			b.step(0, done, total)
+		}
+		if err := sizesWriter.Put(uint64(objectId), uint64(shallowSize)); err != nil {
+			return err
+		}
+		return classesWriter.Put(uint64(objectId), class)
+	}
+	err = b.parsedAccessor.ScanHprofGcInstanceDumps(func(record core.HprofGcClassDumpInstanceDumpHeader) error {
+		shallowSize, err := b.heap.InstanceSize(record.ClassObjectId, size)
+		if err != nil {
+			return err
+		}
+		return put(record.ObjectId, shallowSize, uint64(record.ClassObjectId))
+	})
+	if err != nil {
+		return err
+	}
+	err = b.parsedAccessor.ScanHprofGcObjArrays(func(record core.HprofGcObjArrayDumpHeader) error {
+		shallowSize := size.OfArray(core.Object, int(record.NumberOfElements))
+		return put(record.ArrayObjectId, shallowSize, uint64(record.ArrayClassId))
+	})
+	if err != nil {
+		return err
+	}
+	err = b.parsedAccessor.ScanHprofGcPrimArrays(func(record core.HprofGcPrimArrayDumpHeader) error {
+		shallowSize := size.OfArray(record.ElementType, int(record.NumberOfElements))
+		return put(record.ArrayObjectId, shallowSize, primArrayClass|uint64(record.ElementType))
+	})
+	if err != nil {
+		return err
+	}
+	// class objects are not attributed to any class
+	for classId := range b.parsedAccessor.HprofGcClassDump {
+		if err := put(classId, 0, 0); err != nil {
+			return err
+		}
+	}
+	if err := sizesWriter.Close(); err != nil {
+		return err
+	}
+	if err := classesWriter.Close(); err != nil {
+		return err
+	}
+	if b.objectSizes, err = sizes.Reader(); err != nil {
+		return err
+	}
+	b.objectClasses, err = classes.Reader()
+	return err
+}
+
+func (b *Builder) objectsCount() int {
+	var count int
+	counters := b.parsedAccessor.MetaStorage.Counters
+	for _, c := range counters.InstancesCount {
+		count += c
+	}
+	for _, c := range counters.ObjArraysCount {
+		count += c
+	}
+	for _, c := range counters.PrimArraysCount {
+		count += c
+	}
+	return count + len(b.parsedAccessor.HprofGcClassDump)
+}
+
+// sortOutbound sorts references index by referrers.
+func (b *Builder) sortOutbound() error {
+	outbound := storage.NewTempIndex(b.newRun)
+	writer := storage.NewSortingIndexWriteStorage(outbound, b.newRun, storage.DefaultBatchSize)
+	err := b.parsedAccessor.ScanReferences(func(from, to core.Identifier) error {
+		return writer.Put(uint64(from), uint64(to))
+	})
+	if err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	b.outbound, err = outbound.Reader()
+	return err
+}
+
+// objectNumber returns the number of the object or 0 if
+// the object is not presented in the dump.
+func (b *Builder) objectNumber(objectId core.Identifier) (int64, error) {
+	position, err := b.objectSizes.Find(uint64(objectId))
+	if err != nil {
+		return 0, err
+	}
+	if position == b.objectSizes.Len() {
+		return 0, nil
+	}
+	key, _, err := b.objectSizes.At(position)
+	if err != nil {
+		return 0, err
+	}
+	if key != uint64(objectId) {
+		return 0, nil
+	}
+	return int64(position) + 1, nil
+}
+
+func (b *Builder) objectAt(number int64) (objectId core.Identifier, shallowSize int, err error) {
+	key, val, err := b.objectSizes.At(int(number - 1))
+	return core.Identifier(key), int(val), err
+}
+
+// dfs assigns preorder numbers starting from 1 (the virtual root) with
+// iterative depth-first search. The stack keeps preorder numbers and the
+// position of the next outbound reference to follow.
+func (b *Builder) dfs(t *tree) error {
+	total := b.objectSizes.Len() + 1
+	var n int64
+	visit := func(number int64, parent int64) error {
+		n++
+		if n%10_000 == 0 {
+			b.step(1, int(n), total)
+		}
+		t.preorder.Set(number, uint64(n))
+		t.vertex.Set(n, uint64(number))
+		t.parent.Set(n, uint64(parent))
+		t.semi.Set(n, uint64(n))
+		t.label.Set(n, uint64(n))
+		if number == 0 {
+			return nil
+		}
+		_, shallowSize, err := b.objectAt(number)
+		t.retained.Set(n, uint64(shallowSize))
+		return err
+	}
+	// preorder of unvisited objects is 0, the virtual root is object 0
+	if err := visit(0, 0); err != nil {
+		return err
+	}
+	var depth int64 = 1
+	t.stack.Set(0, 1)
+	t.stackState.Set(0, 0)
+	for depth > 0 {
+		current := int64(t.stack.Get(depth - 1))
+		state := int64(t.stackState.Get(depth - 1))
+		next, nextState, ok, err := b.nextSuccessor(t, current, state)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			depth--
+			continue
+		}
+		t.stackState.Set(depth-1, uint64(nextState))
+		number, err := b.objectNumber(next)
+		if err != nil {
+			return err
+		}
+		if number == 0 || t.preorder.Get(number) != 0 {
+			continue
+		}
+		if err := visit(number, current); err != nil {
+			return err
+		}
+		cursor, err := b.outbound.Find(uint64(next))
+		if err != nil {
+			return err
+		}
+		t.stack.Set(depth, uint64(n))
+		t.stackState.Set(depth, uint64(cursor))
+		depth++
+		if err := t.err(); err != nil {
+			return err
+		}
+	}
+	t.size = n
+	return t.err()
+}
+
+// nextSuccessor returns the successor of the object with given preorder number
+// at the given position. For the virtual root the position is the index in the
+// list of GC roots, for other objects - the position in outbound references.
+func (b *Builder) nextSuccessor(t *tree, pre int64, position int64) (core.Identifier, int64, bool, error) {
+	if pre == 1 {
+		if position >= int64(len(b.roots)) {
+			return 0, 0, false, nil
+		}
+		return b.roots[position], position + 1, true, nil
+	}
+	if position >= int64(b.outbound.Len()) {
+		return 0, 0, false, nil
+	}
+	objectId, _, err := b.objectAt(int64(t.vertex.Get(pre)))
+	if err != nil {
+		return 0, 0, false, err
+	}
+	from, to, err := b.outbound.At(int(position))
+	if err != nil {
+		return 0, 0, false, err
+	}
+	if from != uint64(objectId) {
+		return 0, 0, false, nil
+	}
+	return core.Identifier(to), position + 1, true, nil
+}
+
+// dominators computes semidominators and then immediate dominators.
+func (b *Builder) dominators(t *tree) error {
+	for w := t.size; w >= 2; w-- {
+		if w%10_000 == 0 {
+			b.step(2, int(t.size-w), int(t.size))
+		}
+		objectId, _, err := b.objectAt(int64(t.vertex.Get(w)))
+		if err != nil {
+			return err
+		}
+		predecessors, err := b.parsedAccessor.GetReferrers(objectId)
+		if err != nil {
+			return err
+		}
+		semi := t.semi.Get(w)
+		for _, predecessor := range predecessors {
+			number, err := b.objectNumber(predecessor)
+			if err != nil {
+				return err
+			}
+			if number == 0 {
+				continue
+			}
+			v := int64(t.preorder.Get(number))
+			if v == 0 {
+				continue
+			}
+			if s := t.semi.Get(b.eval(t, v)); s < semi {
+				semi = s
+			}
+		}
+		if b.isRoot[objectId] {
+			semi = 1
+		}
+		t.semi.Set(w, semi)
+		t.ancestor.Set(w, t.parent.Get(w))
+		if err := t.err(); err != nil {
+			return err
+		}
+	}
+	b.step(3, 0, 1)
+	t.idom.Set(1, 0)
+	for w := int64(2); w <= t.size; w++ {
+		idom := t.parent.Get(w)
+		semi := t.semi.Get(w)
+		for idom > semi {
+			idom = t.idom.Get(int64(idom))
+		}
+		t.idom.Set(w, idom)
+	}
+	return t.err()
+}
+
+// eval returns the vertex with minimal semidominator on the path from v
+// to the root of its tree in the link-eval forest excluding the root. The
+// path is compressed on the way, stack array is reused to keep the path.
+func (b *Builder) eval(t *tree, v int64) int64 {
+	if t.ancestor.Get(v) == 0 {
+		return v
+	}
+	var depth int64
+	for u := v; t.ancestor.Get(int64(t.ancestor.Get(u))) != 0; u = int64(t.ancestor.Get(u)) {
+		t.stack.Set(depth, uint64(u))
+		depth++
+	}
+	for depth > 0 {
+		depth--
+		u := int64(t.stack.Get(depth))
+		a := int64(t.ancestor.Get(u))
+		if t.semi.Get(int64(t.label.Get(a))) < t.semi.Get(int64(t.label.Get(u))) {
+			t.label.Set(u, t.label.Get(a))
+		}
+		t.ancestor.Set(u, t.ancestor.Get(a))
+	}
+	return int64(t.label.Get(v))
+}
+
+func (b *Builder) retainedSizes(t *tree) {
+	b.step(4, 0, 1)
+	for w := t.size; w >= 2; w-- {
+		idom := int64(t.idom.Get(w))
+		t.retained.Set(idom, t.retained.Get(idom)+t.retained.Get(w))
+	}
+}
+
+// write stores the tree in the order of identifiers.
+func (b *Builder) write(t *tree, destination *storage.DominatorsWriteStorage) error {
+	total := b.objectSizes.Len()
+	for number := int64(1); number <= int64(total); number++ {
+		if number%10_000 == 0 {
+			b.step(5, int(number), total)
+		}
+		pre := int64(t.preorder.Get(number))
+		if pre == 0 {
+			continue
+		}
+		objectId, _, err := b.objectAt(number)
+		if err != nil {
+			return err
+		}
+		var immediateDominator core.Identifier
+		if idom := int64(t.idom.Get(pre)); idom > 1 {
+			immediateDominator, _, err = b.objectAt(int64(t.vertex.Get(idom)))
+			if err != nil {
+				return err
+			}
+		}
+		if err := destination.PutDominator(objectId, immediateDominator, int(t.retained.Get(pre))); err != nil {
+			return err
+		}
+	}
+	return t.err()
+}
+
+// classRetainedSizes walks the dominator tree and sums up retained sizes
+// of the objects by classes. The object is counted only if there is no
+// object of the same class above it in the tree, otherwise its retained
+// size is already included.
+func (b *Builder) classRetainedSizes(t *tree, retainedSizes storage.RetainedSizes) error {
+	children := storage.NewTempIndex(b.newRun)
+	writer := storage.NewSortingIndexWriteStorage(children, b.newRun, storage.DefaultBatchSize)
+	for w := int64(2); w <= t.size; w++ {
+		if err := writer.Put(t.idom.Get(w), uint64(w)); err != nil {
+			return err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	childrenReader, err := children.Reader()
+	if err != nil {
+		return err
+	}
+	defer childrenReader.Close()
+
+	onPath := make(map[uint64]int)
+	classOf := func(pre int64) (uint64, error) {
+		_, class, err := b.objectClasses.At(int(t.vertex.Get(pre)) - 1)
+		return class, err
+	}
+	// stack keeps preorder numbers, state - position of the next child
+	var depth int64 = 1
+	t.stack.Set(0, 1)
+	t.stackState.Set(0, 0)
+	for depth > 0 {
+		current := int64(t.stack.Get(depth - 1))
+		position := int(t.stackState.Get(depth - 1))
+		var next int64
+		if position < childrenReader.Len() {
+			parent, child, err := childrenReader.At(position)
+			if err != nil {
+				return err
+			}
+			if int64(parent) == current {
+				next = int64(child)
+			}
+		}
+		if next == 0 {
+			depth--
+			if current != 1 {
+				class, err := classOf(current)
+				if err != nil {
+					return err
+				}
+				onPath[class]--
+			}
+			continue
+		}
+		t.stackState.Set(depth-1, uint64(position+1))
+		class, err := classOf(next)
+		if err != nil {
+			return err
+		}
+		if onPath[class] == 0 && class != 0 {
+			retained := int(t.retained.Get(next))
+			if class&primArrayClass != 0 {
+				retainedSizes.PrimArrays[core.JavaType(class&0xff)] += retained
+			} else {
+				retainedSizes.Classes[core.Identifier(class)] += retained
+			}
+		}
+		onPath[class]++
+		firstChild, err := childrenReader.Find(uint64(next))
+		if err != nil {
+			return err
+		}
+		t.stack.Set(depth, uint64(next))
+		t.stackState.Set(depth, uint64(firstChild))
+		depth++
+	}
+	return t.err()
+}
+
+func (b *Builder) release() {
+	for _, a := range b.arrays {
+		a.Close()
+	}
+	for _, index := range []*storage.IndexRecordsReadStorage{b.objectSizes, b.objectClasses, b.outbound} {
+		if index != nil {
+			index.Close()
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package dominator
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/danielleontiev/neojhat/internal/core"
+	"github.com/danielleontiev/neojhat/internal/storage"
+	td "github.com/danielleontiev/neojhat/internal/testdump"
+)
+
+// class Node { Node a; Node b; }
+//
+//	root → 1000 → 1001 → 1003 → 1004
+//	         ↘ 1002 ↗      ↘ 1005 (int[1])
+//	root → 2000 → 3000 (Object[]) → 3001 (byte[10])
+//	                         ↘ 1001
+//	5000 is unreachable
+var dominatorsSample = td.Dump(
+	[][]byte{
+		td.Utf8(1, "Node"),
+		td.Utf8(2, "java/lang/Object"),
+		td.Utf8(3, "a"),
+		td.Utf8(4, "b"),
+		td.Utf8(5, "[Ljava/lang/Object;"),
+		td.LoadClass(1, 100, 1),
+		td.LoadClass(2, 101, 2),
+		td.LoadClass(3, 102, 5),
+	},
+	td.RootJniGlobal(1000),
+	td.RootJavaFrame(2000, 1, 0),
+	td.RootStickyClass(101),
+	td.ClassDump(101, 0, 0, nil, nil),
+	td.ClassDump(100, 101, 16, nil, []td.Field{{NameId: 3, Type: core.Object}, {NameId: 4, Type: core.Object}}),
+	td.ClassDump(102, 101, 0, nil, nil),
+	td.InstanceDump(1000, 100, td.Id(1001), td.Id(1002)),
+	td.InstanceDump(1001, 100, td.Id(1003), td.Id(0)),
+	td.InstanceDump(1002, 100, td.Id(1003), td.Id(0)),
+	td.InstanceDump(1003, 100, td.Id(1004), td.Id(1005)),
+	td.InstanceDump(1004, 100, td.Id(0), td.Id(0)),
+	td.InstanceDump(2000, 100, td.Id(3000), td.Id(0)),
+	td.InstanceDump(5000, 100, td.Id(1000), td.Id(0)),
+	td.ObjArrayDump(3000, 102, 3001, 1001),
+	td.PrimArrayDump(1005, core.Int, 1, td.U4(7)),
+	td.PrimArrayDump(3001, core.Byte, 10, make([]byte, 10)),
+)
+
+func TestBuilder_Build(t *testing.T) {
+	parsedAccessor, err := td.NewParsedAccessor(dominatorsSample, true)
+	if err != nil {
+		t.Fatalf("cannot parse sample: %v", err)
+	}
+	dominatorsVolume := storage.NewRamWriteVolume()
+	retainedVolume := storage.NewRamWriteVolume()
+	dominatedVolume := storage.NewRamWriteVolume()
+	writer := storage.NewDominatorsWriteStorage(dominatorsVolume, retainedVolume, dominatedVolume, td.NewRun)
+	builder := NewBuilder(parsedAccessor, td.NewRun, td.NewArray)
+	// pages of 2 elements force paging even on the small graph
+	builder.pageSize, builder.maxPages = 2, 3
+	if err := builder.Build(writer); err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if got := builder.GetPosition(); got != 1000 {
+		t.Errorf("GetPosition() = %v, want 1000", got)
+	}
+	retainedSizesBuf := bytes.NewBuffer(nil)
+	if err := writer.SerializeTo(retainedSizesBuf); err != nil {
+		t.Fatalf("SerializeTo() error = %v", err)
+	}
+	reader, err := storage.NewDominatorsReadStorage(
+		storage.NewRamReadVolume(dominatorsVolume.Bytes()), dominatorsVolume.Len(),
+		storage.NewRamReadVolume(retainedVolume.Bytes()), retainedVolume.Len(),
+		storage.NewRamReadVolume(dominatedVolume.Bytes()), dominatedVolume.Len(),
+	)
+	if err != nil {
+		t.Fatalf("cannot create reader: %v", err)
+	}
+	if err := reader.RestoreFrom(retainedSizesBuf); err != nil {
+		t.Fatalf("RestoreFrom() error = %v", err)
+	}
+
+	// shallow sizes with compressed oops: Node is 24 (12-byte header and
+	// two references), int[1] is 24, byte[10] is 32, Object[2] is 24
+	tests := []struct {
+		id       core.Identifier
+		idom     core.Identifier
+		retained int
+	}{
+		{id: 101, idom: 0, retained: 0},
+		{id: 1000, idom: 0, retained: 48},
+		{id: 1001, idom: 0, retained: 24},
+		{id: 1002, idom: 1000, retained: 24},
+		{id: 1003, idom: 0, retained: 72},
+		{id: 1004, idom: 1003, retained: 24},
+		{id: 1005, idom: 1003, retained: 24},
+		{id: 2000, idom: 0, retained: 80},
+		{id: 3000, idom: 2000, retained: 56},
+		{id: 3001, idom: 3000, retained: 32},
+	}
+	for _, tt := range tests {
+		idom, err := reader.GetImmediateDominator(tt.id)
+		if err != nil {
+			t.Errorf("GetImmediateDominator(%v) error = %v", tt.id, err)
+		}
+		if idom != tt.idom {
+			t.Errorf("GetImmediateDominator(%v) = %v, want %v", tt.id, idom, tt.idom)
+		}
+		retained, err := reader.GetRetainedSize(tt.id)
+		if err != nil {
+			t.Errorf("GetRetainedSize(%v) error = %v", tt.id, err)
+		}
+		if retained != tt.retained {
+			t.Errorf("GetRetainedSize(%v) = %v, want %v", tt.id, retained, tt.retained)
+		}
+	}
+	for _, unreachable := range []core.Identifier{5000, 100, 102} {
+		if _, err := reader.GetImmediateDominator(unreachable); err == nil {
+			t.Errorf("GetImmediateDominator(%v) of unreachable object, error expected", unreachable)
+		}
+	}
+
+	dominated, err := reader.GetDominated(0)
+	if err != nil {
+		t.Errorf("GetDominated() error = %v", err)
+	}
+	if want := []core.Identifier{101, 1000, 1001, 1003, 2000}; !reflect.DeepEqual(dominated, want) {
+		t.Errorf("GetDominated(0) = %v, want %v", dominated, want)
+	}
+
+	wantRetainedSizes := storage.RetainedSizes{
+		// 1002 and 1004 are dominated by other nodes
+		Classes:    map[core.Identifier]int{100: 48 + 80 + 24 + 72, 102: 56},
+		PrimArrays: map[core.JavaType]int{core.Byte: 32, core.Int: 24},
+	}
+	if !reflect.DeepEqual(reader.RetainedSizes, wantRetainedSizes) {
+		t.Errorf("RetainedSizes = %+v, want %+v", reader.RetainedSizes, wantRetainedSizes)
+	}
+}
+
+// TestBuilder_BuildRandom compares dominators of random graphs with
+// the naive definition: d dominates v if v is unreachable without d.
+func TestBuilder_BuildRandom(t *testing.T) {
+	random := rand.New(rand.NewSource(42))
+	for iteration := 0; iteration < 50; iteration++ {
+		const n = 30
+		successors := make([][]uint64, n)
+		for i := range successors {
+			for j := random.Intn(4); j > 0; j-- {
+				successors[i] = append(successors[i], uint64(random.Intn(n)))
+			}
+		}
+		roots := []uint64{0, uint64(random.Intn(n))}
+		subRecords := [][]byte{td.ClassDump(102, 0, 0, nil, nil)}
+		for _, r := range roots {
+			subRecords = append(subRecords, td.RootJniGlobal(1000+r))
+		}
+		for i, s := range successors {
+			elements := make([]uint64, len(s))
+			for j := range s {
+				elements[j] = 1000 + s[j]
+			}
+			subRecords = append(subRecords, td.ObjArrayDump(1000+uint64(i), 102, elements...))
+		}
+		sample := td.Dump([][]byte{td.Utf8(5, "[Ljava/lang/Object;"), td.LoadClass(3, 102, 5)}, subRecords...)
+
+		parsedAccessor, err := td.NewParsedAccessor(sample, true)
+		if err != nil {
+			t.Fatalf("cannot parse sample: %v", err)
+		}
+		dominatorsVolume := storage.NewRamWriteVolume()
+		writer := storage.NewDominatorsWriteStorage(dominatorsVolume, storage.NewRamWriteVolume(), storage.NewRamWriteVolume(), td.NewRun)
+		builder := NewBuilder(parsedAccessor, td.NewRun, td.NewArray)
+		builder.pageSize, builder.maxPages = 4, 2
+		if err := builder.Build(writer); err != nil {
+			t.Fatalf("Build() error = %v", err)
+		}
+		reader, err := storage.NewIndexRecordsReadStorage(storage.NewRamReadVolume(dominatorsVolume.Bytes()), dominatorsVolume.Len())
+		if err != nil {
+			t.Fatalf("cannot create reader: %v", err)
+		}
+		got := make(map[uint64]uint64)
+		reader.Scan(func(key, val uint64) error {
+			got[key-1000] = val
+			return nil
+		})
+		want := naiveDominators(successors, roots)
+		for v, idom := range want {
+			if got[v] != idom {
+				t.Errorf("iteration %v: idom(%v) = %v, want %v", iteration, v+1000, got[v], idom)
+			}
+		}
+		if len(got) != len(want) {
+			t.Errorf("iteration %v: %v objects in the tree, want %v", iteration, len(got), len(want))
+		}
+	}
+}
+
+// naiveDominators returns immediate dominators of reachable nodes as
+// identifiers (1000 + node), 0 is the virtual root.
+func naiveDominators(successors [][]uint64, roots []uint64) map[uint64]uint64 {
+	reachable := func(removed int) map[uint64]bool {
+		seen := make(map[uint64]bool)
+		var queue []uint64
+		for _, r := range roots {
+			if int(r) != removed && !seen[r] {
+				seen[r] = true
+				queue = append(queue, r)
+			}
+		}
+		for len(queue) > 0 {
+			v := queue[0]
+			queue = queue[1:]
+			for _, s := range successors[v] {
+				if int(s) != removed && !seen[s] {
+					seen[s] = true
+					queue = append(queue, s)
+				}
+			}
+		}
+		return seen
+	}
+	all := reachable(-1)
+	dominators := make(map[uint64][]uint64)
+	for d := range successors {
+		without := reachable(d)
+		for v := range all {
+			if v != uint64(d) && !without[v] {
+				dominators[v] = append(dominators[v], uint64(d))
+			}
+		}
+	}
+	result := make(map[uint64]uint64)
+	for v := range all {
+		// the immediate dominator has the most dominators itself
+		var idom uint64
+		best := -1
+		for _, d := range dominators[v] {
+			if len(dominators[d]) > best {
+				best = len(dominators[d])
+				idom = 1000 + d
+			}
+		}
+		result[v] = idom
+	}
+	return result
+}
@@ -0,0 +1,424 @@
+package dump
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/danielleontiev/neojhat/internal/core"
+	"github.com/danielleontiev/neojhat/internal/storage"
+)
+
+// ParallelParser saves the same information to storages as Parser but
+// parses heap dump segments with several goroutines. The first pass walks
+// top-level records and finds where the segments are, then the segments
+// are parsed by the pool of workers. Every worker writes its own part of
+// the indexes, so the parts are merged in the end. Small records and
+// counters are collected per segment and combined in the order of
+// segments.
+//
+// Lengths of segments are the only way to find them without parsing, so
+// the dump is parsed by Parser when some length is missing or points past
+// the end of the file. The same happens when there are less than two
+// segments or jobs.
+type ParallelParser struct {
+	heapDump                 io.ReaderAt
+	size                     int64
+	jobs                     int
+	newRun                   func() (storage.RunVolume, error)
+	smallRecordsWriteStorage *storage.SmallRecordsWriteStorage
+	bigRecordsWriteStorage   *storage.BigRecordsWriteStorage
+	metaWriteStorage         *storage.MetaWriteStorage
+	referencesWriteStorage   *storage.ReferencesWriteStorage
+	// bytes of the dump parsed so far
+	pos atomic.Int64
+	// parser that parses the dump when segments cannot be parsed in parallel
+	sequential atomic.Pointer[Parser]
+}
+
+// NewParallelParser creates the parser of the heap dump of the given size.
+// jobs is the number of goroutines parsing segments, newRun creates
+// temporary volumes for the parts of the indexes.
+func NewParallelParser(
+	heapDump io.ReaderAt,
+	size int64,
+	jobs int,
+	newRun func() (storage.RunVolume, error),
+	smallRecordsWriteStorage *storage.SmallRecordsWriteStorage,
+	bigRecordsWriteStorage *storage.BigRecordsWriteStorage,
+	metaWriteStorage *storage.MetaWriteStorage,
+) *ParallelParser {
+	return &ParallelParser{
+		heapDump:                 heapDump,
+		size:                     size,
+		jobs:                     jobs,
+		newRun:                   newRun,
+		smallRecordsWriteStorage: smallRecordsWriteStorage,
+		bigRecordsWriteStorage:   bigRecordsWriteStorage,
+		metaWriteStorage:         metaWriteStorage,
+	}
+}
+
+// WithReferences enables extraction of references, see Parser.WithReferences.
+func (parser *ParallelParser) WithReferences(referencesWriteStorage *storage.ReferencesWriteStorage) *ParallelParser {
+	parser.referencesWriteStorage = referencesWriteStorage
+	return parser
+}
+
+// GetPosition returns the number of bytes parsed so far,
+// see Parser.GetPosition. Segments are counted when
+// they are parsed completely.
+func (parser *ParallelParser) GetPosition() int {
+	if sequential := parser.sequential.Load(); sequential != nil {
+		return sequential.GetPosition()
+	}
+	return int(parser.pos.Load())
+}
+
+// ParseHeapDump parses heap dump to storages.
+func (parser *ParallelParser) ParseHeapDump() error {
+	if parser.jobs > 1 {
+		var segments []segment
+		records := storage.NewSmallRecordsWriteStorage()
+		fileHeader, err := parser.findSegments(records, &segments)
+		if err == nil && len(segments) > 1 {
+			parser.smallRecordsWriteStorage.PutIdSize(fileHeader.IdentifierSize)
+			parser.smallRecordsWriteStorage.PutTimestamp(fileHeader.Timestamp)
+			parser.smallRecordsWriteStorage.Append(records)
+			return parser.parseHeapDump(fileHeader.IdentifierSize, segments)
+		}
+	}
+	sequential := NewParser(io.NewSectionReader(parser.heapDump, 0, parser.size), parser.smallRecordsWriteStorage, parser.bigRecordsWriteStorage, parser.metaWriteStorage)
+	if parser.referencesWriteStorage != nil {
+		sequential.WithReferences(parser.referencesWriteStorage)
+	}
+	parser.sequential.Store(sequential)
+	return sequential.ParseHeapDump()
+}
+
+func (parser *ParallelParser) parseHeapDump(idSize uint32, segments []segment) error {
+	err := parser.parseSegments(idSize, segments)
+	if parser.referencesWriteStorage != nil {
+		if closeErr := parser.referencesWriteStorage.Close(); err == nil && closeErr != nil {
+			return fmt.Errorf("cannot write references index: %w", closeErr)
+		}
+	}
+	return err
+}
+
+var errCanceled = errors.New("parsing is canceled")
+
+// segment is the part of the dump with sub-records
+// of HPROF_HEAP_DUMP or HPROF_HEAP_DUMP_SEGMENT
+type segment struct {
+	tag        core.Tag
+	start, end int
+	small      *storage.SmallRecordsWriteStorage
+	meta       *storage.MetaWriteStorage
+	err        error
+}
+
+// findSegments parses top-level records to the given storage and
+// returns the file header. Segments are skipped using their lengths.
+// Error means that segments cannot be parsed in parallel.
+func (parser *ParallelParser) findSegments(records *storage.SmallRecordsWriteStorage, segments *[]segment) (core.FileHeader, error) {
+	bufferedHeapDump := bufio.NewReader(io.NewSectionReader(parser.heapDump, 0, parser.size))
+	fileHeader, err := core.ParseFileHeader(bufferedHeapDump)
+	if err != nil {
+		return fileHeader, fmt.Errorf("error parsing .hprof header: %w", err)
+	}
+	recordParser := core.NewRecordParser(bufferedHeapDump, fileHeader.IdentifierSize)
+	walker := &Parser{pos: 31, smallRecordsWriteStorage: records}
+	parser.pos.Store(31)
+	for {
+		header, err := recordParser.ParseRecordHeader()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return fileHeader, nil
+			}
+			return fileHeader, fmt.Errorf("error parsing record header: %w", err)
+		}
+		walker.pos += 9
+		switch header.Tag {
+		case core.HprofHeapDumpTag, core.HprofHeapDumpSegmentTag:
+			end := walker.pos + int(header.Remaining)
+			if header.Remaining == 0 || int64(end) > parser.size {
+				return fileHeader, fmt.Errorf("%v at %v has unreliable length %v", header.Tag, walker.pos-9, header.Remaining)
+			}
+			*segments = append(*segments, segment{tag: header.Tag, start: walker.pos, end: end})
+			bufferedHeapDump.Reset(io.NewSectionReader(parser.heapDump, int64(end), parser.size-int64(end)))
+			walker.pos = end
+			continue
+		case core.HprofHeapDumpEndTag:
+		default:
+			if err := walker.parseRecord(header, recordParser, bufferedHeapDump); err != nil {
+				return fileHeader, err
+			}
+		}
+		parser.pos.Add(int64(9 + header.Remaining))
+	}
+}
+
+// parseSegments parses the segments with the pool of workers
+// and merges their results to the storages.
+func (parser *ParallelParser) parseSegments(idSize uint32, segments []segment) error {
+	jobs := min(parser.jobs, len(segments))
+	workers := make([]*segmentWorker, jobs)
+	classDumps := newSharedClassDumps(len(segments))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for i := range workers {
+		workers[i] = parser.newSegmentWorker(idSize, classDumps)
+		wg.Add(1)
+		go func(w *segmentWorker) {
+			defer wg.Done()
+			for i := range next {
+				w.parse(i, &segments[i])
+			}
+		}(workers[i])
+	}
+	for i := range segments {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	var err error
+	for i := range segments {
+		if segments[i].err != nil {
+			// segments taken by workers after the failure are canceled,
+			// even the ones preceding the failed segment
+			if err == nil || errors.Is(err, errCanceled) {
+				err = segments[i].err
+			}
+			continue
+		}
+		if err == nil {
+			parser.smallRecordsWriteStorage.Append(segments[i].small)
+			parser.metaWriteStorage.Append(segments[i].meta)
+		}
+	}
+	if mergeErr := parser.mergeIndexes(workers); err == nil {
+		err = mergeErr
+	}
+	return err
+}
+
+// mergeIndexes closes the parts of the indexes written by the
+// workers and merges them to the big records storage.
+func (parser *ParallelParser) mergeIndexes(workers []*segmentWorker) error {
+	defer parser.bigRecordsWriteStorage.Close()
+	var instances, objArrays, primArrays []*storage.IndexRecordsReadStorage
+	defer func() {
+		for _, index := range append(append(instances, objArrays...), primArrays...) {
+			index.Close()
+		}
+	}()
+	var err error
+	for _, w := range workers {
+		if closeErr := w.close(); err == nil && closeErr != nil {
+			err = fmt.Errorf("cannot write indexes of segments: %w", closeErr)
+		}
+		for _, part := range []struct {
+			index   *storage.TempIndex
+			indexes *[]*storage.IndexRecordsReadStorage
+		}{
+			{w.instances, &instances},
+			{w.objArrays, &objArrays},
+			{w.primArrays, &primArrays},
+		} {
+			index, readErr := part.index.Reader()
+			if readErr != nil {
+				if err == nil {
+					err = fmt.Errorf("cannot read indexes of segments: %w", readErr)
+				}
+				continue
+			}
+			*part.indexes = append(*part.indexes, index)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	for _, merge := range []struct {
+		indexes []*storage.IndexRecordsReadStorage
+		put     func(id core.Identifier, offset int) error
+	}{
+		{instances, parser.bigRecordsWriteStorage.HprofGcInstanceDumpPutOffset},
+		{objArrays, parser.bigRecordsWriteStorage.HprofGcObjArrayDumpPutOffset},
+		{primArrays, parser.bigRecordsWriteStorage.HprofGcPrimArrayDumpPutOffset},
+	} {
+		err := storage.MergeIndexes(merge.indexes, func(key uint64, val uint64) error {
+			return merge.put(core.Identifier(key), int(val))
+		})
+		if err != nil {
+			return fmt.Errorf("indexing error: cannot merge indexes of segments: %w", err)
+		}
+	}
+	return nil
+}
+
+// segmentWorker parses segments one by one. Segments are taken in
+// the order of the dump, so the identifiers of objects increase
+// within the parts of the indexes written by the worker.
+type segmentWorker struct {
+	parser                           *ParallelParser
+	size                             *core.SizeInfo
+	instances, objArrays, primArrays *storage.TempIndex
+	bigRecordsWriteStorage           *storage.BigRecordsWriteStorage
+	referencesWriteStorage           *storage.ReferencesWriteStorage
+	references                       *referencesExtractor
+	classDumps                       *sharedClassDumps
+	bufferedHeapDump                 *bufio.Reader
+	recordParser                     *core.RecordParser
+	current                          int
+	currentSmallRecordsWriteStorage  *storage.SmallRecordsWriteStorage
+}
+
+func (parser *ParallelParser) newSegmentWorker(idSize uint32, classDumps *sharedClassDumps) *segmentWorker {
+	w := &segmentWorker{
+		parser:     parser,
+		size:       core.NewSizeInfo(idSize),
+		instances:  storage.NewTempIndex(parser.newRun),
+		objArrays:  storage.NewTempIndex(parser.newRun),
+		primArrays: storage.NewTempIndex(parser.newRun),
+		classDumps: classDumps,
+	}
+	w.bigRecordsWriteStorage = storage.NewBigRecordsWriteStorage(w.instances, w.objArrays, w.primArrays)
+	if parser.referencesWriteStorage != nil {
+		w.referencesWriteStorage = parser.referencesWriteStorage.Fork()
+		w.references = newReferencesExtractor(w.referencesWriteStorage, w.lookupClassDump, idSize)
+	}
+	w.bufferedHeapDump = bufio.NewReader(nil)
+	w.recordParser = core.NewRecordParser(w.bufferedHeapDump, idSize)
+	return w
+}
+
+// parse parses i-th segment, the error is saved to the segment.
+// Once some segment fails the following ones are not parsed.
+func (w *segmentWorker) parse(i int, s *segment) {
+	defer func() {
+		w.classDumps.parsed(i, s.small)
+		w.parser.pos.Add(int64(s.end - s.start + 9))
+	}()
+	if w.classDumps.failed() {
+		s.err = errCanceled
+		return
+	}
+	s.small = storage.NewSmallRecordsWriteStorage()
+	s.meta = storage.NewMetaWriteStorage()
+	w.current = i
+	w.currentSmallRecordsWriteStorage = s.small
+	w.bufferedHeapDump.Reset(io.NewSectionReader(w.parser.heapDump, int64(s.start), int64(s.end-s.start)))
+	parser := &Parser{
+		pos:                      s.start,
+		smallRecordsWriteStorage: s.small,
+		bigRecordsWriteStorage:   w.bigRecordsWriteStorage,
+		metaWriteStorage:         s.meta,
+	}
+	if _, err := parser.parseSubRecords(w.recordParser, w.bufferedHeapDump, w.size, w.references, s.end); err != nil {
+		s.err = err
+	} else if parser.pos != s.end {
+		s.err = fmt.Errorf("sub-records of %v end at %v instead of %v", s.tag, parser.pos, s.end)
+	}
+	if s.err != nil {
+		w.classDumps.fail()
+	}
+}
+
+// lookupClassDump finds the class dump among the ones met before
+// in the current segment or in the preceding segments.
+func (w *segmentWorker) lookupClassDump(classObjectId core.Identifier) (core.HprofGcClassDump, bool) {
+	if classDump, ok := w.currentSmallRecordsWriteStorage.HprofGcClassDump[classObjectId]; ok {
+		return classDump, true
+	}
+	return w.classDumps.lookup(w.current, classObjectId)
+}
+
+func (w *segmentWorker) close() error {
+	err := w.bigRecordsWriteStorage.Close()
+	if w.referencesWriteStorage != nil {
+		if closeErr := w.referencesWriteStorage.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// sharedClassDumps collects class dumps of parsed segments. Class
+// dumps usually precede all the instances, so the worker parsing
+// instances waits until the segment with their class dump is parsed.
+type sharedClassDumps struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+	// class dumps with the index of their segment
+	classDumps map[core.Identifier]segmentClassDump
+	done       []bool
+	// all segments before it are parsed
+	parsedPrefix int
+	err          bool
+}
+
+type segmentClassDump struct {
+	segment   int
+	classDump core.HprofGcClassDump
+}
+
+func newSharedClassDumps(segments int) *sharedClassDumps {
+	s := &sharedClassDumps{
+		classDumps: make(map[core.Identifier]segmentClassDump),
+		done:       make([]bool, segments),
+	}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+// parsed publishes class dumps of i-th segment, small is nil
+// if the segment has not been parsed.
+func (s *sharedClassDumps) parsed(i int, small *storage.SmallRecordsWriteStorage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if small != nil {
+		for classObjectId, classDump := range small.HprofGcClassDump {
+			if existing, ok := s.classDumps[classObjectId]; !ok || i < existing.segment {
+				s.classDumps[classObjectId] = segmentClassDump{segment: i, classDump: classDump}
+			}
+		}
+	}
+	s.done[i] = true
+	for s.parsedPrefix < len(s.done) && s.done[s.parsedPrefix] {
+		s.parsedPrefix++
+	}
+	s.cond.Broadcast()
+}
+
+// lookup finds the class dump in the segments preceding
+// i-th one. It waits until they are parsed if needed.
+func (s *sharedClassDumps) lookup(i int, classObjectId core.Identifier) (core.HprofGcClassDump, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for {
+		if classDump, ok := s.classDumps[classObjectId]; ok && classDump.segment < i {
+			return classDump.classDump, true
+		}
+		if s.parsedPrefix >= i {
+			return core.HprofGcClassDump{}, false
+		}
+		s.cond.Wait()
+	}
+}
+
+func (s *sharedClassDumps) fail() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = true
+}
+
+func (s *sharedClassDumps) failed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
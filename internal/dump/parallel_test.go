@@ -0,0 +1,261 @@
+package dump
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/danielleontiev/neojhat/internal/core"
+	"github.com/danielleontiev/neojhat/internal/storage"
+)
+
+// createSegment creates HPROF_HEAP_DUMP_SEGMENT with the real length
+func createSegment(subRecords ...[]byte) []byte {
+	body := concat(nil, subRecords...)
+	return concat(createRecordHeader(core.HprofHeapDumpSegmentTag, uint32(len(body))), body)
+}
+
+// createNodeClassDump creates class dump of the class with
+// "next" object field and int field, static field "first"
+// references the given object
+func createNodeClassDump(classId, first uint64) []byte {
+	return concat(
+		createSubRecordHeader(core.HprofGcClassDumpType),
+		id8(classId),
+		one4,                      // stack trace serial number
+		id8(0),                    // super class object id
+		id8(0),                    // class loader object id
+		id8(0),                    // signers object id
+		id8(0),                    // protection domain object id
+		id8(0),                    // reserved
+		id8(0),                    // reserved
+		[]byte{0, 0, 0, 12},       // instance size
+		[]byte{0, 0},              // constant pool size
+		one2,                      // number of static fields
+		one8,                      // static field name id
+		[]byte{byte(core.Object)}, // static field type
+		id8(first),                // static field value
+		two2,                      // number of instance fields
+		one8,                      // field name id
+		[]byte{byte(core.Object)}, // field type
+		two8,                      // field name id
+		[]byte{byte(core.Int)},    // field type
+	)
+}
+
+// createSegmentedHeapDump creates the dump with class dumps in the first
+// segment and the objects in the following segments. Every object
+// references the previous one, the middle segment switches the heap.
+func createSegmentedHeapDump(segments, objectsPerSegment int) []byte {
+	const classId = 0x10
+	dump := concat(nil,
+		readerTestFileHeader,
+		createRecordHeader(core.HprofUtf8Tag, 8+4),
+		one8, []byte("next"),
+		createRecordHeader(core.HprofUtf8Tag, 8+5),
+		two8, []byte("count"),
+		createRecordHeader(core.HprofLoadClassTag, 4+8+4+8),
+		one4, id8(classId), one4, one8,
+		createSegment(
+			createNodeClassDump(classId, 0x100),
+			createSubRecordHeader(core.HprofGcRootStickyClassType), id8(classId),
+		),
+	)
+	id := uint64(0x100)
+	for s := 1; s < segments; s++ {
+		var subRecords [][]byte
+		if s == segments/2 {
+			subRecords = append(subRecords,
+				createSubRecordHeader(core.HprofHeapDumpInfoType),
+				[]byte{0x00, 0x00, 0x00, 'A'}, // heap type
+				two8,                          // heap name id
+			)
+		}
+		subRecords = append(subRecords, createSubRecordHeader(core.HprofGcRootJniGlobalType), id8(id), id8(1))
+		for i := 0; i < objectsPerSegment; i++ {
+			subRecords = append(subRecords,
+				createSubRecordHeader(core.HprofGcInstanceDumpType),
+				id8(id), one4, id8(classId), []byte{0, 0, 0, 12}, id8(id-3), one4,
+				createSubRecordHeader(core.HprofGcObjArrayDumpType),
+				id8(id+1), one4, []byte{0, 0, 0, 2}, id8(classId), id8(id), id8(0),
+				createSubRecordHeader(core.HprofGcPrimArrayDumpType),
+				id8(id+2), one4, one4, []byte{byte(core.Long)}, id8(id),
+			)
+			id += 3
+		}
+		dump = concat(dump, createSegment(subRecords...))
+	}
+	return concat(dump, createRecordHeader(core.HprofHeapDumpEndTag, 0))
+}
+
+// parsedStorages are the storages filled by the parser
+type parsedStorages struct {
+	small      *storage.SmallRecordsWriteStorage
+	meta       *storage.MetaWriteStorage
+	instances  *storage.RamWriteVolume
+	objArrays  *storage.RamWriteVolume
+	primArrays *storage.RamWriteVolume
+	references *storage.RamWriteVolume
+}
+
+// parseInParallel parses the dump to in-memory storages, references
+// are sorted in batches of the given size if batchSize is not zero
+func parseInParallel(in []byte, jobs int, batchSize storage.BatchSize) (parsedStorages, *ParallelParser, error) {
+	s := parsedStorages{
+		small:      storage.NewSmallRecordsWriteStorage(),
+		meta:       storage.NewMetaWriteStorage(),
+		instances:  storage.NewRamWriteVolume(),
+		objArrays:  storage.NewRamWriteVolume(),
+		primArrays: storage.NewRamWriteVolume(),
+		references: storage.NewRamWriteVolume(),
+	}
+	newRun := func() (storage.RunVolume, error) {
+		return storage.NewRamRunVolume(), nil
+	}
+	bigWriter := storage.NewBigRecordsWriteStorage(s.instances, s.objArrays, s.primArrays)
+	parser := NewParallelParser(bytes.NewReader(in), int64(len(in)), jobs, newRun, s.small, bigWriter, s.meta)
+	if batchSize > 0 {
+		parser.WithReferences(storage.NewReferencesWriteStorage(s.references, newRun, batchSize))
+	}
+	err := parser.ParseHeapDump()
+	return s, parser, err
+}
+
+func assertSameStorages(t *testing.T, got, want parsedStorages) {
+	t.Helper()
+	if !reflect.DeepEqual(got.small, want.small) {
+		t.Errorf("small records = %+v, want %+v", got.small, want.small)
+	}
+	if !reflect.DeepEqual(got.meta.MetaStorage, want.meta.MetaStorage) {
+		t.Errorf("meta = %+v, want %+v", got.meta.MetaStorage, want.meta.MetaStorage)
+	}
+	for _, index := range []struct {
+		name      string
+		got, want *storage.RamWriteVolume
+	}{
+		{"instances", got.instances, want.instances},
+		{"object arrays", got.objArrays, want.objArrays},
+		{"primitive arrays", got.primArrays, want.primArrays},
+		{"references", got.references, want.references},
+	} {
+		if !bytes.Equal(index.got.Bytes(), index.want.Bytes()) {
+			t.Errorf("index of %s differs, %v bytes instead of %v", index.name, index.got.Len(), index.want.Len())
+		}
+	}
+}
+
+func TestParallelParser_ParseHeapDump(t *testing.T) {
+	input := createSegmentedHeapDump(9, 50)
+	// tiny batches to exercise external sorting
+	for _, batchSize := range []storage.BatchSize{0, 16} {
+		want, _, err := parseInParallel(input, 1, batchSize)
+		if err != nil {
+			t.Fatalf("ParseHeapDump() with single job error = %v", err)
+		}
+		if want.instances.Len() != 8*50*16 {
+			t.Fatalf("sequential parser indexed %v bytes of instances", want.instances.Len())
+		}
+		for _, jobs := range []int{2, 3, 16} {
+			t.Run(fmt.Sprintf("jobs=%v,references=%v", jobs, batchSize > 0), func(t *testing.T) {
+				got, parser, err := parseInParallel(input, jobs, batchSize)
+				if err != nil {
+					t.Fatalf("ParseHeapDump() error = %v", err)
+				}
+				if parser.sequential.Load() != nil {
+					t.Errorf("segments are parsed sequentially")
+				}
+				if pos := parser.GetPosition(); pos != len(input) {
+					t.Errorf("wrong position = %v, want %v", pos, len(input))
+				}
+				assertSameStorages(t, got, want)
+			})
+		}
+	}
+}
+
+func TestParallelParser_ParseHeapDumpSequentially(t *testing.T) {
+	// lengths of segments are zero, so segments cannot be found
+	input := createSegmentedHeapDump(4, 10)
+	for offset := len(readerTestFileHeader); offset < len(input); {
+		length := int(input[offset+5])<<24 | int(input[offset+6])<<16 | int(input[offset+7])<<8 | int(input[offset+8])
+		if core.Tag(input[offset]) == core.HprofHeapDumpSegmentTag {
+			copy(input[offset+5:offset+9], []byte{0, 0, 0, 0})
+		}
+		offset += 9 + length
+	}
+	want, _, err := parseInParallel(input, 1, 16)
+	if err != nil {
+		t.Fatalf("ParseHeapDump() with single job error = %v", err)
+	}
+	got, parser, err := parseInParallel(input, 4, 16)
+	if err != nil {
+		t.Fatalf("ParseHeapDump() error = %v", err)
+	}
+	if parser.sequential.Load() == nil {
+		t.Errorf("segments with zero lengths are parsed in parallel")
+	}
+	if pos := parser.GetPosition(); pos != len(input) {
+		t.Errorf("wrong position = %v, want %v", pos, len(input))
+	}
+	assertSameStorages(t, got, want)
+}
+
+func TestParallelParser_ParseHeapDumpErrors(t *testing.T) {
+	instance := concat(
+		createSubRecordHeader(core.HprofGcInstanceDumpType),
+		id8(0x100), one4, id8(0x10), []byte{0, 0, 0, 12}, id8(0), one4,
+	)
+	tests := []struct {
+		name  string
+		input []byte
+		want  string
+	}{
+		{
+			name: "class dump follows instances",
+			input: concat(nil,
+				readerTestFileHeader,
+				createSegment(createSubRecordHeader(core.HprofGcRootJniGlobalType), one8, one8),
+				createSegment(instance),
+				createSegment(createNodeClassDump(0x10, 0)),
+			),
+			want: "class dump 16 is not found before its instances",
+		},
+		{
+			name: "unknown sub-record",
+			input: concat(nil,
+				readerTestFileHeader,
+				createSegment(createNodeClassDump(0x10, 0)),
+				createSegment(instance, []byte{0x42}),
+				createSegment(createSubRecordHeader(core.HprofGcRootJniGlobalType), one8, one8),
+			),
+			want: "unknown sub-record type",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, parser, err := parseInParallel(tt.input, 4, 16)
+			if parser.sequential.Load() != nil {
+				t.Fatalf("segments are parsed sequentially")
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseHeapDump() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func BenchmarkParallelParser_ParseHeapDump(b *testing.B) {
+	input := createSegmentedHeapDump(64, 2000)
+	for _, jobs := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("jobs=%v", jobs), func(b *testing.B) {
+			b.SetBytes(int64(len(input)))
+			for i := 0; i < b.N; i++ {
+				if _, _, err := parseInParallel(input, jobs, storage.DefaultBatchSize); err != nil {
+					b.Fatalf("ParseHeapDump() error = %v", err)
+				}
+			}
+		})
+	}
+}
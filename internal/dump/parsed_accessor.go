@@ -1,8 +1,11 @@
 package dump
 
 import (
+	"bufio"
 	"fmt"
 	"io"
+	"math"
+	"sort"
 
 	"github.com/danielleontiev/neojhat/internal/core"
 	"github.com/danielleontiev/neojhat/internal/storage"
@@ -12,25 +15,29 @@ import (
 // offsets of records from index and parses the objects from the position
 // obtained from index (for big objects) and restores in-memory storage for
 // small objects and provides access to it.
+//
+// The dump is read with io.ReaderAt and nothing is modified once
+// the indexes are attached, so it's safe for concurrent use.
 type ParsedAccessor struct {
-	heapDump              io.ReadSeeker
-	recordParser          *core.RecordParser
+	heapDump              io.ReaderAt
+	sizeInfo              *core.SizeInfo
 	IdentifierSize        uint32
 	bigRecordsReadStorage *storage.BigRecordsReadStorage
+	referencesReadStorage *storage.ReferencesReadStorage
+	dominatorsReadStorage *storage.DominatorsReadStorage
 	*storage.SmallRecordsReadStorage
 	*storage.MetaReadStorage
 }
 
 func NewParsedAccessor(
-	headDump io.ReadSeeker,
+	heapDump io.ReaderAt,
 	bigRecordsReadStorage *storage.BigRecordsReadStorage,
 	smallRecordsReadStorage *storage.SmallRecordsReadStorage,
 	metaReadStorage *storage.MetaReadStorage,
 ) *ParsedAccessor {
-	recordParser := core.NewRecordParser(headDump, smallRecordsReadStorage.IdSize)
 	return &ParsedAccessor{
-		heapDump:                headDump,
-		recordParser:            recordParser,
+		heapDump:                heapDump,
+		sizeInfo:                core.NewSizeInfo(smallRecordsReadStorage.IdSize),
 		IdentifierSize:          smallRecordsReadStorage.IdSize,
 		bigRecordsReadStorage:   bigRecordsReadStorage,
 		SmallRecordsReadStorage: smallRecordsReadStorage,
@@ -38,63 +45,281 @@ func NewParsedAccessor(
 	}
 }
 
+// WithReferences attaches the references index built by the parser,
+// it's required for GetReferrers.
+func (a *ParsedAccessor) WithReferences(referencesReadStorage *storage.ReferencesReadStorage) *ParsedAccessor {
+	a.referencesReadStorage = referencesReadStorage
+	return a
+}
+
+// Heap is one of the heaps of Android heap dump: app, image or zygote.
+type Heap struct {
+	Type     uint32
+	Name     string
+	Counters storage.Counters
+}
+
+// ListHeaps returns the heaps of Android heap dump sorted by type.
+// HotSpot heap dumps have no heaps, all objects live in the single one.
+func (a *ParsedAccessor) ListHeaps() []Heap {
+	var heaps []Heap
+	for heapType, counters := range a.HeapCounters {
+		name := fmt.Sprintf("heap %v", heapType)
+		if info, err := a.GetHprofHeapDumpInfo(heapType); err == nil {
+			if utf8, err := a.GetHprofUtf8(info.HeapNameId); err == nil {
+				name = utf8.Characters
+			}
+		}
+		heaps = append(heaps, Heap{Type: heapType, Name: name, Counters: counters})
+	}
+	sort.Slice(heaps, func(i, j int) bool { return heaps[i].Type < heaps[j].Type })
+	return heaps
+}
+
+// GetReferrers returns identifiers of objects holding references to the given one.
+func (a *ParsedAccessor) GetReferrers(objectId core.Identifier) ([]core.Identifier, error) {
+	if a.referencesReadStorage == nil {
+		return nil, fmt.Errorf("references index is not available")
+	}
+	referrers, err := a.referencesReadStorage.GetReferrers(objectId)
+	if err != nil {
+		return nil, fmt.Errorf("error getting referrers of object with objectId %v: %w", objectId, err)
+	}
+	return referrers, nil
+}
+
+// WithDominators attaches the dominator tree, it's
+// required for retained sizes.
+func (a *ParsedAccessor) WithDominators(dominatorsReadStorage *storage.DominatorsReadStorage) *ParsedAccessor {
+	a.dominatorsReadStorage = dominatorsReadStorage
+	return a
+}
+
+// Dominators returns the dominator tree or error if
+// it was not built.
+func (a *ParsedAccessor) Dominators() (*storage.DominatorsReadStorage, error) {
+	if a.dominatorsReadStorage == nil {
+		return nil, fmt.Errorf("dominator tree is not available")
+	}
+	return a.dominatorsReadStorage, nil
+}
+
 func (a *ParsedAccessor) GetHprofGcInstanceDump(objectId core.Identifier) (core.HprofGcClassDumpInstanceDumpHeader, error) {
+	return a.getHprofGcInstanceDump(a.newCursor(), objectId)
+}
+
+// GetHprofGcInstanceDumpWithPayload is the same as GetHprofGcInstanceDump
+// but it also reads the values of the fields that follow the header.
+func (a *ParsedAccessor) GetHprofGcInstanceDumpWithPayload(objectId core.Identifier) (core.HprofGcClassDumpInstanceDumpHeader, []byte, error) {
+	c := a.newCursor()
+	res, err := a.getHprofGcInstanceDump(c, objectId)
+	if err != nil {
+		return core.HprofGcClassDumpInstanceDumpHeader{}, nil, err
+	}
+	payload, err := a.readPayload(c, res)
+	if err != nil {
+		return core.HprofGcClassDumpInstanceDumpHeader{}, nil, fmt.Errorf("error reading instance with objectId %v: %w", objectId, err)
+	}
+	return res, payload, nil
+}
+
+func (a *ParsedAccessor) GetHprofGcObjArray(arrayObjectId core.Identifier) (core.HprofGcObjArrayDumpHeader, error) {
+	return a.getHprofGcObjArray(a.newCursor(), arrayObjectId)
+}
+
+// GetHprofGcObjArrayWithPayload is the same as GetHprofGcObjArray
+// but it also reads the elements that follow the header.
+func (a *ParsedAccessor) GetHprofGcObjArrayWithPayload(arrayObjectId core.Identifier) (core.HprofGcObjArrayDumpHeader, []byte, error) {
+	c := a.newCursor()
+	res, err := a.getHprofGcObjArray(c, arrayObjectId)
+	if err != nil {
+		return core.HprofGcObjArrayDumpHeader{}, nil, err
+	}
+	payload, err := a.readPayload(c, res)
+	if err != nil {
+		return core.HprofGcObjArrayDumpHeader{}, nil, fmt.Errorf("error reading object array with arrayObjectId %v: %w", arrayObjectId, err)
+	}
+	return res, payload, nil
+}
+
+func (a *ParsedAccessor) GetHprofGcPrimArray(arrayObjectId core.Identifier) (core.HprofGcPrimArrayDumpHeader, error) {
+	return a.getHprofGcPrimArray(a.newCursor(), arrayObjectId)
+}
+
+// GetHprofGcPrimArrayWithPayload is the same as GetHprofGcPrimArray
+// but it also reads the elements that follow the header.
+func (a *ParsedAccessor) GetHprofGcPrimArrayWithPayload(arrayObjectId core.Identifier) (core.HprofGcPrimArrayDumpHeader, []byte, error) {
+	c := a.newCursor()
+	res, err := a.getHprofGcPrimArray(c, arrayObjectId)
+	if err != nil {
+		return core.HprofGcPrimArrayDumpHeader{}, nil, err
+	}
+	payload, err := a.readPayload(c, res)
+	if err != nil {
+		return core.HprofGcPrimArrayDumpHeader{}, nil, fmt.Errorf("error reading primitive array with arrayObjectId %v: %w", arrayObjectId, err)
+	}
+	return res, payload, nil
+}
+
+func (a *ParsedAccessor) getHprofGcInstanceDump(c *cursor, objectId core.Identifier) (core.HprofGcClassDumpInstanceDumpHeader, error) {
 	offset, err := a.bigRecordsReadStorage.HprofGcInstanceDumpGetOffset(objectId)
 	if err != nil {
 		return core.HprofGcClassDumpInstanceDumpHeader{}, fmt.Errorf("error getting offset of HprofGcClassDumpInstanceDumpHeader with objectId %v: %w", objectId, err)
 	}
-	if err := a.seek(offset); err != nil {
-		return core.HprofGcClassDumpInstanceDumpHeader{}, err
-	}
-	res, err := a.recordParser.ParseHprofGcClassDumpInstanceDumpHeader()
+	c.seek(offset)
+	res, err := c.ParseHprofGcClassDumpInstanceDumpHeader()
 	if err != nil {
 		return core.HprofGcClassDumpInstanceDumpHeader{}, fmt.Errorf("error reading HprofGcClassDumpInstanceDumpHeader at offset %v: %w", offset, err)
 	}
 	return res, nil
 }
 
-func (a *ParsedAccessor) GetHprofGcObjArray(arrayObjectId core.Identifier) (core.HprofGcObjArrayDumpHeader, error) {
+func (a *ParsedAccessor) getHprofGcObjArray(c *cursor, arrayObjectId core.Identifier) (core.HprofGcObjArrayDumpHeader, error) {
 	offset, err := a.bigRecordsReadStorage.HprofGcObjArrayDumpGetOffset(arrayObjectId)
 	if err != nil {
 		return core.HprofGcObjArrayDumpHeader{}, fmt.Errorf("error getting offset of HprofGcObjArrayDumpHeader with arrayObjectId %v: %w", arrayObjectId, err)
 	}
-	if err := a.seek(offset); err != nil {
-		return core.HprofGcObjArrayDumpHeader{}, err
-	}
-	res, err := a.recordParser.ParseHprofGcObjArrayDumpHeader()
+	c.seek(offset)
+	res, err := c.ParseHprofGcObjArrayDumpHeader()
 	if err != nil {
 		return core.HprofGcObjArrayDumpHeader{}, fmt.Errorf("error reading HprofGcObjArrayDumpHeader at offset %v: %w", offset, err)
 	}
 	return res, nil
 }
 
-func (a *ParsedAccessor) GetHprofGcPrimArray(arrayObjectId core.Identifier) (core.HprofGcPrimArrayDumpHeader, error) {
+func (a *ParsedAccessor) getHprofGcPrimArray(c *cursor, arrayObjectId core.Identifier) (core.HprofGcPrimArrayDumpHeader, error) {
 	offset, err := a.bigRecordsReadStorage.HprofGcPrimArrayDumpGetOffset(arrayObjectId)
 	if err != nil {
 		return core.HprofGcPrimArrayDumpHeader{}, fmt.Errorf("error getting offset of HprofGcPrimArrayDumpHeader with arrayObjectId %v: %w", arrayObjectId, err)
 	}
-	if err := a.seek(offset); err != nil {
-		return core.HprofGcPrimArrayDumpHeader{}, err
-	}
-	res, err := a.recordParser.ParseHprofGcPrimArrayDumpHeader()
+	c.seek(offset)
+	res, err := c.ParseHprofGcPrimArrayDumpHeader()
 	if err != nil {
 		return core.HprofGcPrimArrayDumpHeader{}, fmt.Errorf("error reading HprofGcPrimArrayDumpHeader at offset %v: %w", offset, err)
 	}
 	return res, nil
 }
 
-func (a *ParsedAccessor) GetBytesFromCurrent(n int) ([]byte, error) {
-	res, err := a.recordParser.ReadBytes(n)
-	if err != nil {
-		return nil, fmt.Errorf("error in ReadBytesFromCurrent: %w", err)
+// ScanHprofGcInstanceDumps parses headers of all the instances one by one
+// in increasing order of identifiers and passes them to fn.
+func (a *ParsedAccessor) ScanHprofGcInstanceDumps(fn func(core.HprofGcClassDumpInstanceDumpHeader) error) error {
+	return a.ScanHprofGcInstanceDumpsWithPayload(
+		func(core.HprofGcClassDumpInstanceDumpHeader) bool { return false },
+		func(res core.HprofGcClassDumpInstanceDumpHeader, _ []byte) error { return fn(res) },
+	)
+}
+
+// ScanHprofGcInstanceDumpsWithPayload is the same as ScanHprofGcInstanceDumps
+// but it also reads the values of the fields of instances accepted by accept,
+// other instances are passed to fn with nil payload.
+func (a *ParsedAccessor) ScanHprofGcInstanceDumpsWithPayload(
+	accept func(core.HprofGcClassDumpInstanceDumpHeader) bool,
+	fn func(core.HprofGcClassDumpInstanceDumpHeader, []byte) error,
+) error {
+	c := a.newCursor()
+	return a.bigRecordsReadStorage.HprofGcInstanceDumpScanOffsets(func(objectId core.Identifier, offset int) error {
+		c.seek(offset)
+		res, err := c.ParseHprofGcClassDumpInstanceDumpHeader()
+		if err != nil {
+			return fmt.Errorf("error reading HprofGcClassDumpInstanceDumpHeader at offset %v: %w", offset, err)
+		}
+		if !accept(res) {
+			return fn(res, nil)
+		}
+		payload, err := a.readPayload(c, res)
+		if err != nil {
+			return fmt.Errorf("error reading instance with objectId %v: %w", objectId, err)
+		}
+		return fn(res, payload)
+	})
+}
+
+// ScanHprofGcObjArrays is the same as ScanHprofGcInstanceDumps but for object arrays.
+func (a *ParsedAccessor) ScanHprofGcObjArrays(fn func(core.HprofGcObjArrayDumpHeader) error) error {
+	c := a.newCursor()
+	return a.bigRecordsReadStorage.HprofGcObjArrayDumpScanOffsets(func(arrayObjectId core.Identifier, offset int) error {
+		c.seek(offset)
+		res, err := c.ParseHprofGcObjArrayDumpHeader()
+		if err != nil {
+			return fmt.Errorf("error reading HprofGcObjArrayDumpHeader at offset %v: %w", offset, err)
+		}
+		return fn(res)
+	})
+}
+
+// ScanHprofGcPrimArrays is the same as ScanHprofGcInstanceDumps but for primitive arrays.
+func (a *ParsedAccessor) ScanHprofGcPrimArrays(fn func(core.HprofGcPrimArrayDumpHeader) error) error {
+	c := a.newCursor()
+	return a.bigRecordsReadStorage.HprofGcPrimArrayDumpScanOffsets(func(arrayObjectId core.Identifier, offset int) error {
+		c.seek(offset)
+		res, err := c.ParseHprofGcPrimArrayDumpHeader()
+		if err != nil {
+			return fmt.Errorf("error reading HprofGcPrimArrayDumpHeader at offset %v: %w", offset, err)
+		}
+		return fn(res)
+	})
+}
+
+// ScanHprofGcPrimArraysWithPayload is the same as ScanHprofGcPrimArrays
+// but it also reads the elements of every array.
+func (a *ParsedAccessor) ScanHprofGcPrimArraysWithPayload(fn func(core.HprofGcPrimArrayDumpHeader, []byte) error) error {
+	c := a.newCursor()
+	return a.bigRecordsReadStorage.HprofGcPrimArrayDumpScanOffsets(func(arrayObjectId core.Identifier, offset int) error {
+		c.seek(offset)
+		res, err := c.ParseHprofGcPrimArrayDumpHeader()
+		if err != nil {
+			return fmt.Errorf("error reading HprofGcPrimArrayDumpHeader at offset %v: %w", offset, err)
+		}
+		payload, err := a.readPayload(c, res)
+		if err != nil {
+			return fmt.Errorf("error reading primitive array with arrayObjectId %v: %w", arrayObjectId, err)
+		}
+		return fn(res, payload)
+	})
+}
+
+// ScanReferences calls fn for every reference between objects
+// in the increasing order of referenced objects.
+func (a *ParsedAccessor) ScanReferences(fn func(from, to core.Identifier) error) error {
+	if a.referencesReadStorage == nil {
+		return fmt.Errorf("references index is not available")
 	}
-	return res, nil
+	return a.referencesReadStorage.Scan(fn)
+}
+
+// cursorBufferSize is enough for the header and the
+// payload of most of the records read by the cursor.
+const cursorBufferSize = 512
+
+// cursor reads records starting at arbitrary offsets of the dump. The
+// cursor is never shared: every call of the accessor creates its own,
+// so the accessor can be used from many goroutines at once.
+type cursor struct {
+	heapDump io.ReaderAt
+	buffer   *bufio.Reader
+	*core.RecordParser
+}
+
+func (a *ParsedAccessor) newCursor() *cursor {
+	buffer := bufio.NewReaderSize(io.NewSectionReader(a.heapDump, 0, 0), cursorBufferSize)
+	return &cursor{
+		heapDump:     a.heapDump,
+		buffer:       buffer,
+		RecordParser: core.NewRecordParser(buffer, a.IdentifierSize),
+	}
+}
+
+func (c *cursor) seek(offset int) {
+	c.buffer.Reset(io.NewSectionReader(c.heapDump, int64(offset), math.MaxInt64-int64(offset)))
 }
 
-func (a *ParsedAccessor) seek(offset int) error {
-	_, err := a.heapDump.Seek(int64(offset), io.SeekStart)
+// readPayload reads the values of fields of instances
+// or elements of arrays that follow the header.
+func (a *ParsedAccessor) readPayload(c *cursor, header any) ([]byte, error) {
+	_, payloadSize := a.sizeInfo.OfObject(header)
+	payload, err := c.ReadBytes(payloadSize)
 	if err != nil {
-		return fmt.Errorf("cannot goto offset %v: %v", offset, err)
+		return nil, fmt.Errorf("error reading payload: %w", err)
 	}
-	return nil
+	return payload, nil
 }
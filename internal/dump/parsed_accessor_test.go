@@ -4,8 +4,8 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
-	"io"
 	"reflect"
+	"sync"
 	"testing"
 
 	"github.com/danielleontiev/neojhat/internal/core"
@@ -323,18 +323,148 @@ func TestReader_GetHprofGcPrimArray(t *testing.T) {
 	}
 }
 
-func TestReader_GetBytesFromCurrent(t *testing.T) {
-	_, err := reader.heapDump.Seek(0, io.SeekStart)
+func TestReader_GetWithPayload(t *testing.T) {
+	_, instancePayload, err := reader.GetHprofGcInstanceDumpWithPayload(1)
 	if err != nil {
-		t.Errorf("error seeking to 0: %v", err)
+		t.Errorf("GetHprofGcInstanceDumpWithPayload() error = %v", err)
 	}
-	got, err := reader.GetBytesFromCurrent(2)
-	want := []byte{0x4a, 0x41}
+	if !reflect.DeepEqual(instancePayload, one1) {
+		t.Errorf("GetHprofGcInstanceDumpWithPayload() payload = %v, want %v", instancePayload, one1)
+	}
+	_, objArrayPayload, err := reader.GetHprofGcObjArrayWithPayload(1)
 	if err != nil {
-		t.Errorf("GetBytesFromCurrent() error = %v", err)
+		t.Errorf("GetHprofGcObjArrayWithPayload() error = %v", err)
 	}
-	if !reflect.DeepEqual(got, want) {
-		t.Errorf("GetBytesFromCurrent() = %v, want %v", got, want)
+	if !reflect.DeepEqual(objArrayPayload, one8) {
+		t.Errorf("GetHprofGcObjArrayWithPayload() payload = %v, want %v", objArrayPayload, one8)
+	}
+	_, primArrayPayload, err := reader.GetHprofGcPrimArrayWithPayload(1)
+	if err != nil {
+		t.Errorf("GetHprofGcPrimArrayWithPayload() error = %v", err)
+	}
+	if !reflect.DeepEqual(primArrayPayload, one1) {
+		t.Errorf("GetHprofGcPrimArrayWithPayload() payload = %v, want %v", primArrayPayload, one1)
+	}
+	if _, _, err := reader.GetHprofGcInstanceDumpWithPayload(2); err == nil {
+		t.Errorf("GetHprofGcInstanceDumpWithPayload() of missing instance, want error")
+	}
+}
+
+func TestReader_ScanWithPayload(t *testing.T) {
+	var payloads [][]byte
+	err := reader.ScanHprofGcInstanceDumpsWithPayload(
+		func(core.HprofGcClassDumpInstanceDumpHeader) bool { return true },
+		func(_ core.HprofGcClassDumpInstanceDumpHeader, payload []byte) error {
+			payloads = append(payloads, payload)
+			return nil
+		})
+	if err != nil {
+		t.Errorf("ScanHprofGcInstanceDumpsWithPayload() error = %v", err)
+	}
+	err = reader.ScanHprofGcInstanceDumpsWithPayload(
+		func(core.HprofGcClassDumpInstanceDumpHeader) bool { return false },
+		func(_ core.HprofGcClassDumpInstanceDumpHeader, payload []byte) error {
+			payloads = append(payloads, payload)
+			return nil
+		})
+	if err != nil {
+		t.Errorf("ScanHprofGcInstanceDumpsWithPayload() error = %v", err)
+	}
+	err = reader.ScanHprofGcPrimArraysWithPayload(func(_ core.HprofGcPrimArrayDumpHeader, payload []byte) error {
+		payloads = append(payloads, payload)
+		return nil
+	})
+	if err != nil {
+		t.Errorf("ScanHprofGcPrimArraysWithPayload() error = %v", err)
+	}
+	want := [][]byte{one1, nil, one1}
+	if !reflect.DeepEqual(payloads, want) {
+		t.Errorf("payloads = %v, want %v", payloads, want)
+	}
+}
+
+// TestReader_Concurrent reads every object from many goroutines
+// at once, payloads are made of identifiers, so the reads that
+// interfere with each other are noticed. Run it with -race.
+func TestReader_Concurrent(t *testing.T) {
+	const objects = 200
+	var subRecords [][]byte
+	for i := uint64(1); i <= objects; i++ {
+		subRecords = append(subRecords,
+			createSubRecordHeader(core.HprofGcInstanceDumpType),
+			id8(i), one4, one8, []byte{0x00, 0x00, 0x00, 0x08}, id8(i),
+			createSubRecordHeader(core.HprofGcObjArrayDumpType),
+			id8(objects+i), one4, one4, one8, id8(i),
+			createSubRecordHeader(core.HprofGcPrimArrayDumpType),
+			id8(2*objects+i), one4, one4, []byte{byte(core.Long)}, id8(i),
+		)
+	}
+	concurrentReader := createReader(concat(
+		readerTestFileHeader,
+		createRecordHeader(core.HprofHeapDumpSegmentTag, 0),
+		concat(nil, subRecords...),
+		createRecordHeader(core.HprofHeapDumpEndTag, 0),
+	))
+
+	check := func(i uint64) error {
+		instance, payload, err := concurrentReader.GetHprofGcInstanceDumpWithPayload(core.Identifier(i))
+		if err != nil {
+			return err
+		}
+		if instance.ObjectId != core.Identifier(i) || !bytes.Equal(payload, id8(i)) {
+			return fmt.Errorf("instance %v = %+v with payload %v", i, instance, payload)
+		}
+		objArray, payload, err := concurrentReader.GetHprofGcObjArrayWithPayload(core.Identifier(objects + i))
+		if err != nil {
+			return err
+		}
+		if objArray.ArrayObjectId != core.Identifier(objects+i) || !bytes.Equal(payload, id8(i)) {
+			return fmt.Errorf("object array %v = %+v with payload %v", objects+i, objArray, payload)
+		}
+		primArray, err := concurrentReader.GetHprofGcPrimArray(core.Identifier(2*objects + i))
+		if err != nil {
+			return err
+		}
+		if primArray.ArrayObjectId != core.Identifier(2*objects+i) || primArray.ElementType != core.Long {
+			return fmt.Errorf("primitive array %v = %+v", 2*objects+i, primArray)
+		}
+		return nil
+	}
+	scan := func() error {
+		i := uint64(0)
+		return concurrentReader.ScanHprofGcPrimArraysWithPayload(func(header core.HprofGcPrimArrayDumpHeader, payload []byte) error {
+			i++
+			if header.ArrayObjectId != core.Identifier(2*objects+i) || !bytes.Equal(payload, id8(i)) {
+				return fmt.Errorf("scanned primitive array %v = %+v with payload %v", 2*objects+i, header, payload)
+			}
+			// lookups in the middle of the scan must not move it
+			return check(objects + 1 - i)
+		})
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			var err error
+			if g%4 == 0 {
+				err = scan()
+			} else {
+				for i := uint64(0); i < objects && err == nil; i++ {
+					err = check((i+uint64(g)*13)%objects + 1)
+				}
+			}
+			if err != nil {
+				errs <- err
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
 	}
 }
 
@@ -350,12 +480,18 @@ func TestReader_GetMeta(t *testing.T) {
 			PrimArrayElementsCount: map[core.JavaType]int{
 				core.Boolean: 1,
 			},
+			PrimArrayLengths: map[core.JavaType]core.LengthResidues{
+				core.Boolean: {1: 1},
+			},
 			ObjArraysCount: map[core.Identifier]int{
 				1: 1,
 			},
 			ObjArrayElementsCount: map[core.Identifier]int{
 				1: 1,
 			},
+			ObjArrayLengths: map[core.Identifier]core.LengthResidues{
+				1: {1: 1},
+			},
 		},
 	}
 
@@ -364,6 +500,43 @@ func TestReader_GetMeta(t *testing.T) {
 	}
 }
 
+func TestReader_ListHeaps(t *testing.T) {
+	appName := []byte("app")
+	in := concat(
+		[]byte("JAVA PROFILE 1.0.3\x00"),
+		[]byte{0x00, 0x00, 0x00, 0x08}, // identifier size
+		[]byte{0x00, 0x00, 0x01, 0x7b}, // timestamp, high word
+		[]byte{0x7f, 0x28, 0xa8, 0x27}, // timestamp, low word
+		createRecordHeader(core.HprofUtf8Tag, uint32(8+len(appName))),
+		one8, // identifier
+		appName,
+		createRecordHeader(core.HprofHeapDumpSegmentTag, 0),
+		createSubRecordHeader(core.HprofHeapDumpInfoType),
+		[]byte{0x00, 0x00, 0x00, 'Z'}, // heap type
+		two8,                          // heap name id, not presented in the dump
+		createSubRecordHeader(core.HprofHeapDumpInfoType),
+		[]byte{0x00, 0x00, 0x00, 'A'}, // heap type
+		one8,                          // heap name id
+		createSubRecordHeader(core.HprofGcPrimArrayDumpType),
+		one8,                   // array object id
+		one4,                   // stack trace serial number
+		one4,                   // number of elements
+		[]byte{byte(core.Int)}, // element type
+		one4,                   // element
+		createRecordHeader(core.HprofHeapDumpEndTag, 0),
+	)
+	heaps := createReader(in).ListHeaps()
+	if len(heaps) != 2 {
+		t.Fatalf("ListHeaps() = %+v, expected 2 heaps", heaps)
+	}
+	if heaps[0].Type != 'A' || heaps[0].Name != "app" || heaps[0].Counters.PrimArraysCount[core.Int] != 1 {
+		t.Errorf("ListHeaps()[0] = %+v, expected app heap with one array", heaps[0])
+	}
+	if heaps[1].Type != 'Z' || heaps[1].Name != "heap 90" || len(heaps[1].Counters.PrimArraysCount) != 0 {
+		t.Errorf("ListHeaps()[1] = %+v, expected empty heap 90", heaps[1])
+	}
+}
+
 func concat(head []byte, tail ...[]byte) []byte {
 	res := head[:]
 	for _, b := range tail {
@@ -414,6 +587,10 @@ func createReader(in []byte) *ParsedAccessor {
 	return reader
 }
 
+func id8(id uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, id)
+}
+
 // record header length = 9
 func createRecordHeader(tag core.Tag, remaining uint32) []byte {
 	start := []byte{
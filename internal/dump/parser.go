@@ -17,6 +17,7 @@ package dump
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
 
@@ -31,6 +32,7 @@ type Parser struct {
 	smallRecordsWriteStorage *storage.SmallRecordsWriteStorage
 	bigRecordsWriteStorage   *storage.BigRecordsWriteStorage
 	metaWriteStorage         *storage.MetaWriteStorage
+	referencesWriteStorage   *storage.ReferencesWriteStorage
 }
 
 func NewParser(
@@ -48,6 +50,15 @@ func NewParser(
 	}
 }
 
+// WithReferences enables extraction of references between objects
+// to the given storage. Parser has to read payloads of instances and
+// object arrays then, so parsing takes longer. The storage is closed
+// by the parser when parsing is over.
+func (parser *Parser) WithReferences(referencesWriteStorage *storage.ReferencesWriteStorage) *Parser {
+	parser.referencesWriteStorage = referencesWriteStorage
+	return parser
+}
+
 // GetPosition returns the current position while
 // parsing. Mainly used to provide interactive
 // progress bar since parsing large heap dumps
@@ -59,19 +70,30 @@ func (creator *Parser) GetPosition() int {
 // ParseHeapDump parses heap dump to storages.
 // Can be used with arbitrary io.Reader.
 func (parser *Parser) ParseHeapDump() error {
+	err := parser.parseHeapDump()
+	if parser.referencesWriteStorage != nil {
+		if closeErr := parser.referencesWriteStorage.Close(); err == nil && closeErr != nil {
+			return fmt.Errorf("cannot write references index: %w", closeErr)
+		}
+	}
+	return err
+}
+
+func (parser *Parser) parseHeapDump() error {
 	bufferedHeapDump := bufio.NewReader(parser.heapDump)
 	fileHeader, err := core.ParseFileHeader(bufferedHeapDump)
 	if err != nil {
 		return fmt.Errorf("error parsing .hprof header: %w", err)
 	}
-	if fileHeader.Header != core.ValidProfileVersion {
-		return fmt.Errorf("unsupported profile version %v, only %v supported", fileHeader.Header, core.ValidProfileVersion)
-	}
 	parser.smallRecordsWriteStorage.PutIdSize(fileHeader.IdentifierSize)
 	parser.smallRecordsWriteStorage.PutTimestamp(fileHeader.Timestamp)
 
 	size := core.NewSizeInfo(fileHeader.IdentifierSize)
 	recordParser := core.NewRecordParser(bufferedHeapDump, fileHeader.IdentifierSize)
+	var references *referencesExtractor
+	if parser.referencesWriteStorage != nil {
+		references = newReferencesExtractor(parser.referencesWriteStorage, parser.lookupClassDump, fileHeader.IdentifierSize)
+	}
 
 	parser.pos = 31
 
@@ -79,155 +101,372 @@ func (parser *Parser) ParseHeapDump() error {
 
 	for {
 		header, err := recordParser.ParseRecordHeader()
-		parser.pos += 9
 		if err != nil {
-			if err == io.EOF {
+			if errors.Is(err, io.EOF) {
 				return nil
 			}
 			return fmt.Errorf("error parsing record header: %w", err)
 		}
+		parser.pos += 9
 		switch header.Tag {
-		case core.HprofUtf8Tag:
-			record, err := recordParser.ParseHprofUtf8(header.Remaining)
+		case core.HprofHeapDumpTag, core.HprofHeapDumpSegmentTag:
+			// heap dump can be followed by any record, so its sub-records end
+			// where the length says. Zero length means that the length is
+			// unknown, then sub-records end at the next heap dump record.
+			end := -1
+			if header.Remaining > 0 {
+				end = parser.pos + int(header.Remaining)
+			}
+			done, err := parser.parseSubRecords(recordParser, bufferedHeapDump, size, references, end)
+			if err != nil || done {
+				return err
+			}
+			if end >= 0 && parser.pos != end {
+				return fmt.Errorf("sub-records of %v end at %v instead of %v", header.Tag, parser.pos, end)
+			}
+		case core.HprofHeapDumpEndTag:
+			continue
+		default:
+			if err := parser.parseRecord(header, recordParser, bufferedHeapDump); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (parser *Parser) lookupClassDump(classObjectId core.Identifier) (core.HprofGcClassDump, bool) {
+	classDump, ok := parser.smallRecordsWriteStorage.HprofGcClassDump[classObjectId]
+	return classDump, ok
+}
+
+// parseRecord parses the record that is not a part of heap dump. The
+// position of the parser is moved to the end of the record.
+func (parser *Parser) parseRecord(header core.RecordHeader, recordParser *core.RecordParser, bufferedHeapDump *bufio.Reader) error {
+	switch header.Tag {
+	case core.HprofUtf8Tag:
+		record, err := recordParser.ParseHprofUtf8(header.Remaining)
+		if err != nil {
+			return fmt.Errorf("error parsing HprofUtf8: %w", err)
+		}
+		parser.smallRecordsWriteStorage.PutHprofUtf8(record)
+		parser.pos += int(header.Remaining)
+	case core.HprofLoadClassTag:
+		record, err := recordParser.ParseHprofLoadClass()
+		if err != nil {
+			return fmt.Errorf("error parsing HprofLoadClass: %w", err)
+		}
+		parser.smallRecordsWriteStorage.PutHprofLoadClass(record)
+		parser.pos += int(header.Remaining)
+	case core.HprofFrameTag:
+		record, err := recordParser.ParseHprofFrame()
+		if err != nil {
+			return fmt.Errorf("error parsing HprofFrame: %w", err)
+		}
+		parser.smallRecordsWriteStorage.PutHprofFrame(record)
+		parser.pos += int(header.Remaining)
+	case core.HprofTraceTag:
+		record, err := recordParser.ParseHprofTrace()
+		if err != nil {
+			return fmt.Errorf("error parsing HprofTrace: %w", err)
+		}
+		parser.smallRecordsWriteStorage.PutHprofTrace(record)
+		parser.pos += int(header.Remaining)
+	case core.HprofUnloadClassTag:
+		// heap dump has class dumps only for the classes alive
+		// at the moment of dumping, so the record is of no use here
+		if _, err := recordParser.ParseHprofUnloadClass(); err != nil {
+			return fmt.Errorf("error parsing HprofUnloadClass: %w", err)
+		}
+		parser.pos += int(header.Remaining)
+	case core.HprofAllocSitesTag:
+		record, err := recordParser.ParseHprofAllocSites()
+		if err != nil {
+			return fmt.Errorf("error parsing HprofAllocSites: %w", err)
+		}
+		parser.smallRecordsWriteStorage.PutHprofAllocSites(record)
+		parser.pos += int(header.Remaining)
+	case core.HprofHeapSummaryTag:
+		record, err := recordParser.ParseHprofHeapSummary()
+		if err != nil {
+			return fmt.Errorf("error parsing HprofHeapSummary: %w", err)
+		}
+		parser.smallRecordsWriteStorage.PutHprofHeapSummary(record)
+		parser.pos += int(header.Remaining)
+	case core.HprofStartThreadTag:
+		record, err := recordParser.ParseHprofStartThread()
+		if err != nil {
+			return fmt.Errorf("error parsing HprofStartThread: %w", err)
+		}
+		parser.smallRecordsWriteStorage.PutHprofStartThread(record)
+		parser.pos += int(header.Remaining)
+	case core.HprofEndThreadTag:
+		record, err := recordParser.ParseHprofEndThread()
+		if err != nil {
+			return fmt.Errorf("error parsing HprofEndThread: %w", err)
+		}
+		parser.smallRecordsWriteStorage.PutHprofEndThread(record)
+		parser.pos += int(header.Remaining)
+	case core.HprofCpuSamplesTag:
+		// samples of CPU profiler are not related to heap
+		if _, err := recordParser.ParseHprofCpuSamples(); err != nil {
+			return fmt.Errorf("error parsing HprofCpuSamples: %w", err)
+		}
+		parser.pos += int(header.Remaining)
+	case core.HprofControlSettingsTag:
+		// settings of profiling agent are not related to heap
+		if _, err := recordParser.ParseHprofControlSettings(); err != nil {
+			return fmt.Errorf("error parsing HprofControlSettings: %w", err)
+		}
+		parser.pos += int(header.Remaining)
+	default:
+		// vendor-specific records are skipped, the length
+		// is the only thing known about them
+		if err := skip(int(header.Remaining), bufferedHeapDump); err != nil {
+			return fmt.Errorf("error skipping %v: %w", header.Tag, err)
+		}
+		parser.pos += int(header.Remaining)
+	}
+	return nil
+}
+
+// parseSubRecords parses sub-records of heap dump up to end. Negative end
+// means that sub-records end at the next record, then it's reported
+// whether the dump ends with sub-records.
+func (parser *Parser) parseSubRecords(
+	recordParser *core.RecordParser,
+	bufferedHeapDump *bufio.Reader,
+	size *core.SizeInfo,
+	references *referencesExtractor,
+	end int,
+) (bool, error) {
+	for end < 0 || parser.pos < end {
+		subRecordHeader, err := recordParser.ParseSubRecordHeader()
+		if err != nil {
+			if end < 0 && errors.Is(err, io.EOF) {
+				return true, nil
+			}
+			return false, fmt.Errorf("error parsing sub-record type: %w", err)
+		}
+		parser.pos++
+		switch subRecordHeader.SubRecordType {
+		case core.HprofGcRootJniGlobalType:
+			record, err := recordParser.ParseHprofGcRootJniGlobal()
+			if err != nil {
+				return false, fmt.Errorf("error parsing HprofGcRootJniGlobal: %w", err)
+			}
+			parser.smallRecordsWriteStorage.PutHprofGcRootJniGlobal(record)
+			parser.pos += size.Of(record)
+		case core.HprofGcRootJniLocalType:
+			record, err := recordParser.ParseHprofGcRootJniLocal()
+			if err != nil {
+				return false, fmt.Errorf("error parsing HprofGcRootJniLocal: %w", err)
+			}
+			parser.smallRecordsWriteStorage.PutHprofGcRootJniLocal(record)
+			parser.pos += size.Of(record)
+		case core.HprofGcRootJavaFrameType:
+			record, err := recordParser.ParseHprofGcRootJavaFrame()
+			if err != nil {
+				return false, fmt.Errorf("error parsing HprofGcRootJavaFrame: %w", err)
+			}
+			parser.smallRecordsWriteStorage.PutHprofGcRootJavaFrame(record)
+			parser.pos += size.Of(record)
+		case core.HprofGcRootStickyClassType:
+			record, err := recordParser.ParseHprofGcRootStickyClass()
+			if err != nil {
+				return false, fmt.Errorf("error parsing HprofGcRootStickyClass: %w", err)
+			}
+			parser.smallRecordsWriteStorage.PutHprofGcRootStickyClass(record)
+			parser.pos += size.Of(record)
+		case core.HprofGcRootThreadObjType:
+			record, err := recordParser.ParseHprofGcRootThreadObj()
+			if err != nil {
+				return false, fmt.Errorf("error parsing HprofGcRootThreadObj: %w", err)
+			}
+			parser.smallRecordsWriteStorage.PutHprofGcRootThreadObj(record)
+			parser.pos += size.Of(record)
+		case core.HprofGcRootUnknownType:
+			record, err := recordParser.ParseHprofGcRootUnknown()
+			if err != nil {
+				return false, fmt.Errorf("error parsing HprofGcRootUnknown: %w", err)
+			}
+			parser.smallRecordsWriteStorage.PutHprofGcRootUnknown(record)
+			parser.pos += size.Of(record)
+		case core.HprofGcRootNativeStackType:
+			record, err := recordParser.ParseHprofGcRootNativeStack()
+			if err != nil {
+				return false, fmt.Errorf("error parsing HprofGcRootNativeStack: %w", err)
+			}
+			parser.smallRecordsWriteStorage.PutHprofGcRootNativeStack(record)
+			parser.pos += size.Of(record)
+		case core.HprofGcRootThreadBlockType:
+			record, err := recordParser.ParseHprofGcRootThreadBlock()
+			if err != nil {
+				return false, fmt.Errorf("error parsing HprofGcRootThreadBlock: %w", err)
+			}
+			parser.smallRecordsWriteStorage.PutHprofGcRootThreadBlock(record)
+			parser.pos += size.Of(record)
+		case core.HprofGcRootMonitorUsedType:
+			record, err := recordParser.ParseHprofGcRootMonitorUsed()
+			if err != nil {
+				return false, fmt.Errorf("error parsing HprofGcRootMonitorUsed: %w", err)
+			}
+			parser.smallRecordsWriteStorage.PutHprofGcRootMonitorUsed(record)
+			parser.pos += size.Of(record)
+		case core.HprofGcRootInternedStringType:
+			record, err := recordParser.ParseHprofGcRootInternedString()
+			if err != nil {
+				return false, fmt.Errorf("error parsing HprofGcRootInternedString: %w", err)
+			}
+			parser.smallRecordsWriteStorage.PutHprofGcRootInternedString(record)
+			parser.pos += size.Of(record)
+		case core.HprofGcRootFinalizingType:
+			record, err := recordParser.ParseHprofGcRootFinalizing()
+			if err != nil {
+				return false, fmt.Errorf("error parsing HprofGcRootFinalizing: %w", err)
+			}
+			parser.smallRecordsWriteStorage.PutHprofGcRootFinalizing(record)
+			parser.pos += size.Of(record)
+		case core.HprofGcRootDebuggerType:
+			record, err := recordParser.ParseHprofGcRootDebugger()
+			if err != nil {
+				return false, fmt.Errorf("error parsing HprofGcRootDebugger: %w", err)
+			}
+			parser.smallRecordsWriteStorage.PutHprofGcRootDebugger(record)
+			parser.pos += size.Of(record)
+		case core.HprofGcRootReferenceCleanupType:
+			record, err := recordParser.ParseHprofGcRootReferenceCleanup()
+			if err != nil {
+				return false, fmt.Errorf("error parsing HprofGcRootReferenceCleanup: %w", err)
+			}
+			parser.smallRecordsWriteStorage.PutHprofGcRootReferenceCleanup(record)
+			parser.pos += size.Of(record)
+		case core.HprofGcRootVmInternalType:
+			record, err := recordParser.ParseHprofGcRootVmInternal()
 			if err != nil {
-				return fmt.Errorf("error parsing HprofUtf8: %w", err)
+				return false, fmt.Errorf("error parsing HprofGcRootVmInternal: %w", err)
 			}
-			parser.smallRecordsWriteStorage.PutHprofUtf8(record)
-			parser.pos += int(header.Remaining)
-		case core.HprofLoadClassTag:
-			record, err := recordParser.ParseHprofLoadClass()
+			parser.smallRecordsWriteStorage.PutHprofGcRootVmInternal(record)
+			parser.pos += size.Of(record)
+		case core.HprofGcRootJniMonitorType:
+			record, err := recordParser.ParseHprofGcRootJniMonitor()
 			if err != nil {
-				return fmt.Errorf("error parsing HprofLoadClass: %w", err)
+				return false, fmt.Errorf("error parsing HprofGcRootJniMonitor: %w", err)
 			}
-			parser.smallRecordsWriteStorage.PutHprofLoadClass(record)
-			parser.pos += int(header.Remaining)
-		case core.HprofFrameTag:
-			record, err := recordParser.ParseHprofFrame()
+			parser.smallRecordsWriteStorage.PutHprofGcRootJniMonitor(record)
+			parser.pos += size.Of(record)
+		case core.HprofGcRootUnreachableType:
+			// unreachable objects are found by traversing
+			// the references from GC roots anyway
+			record, err := recordParser.ParseHprofGcRootUnreachable()
 			if err != nil {
-				return fmt.Errorf("error parsing HprofFrame: %w", err)
+				return false, fmt.Errorf("error parsing HprofGcRootUnreachable: %w", err)
 			}
-			parser.smallRecordsWriteStorage.PutHprofFrame(record)
-			parser.pos += int(header.Remaining)
-		case core.HprofTraceTag:
-			record, err := recordParser.ParseHprofTrace()
+			parser.pos += size.Of(record)
+		case core.HprofHeapDumpInfoType:
+			record, err := recordParser.ParseHprofHeapDumpInfo()
 			if err != nil {
-				return fmt.Errorf("error parsing HprofTrace: %w", err)
+				return false, fmt.Errorf("error parsing HprofHeapDumpInfo: %w", err)
 			}
-			parser.smallRecordsWriteStorage.PutHprofTrace(record)
-			parser.pos += int(header.Remaining)
-		case core.HprofHeapDumpSegmentTag:
-		loop:
-			for {
-				subRecordHeader, err := recordParser.ParseSubRecordHeader()
-				if err != nil {
-					return fmt.Errorf("error parsing sub-record type: %w", err)
+			parser.smallRecordsWriteStorage.PutHprofHeapDumpInfo(record)
+			parser.metaWriteStorage.SetHeap(record.HeapType)
+			parser.pos += size.Of(record)
+		case core.HprofGcClassDumpType:
+			record, err := recordParser.ParseHprofGcClassDump()
+			if err != nil {
+				return false, fmt.Errorf("error parsing HprofGcClassDump: %w", err)
+			}
+			parser.smallRecordsWriteStorage.PutHprofGcClassDump(record)
+			parser.pos += size.Of(record)
+			if references != nil {
+				if err := references.classDump(record); err != nil {
+					return false, fmt.Errorf("error extracting references of HprofGcClassDump: %w", err)
 				}
-				parser.pos++
-				switch subRecordHeader.SubRecordType {
-				case core.HprofGcRootJniGlobalType:
-					record, err := recordParser.ParseHprofGcRootJniGlobal()
-					if err != nil {
-						return fmt.Errorf("error parsing HprofGcRootJniGlobal: %w", err)
-					}
-					parser.smallRecordsWriteStorage.PutHprofGcRootJniGlobal(record)
-					parser.pos += size.Of(record)
-				case core.HprofGcRootJniLocalType:
-					record, err := recordParser.ParseHprofGcRootJniLocal()
-					if err != nil {
-						return fmt.Errorf("error parsing HprofGcRootJniLocal: %w", err)
-					}
-					parser.smallRecordsWriteStorage.PutHprofGcRootJniLocal(record)
-					parser.pos += size.Of(record)
-				case core.HprofGcRootJavaFrameType:
-					record, err := recordParser.ParseHprofGcRootJavaFrame()
-					if err != nil {
-						return fmt.Errorf("error parsing HprofGcRootJavaFrame: %w", err)
-					}
-					parser.smallRecordsWriteStorage.PutHprofGcRootJavaFrame(record)
-					parser.pos += size.Of(record)
-				case core.HprofGcRootStickyClassType:
-					record, err := recordParser.ParseHprofGcRootStickyClass()
-					if err != nil {
-						return fmt.Errorf("error parsing HprofGcRootStickyClass: %w", err)
-					}
-					parser.smallRecordsWriteStorage.PutHprofGcRootStickyClass(record)
-					parser.pos += size.Of(record)
-				case core.HprofGcRootThreadObjType:
-					record, err := recordParser.ParseHprofGcRootThreadObj()
-					if err != nil {
-						return fmt.Errorf("error parsing HprofGcRootThreadObj: %w", err)
-					}
-					parser.smallRecordsWriteStorage.PutHprofGcRootThreadObj(record)
-					parser.pos += size.Of(record)
-				case core.HprofGcClassDumpType:
-					record, err := recordParser.ParseHprofGcClassDump()
-					if err != nil {
-						return fmt.Errorf("error parsing HprofGcClassDump: %w", err)
-					}
-					parser.smallRecordsWriteStorage.PutHprofGcClassDump(record)
-					parser.pos += size.Of(record)
-				case core.HprofGcInstanceDumpType:
-					record, err := recordParser.ParseHprofGcClassDumpInstanceDumpHeader()
-					if err != nil {
-						return fmt.Errorf("error parsing HprofGcClassDumpInstanceDump: %w", err)
-					}
-					if err := parser.bigRecordsWriteStorage.HprofGcInstanceDumpPutOffset(record.ObjectId, parser.pos); err != nil {
-						return fmt.Errorf("indexing error: HprofGcInstanceDumpPutOffset: %w", err)
-					}
-					fullSize, recordsSize := size.OfObject(record)
-					parser.pos += fullSize
-					parser.metaWriteStorage.AddInstance(record)
-					if err := skip(recordsSize, bufferedHeapDump); err != nil {
-						return fmt.Errorf("error discarding records of HprofGcClassDumpInstanceDump: %w", err)
-					}
-				case core.HprofGcObjArrayDumpType:
-					record, err := recordParser.ParseHprofGcObjArrayDumpHeader()
-					if err != nil {
-						return fmt.Errorf("error parsing HprofGcObjArrayDump: %w", err)
-					}
-					if err := parser.bigRecordsWriteStorage.HprofGcObjArrayDumpPutOffset(record.ArrayObjectId, parser.pos); err != nil {
-						return fmt.Errorf("indexing error: HprofGcObjArrayDumpPutOffset: %w", err)
-					}
-					fullSize, recordsSize := size.OfObject(record)
-					parser.pos += fullSize
-					parser.metaWriteStorage.AddInstance(record)
-					if err := skip(recordsSize, bufferedHeapDump); err != nil {
-						return fmt.Errorf("error discarding records of HprofGcObjArrayDump: %w", err)
-					}
-				case core.HprofGcPrimArrayDumpType:
-					record, err := recordParser.ParseHprofGcPrimArrayDumpHeader()
-					if err != nil {
-						return fmt.Errorf("error parsing HprofGcPrimArrayDump: %w", err)
-					}
-					if err := parser.bigRecordsWriteStorage.HprofGcPrimArrayDumpPutOffset(record.ArrayObjectId, parser.pos); err != nil {
-						return fmt.Errorf("indexing error: HprofGcPrimArrayDumpPutOffset: %w", err)
-					}
-					fullSize, recordsSize := size.OfObject(record)
-					parser.pos += fullSize
-					parser.metaWriteStorage.AddInstance(record)
-					if err := skip(recordsSize, bufferedHeapDump); err != nil {
-						return fmt.Errorf("error discarding records of HprofGcPrimArrayDump: %w", err)
-					}
-				case core.HprofHeapDumpEndSubRecord:
-					if err := unreadByte(bufferedHeapDump); err != nil {
-						return fmt.Errorf("error unreading byte at HprofHeapDumpEndSubRecord: %w", err)
-					}
-					parser.pos--
-					break loop
-				case core.HprofHeapDumpSegmentSubRecord:
-					if err := unreadByte(bufferedHeapDump); err != nil {
-						return fmt.Errorf("error unreading byte at HprofHeapDumpSegmentSubRecord: %w", err)
-					}
-					parser.pos--
-					break loop
+			}
+		case core.HprofGcInstanceDumpType:
+			record, err := recordParser.ParseHprofGcClassDumpInstanceDumpHeader()
+			if err != nil {
+				return false, fmt.Errorf("error parsing HprofGcClassDumpInstanceDump: %w", err)
+			}
+			if err := parser.bigRecordsWriteStorage.HprofGcInstanceDumpPutOffset(record.ObjectId, parser.pos); err != nil {
+				return false, fmt.Errorf("indexing error: HprofGcInstanceDumpPutOffset: %w", err)
+			}
+			fullSize, recordsSize := size.OfObject(record)
+			parser.pos += fullSize
+			parser.metaWriteStorage.AddInstance(record)
+			if references != nil {
+				if err := references.instanceDump(record, recordsSize, bufferedHeapDump); err != nil {
+					return false, fmt.Errorf("error extracting references of HprofGcClassDumpInstanceDump: %w", err)
 				}
+			} else if err := skip(recordsSize, bufferedHeapDump); err != nil {
+				return false, fmt.Errorf("error discarding records of HprofGcClassDumpInstanceDump: %w", err)
 			}
-		case core.HprofHeapDumpEndTag:
-			return nil
+		case core.HprofGcObjArrayDumpType:
+			record, err := recordParser.ParseHprofGcObjArrayDumpHeader()
+			if err != nil {
+				return false, fmt.Errorf("error parsing HprofGcObjArrayDump: %w", err)
+			}
+			if err := parser.bigRecordsWriteStorage.HprofGcObjArrayDumpPutOffset(record.ArrayObjectId, parser.pos); err != nil {
+				return false, fmt.Errorf("indexing error: HprofGcObjArrayDumpPutOffset: %w", err)
+			}
+			fullSize, recordsSize := size.OfObject(record)
+			parser.pos += fullSize
+			parser.metaWriteStorage.AddInstance(record)
+			if references != nil {
+				if err := references.objArrayDump(record, recordsSize, bufferedHeapDump); err != nil {
+					return false, fmt.Errorf("error extracting references of HprofGcObjArrayDump: %w", err)
+				}
+			} else if err := skip(recordsSize, bufferedHeapDump); err != nil {
+				return false, fmt.Errorf("error discarding records of HprofGcObjArrayDump: %w", err)
+			}
+		case core.HprofGcPrimArrayDumpType:
+			record, err := recordParser.ParseHprofGcPrimArrayDumpHeader()
+			if err != nil {
+				return false, fmt.Errorf("error parsing HprofGcPrimArrayDump: %w", err)
+			}
+			if err := parser.bigRecordsWriteStorage.HprofGcPrimArrayDumpPutOffset(record.ArrayObjectId, parser.pos); err != nil {
+				return false, fmt.Errorf("indexing error: HprofGcPrimArrayDumpPutOffset: %w", err)
+			}
+			fullSize, recordsSize := size.OfObject(record)
+			parser.pos += fullSize
+			parser.metaWriteStorage.AddInstance(record)
+			if err := skip(recordsSize, bufferedHeapDump); err != nil {
+				return false, fmt.Errorf("error discarding records of HprofGcPrimArrayDump: %w", err)
+			}
+		case core.HprofGcPrimArrayNoDataDumpType:
+			// the header of primitive array without elements, such
+			// arrays are counted but cannot be read from the dump
+			record, err := recordParser.ParseHprofGcPrimArrayDumpHeader()
+			if err != nil {
+				return false, fmt.Errorf("error parsing HprofGcPrimArrayNoDataDump: %w", err)
+			}
+			fullSize, recordsSize := size.OfObject(record)
+			parser.pos += fullSize - recordsSize
+			parser.metaWriteStorage.AddInstance(record)
+		case core.HprofHeapDumpEndSubRecord:
+			if err := unreadByte(bufferedHeapDump); err != nil {
+				return false, fmt.Errorf("error unreading byte at HprofHeapDumpEndSubRecord: %w", err)
+			}
+			parser.pos--
+			return false, nil
+		case core.HprofHeapDumpSegmentSubRecord:
+			if err := unreadByte(bufferedHeapDump); err != nil {
+				return false, fmt.Errorf("error unreading byte at HprofHeapDumpSegmentSubRecord: %w", err)
+			}
+			parser.pos--
+			return false, nil
+		case core.HprofHeapDumpSubRecord:
+			if err := unreadByte(bufferedHeapDump); err != nil {
+				return false, fmt.Errorf("error unreading byte at HprofHeapDumpSubRecord: %w", err)
+			}
+			parser.pos--
+			return false, nil
 		default:
-			return fmt.Errorf("unknown tag: %v", header.Tag)
+			// sub-records have no length, so the
+			// rest of the dump cannot be read
+			return false, fmt.Errorf("unknown sub-record type %v at position %v", subRecordHeader.SubRecordType, parser.pos-1)
 		}
 	}
+	return false, nil
 }
 
 // skip calls underlying bufio.Reader.Discard
@@ -229,3 +229,428 @@ func seekTo(offset int, readSeeker io.ReadSeeker, t *testing.T) {
 		t.Errorf("cannot seek to %v: %v", offset, err)
 	}
 }
+
+func TestParser_ParseHeapDump101(t *testing.T) {
+	header101 := []byte{
+		0x4a, 0x41, 0x56, 0x41, 0x20, 0x50, 0x52, 0x4f, 0x46, 0x49, 0x4c, 0x45, 0x20, 0x31, 0x2e, 0x30, 0x2e, 0x31, 0x00, // JAVA PROFILE 1.0.1
+		0x00, 0x00, 0x00, 0x08, // identifier size
+		0x00, 0x00, 0x01, 0x7b, // timestamp, high word
+		0x7f, 0x28, 0xa8, 0x27, // timestamp, low word
+	}
+	heapDumpRecords := concat(
+		// 0
+		createSubRecordHeader(core.HprofGcRootStickyClassType),
+		// 1
+		one8, // object id
+		// 9
+		createSubRecordHeader(core.HprofGcInstanceDumpType),
+		// 10
+		two8, // object id
+		one4, // stack trace serial number
+		one8, // class object id
+		one4, // number of bytes that follow
+		one1, // single byte
+		// 35
+	)
+	utf8 := concat(
+		createRecordHeader(core.HprofUtf8Tag, 8+4),
+		one8,                           // identifier
+		[]byte{0x4a, 0x41, 0x56, 0x41}, // "JAVA" string
+	)
+	tests := []struct {
+		name    string
+		input   []byte
+		wantErr bool
+	}{
+		{
+			// the tag of HPROF_UTF8 is the same as the type of
+			// HPROF_GC_ROOT_JNI_GLOBAL, so the length is the only
+			// way to find the end of HPROF_HEAP_DUMP
+			name: "heap dump followed by other record",
+			input: concat(
+				header101,
+				createRecordHeader(core.HprofHeapDumpTag, uint32(len(heapDumpRecords))),
+				heapDumpRecords,
+				utf8,
+			),
+		},
+		{
+			name: "heap dump without length at the end of file",
+			input: concat(
+				header101,
+				utf8,
+				createRecordHeader(core.HprofHeapDumpTag, 0),
+				heapDumpRecords,
+			),
+		},
+		{
+			name: "heap dump followed by heap dump end",
+			input: concat(
+				header101,
+				utf8,
+				createRecordHeader(core.HprofHeapDumpTag, uint32(len(heapDumpRecords))),
+				heapDumpRecords,
+				createRecordHeader(core.HprofHeapDumpEndTag, 0),
+			),
+		},
+		{
+			name: "heap dump with wrong length",
+			input: concat(
+				header101,
+				createRecordHeader(core.HprofHeapDumpTag, uint32(len(heapDumpRecords)-1)),
+				heapDumpRecords,
+				utf8,
+			),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			smallWriter := storage.NewSmallRecordsWriteStorage()
+			instanceDumpWriteVolume := storage.NewRamWriteVolume()
+			bigWriter := storage.NewBigRecordsWriteStorage(
+				instanceDumpWriteVolume, storage.NewRamWriteVolume(), storage.NewRamWriteVolume())
+			metaWriter := storage.NewMetaWriteStorage()
+			parser := NewParser(bytes.NewReader(tt.input), smallWriter, bigWriter, metaWriter)
+
+			err := parser.ParseHeapDump()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseHeapDump() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if pos := parser.GetPosition(); pos != len(tt.input) {
+				t.Errorf("wrong position = %v, want %v", pos, len(tt.input))
+			}
+			smallReader := storage.NewSmallRecordsReadStorage()
+			smallBuf := bytes.NewBuffer(nil)
+			if err := smallWriter.SerializeTo(smallBuf); err != nil {
+				t.Fatalf("error serializing smallwriter: %v", err)
+			}
+			if err := smallReader.RestoreFrom(smallBuf); err != nil {
+				t.Fatalf("error creating smallreader: %v", err)
+			}
+			if _, err := smallReader.GetHprofUtf8(1); err != nil {
+				t.Errorf("GetHprofUtf8() error = %v", err)
+			}
+			stickyClasses := smallReader.ListHprofGcRootStickyClass()
+			expectedStickyClasses := []core.HprofGcRootStickyClass{{ObjectId: 1}}
+			if !reflect.DeepEqual(stickyClasses, expectedStickyClasses) {
+				t.Errorf("ListHprofGcRootStickyClass = %v, want %v", stickyClasses, expectedStickyClasses)
+			}
+			if jniGlobals := smallReader.ListHprofGcRootJniGlobal(); len(jniGlobals) != 0 {
+				t.Errorf("ListHprofGcRootJniGlobal = %v, want none", jniGlobals)
+			}
+			bigReader, err := storage.NewBigRecordsReadStorage(
+				storage.NewRamReadVolume(instanceDumpWriteVolume.Bytes()), instanceDumpWriteVolume.Len(),
+				storage.NewRamReadVolume(nil), 0,
+				storage.NewRamReadVolume(nil), 0,
+			)
+			if err != nil {
+				t.Fatalf("error creating bigreader: %v", err)
+			}
+			if _, err := bigReader.HprofGcInstanceDumpGetOffset(2); err != nil {
+				t.Errorf("HprofGcInstanceDumpGetOffset() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestParser_ParseHeapDumpAllTags(t *testing.T) {
+	input := concat(
+		[]byte{
+			0x4a, 0x41, 0x56, 0x41, 0x20, 0x50, 0x52, 0x4f, 0x46, 0x49, 0x4c, 0x45, 0x20, 0x31, 0x2e, 0x30, 0x2e, 0x32, 0x00, // JAVA PROFILE 1.0.2
+			0x00, 0x00, 0x00, 0x08, // identifier size
+			0x00, 0x00, 0x01, 0x7b, // timestamp, high word
+			0x7f, 0x28, 0xa8, 0x27, // timestamp, low word
+		},
+		createRecordHeader(core.HprofUtf8Tag, 8+4),
+		one8,                           // identifier
+		[]byte{0x6d, 0x61, 0x69, 0x6e}, // "main" string
+		createRecordHeader(core.HprofStartThreadTag, 4+8+4+8+8+8),
+		one4, // thread serial number
+		one8, // thread object id
+		one4, // stack trace serial number
+		one8, // thread name id
+		two8, // thread group name id
+		two8, // thread parent group name id
+		createRecordHeader(core.HprofUnloadClassTag, 4),
+		one4, // class serial number
+		createRecordHeader(core.HprofAllocSitesTag, 2+4+4+4+8+8+4+25),
+		one2,               // flags
+		[]byte{0, 0, 0, 0}, // cutoff ratio
+		one4,               // total live bytes
+		one4,               // total live instances
+		one8,               // total bytes allocated
+		one8,               // total instances allocated
+		one4,               // number of sites
+		[]byte{0x00},       // array indicator
+		one4, one4, one4, one4, one4, one4,
+		createRecordHeader(core.HprofHeapSummaryTag, 4+4+8+8),
+		one4, // total live bytes
+		one4, // total live instances
+		one8, // total bytes allocated
+		one8, // total instances allocated
+		createRecordHeader(core.HprofCpuSamplesTag, 4+4+8),
+		one4, // total number of samples
+		one4, // number of traces
+		one4, // number of samples
+		one4, // stack trace serial number
+		createRecordHeader(core.HprofControlSettingsTag, 4+2),
+		one4, // flags
+		one2, // stack trace depth
+		createRecordHeader(core.Tag(0x42), 3),
+		[]byte{0x01, 0x02, 0x03}, // unknown record
+		createRecordHeader(core.HprofHeapDumpSegmentTag, 1+8),
+		createSubRecordHeader(core.HprofGcRootStickyClassType),
+		one8, // object id
+		createRecordHeader(core.HprofEndThreadTag, 4),
+		one4, // thread serial number
+		createRecordHeader(core.HprofHeapDumpSegmentTag, 0),
+		createSubRecordHeader(core.HprofGcRootStickyClassType),
+		two8, // object id
+		createRecordHeader(core.HprofHeapDumpEndTag, 0),
+		createRecordHeader(core.HprofCpuSamplesTag, 4+4),
+		[]byte{0, 0, 0, 0}, // total number of samples
+		[]byte{0, 0, 0, 0}, // number of traces
+		createRecordHeader(core.HprofControlSettingsTag, 4+2),
+		[]byte{0, 0, 0, 0}, // flags
+		one2,               // stack trace depth
+		createRecordHeader(core.HprofEndThreadTag, 4),
+		[]byte{0, 0, 0, 2}, // thread serial number
+		createRecordHeader(core.HprofUtf8Tag, 8+6),
+		two8, // identifier
+		[]byte{0x77, 0x6f, 0x72, 0x6b, 0x65, 0x72}, // "worker" string
+	)
+	smallWriter := storage.NewSmallRecordsWriteStorage()
+	bigWriter := storage.NewBigRecordsWriteStorage(
+		storage.NewRamWriteVolume(), storage.NewRamWriteVolume(), storage.NewRamWriteVolume())
+	parser := NewParser(bytes.NewReader(input), smallWriter, bigWriter, storage.NewMetaWriteStorage())
+
+	if err := parser.ParseHeapDump(); err != nil {
+		t.Fatalf("ParseHeapDump() error = %v", err)
+	}
+	if pos := parser.GetPosition(); pos != len(input) {
+		t.Errorf("wrong position = %v, want %v", pos, len(input))
+	}
+	smallReader := storage.NewSmallRecordsReadStorage()
+	smallBuf := bytes.NewBuffer(nil)
+	if err := smallWriter.SerializeTo(smallBuf); err != nil {
+		t.Fatalf("error serializing smallwriter: %v", err)
+	}
+	if err := smallReader.RestoreFrom(smallBuf); err != nil {
+		t.Fatalf("error creating smallreader: %v", err)
+	}
+	startThread, err := smallReader.GetHprofStartThread(1)
+	if err != nil {
+		t.Errorf("GetHprofStartThread() error = %v", err)
+	}
+	expectedStartThread := core.HprofStartThread{
+		ThreadSerialNumber: 1, ThreadObjectId: 1, StackTraceSerialNumber: 1,
+		ThreadNameId: 1, ThreadGroupNameId: 2, ThreadParentGroupNameId: 2,
+	}
+	if !reflect.DeepEqual(startThread, expectedStartThread) {
+		t.Errorf("GetHprofStartThread = %v, want %v", startThread, expectedStartThread)
+	}
+	endThreads := smallReader.ListHprofEndThread()
+	expectedEndThreads := []core.HprofEndThread{{ThreadSerialNumber: 1}, {ThreadSerialNumber: 2}}
+	if !reflect.DeepEqual(endThreads, expectedEndThreads) {
+		t.Errorf("ListHprofEndThread = %v, want %v", endThreads, expectedEndThreads)
+	}
+	heapSummary, err := smallReader.GetHprofHeapSummary()
+	if err != nil {
+		t.Errorf("GetHprofHeapSummary() error = %v", err)
+	}
+	expectedHeapSummary := core.HprofHeapSummary{
+		TotalLiveBytes: 1, TotalLiveInstances: 1, TotalBytesAllocated: 1, TotalInstancesAllocated: 1}
+	if heapSummary != expectedHeapSummary {
+		t.Errorf("GetHprofHeapSummary = %v, want %v", heapSummary, expectedHeapSummary)
+	}
+	allocSites := smallReader.ListHprofAllocSites()
+	if len(allocSites) != 1 || len(allocSites[0].Sites) != 1 || allocSites[0].Sites[0].ClassSerialNumber != 1 {
+		t.Errorf("ListHprofAllocSites = %v, want single site", allocSites)
+	}
+	stickyClasses := smallReader.ListHprofGcRootStickyClass()
+	expectedStickyClasses := []core.HprofGcRootStickyClass{{ObjectId: 1}, {ObjectId: 2}}
+	if !reflect.DeepEqual(stickyClasses, expectedStickyClasses) {
+		t.Errorf("ListHprofGcRootStickyClass = %v, want %v", stickyClasses, expectedStickyClasses)
+	}
+	utf8, err := smallReader.GetHprofUtf8(2)
+	if err != nil {
+		t.Errorf("GetHprofUtf8() error = %v", err)
+	}
+	if utf8.Characters != "worker" {
+		t.Errorf("GetHprofUtf8 = %v, want worker", utf8)
+	}
+	parallel, _, err := parseInParallel(input, 4, 0)
+	if err != nil {
+		t.Fatalf("ParallelParser.ParseHeapDump() error = %v", err)
+	}
+	if !reflect.DeepEqual(parallel.small, smallWriter) {
+		t.Errorf("ParallelParser small records = %+v, want %+v", parallel.small, smallWriter)
+	}
+}
+
+func TestParser_ParseHeapDumpRoots(t *testing.T) {
+	header := []byte{
+		0x4a, 0x41, 0x56, 0x41, 0x20, 0x50, 0x52, 0x4f, 0x46, 0x49, 0x4c, 0x45, 0x20, 0x31, 0x2e, 0x30, 0x2e, 0x32, 0x00, // JAVA PROFILE 1.0.2
+		0x00, 0x00, 0x00, 0x08, // identifier size
+		0x00, 0x00, 0x01, 0x7b, // timestamp, high word
+		0x7f, 0x28, 0xa8, 0x27, // timestamp, low word
+	}
+	roots := concat(
+		createSubRecordHeader(core.HprofGcRootUnknownType),
+		one8, // object id
+		createSubRecordHeader(core.HprofGcRootNativeStackType),
+		one8, // object id
+		one4, // thread serial number
+		createSubRecordHeader(core.HprofGcRootThreadBlockType),
+		two8, // object id
+		one4, // thread serial number
+		createSubRecordHeader(core.HprofGcRootMonitorUsedType),
+		two8, // object id
+	)
+	tests := []struct {
+		name    string
+		input   []byte
+		wantErr bool
+	}{
+		{
+			name: "all roots",
+			input: concat(
+				header,
+				createRecordHeader(core.HprofHeapDumpSegmentTag, 0),
+				roots,
+				createRecordHeader(core.HprofHeapDumpEndTag, 0),
+			),
+		},
+		{
+			name: "unknown sub-record",
+			input: concat(
+				header,
+				createRecordHeader(core.HprofHeapDumpSegmentTag, 0),
+				roots,
+				[]byte{0x42}, // unknown sub-record type
+				one8,
+				createRecordHeader(core.HprofHeapDumpEndTag, 0),
+			),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			smallWriter := storage.NewSmallRecordsWriteStorage()
+			bigWriter := storage.NewBigRecordsWriteStorage(
+				storage.NewRamWriteVolume(), storage.NewRamWriteVolume(), storage.NewRamWriteVolume())
+			parser := NewParser(bytes.NewReader(tt.input), smallWriter, bigWriter, storage.NewMetaWriteStorage())
+
+			err := parser.ParseHeapDump()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseHeapDump() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if pos := parser.GetPosition(); pos != len(tt.input) {
+				t.Errorf("wrong position = %v, want %v", pos, len(tt.input))
+			}
+			smallReader := storage.NewSmallRecordsReadStorage()
+			smallBuf := bytes.NewBuffer(nil)
+			if err := smallWriter.SerializeTo(smallBuf); err != nil {
+				t.Fatalf("error serializing smallwriter: %v", err)
+			}
+			if err := smallReader.RestoreFrom(smallBuf); err != nil {
+				t.Fatalf("error creating smallreader: %v", err)
+			}
+			if got, want := smallReader.ListHprofGcRootUnknown(), []core.HprofGcRootUnknown{{ObjectId: 1}}; !reflect.DeepEqual(got, want) {
+				t.Errorf("ListHprofGcRootUnknown = %v, want %v", got, want)
+			}
+			if got, want := smallReader.ListHprofGcRootNativeStack(), []core.HprofGcRootNativeStack{{ObjectId: 1, ThreadSerialNumber: 1}}; !reflect.DeepEqual(got, want) {
+				t.Errorf("ListHprofGcRootNativeStack = %v, want %v", got, want)
+			}
+			if got, want := smallReader.ListHprofGcRootThreadBlock(), []core.HprofGcRootThreadBlock{{ObjectId: 2, ThreadSerialNumber: 1}}; !reflect.DeepEqual(got, want) {
+				t.Errorf("ListHprofGcRootThreadBlock = %v, want %v", got, want)
+			}
+			if got, want := smallReader.ListHprofGcRootMonitorUsed(), []core.HprofGcRootMonitorUsed{{ObjectId: 2}}; !reflect.DeepEqual(got, want) {
+				t.Errorf("ListHprofGcRootMonitorUsed = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestParser_ParseHeapDumpArt(t *testing.T) {
+	input := concat(
+		[]byte("JAVA PROFILE 1.0.3\x00"),
+		[]byte{0x00, 0x00, 0x00, 0x08}, // identifier size
+		[]byte{0x00, 0x00, 0x01, 0x7b}, // timestamp, high word
+		[]byte{0x7f, 0x28, 0xa8, 0x27}, // timestamp, low word
+		createRecordHeader(core.HprofHeapDumpSegmentTag, 0),
+		createSubRecordHeader(core.HprofHeapDumpInfoType),
+		[]byte{0x00, 0x00, 0x00, 'Z'}, // heap type
+		one8,                          // heap name id
+		createSubRecordHeader(core.HprofGcRootInternedStringType),
+		one8, // object id
+		createSubRecordHeader(core.HprofGcRootFinalizingType),
+		one8, // object id
+		createSubRecordHeader(core.HprofGcRootDebuggerType),
+		one8, // object id
+		createSubRecordHeader(core.HprofGcRootReferenceCleanupType),
+		one8, // object id
+		createSubRecordHeader(core.HprofGcRootVmInternalType),
+		one8, // object id
+		createSubRecordHeader(core.HprofGcRootJniMonitorType),
+		one8, // object id
+		one4, // thread serial number
+		one4, // stack trace depth
+		createSubRecordHeader(core.HprofGcRootUnreachableType),
+		two8, // object id
+		createSubRecordHeader(core.HprofGcPrimArrayNoDataDumpType),
+		one8,                   // array object id
+		one4,                   // stack trace serial number
+		three4,                 // number of elements
+		[]byte{byte(core.Int)}, // element type, no elements follow
+		createSubRecordHeader(core.HprofHeapDumpInfoType),
+		[]byte{0x00, 0x00, 0x00, 'A'}, // heap type
+		two8,                          // heap name id
+		createSubRecordHeader(core.HprofGcPrimArrayDumpType),
+		two8,                   // array object id
+		one4,                   // stack trace serial number
+		one4,                   // number of elements
+		[]byte{byte(core.Int)}, // element type
+		one4,                   // element
+		createRecordHeader(core.HprofHeapDumpEndTag, 0),
+	)
+	smallWriter := storage.NewSmallRecordsWriteStorage()
+	bigWriter := storage.NewBigRecordsWriteStorage(
+		storage.NewRamWriteVolume(), storage.NewRamWriteVolume(), storage.NewRamWriteVolume())
+	metaWriter := storage.NewMetaWriteStorage()
+	parser := NewParser(bytes.NewReader(input), smallWriter, bigWriter, metaWriter)
+
+	if err := parser.ParseHeapDump(); err != nil {
+		t.Fatalf("ParseHeapDump() error = %v", err)
+	}
+	if pos := parser.GetPosition(); pos != len(input) {
+		t.Errorf("wrong position = %v, want %v", pos, len(input))
+	}
+	if got, want := smallWriter.HprofGcRootJniMonitor, []core.HprofGcRootJniMonitor{{ObjectId: 1, ThreadSerialNumber: 1, StackTraceDepth: 1}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("HprofGcRootJniMonitor = %v, want %v", got, want)
+	}
+	roots := len(smallWriter.HprofGcRootInternedString) + len(smallWriter.HprofGcRootFinalizing) +
+		len(smallWriter.HprofGcRootDebugger) + len(smallWriter.HprofGcRootReferenceCleanup) + len(smallWriter.HprofGcRootVmInternal)
+	if roots != 5 {
+		t.Errorf("number of roots = %v, want 5", roots)
+	}
+	if got, want := smallWriter.HprofHeapDumpInfo['A'], (core.HprofHeapDumpInfo{HeapType: 'A', HeapNameId: 2}); got != want {
+		t.Errorf("HprofHeapDumpInfo = %v, want %v", got, want)
+	}
+	if got := metaWriter.Counters.PrimArraysCount[core.Int]; got != 2 {
+		t.Errorf("PrimArraysCount = %v, want 2", got)
+	}
+	if got := metaWriter.HeapCounters['Z'].PrimArrayElementsCount[core.Int]; got != 3 {
+		t.Errorf("zygote PrimArrayElementsCount = %v, want 3", got)
+	}
+	if got := metaWriter.HeapCounters['A'].PrimArrayElementsCount[core.Int]; got != 1 {
+		t.Errorf("app PrimArrayElementsCount = %v, want 1", got)
+	}
+}
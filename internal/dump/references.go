@@ -0,0 +1,145 @@
+package dump
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"github.com/danielleontiev/neojhat/internal/core"
+	"github.com/danielleontiev/neojhat/internal/storage"
+)
+
+// referencesExtractor reads payloads of instances and object arrays
+// instead of skipping them and records every non-null reference to
+// the references storage. Layout of instance fields is computed from
+// class dumps, which precede instances in the dump, and cached per class.
+type referencesExtractor struct {
+	referencesWriteStorage *storage.ReferencesWriteStorage
+	lookupClassDump        func(classObjectId core.Identifier) (core.HprofGcClassDump, bool)
+	size                   *core.SizeInfo
+	idSize                 int
+	objectFieldOffsets     map[core.Identifier][]int
+	payload                []byte
+}
+
+// newReferencesExtractor creates the extractor, lookupClassDump
+// looks up the class dumps parsed so far.
+func newReferencesExtractor(
+	referencesWriteStorage *storage.ReferencesWriteStorage,
+	lookupClassDump func(classObjectId core.Identifier) (core.HprofGcClassDump, bool),
+	idSize uint32,
+) *referencesExtractor {
+	return &referencesExtractor{
+		referencesWriteStorage: referencesWriteStorage,
+		lookupClassDump:        lookupClassDump,
+		size:                   core.NewSizeInfo(idSize),
+		idSize:                 int(idSize),
+		objectFieldOffsets:     make(map[core.Identifier][]int),
+	}
+}
+
+// classDump records references held by static fields.
+func (e *referencesExtractor) classDump(record core.HprofGcClassDump) error {
+	for _, field := range record.StaticFieldRecords {
+		if field.Ty != core.Object {
+			continue
+		}
+		to, err := field.Value.ToObject()
+		if err != nil {
+			return fmt.Errorf("error reading static field of class %v: %w", record.ClassObjectId, err)
+		}
+		if to == 0 {
+			continue
+		}
+		if err := e.referencesWriteStorage.PutReference(record.ClassObjectId, to); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// instanceDump consumes payload of the instance and records
+// references held by its object fields.
+func (e *referencesExtractor) instanceDump(record core.HprofGcClassDumpInstanceDumpHeader, recordsSize int, heapDump *bufio.Reader) error {
+	payload, err := e.read(recordsSize, heapDump)
+	if err != nil {
+		return err
+	}
+	offsets, err := e.objectFieldOffsetsOf(record.ClassObjectId)
+	if err != nil {
+		return err
+	}
+	for _, offset := range offsets {
+		if offset+e.idSize > len(payload) {
+			return fmt.Errorf("field at offset %v is out of instance %v payload", offset, record.ObjectId)
+		}
+		if err := e.put(record.ObjectId, payload[offset:offset+e.idSize]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// objArrayDump consumes elements of the array and records them
+// as references held by the array.
+func (e *referencesExtractor) objArrayDump(record core.HprofGcObjArrayDumpHeader, recordsSize int, heapDump *bufio.Reader) error {
+	payload, err := e.read(recordsSize, heapDump)
+	if err != nil {
+		return err
+	}
+	for offset := 0; offset+e.idSize <= len(payload); offset += e.idSize {
+		if err := e.put(record.ArrayObjectId, payload[offset:offset+e.idSize]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *referencesExtractor) put(from core.Identifier, idBytes []byte) error {
+	var to core.Identifier
+	for _, b := range idBytes {
+		to = to<<8 | core.Identifier(b)
+	}
+	if to == 0 {
+		return nil
+	}
+	return e.referencesWriteStorage.PutReference(from, to)
+}
+
+// read reuses the buffer between records since
+// payloads are not needed after extraction.
+func (e *referencesExtractor) read(n int, heapDump *bufio.Reader) ([]byte, error) {
+	if cap(e.payload) < n {
+		e.payload = make([]byte, n)
+	}
+	e.payload = e.payload[:n]
+	if _, err := io.ReadFull(heapDump, e.payload); err != nil {
+		return nil, fmt.Errorf("cannot read payload: %w", err)
+	}
+	return e.payload, nil
+}
+
+// objectFieldOffsetsOf returns offsets of object fields within instance
+// payload. Fields of the class go first and then fields of superclasses.
+func (e *referencesExtractor) objectFieldOffsetsOf(classObjectId core.Identifier) ([]int, error) {
+	if offsets, ok := e.objectFieldOffsets[classObjectId]; ok {
+		return offsets, nil
+	}
+	var offsets []int
+	offset := 0
+	for classId := classObjectId; classId != 0; {
+		classDump, ok := e.lookupClassDump(classId)
+		if !ok {
+			return nil, fmt.Errorf("class dump %v is not found before its instances", classId)
+		}
+		for _, field := range classDump.InstanceFieldRecords {
+			if field.Ty == core.Object {
+				offsets = append(offsets, offset)
+			}
+			offset += e.size.OfType(field.Ty)
+		}
+		classId = classDump.SuperclassObjectId
+	}
+	e.objectFieldOffsets[classObjectId] = offsets
+	return offsets, nil
+}
@@ -0,0 +1,104 @@
+package duplicates
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+
+	"github.com/danielleontiev/neojhat/internal/core"
+	"github.com/danielleontiev/neojhat/internal/dump"
+	"github.com/danielleontiev/neojhat/internal/java"
+	"github.com/danielleontiev/neojhat/internal/storage"
+)
+
+// GetDuplicateArrays returns top groups of primitive arrays with equal
+// content. The arrays are read from the index of primitive arrays, the
+// hash covers the element type and the length too, so equal payloads
+// of different types are never grouped. newRun provides temporary
+// storage for sorting.
+func GetDuplicateArrays(parsedAccessor *dump.ParsedAccessor, top int, newRun func() (storage.RunVolume, error)) (DuplicateArrays, error) {
+	size, err := java.NewHeap(parsedAccessor).SizeInfo("")
+	if err != nil {
+		return DuplicateArrays{}, err
+	}
+	var result DuplicateArrays
+	sorted, err := sortHashes(newRun, func(put func(uint64, core.Identifier) error) error {
+		return parsedAccessor.ScanHprofGcPrimArraysWithPayload(func(header core.HprofGcPrimArrayDumpHeader, payload []byte) error {
+			result.TotalCount++
+			return put(hashOf(arrayKey(header), payload), header.ArrayObjectId)
+		})
+	})
+	if err != nil {
+		return DuplicateArrays{}, err
+	}
+	defer sorted.Close()
+
+	topArrays := &topGroups[DuplicateArray]{n: top, wasted: func(a DuplicateArray) int { return a.WastedSize }}
+	// arrays with the same hash are compared byte by byte,
+	// different contents go to different groups
+	var groups []*arrayGroup
+	add := func(arrayId core.Identifier) error {
+		header, payload, err := parsedAccessor.GetHprofGcPrimArrayWithPayload(arrayId)
+		if err != nil {
+			return err
+		}
+		arraySize := size.OfArray(header.ElementType, int(header.NumberOfElements))
+		key := arrayKey(header)
+		for _, g := range groups {
+			if bytes.Equal(g.key, key) && bytes.Equal(g.payload, payload) {
+				g.Count++
+				g.WastedSize += arraySize
+				return nil
+			}
+		}
+		groups = append(groups, &arrayGroup{
+			DuplicateArray: DuplicateArray{
+				ElementType: header.ElementType,
+				Length:      int(header.NumberOfElements),
+				Count:       1,
+				SampleId:    arrayId,
+			},
+			key:     key,
+			payload: payload,
+		})
+		return nil
+	}
+	flush := func() {
+		for _, g := range groups {
+			if g.Count < 2 {
+				continue
+			}
+			result.DuplicatedCount += g.Count
+			result.WastedSize += g.WastedSize
+			topArrays.add(g.DuplicateArray)
+		}
+		groups = groups[:0]
+	}
+	if err := scanCollisions(sorted, add, flush); err != nil {
+		return DuplicateArrays{}, err
+	}
+
+	result.Items = topArrays.groups
+	sort.Slice(result.Items, func(i, j int) bool {
+		if result.Items[i].WastedSize != result.Items[j].WastedSize {
+			return result.Items[i].WastedSize > result.Items[j].WastedSize
+		}
+		return result.Items[i].SampleId < result.Items[j].SampleId
+	})
+	return result, nil
+}
+
+// arrayKey is the element type followed by the
+// length of the array, it's hashed before payload.
+func arrayKey(header core.HprofGcPrimArrayDumpHeader) []byte {
+	key := make([]byte, 5)
+	key[0] = byte(header.ElementType)
+	binary.BigEndian.PutUint32(key[1:], header.NumberOfElements)
+	return key
+}
+
+type arrayGroup struct {
+	DuplicateArray
+	key     []byte
+	payload []byte
+}
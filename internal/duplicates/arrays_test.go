@@ -0,0 +1,51 @@
+package duplicates
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/danielleontiev/neojhat/internal/core"
+	td "github.com/danielleontiev/neojhat/internal/testdump"
+)
+
+// byte[3] takes 16+3 = 19 bytes aligned to 24, empty char[] - 16 bytes.
+var arraysSample = td.Dump(
+	nil,
+	td.PrimArrayDump(300, core.Byte, 3, []byte("abc")),
+	td.PrimArrayDump(301, core.Byte, 3, []byte("abc")),
+	td.PrimArrayDump(302, core.Byte, 3, []byte("abd")),
+	td.PrimArrayDump(304, core.Byte, 4, []byte("abcd")),
+	td.PrimArrayDump(305, core.Int, 1, []byte("abcd")), // same payload, other type
+	td.PrimArrayDump(306, core.Byte, 3, []byte("abc")),
+	td.PrimArrayDump(307, core.Char, 0, nil),
+	td.PrimArrayDump(308, core.Char, 0, nil),
+)
+
+func TestGetDuplicateArrays(t *testing.T) {
+	parsedAccessor, err := td.NewParsedAccessor(arraysSample, false)
+	if err != nil {
+		t.Fatalf("cannot parse sample: %v", err)
+	}
+	abc := DuplicateArray{ElementType: core.Byte, Length: 3, Count: 3, WastedSize: 48, SampleId: 300}
+	empty := DuplicateArray{ElementType: core.Char, Length: 0, Count: 2, WastedSize: 16, SampleId: 307}
+	tests := []struct {
+		name string
+		top  int
+		want []DuplicateArray
+	}{
+		{name: "all groups", top: 10, want: []DuplicateArray{abc, empty}},
+		{name: "top group only", top: 1, want: []DuplicateArray{abc}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetDuplicateArrays(parsedAccessor, tt.top, newRun)
+			if err != nil {
+				t.Fatalf("GetDuplicateArrays() error = %v", err)
+			}
+			want := DuplicateArrays{Items: tt.want, TotalCount: 8, DuplicatedCount: 5, WastedSize: 64}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("GetDuplicateArrays() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
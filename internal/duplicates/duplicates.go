@@ -0,0 +1,102 @@
+// duplicates finds objects having equal content which could be shared
+// instead. Dumps can have hundreds of millions of strings, so the values
+// are never kept in memory all together. Every value is hashed and the
+// pairs of hash and identifier are sorted on disk. Objects with equal
+// hashes are then neighbours and only they are read again to confirm
+// they are equal indeed, so the memory is bounded by the sort batch and
+// the biggest group of equal hashes.
+package duplicates
+
+import (
+	"container/heap"
+	"fmt"
+	"hash/fnv"
+
+	"github.com/danielleontiev/neojhat/internal/core"
+	"github.com/danielleontiev/neojhat/internal/storage"
+)
+
+// sortHashes collects the hashes put by scan and
+// returns them sorted by hash and then by identifier.
+func sortHashes(newRun func() (storage.RunVolume, error), scan func(put func(hash uint64, objectId core.Identifier) error) error) (*storage.IndexRecordsReadStorage, error) {
+	hashes := storage.NewTempIndex(newRun)
+	writer := storage.NewSortingIndexWriteStorage(hashes, newRun, storage.DefaultBatchSize)
+	err := scan(func(hash uint64, objectId core.Identifier) error {
+		return writer.Put(hash, uint64(objectId))
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("cannot sort hashes: %w", err)
+	}
+	return hashes.Reader()
+}
+
+// scanCollisions calls add for every object having the same hash as
+// another one and flush after every run of equal hashes. Objects with
+// unique hashes are skipped without reading them again.
+func scanCollisions(sorted *storage.IndexRecordsReadStorage, add func(objectId core.Identifier) error, flush func()) error {
+	var currentHash uint64
+	var first core.Identifier
+	var runLength int
+	err := sorted.Scan(func(hash, id uint64) error {
+		if runLength > 0 && hash == currentHash {
+			if runLength == 1 {
+				if err := add(first); err != nil {
+					return err
+				}
+			}
+			runLength++
+			return add(core.Identifier(id))
+		}
+		flush()
+		currentHash, first, runLength = hash, core.Identifier(id), 1
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	flush()
+	return nil
+}
+
+func hashOf(parts ...[]byte) uint64 {
+	hash := fnv.New64a()
+	for _, part := range parts {
+		hash.Write(part)
+	}
+	return hash.Sum64()
+}
+
+// topGroups implements heap.Interface keeping at most n groups with
+// the biggest wasted size. The group with the smallest wasted size
+// is on top to be dropped first.
+type topGroups[T any] struct {
+	n      int
+	groups []T
+	wasted func(T) int
+}
+
+func (g *topGroups[T]) add(group T) {
+	heap.Push(g, group)
+	if g.Len() > g.n {
+		heap.Pop(g)
+	}
+}
+
+func (g topGroups[T]) Len() int { return len(g.groups) }
+
+func (g topGroups[T]) Less(i, j int) bool {
+	return g.wasted(g.groups[i]) < g.wasted(g.groups[j])
+}
+
+func (g topGroups[T]) Swap(i, j int) { g.groups[i], g.groups[j] = g.groups[j], g.groups[i] }
+
+func (g *topGroups[T]) Push(x any) { g.groups = append(g.groups, x.(T)) }
+
+func (g *topGroups[T]) Pop() any {
+	last := g.groups[len(g.groups)-1]
+	g.groups = g.groups[:len(g.groups)-1]
+	return last
+}
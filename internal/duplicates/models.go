@@ -0,0 +1,44 @@
+package duplicates
+
+import "github.com/danielleontiev/neojhat/internal/core"
+
+// DuplicateString is the group of java.lang.String instances with the
+// same value. WastedSize is the memory that would be freed if all of
+// them were replaced by one instance. SampleReferrer is the class of
+// the object referencing SampleId, empty if nothing references it.
+type DuplicateString struct {
+	Value          string          `json:"value"`
+	Count          int             `json:"count"`
+	WastedSize     int             `json:"wastedSize"`
+	SampleId       core.Identifier `json:"sampleId"`
+	SampleReferrer string          `json:"sampleReferrer,omitempty"`
+}
+
+// DuplicateStrings has the top groups ordered by wasted size. Totals
+// are counted over all the groups, not only the top ones.
+type DuplicateStrings struct {
+	Items           []DuplicateString `json:"items"`
+	TotalCount      int               `json:"totalCount"`
+	DuplicatedCount int               `json:"duplicatedCount"`
+	WastedSize      int               `json:"wastedSize"`
+}
+
+// DuplicateArray is the group of primitive arrays of the same type
+// and length with equal content. WastedSize is the size of all the
+// arrays of the group but one.
+type DuplicateArray struct {
+	ElementType core.JavaType   `json:"elementType"`
+	Length      int             `json:"length"`
+	Count       int             `json:"count"`
+	WastedSize  int             `json:"wastedSize"`
+	SampleId    core.Identifier `json:"sampleId"`
+}
+
+// DuplicateArrays has the top groups ordered by wasted size. Totals
+// are counted over all the groups, not only the top ones.
+type DuplicateArrays struct {
+	Items           []DuplicateArray `json:"items"`
+	TotalCount      int              `json:"totalCount"`
+	DuplicatedCount int              `json:"duplicatedCount"`
+	WastedSize      int              `json:"wastedSize"`
+}
@@ -0,0 +1,152 @@
+package duplicates
+
+import (
+	"sort"
+
+	"github.com/danielleontiev/neojhat/internal/core"
+	"github.com/danielleontiev/neojhat/internal/dump"
+	"github.com/danielleontiev/neojhat/internal/java"
+	"github.com/danielleontiev/neojhat/internal/storage"
+)
+
+// GetDuplicateStrings returns top groups of java.lang.String instances
+// with equal values. newRun provides temporary storage for sorting.
+// Referrers index is required to find the sample referrers.
+func GetDuplicateStrings(parsedAccessor *dump.ParsedAccessor, top int, newRun func() (storage.RunVolume, error)) (DuplicateStrings, error) {
+	h := java.NewHeap(parsedAccessor)
+	stringClasses := make(map[core.Identifier]bool)
+	for _, loadClass := range parsedAccessor.ListHprofLoadClass() {
+		class, err := h.ParseClass(loadClass.ClassObjectId)
+		if err != nil {
+			// loaded classes without class dump have no instances
+			continue
+		}
+		if class.Name == "java/lang/String" {
+			stringClasses[loadClass.ClassObjectId] = true
+		}
+	}
+
+	var result DuplicateStrings
+	sorted, err := sortHashes(newRun, func(put func(uint64, core.Identifier) error) error {
+		accept := func(classId core.Identifier) bool { return stringClasses[classId] }
+		return h.ScanInstances(accept, func(objectId core.Identifier, object java.NormalObject) error {
+			value, err := h.ParseJavaString(objectValue(objectId))
+			if err != nil {
+				return err
+			}
+			result.TotalCount++
+			return put(hashOf([]byte(value)), objectId)
+		})
+	})
+	if err != nil {
+		return DuplicateStrings{}, err
+	}
+	defer sorted.Close()
+
+	size, err := h.SizeInfo("")
+	if err != nil {
+		return DuplicateStrings{}, err
+	}
+	topStrings := &topGroups[DuplicateString]{n: top, wasted: func(s DuplicateString) int { return s.WastedSize }}
+	groups := make(map[string]*stringGroup)
+	add := func(objectId core.Identifier) error {
+		value, arrayId, stringSize, arraySize, err := readString(h, parsedAccessor, size, objectId)
+		if err != nil {
+			return err
+		}
+		g, ok := groups[value]
+		if !ok {
+			groups[value] = &stringGroup{
+				DuplicateString: DuplicateString{Value: value, Count: 1, SampleId: objectId},
+				arrays:          map[core.Identifier]bool{arrayId: true},
+			}
+			return nil
+		}
+		g.Count++
+		g.WastedSize += stringSize
+		// strings deduplicated by GC share the array already
+		if !g.arrays[arrayId] {
+			g.arrays[arrayId] = true
+			g.WastedSize += arraySize
+		}
+		return nil
+	}
+	flush := func() {
+		for _, g := range groups {
+			if g.Count < 2 {
+				continue
+			}
+			result.DuplicatedCount += g.Count
+			result.WastedSize += g.WastedSize
+			topStrings.add(g.DuplicateString)
+		}
+		clear(groups)
+	}
+	if err := scanCollisions(sorted, add, flush); err != nil {
+		return DuplicateStrings{}, err
+	}
+
+	for _, s := range topStrings.groups {
+		if s.SampleReferrer, err = sampleReferrer(h, parsedAccessor, s.SampleId); err != nil {
+			return DuplicateStrings{}, err
+		}
+		result.Items = append(result.Items, s)
+	}
+	sort.Slice(result.Items, func(i, j int) bool {
+		if result.Items[i].WastedSize != result.Items[j].WastedSize {
+			return result.Items[i].WastedSize > result.Items[j].WastedSize
+		}
+		return result.Items[i].Value < result.Items[j].Value
+	})
+	return result, nil
+}
+
+// readString reads the value of the string together with the shallow
+// sizes of the string and of its value array.
+func readString(h *java.Heap, parsedAccessor *dump.ParsedAccessor, size *core.SizeInfo, objectId core.Identifier) (value string, arrayId core.Identifier, stringSize, arraySize int, err error) {
+	if value, err = h.ParseJavaString(objectValue(objectId)); err != nil {
+		return
+	}
+	instance, err := parsedAccessor.GetHprofGcInstanceDump(objectId)
+	if err != nil {
+		return
+	}
+	if stringSize, err = h.InstanceSize(instance.ClassObjectId, size); err != nil {
+		return
+	}
+	object, err := h.ParseNormalObject(objectId)
+	if err != nil {
+		return
+	}
+	field, err := object.GetFieldValueByName("value")
+	if err != nil {
+		return
+	}
+	if arrayId, err = field.Value.ToObject(); err != nil || arrayId == 0 {
+		return
+	}
+	array, err := parsedAccessor.GetHprofGcPrimArray(arrayId)
+	if err != nil {
+		return
+	}
+	arraySize = size.OfArray(array.ElementType, int(array.NumberOfElements))
+	return
+}
+
+// sampleReferrer returns the class of any object referencing the given one.
+func sampleReferrer(h *java.Heap, parsedAccessor *dump.ParsedAccessor, objectId core.Identifier) (string, error) {
+	referrers, err := parsedAccessor.GetReferrers(objectId)
+	if err != nil || len(referrers) == 0 {
+		return "", err
+	}
+	return h.ObjectTypeName(referrers[0])
+}
+
+func objectValue(id core.Identifier) core.JavaValue {
+	return core.JavaValue{Type: core.Object, Value: id}
+}
+
+type stringGroup struct {
+	DuplicateString
+	arrays map[core.Identifier]bool
+}
@@ -0,0 +1,70 @@
+package duplicates
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/danielleontiev/neojhat/internal/core"
+	"github.com/danielleontiev/neojhat/internal/storage"
+	td "github.com/danielleontiev/neojhat/internal/testdump"
+)
+
+// Strings take 12+4+1 = 17 bytes aligned to 24, their byte[3] arrays - 16+3 = 19 bytes aligned to 24.
+var stringsSample = td.Dump(
+	[][]byte{
+		td.Utf8(1, "java/lang/String"),
+		td.Utf8(2, "value"),
+		td.Utf8(3, "coder"),
+		td.Utf8(4, "Holder"),
+		td.Utf8(5, "s"),
+		td.LoadClass(1, 100, 1),
+		td.LoadClass(2, 101, 4),
+	},
+	td.ClassDump(100, 0, 16, nil, []td.Field{{NameId: 2, Type: core.Object}, {NameId: 3, Type: core.Byte}}),
+	td.ClassDump(101, 0, 8, nil, []td.Field{{NameId: 5, Type: core.Object}}),
+	td.InstanceDump(200, 100, td.Id(300), []byte{0}),
+	td.InstanceDump(201, 100, td.Id(300), []byte{0}), // shares the array with 200
+	td.InstanceDump(202, 100, td.Id(304), []byte{0}),
+	td.InstanceDump(203, 100, td.Id(301), []byte{0}),
+	td.InstanceDump(204, 100, td.Id(302), []byte{0}),
+	td.InstanceDump(205, 100, td.Id(303), []byte{0}),
+	td.InstanceDump(210, 101, td.Id(200)),
+	td.PrimArrayDump(300, core.Byte, 3, []byte("foo")),
+	td.PrimArrayDump(301, core.Byte, 3, []byte("bar")),
+	td.PrimArrayDump(302, core.Byte, 3, []byte("baz")),
+	td.PrimArrayDump(303, core.Byte, 3, []byte("baz")),
+	td.PrimArrayDump(304, core.Byte, 3, []byte("foo")),
+)
+
+func newRun() (storage.RunVolume, error) {
+	return storage.NewRamRunVolume(), nil
+}
+
+func TestGetDuplicateStrings(t *testing.T) {
+	parsedAccessor, err := td.NewParsedAccessor(stringsSample, true)
+	if err != nil {
+		t.Fatalf("cannot parse sample: %v", err)
+	}
+	foo := DuplicateString{Value: "foo", Count: 3, WastedSize: 24 + 24 + 24, SampleId: 200, SampleReferrer: "Holder"}
+	baz := DuplicateString{Value: "baz", Count: 2, WastedSize: 24 + 24, SampleId: 204}
+	tests := []struct {
+		name string
+		top  int
+		want []DuplicateString
+	}{
+		{name: "all groups", top: 10, want: []DuplicateString{foo, baz}},
+		{name: "top group only", top: 1, want: []DuplicateString{foo}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetDuplicateStrings(parsedAccessor, tt.top, newRun)
+			if err != nil {
+				t.Fatalf("GetDuplicateStrings() error = %v", err)
+			}
+			want := DuplicateStrings{Items: tt.want, TotalCount: 6, DuplicatedCount: 5, WastedSize: 72 + 48}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("GetDuplicateStrings() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
@@ -96,3 +96,7 @@ func Signature(signature string) (string, string) {
 	}
 	return args, result[len(result)-1]
 }
+
+func ObjectId(id uint64) string {
+	return fmt.Sprintf("0x%x", id)
+}
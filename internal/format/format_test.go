@@ -109,3 +109,21 @@ func TestSize(t *testing.T) {
 		})
 	}
 }
+
+func TestObjectId(t *testing.T) {
+	tests := []struct {
+		id   uint64
+		want string
+	}{
+		{id: 0, want: "0x0"},
+		{id: 255, want: "0xff"},
+		{id: 0x7ff00012a8, want: "0x7ff00012a8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := format.ObjectId(tt.id); got != tt.want {
+				t.Errorf("ObjectId() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,517 @@
+package java
+
+import (
+	"fmt"
+
+	"github.com/danielleontiev/neojhat/internal/core"
+)
+
+// collectionDecoder reads the elements of the collection which class
+// (or superclass) is known. It returns false if the object has the
+// layout of another JDK version, the superclass is tried then.
+type collectionDecoder func(h *Heap, object NormalObject) (CollectionKind, []Entry, bool, error)
+
+func decoderOf(className string) (collectionDecoder, bool) {
+	switch className {
+	case "java/util/HashMap", "java/util/Hashtable":
+		return hashMapDecoder("value"), true
+	case "java/util/concurrent/ConcurrentHashMap":
+		return hashMapDecoder("val"), true
+	case "java/util/Properties":
+		return propertiesDecoder, true
+	case "java/util/TreeMap":
+		return treeMapDecoder, true
+	case "java/util/concurrent/ConcurrentSkipListMap":
+		return skipListMapDecoder, true
+	case "java/util/HashSet":
+		return setDecoder("map"), true
+	case "java/util/TreeSet":
+		return setDecoder("m"), true
+	case "java/util/ArrayList":
+		return arrayListDecoder("elementData", "size"), true
+	case "java/util/Vector":
+		return arrayListDecoder("elementData", "elementCount"), true
+	case "java/util/concurrent/CopyOnWriteArrayList":
+		return arrayListDecoder("array", ""), true
+	case "java/util/LinkedList":
+		return linkedListDecoder, true
+	case "java/util/ArrayDeque":
+		return arrayDequeDecoder, true
+	}
+	return nil, false
+}
+
+// ParseCollection reads the elements of standard JDK collection or map:
+// HashMap, LinkedHashMap, Hashtable, Properties, ConcurrentHashMap,
+// TreeMap, ConcurrentSkipListMap, HashSet, LinkedHashSet, TreeSet,
+// ArrayList, Vector, CopyOnWriteArrayList, LinkedList, ArrayDeque and
+// their subclasses. Both JDK 8 and JDK 11+ layouts are supported.
+// Elements of hash maps are read bin by bin following the chains of
+// nodes, tree bins of ConcurrentHashMap are read from the list of
+// their nodes.
+func (h *Heap) ParseCollection(objectId core.Identifier) (Collection, error) {
+	object, err := h.ParseNormalObject(objectId)
+	if err != nil {
+		return Collection{}, fmt.Errorf("error parsing collection object: %w", err)
+	}
+	for class := &object.Class; class != nil; class = class.Superclass {
+		decoder, ok := decoderOf(class.Name)
+		if !ok {
+			continue
+		}
+		kind, entries, ok, err := decoder(h, object)
+		if err != nil {
+			return Collection{}, fmt.Errorf("error reading %s: %w", class.Name, err)
+		}
+		if ok {
+			return Collection{ClassName: object.Class.Name, Kind: kind, Entries: entries}, nil
+		}
+	}
+	return Collection{}, fmt.Errorf("%s is not a supported collection", object.Class.Name)
+}
+
+func hashMapDecoder(valueField string) collectionDecoder {
+	return func(h *Heap, object NormalObject) (CollectionKind, []Entry, bool, error) {
+		table, err := objectField(object, "table")
+		if err != nil {
+			return 0, nil, false, nil
+		}
+		entries, err := h.hashTableEntries(table, valueField)
+		return MapCollection, entries, true, err
+	}
+}
+
+// hashTableEntries reads all the nodes of hash table. Bins are the chains
+// of nodes linked with `next`, treeified bins of HashMap keep `next` too.
+// TreeBin of ConcurrentHashMap keeps the chain in `first`. The dump may
+// be written in the middle of resize of ConcurrentHashMap: the bins that
+// are moved already have ForwardingNode to `nextTable` where the entries
+// are now, ReservationNode holds the bin being computed and has no entry.
+func (h *Heap) hashTableEntries(table core.Identifier, valueField string) ([]Entry, error) {
+	var entries []Entry
+	err := h.collectHashTableEntries(table, valueField, make(map[core.Identifier]bool), &entries)
+	return entries, err
+}
+
+// collectHashTableEntries appends the entries of the table to entries,
+// seen has the nodes and the tables read already, so the next table
+// referenced by many forwarding nodes is read once.
+func (h *Heap) collectHashTableEntries(table core.Identifier, valueField string, seen map[core.Identifier]bool, entries *[]Entry) error {
+	if table == 0 || seen[table] {
+		return nil
+	}
+	seen[table] = true
+	bins, err := h.ParseObjectArrayFull(table)
+	if err != nil {
+		return fmt.Errorf("error parsing table: %w", err)
+	}
+	for _, bin := range bins.Elements {
+		for nodeId := bin; nodeId != 0 && !seen[nodeId]; {
+			seen[nodeId] = true
+			node, err := h.ParseNormalObject(nodeId)
+			if err != nil {
+				return fmt.Errorf("error parsing node %v: %w", nodeId, err)
+			}
+			if isReservationNode(node) {
+				break
+			}
+			if isForwardingNode(node) {
+				nextTable, err := objectField(node, "nextTable")
+				if err != nil {
+					return fmt.Errorf("error reading nextTable of node %v: %w", nodeId, err)
+				}
+				if err := h.collectHashTableEntries(nextTable, valueField, seen, entries); err != nil {
+					return err
+				}
+				break
+			}
+			if first, err := objectField(node, "first"); err == nil && isTreeBin(node) {
+				nodeId = first
+				continue
+			}
+			key, err := node.GetFieldValueByName("key")
+			if err != nil {
+				return fmt.Errorf("error reading key of node %v: %w", nodeId, err)
+			}
+			value, err := node.GetFieldValueByName(valueField)
+			if err != nil {
+				return fmt.Errorf("error reading %s of node %v: %w", valueField, nodeId, err)
+			}
+			*entries = append(*entries, Entry{Key: key.Value, Value: value.Value})
+			next, err := objectField(node, "next")
+			if err != nil {
+				return fmt.Errorf("error reading next of node %v: %w", nodeId, err)
+			}
+			nodeId = next
+		}
+	}
+	return nil
+}
+
+func isTreeBin(node NormalObject) bool {
+	return node.Class.Name == "java/util/concurrent/ConcurrentHashMap$TreeBin"
+}
+
+func isForwardingNode(node NormalObject) bool {
+	return node.Class.Name == "java/util/concurrent/ConcurrentHashMap$ForwardingNode"
+}
+
+func isReservationNode(node NormalObject) bool {
+	return node.Class.Name == "java/util/concurrent/ConcurrentHashMap$ReservationNode"
+}
+
+// propertiesDecoder reads java.util.Properties of JDK 9+ that keep the
+// entries in ConcurrentHashMap `map`. Properties of JDK 8 extend
+// Hashtable, so the superclass decoder reads them.
+func propertiesDecoder(h *Heap, object NormalObject) (CollectionKind, []Entry, bool, error) {
+	m, err := objectField(object, "map")
+	if err != nil {
+		return 0, nil, false, nil
+	}
+	inner, err := h.ParseCollection(m)
+	if err != nil {
+		return 0, nil, false, err
+	}
+	return MapCollection, inner.Entries, true, nil
+}
+
+// treeMapDecoder traverses red-black tree in order of the keys.
+func treeMapDecoder(h *Heap, object NormalObject) (CollectionKind, []Entry, bool, error) {
+	root, err := objectField(object, "root")
+	if err != nil {
+		return 0, nil, false, nil
+	}
+	var entries []Entry
+	var stack []NormalObject
+	seen := make(map[core.Identifier]bool)
+	pushLeft := func(nodeId core.Identifier) error {
+		for nodeId != 0 && !seen[nodeId] {
+			seen[nodeId] = true
+			node, err := h.ParseNormalObject(nodeId)
+			if err != nil {
+				return fmt.Errorf("error parsing node %v: %w", nodeId, err)
+			}
+			stack = append(stack, node)
+			if nodeId, err = objectField(node, "left"); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := pushLeft(root); err != nil {
+		return 0, nil, false, err
+	}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		key, err := node.GetFieldValueByName("key")
+		if err != nil {
+			return 0, nil, false, err
+		}
+		value, err := node.GetFieldValueByName("value")
+		if err != nil {
+			return 0, nil, false, err
+		}
+		entries = append(entries, Entry{Key: key.Value, Value: value.Value})
+		right, err := objectField(node, "right")
+		if err != nil {
+			return 0, nil, false, err
+		}
+		if err := pushLeft(right); err != nil {
+			return 0, nil, false, err
+		}
+	}
+	return MapCollection, entries, true, nil
+}
+
+// skipListMapDecoder reads the bottom level of ConcurrentSkipListMap,
+// it's the ordered list of all the nodes. Deleted nodes have no value.
+func skipListMapDecoder(h *Heap, object NormalObject) (CollectionKind, []Entry, bool, error) {
+	head, err := objectField(object, "head")
+	if err != nil {
+		return 0, nil, false, nil
+	}
+	if head == 0 {
+		return MapCollection, nil, true, nil
+	}
+	index, err := h.ParseNormalObject(head)
+	if err != nil {
+		return 0, nil, false, fmt.Errorf("error parsing head: %w", err)
+	}
+	nodeId, err := objectField(index, "node")
+	if err != nil {
+		return 0, nil, false, err
+	}
+	var entries []Entry
+	seen := make(map[core.Identifier]bool)
+	for nodeId != 0 && !seen[nodeId] {
+		seen[nodeId] = true
+		node, err := h.ParseNormalObject(nodeId)
+		if err != nil {
+			return 0, nil, false, fmt.Errorf("error parsing node %v: %w", nodeId, err)
+		}
+		key, err := node.GetFieldValueByName("key")
+		if err != nil {
+			return 0, nil, false, err
+		}
+		value, err := node.GetFieldValueByName("val")
+		if err != nil {
+			// JDK 8 names the field `value`
+			if value, err = node.GetFieldValueByName("value"); err != nil {
+				return 0, nil, false, err
+			}
+		}
+		if !isNull(key.Value) && !isNull(value.Value) {
+			entries = append(entries, Entry{Key: key.Value, Value: value.Value})
+		}
+		if nodeId, err = objectField(node, "next"); err != nil {
+			return 0, nil, false, err
+		}
+	}
+	return MapCollection, entries, true, nil
+}
+
+// setDecoder reads the keys of the map backing the set.
+func setDecoder(mapField string) collectionDecoder {
+	return func(h *Heap, object NormalObject) (CollectionKind, []Entry, bool, error) {
+		m, err := objectField(object, mapField)
+		if err != nil {
+			return 0, nil, false, nil
+		}
+		var entries []Entry
+		if m != 0 {
+			inner, err := h.ParseCollection(m)
+			if err != nil {
+				return 0, nil, false, err
+			}
+			for _, entry := range inner.Entries {
+				entries = append(entries, Entry{Value: entry.Key})
+			}
+		}
+		return SetCollection, entries, true, nil
+	}
+}
+
+// arrayListDecoder reads first `size` elements of the array,
+// all the elements are read if sizeField is empty.
+func arrayListDecoder(arrayField, sizeField string) collectionDecoder {
+	return func(h *Heap, object NormalObject) (CollectionKind, []Entry, bool, error) {
+		array, err := objectField(object, arrayField)
+		if err != nil {
+			return 0, nil, false, nil
+		}
+		if array == 0 {
+			return ListCollection, nil, true, nil
+		}
+		elements, err := h.ParseObjectArrayFull(array)
+		if err != nil {
+			return 0, nil, false, fmt.Errorf("error parsing %s: %w", arrayField, err)
+		}
+		size := len(elements.Elements)
+		if sizeField != "" {
+			if size, err = intField(object, sizeField); err != nil {
+				return 0, nil, false, err
+			}
+			size = min(max(size, 0), len(elements.Elements))
+		}
+		entries := make([]Entry, 0, size)
+		for _, element := range elements.Elements[:size] {
+			entries = append(entries, Entry{Value: objectValue(element)})
+		}
+		return ListCollection, entries, true, nil
+	}
+}
+
+func linkedListDecoder(h *Heap, object NormalObject) (CollectionKind, []Entry, bool, error) {
+	nodeId, err := objectField(object, "first")
+	if err != nil {
+		// JDK 6 keeps the circular list in `header`
+		return 0, nil, false, nil
+	}
+	var entries []Entry
+	seen := make(map[core.Identifier]bool)
+	for nodeId != 0 && !seen[nodeId] {
+		seen[nodeId] = true
+		node, err := h.ParseNormalObject(nodeId)
+		if err != nil {
+			return 0, nil, false, fmt.Errorf("error parsing node %v: %w", nodeId, err)
+		}
+		item, err := node.GetFieldValueByName("item")
+		if err != nil {
+			return 0, nil, false, err
+		}
+		entries = append(entries, Entry{Value: item.Value})
+		if nodeId, err = objectField(node, "next"); err != nil {
+			return 0, nil, false, err
+		}
+	}
+	return ListCollection, entries, true, nil
+}
+
+// arrayDequeDecoder reads the circular buffer from `head` to `tail`.
+func arrayDequeDecoder(h *Heap, object NormalObject) (CollectionKind, []Entry, bool, error) {
+	array, err := objectField(object, "elements")
+	if err != nil {
+		return 0, nil, false, nil
+	}
+	head, err := intField(object, "head")
+	if err != nil {
+		return 0, nil, false, err
+	}
+	tail, err := intField(object, "tail")
+	if err != nil {
+		return 0, nil, false, err
+	}
+	if array == 0 {
+		return ListCollection, nil, true, nil
+	}
+	elements, err := h.ParseObjectArrayFull(array)
+	if err != nil {
+		return 0, nil, false, fmt.Errorf("error parsing elements: %w", err)
+	}
+	n := len(elements.Elements)
+	var entries []Entry
+	if n == 0 || head < 0 || head >= n || tail < 0 || tail >= n {
+		return ListCollection, entries, true, nil
+	}
+	for i := head; i != tail; i = (i + 1) % n {
+		entries = append(entries, Entry{Value: objectValue(elements.Elements[i])})
+	}
+	return ListCollection, entries, true, nil
+}
+
+// CollectionFill reads the number of elements of the collection and the
+// length of the array backing it without reading the elements. HashMap,
+// HashSet, ConcurrentHashMap, ArrayList, ArrayDeque and their subclasses
+// are supported.
+func (h *Heap) CollectionFill(object NormalObject) (Fill, error) {
+	for class := &object.Class; class != nil; class = class.Superclass {
+		switch class.Name {
+		case "java/util/HashMap":
+			return h.arrayFill(object, "table", "size")
+		case "java/util/ArrayList":
+			return h.arrayFill(object, "elementData", "size")
+		case "java/util/HashSet":
+			m, err := objectField(object, "map")
+			if err != nil || m == 0 {
+				return Fill{}, err
+			}
+			inner, err := h.ParseNormalObject(m)
+			if err != nil {
+				return Fill{}, fmt.Errorf("error parsing map of HashSet: %w", err)
+			}
+			return h.CollectionFill(inner)
+		case "java/util/concurrent/ConcurrentHashMap":
+			fill, err := h.arrayFill(object, "table", "")
+			if err != nil {
+				return Fill{}, err
+			}
+			fill.Size, err = h.concurrentHashMapSize(object)
+			return fill, err
+		case "java/util/ArrayDeque":
+			fill, err := h.arrayFill(object, "elements", "")
+			if err != nil || fill.Capacity == 0 {
+				return fill, err
+			}
+			head, err := intField(object, "head")
+			if err != nil {
+				return Fill{}, err
+			}
+			tail, err := intField(object, "tail")
+			if err != nil {
+				return Fill{}, err
+			}
+			fill.Size = ((tail-head)%fill.Capacity + fill.Capacity) % fill.Capacity
+			return fill, nil
+		}
+	}
+	return Fill{}, fmt.Errorf("%s is not a supported collection", object.Class.Name)
+}
+
+// arrayFill reads the length of the array from its header only,
+// the size is not read if sizeField is empty.
+func (h *Heap) arrayFill(object NormalObject, arrayField, sizeField string) (Fill, error) {
+	var fill Fill
+	array, err := objectField(object, arrayField)
+	if err != nil {
+		return Fill{}, err
+	}
+	if array != 0 {
+		header, err := h.parsedAccessor.GetHprofGcObjArray(array)
+		if err != nil {
+			return Fill{}, fmt.Errorf("error parsing %s: %w", arrayField, err)
+		}
+		fill.Capacity = int(header.NumberOfElements)
+	}
+	if sizeField != "" {
+		if fill.Size, err = intField(object, sizeField); err != nil {
+			return Fill{}, err
+		}
+	}
+	return fill, nil
+}
+
+// concurrentHashMapSize sums `baseCount` and the values of `counterCells`
+// the same way ConcurrentHashMap.size() does.
+func (h *Heap) concurrentHashMapSize(object NormalObject) (int, error) {
+	baseCount, err := object.GetFieldValueByName("baseCount")
+	if err != nil {
+		return 0, err
+	}
+	size, err := baseCount.Value.ToLong()
+	if err != nil {
+		return 0, err
+	}
+	counterCells, err := objectField(object, "counterCells")
+	if err != nil || counterCells == 0 {
+		return size, err
+	}
+	cells, err := h.ParseObjectArrayFull(counterCells)
+	if err != nil {
+		return 0, fmt.Errorf("error parsing counterCells: %w", err)
+	}
+	for _, cellId := range cells.Elements {
+		if cellId == 0 {
+			continue
+		}
+		cell, err := h.ParseNormalObject(cellId)
+		if err != nil {
+			return 0, fmt.Errorf("error parsing counter cell %v: %w", cellId, err)
+		}
+		value, err := cell.GetFieldValueByName("value")
+		if err != nil {
+			return 0, err
+		}
+		count, err := value.Value.ToLong()
+		if err != nil {
+			return 0, err
+		}
+		size += count
+	}
+	return size, nil
+}
+
+func objectField(object NormalObject, name string) (core.Identifier, error) {
+	field, err := object.GetFieldValueByName(name)
+	if err != nil {
+		return 0, err
+	}
+	return field.Value.ToObject()
+}
+
+func intField(object NormalObject, name string) (int, error) {
+	field, err := object.GetFieldValueByName(name)
+	if err != nil {
+		return 0, err
+	}
+	return field.Value.ToInt()
+}
+
+func objectValue(id core.Identifier) core.JavaValue {
+	return core.JavaValue{Type: core.Object, Value: id}
+}
+
+func isNull(value core.JavaValue) bool {
+	id, ok := value.Value.(core.Identifier)
+	return value.Type == core.Object && ok && id == 0
+}
@@ -0,0 +1,259 @@
+package java
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/danielleontiev/neojhat/internal/core"
+	td "github.com/danielleontiev/neojhat/internal/testdump"
+)
+
+func objField(nameId uint64) td.Field   { return td.Field{NameId: nameId, Type: core.Object} }
+func intFieldOf(nameId uint64) td.Field { return td.Field{NameId: nameId, Type: core.Int} }
+func longField(nameId uint64) td.Field  { return td.Field{NameId: nameId, Type: core.Long} }
+func long(v uint32) []byte              { return td.Concat(td.U4(0), td.U4(v)) }
+
+// Integer objects 901-908 hold values 1-8.
+var collectionsSample = td.Dump(
+	[][]byte{
+		td.Utf8(1, "java/lang/Object"),
+		td.Utf8(2, "java/util/HashMap"),
+		td.Utf8(3, "java/util/HashMap$Node"),
+		td.Utf8(4, "java/lang/Integer"),
+		td.Utf8(5, "table"),
+		td.Utf8(6, "key"),
+		td.Utf8(7, "value"),
+		td.Utf8(8, "next"),
+		td.Utf8(9, "val"),
+		td.Utf8(10, "java/util/concurrent/ConcurrentHashMap"),
+		td.Utf8(11, "java/util/concurrent/ConcurrentHashMap$Node"),
+		td.Utf8(12, "java/util/concurrent/ConcurrentHashMap$TreeBin"),
+		td.Utf8(13, "first"),
+		td.Utf8(14, "java/util/TreeMap"),
+		td.Utf8(15, "java/util/TreeMap$Entry"),
+		td.Utf8(16, "root"),
+		td.Utf8(17, "left"),
+		td.Utf8(18, "right"),
+		td.Utf8(19, "java/util/ArrayList"),
+		td.Utf8(20, "elementData"),
+		td.Utf8(21, "size"),
+		td.Utf8(22, "java/util/LinkedList"),
+		td.Utf8(23, "java/util/LinkedList$Node"),
+		td.Utf8(24, "item"),
+		td.Utf8(25, "java/util/ArrayDeque"),
+		td.Utf8(26, "elements"),
+		td.Utf8(27, "head"),
+		td.Utf8(28, "tail"),
+		td.Utf8(29, "java/util/HashSet"),
+		td.Utf8(30, "map"),
+		td.Utf8(31, "java/util/Hashtable"),
+		td.Utf8(32, "java/util/Hashtable$Entry"),
+		td.Utf8(33, "java/util/Properties"),
+		td.Utf8(34, "[Ljava/lang/Object;"),
+		td.Utf8(35, "java/util/LinkedHashMap"),
+		td.Utf8(36, "baseCount"),
+		td.Utf8(37, "counterCells"),
+		td.Utf8(38, "java/util/concurrent/ConcurrentHashMap$CounterCell"),
+		td.Utf8(39, "java/util/concurrent/ConcurrentHashMap$ForwardingNode"),
+		td.Utf8(40, "java/util/concurrent/ConcurrentHashMap$ReservationNode"),
+		td.Utf8(41, "nextTable"),
+		td.LoadClass(1, 100, 1),
+		td.LoadClass(2, 101, 2),
+		td.LoadClass(3, 102, 3),
+		td.LoadClass(4, 103, 4),
+		td.LoadClass(5, 104, 10),
+		td.LoadClass(6, 105, 11),
+		td.LoadClass(7, 106, 12),
+		td.LoadClass(8, 107, 14),
+		td.LoadClass(9, 108, 15),
+		td.LoadClass(10, 109, 19),
+		td.LoadClass(11, 110, 22),
+		td.LoadClass(12, 111, 23),
+		td.LoadClass(13, 112, 25),
+		td.LoadClass(14, 113, 29),
+		td.LoadClass(15, 114, 31),
+		td.LoadClass(16, 115, 32),
+		td.LoadClass(17, 116, 33),
+		td.LoadClass(18, 117, 35),
+		td.LoadClass(19, 118, 34),
+		td.LoadClass(20, 119, 38),
+		td.LoadClass(21, 120, 39),
+		td.LoadClass(22, 121, 40),
+	},
+	td.ClassDump(100, 0, 0, nil, nil),
+	td.ClassDump(101, 100, 12, nil, []td.Field{objField(5), intFieldOf(21)}),
+	td.ClassDump(102, 100, 24, nil, []td.Field{objField(6), objField(7), objField(8)}),
+	td.ClassDump(103, 100, 4, nil, []td.Field{intFieldOf(7)}),
+	td.ClassDump(104, 100, 24, nil, []td.Field{objField(5), longField(36), objField(37)}),
+	td.ClassDump(105, 100, 24, nil, []td.Field{objField(6), objField(9), objField(8)}),
+	td.ClassDump(106, 100, 8, nil, []td.Field{objField(13)}),
+	td.ClassDump(107, 100, 8, nil, []td.Field{objField(16)}),
+	td.ClassDump(108, 100, 32, nil, []td.Field{objField(6), objField(7), objField(17), objField(18)}),
+	td.ClassDump(109, 100, 12, nil, []td.Field{objField(20), intFieldOf(21)}),
+	td.ClassDump(110, 100, 8, nil, []td.Field{objField(13)}),
+	td.ClassDump(111, 100, 16, nil, []td.Field{objField(24), objField(8)}),
+	td.ClassDump(112, 100, 16, nil, []td.Field{objField(26), intFieldOf(27), intFieldOf(28)}),
+	td.ClassDump(113, 100, 8, nil, []td.Field{objField(30)}),
+	td.ClassDump(114, 100, 8, nil, []td.Field{objField(5)}),
+	td.ClassDump(115, 100, 24, nil, []td.Field{objField(6), objField(7), objField(8)}),
+	td.ClassDump(116, 114, 8, nil, nil),
+	td.ClassDump(117, 101, 8, nil, nil),
+	td.ClassDump(118, 100, 0, nil, nil),
+	td.ClassDump(119, 100, 8, nil, []td.Field{longField(7)}),
+	td.ClassDump(120, 105, 4, nil, []td.Field{objField(41)}),
+	td.ClassDump(121, 105, 0, nil, nil),
+	// HashMap with the chain in the second bin
+	td.InstanceDump(200, 101, td.Id(201), td.U4(2)),
+	td.ObjArrayDump(201, 118, 0, 202, 0),
+	td.InstanceDump(202, 102, td.Id(901), td.Id(902), td.Id(203)),
+	td.InstanceDump(203, 102, td.Id(903), td.Id(904), td.Id(0)),
+	// LinkedHashMap
+	td.InstanceDump(205, 117, td.Id(206), td.U4(1)),
+	td.ObjArrayDump(206, 118, 203),
+	// ConcurrentHashMap with tree bin
+	td.InstanceDump(210, 104, td.Id(211), long(1), td.Id(216)),
+	td.ObjArrayDump(211, 118, 212, 213, 0),
+	td.InstanceDump(212, 105, td.Id(901), td.Id(902), td.Id(0)),
+	td.InstanceDump(213, 106, td.Id(214)),
+	td.InstanceDump(214, 105, td.Id(903), td.Id(904), td.Id(215)),
+	td.InstanceDump(215, 105, td.Id(905), td.Id(906), td.Id(0)),
+	td.ObjArrayDump(216, 118, 217, 0),
+	td.InstanceDump(217, 119, long(2)),
+	// TreeMap
+	td.InstanceDump(220, 107, td.Id(221)),
+	td.InstanceDump(221, 108, td.Id(902), td.Id(0), td.Id(222), td.Id(223)),
+	td.InstanceDump(222, 108, td.Id(901), td.Id(0), td.Id(0), td.Id(0)),
+	td.InstanceDump(223, 108, td.Id(903), td.Id(0), td.Id(0), td.Id(0)),
+	// ArrayList
+	td.InstanceDump(230, 109, td.Id(231), td.U4(2)),
+	td.ObjArrayDump(231, 118, 901, 902, 0, 0),
+	// LinkedList
+	td.InstanceDump(240, 110, td.Id(241)),
+	td.InstanceDump(241, 111, td.Id(901), td.Id(242)),
+	td.InstanceDump(242, 111, td.Id(0), td.Id(0)),
+	// ArrayDeque wrapped around the end of the array
+	td.InstanceDump(250, 112, td.Id(251), td.U4(3), td.U4(1)),
+	td.ObjArrayDump(251, 118, 903, 0, 0, 901, 902),
+	// HashSet
+	td.InstanceDump(260, 113, td.Id(200)),
+	// Properties of JDK 8
+	td.InstanceDump(270, 116, td.Id(271)),
+	td.ObjArrayDump(271, 118, 272),
+	td.InstanceDump(272, 115, td.Id(907), td.Id(908), td.Id(0)),
+	// ConcurrentHashMap in the middle of resize, two bins moved to 285
+	td.InstanceDump(280, 104, td.Id(281), long(3), td.Id(0)),
+	td.ObjArrayDump(281, 118, 282, 283, 284, 286),
+	td.InstanceDump(282, 120, td.Id(285), td.Id(0), td.Id(0), td.Id(0)),
+	td.InstanceDump(283, 105, td.Id(905), td.Id(906), td.Id(0)),
+	td.InstanceDump(284, 121, td.Id(0), td.Id(0), td.Id(0)),
+	td.ObjArrayDump(285, 118, 287, 0, 288, 0),
+	td.InstanceDump(286, 120, td.Id(285), td.Id(0), td.Id(0), td.Id(0)),
+	td.InstanceDump(287, 105, td.Id(901), td.Id(902), td.Id(0)),
+	td.InstanceDump(288, 105, td.Id(903), td.Id(904), td.Id(0)),
+	td.InstanceDump(901, 103, td.U4(1)),
+	td.InstanceDump(902, 103, td.U4(2)),
+	td.InstanceDump(903, 103, td.U4(3)),
+	td.InstanceDump(904, 103, td.U4(4)),
+	td.InstanceDump(905, 103, td.U4(5)),
+	td.InstanceDump(906, 103, td.U4(6)),
+	td.InstanceDump(907, 103, td.U4(7)),
+	td.InstanceDump(908, 103, td.U4(8)),
+)
+
+func TestHeap_ParseCollection(t *testing.T) {
+	heap := createHeap(collectionsSample, false, t)
+	tests := []struct {
+		name     string
+		id       core.Identifier
+		wantKind CollectionKind
+		want     [][2]int // key and value, 0 is null
+		wantErr  bool
+	}{
+		{name: "HashMap", id: 200, wantKind: MapCollection, want: [][2]int{{1, 2}, {3, 4}}},
+		{name: "LinkedHashMap", id: 205, wantKind: MapCollection, want: [][2]int{{3, 4}}},
+		{name: "ConcurrentHashMap", id: 210, wantKind: MapCollection, want: [][2]int{{1, 2}, {3, 4}, {5, 6}}},
+		{name: "resizing ConcurrentHashMap", id: 280, wantKind: MapCollection, want: [][2]int{{1, 2}, {3, 4}, {5, 6}}},
+		{name: "TreeMap", id: 220, wantKind: MapCollection, want: [][2]int{{1, 0}, {2, 0}, {3, 0}}},
+		{name: "ArrayList", id: 230, wantKind: ListCollection, want: [][2]int{{0, 1}, {0, 2}}},
+		{name: "LinkedList", id: 240, wantKind: ListCollection, want: [][2]int{{0, 1}, {0, 0}}},
+		{name: "ArrayDeque", id: 250, wantKind: ListCollection, want: [][2]int{{0, 1}, {0, 2}, {0, 3}}},
+		{name: "HashSet", id: 260, wantKind: SetCollection, want: [][2]int{{0, 1}, {0, 3}}},
+		{name: "Properties", id: 270, wantKind: MapCollection, want: [][2]int{{7, 8}}},
+		{name: "not a collection", id: 901, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := heap.ParseCollection(tt.id)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCollection() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Kind != tt.wantKind {
+				t.Errorf("ParseCollection() kind = %v, want %v", got.Kind, tt.wantKind)
+			}
+			var entries [][2]int
+			for _, entry := range got.Entries {
+				entries = append(entries, [2]int{integerValue(heap, entry.Key, t), integerValue(heap, entry.Value, t)})
+			}
+			if !reflect.DeepEqual(entries, tt.want) {
+				t.Errorf("ParseCollection() entries = %v, want %v", entries, tt.want)
+			}
+		})
+	}
+}
+
+// integerValue reads java.lang.Integer, null and missing key are 0.
+func integerValue(heap *Heap, value core.JavaValue, t *testing.T) int {
+	id, ok := value.Value.(core.Identifier)
+	if !ok || id == 0 {
+		return 0
+	}
+	integer, err := heap.ParseNormalObject(id)
+	if err != nil {
+		t.Fatalf("cannot parse Integer %v: %v", id, err)
+	}
+	v, err := integer.GetFieldValueByName("value")
+	if err != nil {
+		t.Fatalf("cannot read value of Integer %v: %v", id, err)
+	}
+	i, err := v.Value.ToInt()
+	if err != nil {
+		t.Fatalf("cannot read value of Integer %v: %v", id, err)
+	}
+	return i
+}
+
+func TestHeap_CollectionFill(t *testing.T) {
+	heap := createHeap(collectionsSample, false, t)
+	tests := []struct {
+		name    string
+		id      core.Identifier
+		want    Fill
+		wantErr bool
+	}{
+		{name: "HashMap", id: 200, want: Fill{Size: 2, Capacity: 3}},
+		{name: "LinkedHashMap", id: 205, want: Fill{Size: 1, Capacity: 1}},
+		{name: "ConcurrentHashMap with counter cells", id: 210, want: Fill{Size: 3, Capacity: 3}},
+		{name: "ArrayList", id: 230, want: Fill{Size: 2, Capacity: 4}},
+		{name: "ArrayDeque", id: 250, want: Fill{Size: 3, Capacity: 5}},
+		{name: "HashSet", id: 260, want: Fill{Size: 2, Capacity: 3}},
+		{name: "TreeMap", id: 220, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			object, err := heap.ParseNormalObject(tt.id)
+			if err != nil {
+				t.Fatalf("ParseNormalObject() error = %v", err)
+			}
+			got, err := heap.CollectionFill(object)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CollectionFill() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("CollectionFill() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -14,33 +14,36 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+	"sync"
+	"unicode/utf16"
 
 	"github.com/danielleontiev/neojhat/internal/core"
 	"github.com/danielleontiev/neojhat/internal/dump"
 )
 
 // Heap contains methods for reading data
-// from the heap dump.
+// from the heap dump. It's safe for concurrent use.
 type Heap struct {
 	parsedAccessor *dump.ParsedAccessor
+	mu             sync.RWMutex // guards classes
+	classes        map[core.Identifier]Class
 }
 
 func NewHeap(parsedAccessor *dump.ParsedAccessor) *Heap {
-	return &Heap{parsedAccessor: parsedAccessor}
+	return &Heap{
+		parsedAccessor: parsedAccessor,
+		classes:        make(map[core.Identifier]Class),
+	}
 }
 
 // ParseNormalObject is used to parse NormalObject by given objectId.
 // It also triggers ObjectReader.ParseClass because NormalObject should
 // contain information about class it represents.
 func (h *Heap) ParseNormalObject(objectId core.Identifier) (NormalObject, error) {
-	instance, err := h.parsedAccessor.GetHprofGcInstanceDump(objectId)
+	instance, instanceBytes, err := h.parsedAccessor.GetHprofGcInstanceDumpWithPayload(objectId)
 	if err != nil {
 		return NormalObject{}, fmt.Errorf("error reading instance with id %v: %w", objectId, err)
 	}
-	instanceBytes, err := h.parsedAccessor.GetBytesFromCurrent(int(instance.NumberOfBytesThatFollow))
-	if err != nil {
-		return NormalObject{}, fmt.Errorf("error reading instance(id = %v) payload: %w", objectId, err)
-	}
 	class, err := h.ParseClass(instance.ClassObjectId)
 	if err != nil {
 		return NormalObject{}, fmt.Errorf("error reading class for instance with id %v: %w", objectId, err)
@@ -52,8 +55,39 @@ func (h *Heap) ParseNormalObject(objectId core.Identifier) (NormalObject, error)
 	}, nil
 }
 
+// ScanInstances streams instances from the instance index in increasing
+// order of identifiers. Only instances of classes accepted by the filter
+// are parsed and passed to fn.
+func (h *Heap) ScanInstances(accept func(classId core.Identifier) bool, fn func(objectId core.Identifier, object NormalObject) error) error {
+	acceptInstance := func(instance core.HprofGcClassDumpInstanceDumpHeader) bool {
+		return accept(instance.ClassObjectId)
+	}
+	return h.parsedAccessor.ScanHprofGcInstanceDumpsWithPayload(acceptInstance, func(instance core.HprofGcClassDumpInstanceDumpHeader, instanceBytes []byte) error {
+		if !acceptInstance(instance) {
+			return nil
+		}
+		class, err := h.ParseClass(instance.ClassObjectId)
+		if err != nil {
+			return fmt.Errorf("error reading class for instance with id %v: %w", instance.ObjectId, err)
+		}
+		return fn(instance.ObjectId, NormalObject{
+			identifierSize: h.parsedAccessor.IdentifierSize,
+			Class:          class,
+			Bytes:          instanceBytes,
+		})
+	})
+}
+
 // ParseJavaString takes the instance values known to be a reference to
-// java.lang.String object and converts it to convenient Go string.
+// java.lang.String object and converts it to convenient Go string. The
+// layout of the string depends on JDK version:
+//
+//   - JDK 9 and later keep characters in byte[] `value`, either one byte
+//     per character in Latin-1 (`coder` is 0) or two bytes per character
+//     in UTF-16 (`coder` is 1);
+//   - JDK 8 and earlier keep UTF-16 characters in char[] `value`;
+//   - JDK 6 strings can share `value`, `offset` and `count` tell
+//     which part of the array belongs to the string.
 func (h *Heap) ParseJavaString(str core.JavaValue) (string, error) {
 	if str.Type == core.Object {
 		id, ok := str.Value.(core.Identifier)
@@ -69,11 +103,25 @@ func (h *Heap) ParseJavaString(str core.JavaValue) (string, error) {
 				}
 				arrayObj, ok := value.Value.Value.(core.Identifier)
 				if ok {
-					byteArr, err := h.parseByteArrayFull(arrayObj)
+					header, payload, err := h.parsePrimitiveArrayFull(arrayObj)
 					if err != nil {
 						return "", fmt.Errorf("error reading `value` array for string %v: %w", str, err)
 					}
-					return string(byteArr), nil
+					switch header.ElementType {
+					case core.Char:
+						chars := decodeUtf16(payload, binary.BigEndian)
+						return string(utf16.Decode(stringRange(javaString, chars))), nil
+					case core.Byte:
+						if coder, err := javaString.GetFieldValueByName("coder"); err == nil {
+							if c, err := coder.Value.ToByte(); err == nil && c == utf16Coder {
+								// UTF-16 byte[] is written in the native byte order of
+								// the JVM, all the platforms in use are little-endian
+								return string(utf16.Decode(decodeUtf16(payload, binary.LittleEndian))), nil
+							}
+						}
+						return decodeLatin1(payload), nil
+					}
+					return "", fmt.Errorf("malformed java string, `value` is %v[]", header.ElementType)
 				}
 				return "", fmt.Errorf("malformed java string, `value` is not a reference to array (%T)", value.Value.Value)
 			}
@@ -84,24 +132,125 @@ func (h *Heap) ParseJavaString(str core.JavaValue) (string, error) {
 	return "", fmt.Errorf("passed value (%v) is not reference to object", str)
 }
 
-func (h *Heap) parseByteArrayFull(arrayObjectId core.Identifier) ([]byte, error) {
-	header, err := h.parsedAccessor.GetHprofGcPrimArray(arrayObjectId)
+// utf16Coder is the value of java.lang.String.coder
+// for strings that are not compacted to Latin-1.
+const utf16Coder = 1
+
+// stringRange cuts the characters of JDK 6 string from
+// shared array. Other strings are returned as is.
+func stringRange(javaString NormalObject, chars []uint16) []uint16 {
+	offsetField, err := javaString.GetFieldValueByName("offset")
+	if err != nil {
+		return chars
+	}
+	countField, err := javaString.GetFieldValueByName("count")
+	if err != nil {
+		return chars
+	}
+	offset, err := offsetField.Value.ToInt()
 	if err != nil {
-		return nil, fmt.Errorf("error parsing byte array with id = %v: %w", arrayObjectId, err)
+		return chars
+	}
+	count, err := countField.Value.ToInt()
+	if err != nil || offset < 0 || count < 0 || offset+count > len(chars) {
+		return chars
+	}
+	return chars[offset : offset+count]
+}
+
+func decodeUtf16(payload []byte, order binary.ByteOrder) []uint16 {
+	chars := make([]uint16, len(payload)/2)
+	for i := range chars {
+		chars[i] = order.Uint16(payload[2*i:])
 	}
-	if header.ElementType != core.Byte {
-		return nil, fmt.Errorf("array with id = %v is not byte array (%T)", arrayObjectId, header.ElementType)
+	return chars
+}
+
+func decodeLatin1(payload []byte) string {
+	runes := make([]rune, len(payload))
+	for i, b := range payload {
+		runes[i] = rune(b)
 	}
-	payload, err := h.parsedAccessor.GetBytesFromCurrent(int(header.NumberOfElements))
+	return string(runes)
+}
+
+func (h *Heap) parsePrimitiveArrayFull(arrayObjectId core.Identifier) (core.HprofGcPrimArrayDumpHeader, []byte, error) {
+	header, payload, err := h.parsedAccessor.GetHprofGcPrimArrayWithPayload(arrayObjectId)
 	if err != nil {
-		return nil, fmt.Errorf("error parsing payload of array with id = %v: %w", arrayObjectId, err)
+		return core.HprofGcPrimArrayDumpHeader{}, nil, fmt.Errorf("error parsing primitive array with id = %v: %w", arrayObjectId, err)
 	}
-	return payload, nil
+	if header.ElementType == core.Object {
+		return core.HprofGcPrimArrayDumpHeader{}, nil, fmt.Errorf("array with id = %v is not primitive array", arrayObjectId)
+	}
+	return header, payload, nil
 }
 
 // ParseClass parses inforamsion from HPROF_DC_CLASS_DUMP record of a class and all
-// its superclasses.
+// its superclasses. Parsed classes are cached, so walking the heap object by object
+// does not resolve the same names over and over again.
 func (h *Heap) ParseClass(classId core.Identifier) (Class, error) {
+	h.mu.RLock()
+	class, ok := h.classes[classId]
+	h.mu.RUnlock()
+	if ok {
+		return class, nil
+	}
+	// the class may be parsed by several goroutines at
+	// once, they all get the same result anyway
+	class, err := h.parseClass(classId)
+	if err != nil {
+		return Class{}, err
+	}
+	h.mu.Lock()
+	h.classes[classId] = class
+	h.mu.Unlock()
+	return class, nil
+}
+
+// InstanceSize is the shallow size of the instance of the class in the
+// JVM heap, the fields of all superclasses are taken into account.
+func (h *Heap) InstanceSize(classId core.Identifier, size *core.SizeInfo) (int, error) {
+	class, err := h.ParseClass(classId)
+	if err != nil {
+		return 0, err
+	}
+	return class.InstanceSize(size), nil
+}
+
+// InstanceSize is the shallow size of the instance of the class,
+// see Heap.InstanceSize.
+func (c *Class) InstanceSize(size *core.SizeInfo) int {
+	var fieldTypes []core.JavaType
+	for class := c; class != nil; class = class.Superclass {
+		for _, field := range class.InstanceFields {
+			fieldTypes = append(fieldTypes, field.Type)
+		}
+	}
+	return size.OfInstance(fieldTypes)
+}
+
+// InstanceOf tells whether the object is an instance of the class
+// or any of its subclasses. className is in the internal form,
+// e.g. java/lang/ref/WeakReference. Only instances are inspected,
+// arrays and classes are never instances of anything.
+func (h *Heap) InstanceOf(objectId core.Identifier, className string) (bool, error) {
+	instance, err := h.parsedAccessor.GetHprofGcInstanceDump(objectId)
+	if err != nil {
+		return false, nil
+	}
+	class, err := h.ParseClass(instance.ClassObjectId)
+	if err != nil {
+		return false, err
+	}
+	for c := &class; c != nil; c = c.Superclass {
+		if c.Name == className {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (h *Heap) parseClass(classId core.Identifier) (Class, error) {
 	class, err := h.parsedAccessor.GetHprofGcClassDump(classId)
 	if err != nil {
 		return Class{}, fmt.Errorf("error reading class with id %v", classId)
@@ -202,6 +351,27 @@ func (o *NormalObject) GetFieldValueByName(name string) (FieldValue, error) {
 	}, nil
 }
 
+// Fields parses values of all instance fields of the object in the order
+// they are laid out in the instance dump: fields of the class itself go
+// first, then fields of its superclass and so on up to java.lang.Object.
+func (o *NormalObject) Fields() ([]Field, error) {
+	primitiveParser := core.NewPrimitiveParser(bytes.NewReader(o.Bytes), o.identifierSize)
+	var fields []Field
+	for class := &o.Class; class != nil; class = class.Superclass {
+		for _, field := range class.InstanceFields {
+			value, err := primitiveParser.ParseJavaValue(field.Type)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse value of field %v: %w", field.Name, err)
+			}
+			fields = append(fields, Field{
+				Name:       field.Name,
+				FieldValue: FieldValue{Value: value, Origin: class.Name},
+			})
+		}
+	}
+	return fields, nil
+}
+
 func findField(name string, fields []InstanceField, idSize uint32) (found bool, ty core.JavaType, offset int) {
 	var size = core.NewSizeInfo(idSize)
 	for _, field := range fields {
@@ -219,18 +389,19 @@ func findField(name string, fields []InstanceField, idSize uint32) (found bool,
 // ParseObjectArrayFull reads the whole object array and
 // returns slice with ObjectArray structs
 func (h *Heap) ParseObjectArrayFull(arrayObjectId core.Identifier) (ObjectArray, error) {
-	header, err := h.parsedAccessor.GetHprofGcObjArray(arrayObjectId)
+	header, payload, err := h.parsedAccessor.GetHprofGcObjArrayWithPayload(arrayObjectId)
 	if err != nil {
-		return ObjectArray{}, fmt.Errorf("error parsing object array header with id %v: %w", arrayObjectId, err)
+		return ObjectArray{}, fmt.Errorf("error parsing object array with id %v: %w", arrayObjectId, err)
 	}
+	idSize := h.parsedAccessor.IdentifierSize
+	primitiveParser := core.NewPrimitiveParser(bytes.NewReader(payload), idSize)
 	var elements []core.Identifier
 	for i := header.NumberOfElements; i > 0; i-- {
-		e, err := h.parsedAccessor.GetBytesFromCurrent(int(h.parsedAccessor.IdentifierSize))
+		id, err := primitiveParser.ParseIdentifier()
 		if err != nil {
 			return ObjectArray{}, fmt.Errorf("error reading element of object array with id %v: %w", arrayObjectId, err)
 		}
-		id := binary.BigEndian.Uint64(e)
-		elements = append(elements, core.Identifier(id))
+		elements = append(elements, id)
 	}
 	return ObjectArray{
 		ArrayClassId: header.ArrayClassId,
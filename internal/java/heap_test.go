@@ -9,6 +9,7 @@ import (
 	"github.com/danielleontiev/neojhat/internal/core"
 	"github.com/danielleontiev/neojhat/internal/dump"
 	"github.com/danielleontiev/neojhat/internal/storage"
+	td "github.com/danielleontiev/neojhat/internal/testdump"
 )
 
 // // Main.java
@@ -481,6 +482,86 @@ func TestObjectReader_ParseJavaString(t *testing.T) {
 	}
 }
 
+// stringSampleOf builds the dump with single java.lang.String (id 200)
+// of the given layout, its `value` is the array with id 300.
+func stringSampleOf(fields []td.Field, values [][]byte, ty core.JavaType, elements uint32, payload []byte) []byte {
+	return td.Dump(
+		[][]byte{
+			td.Utf8(1, "java/lang/String"),
+			td.Utf8(2, "value"),
+			td.Utf8(3, "coder"),
+			td.Utf8(4, "offset"),
+			td.Utf8(5, "count"),
+			td.LoadClass(1, 100, 1),
+		},
+		td.ClassDump(100, 0, 16, nil, fields),
+		td.InstanceDump(200, 100, values...),
+		td.PrimArrayDump(300, ty, elements, payload),
+	)
+}
+
+func TestHeap_ParseJavaString_Layouts(t *testing.T) {
+	value := td.Field{NameId: 2, Type: core.Object}
+	coder := td.Field{NameId: 3, Type: core.Byte}
+	offset := td.Field{NameId: 4, Type: core.Int}
+	count := td.Field{NameId: 5, Type: core.Int}
+	tests := []struct {
+		name    string
+		in      []byte
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "latin-1 byte[]",
+			in: stringSampleOf([]td.Field{value, coder}, [][]byte{td.Id(300), {0}},
+				core.Byte, 4, []byte{'c', 'a', 'f', 0xe9}),
+			want: "café",
+		},
+		{
+			name: "utf-16 byte[]",
+			in: stringSampleOf([]td.Field{value, coder}, [][]byte{td.Id(300), {1}},
+				core.Byte, 6, []byte{0x3f, 0x04, 0x40, 0x04, 0x3d, 0xd8}),
+			want: "пр\ufffd", // unpaired surrogate
+		},
+		{
+			name: "utf-16 byte[] with surrogate pair",
+			in: stringSampleOf([]td.Field{value, coder}, [][]byte{td.Id(300), {1}},
+				core.Byte, 6, []byte{'a', 0x00, 0x3d, 0xd8, 0x00, 0xde}),
+			want: "a😀",
+		},
+		{
+			name: "char[]",
+			in: stringSampleOf([]td.Field{value}, [][]byte{td.Id(300)},
+				core.Char, 3, []byte{0x00, 'h', 0x00, 0xe9, 0x04, 0x3f}),
+			want: "héп",
+		},
+		{
+			name: "char[] with offset and count",
+			in: stringSampleOf([]td.Field{value, offset, count}, [][]byte{td.Id(300), td.U4(1), td.U4(2)},
+				core.Char, 4, []byte{0x00, 'x', 0x00, 'o', 0x00, 'k', 0x00, 'x'}),
+			want: "ok",
+		},
+		{
+			name: "int[]",
+			in: stringSampleOf([]td.Field{value}, [][]byte{td.Id(300)},
+				core.Int, 1, []byte{0x00, 0x00, 0x00, 0x01}),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			heap := createHeap(tt.in, false, t)
+			got, err := heap.ParseJavaString(core.JavaValue{Type: core.Object, Value: core.Identifier(200)})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseJavaString() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseJavaString() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 var (
 	sampleObjectArray = concat(
 		objectReaderTestFileHeader,
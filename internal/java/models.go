@@ -1,6 +1,8 @@
 package java
 
 import (
+	"fmt"
+
 	"github.com/danielleontiev/neojhat/internal/core"
 )
 
@@ -65,3 +67,201 @@ type ObjectArray struct {
 	ArrayClassId core.Identifier
 	Elements     []core.Identifier
 }
+
+// Field is a single instance field value of NormalObject
+// along with the name of the field. It's returned
+// from NormalObject.Fields.
+type Field struct {
+	Name string
+	FieldValue
+}
+
+// ObjectKind tells what kind of heap record
+// stands behind the object identifier.
+type ObjectKind int
+
+const (
+	UnknownObject ObjectKind = iota
+	InstanceObject
+	ObjectArrayObject
+	PrimitiveArrayObject
+	ClassObject
+)
+
+func (k ObjectKind) String() string {
+	switch k {
+	case InstanceObject:
+		return "instance"
+	case ObjectArrayObject:
+		return "object-array"
+	case PrimitiveArrayObject:
+		return "primitive-array"
+	case ClassObject:
+		return "class"
+	}
+	return "unknown"
+}
+
+func (k ObjectKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// ReferenceKind tells how one heap object
+// points to another.
+type ReferenceKind int
+
+const (
+	InstanceFieldReference ReferenceKind = iota
+	ArrayElementReference
+	StaticFieldReference
+)
+
+func (k ReferenceKind) String() string {
+	switch k {
+	case InstanceFieldReference:
+		return "field"
+	case ArrayElementReference:
+		return "element"
+	case StaticFieldReference:
+		return "static"
+	}
+	return "unknown"
+}
+
+// Reference is the edge of the heap graph. From is the
+// object that holds the reference and To is the object
+// being referenced. Field has the name of the field for
+// instance and static field references, Index has the
+// position of the element for array references.
+type Reference struct {
+	Kind  ReferenceKind
+	From  core.Identifier
+	To    core.Identifier
+	Field string
+	Index int
+}
+
+// Name returns the name of the reference as it would
+// appear in Java source: field name or array index.
+func (r Reference) Name() string {
+	switch r.Kind {
+	case ArrayElementReference:
+		return fmt.Sprintf("[%d]", r.Index)
+	case StaticFieldReference:
+		return "static " + r.Field
+	}
+	return r.Field
+}
+
+// GcRootKind tells why the object is a GC root.
+type GcRootKind int
+
+const (
+	JniGlobalRoot GcRootKind = iota
+	JniLocalRoot
+	JavaFrameRoot
+	StickyClassRoot
+	ThreadObjectRoot
+	NativeStackRoot
+	ThreadBlockRoot
+	MonitorUsedRoot
+	InternedStringRoot
+	FinalizingRoot
+	DebuggerRoot
+	ReferenceCleanupRoot
+	VmInternalRoot
+	JniMonitorRoot
+	UnknownRoot
+)
+
+func (k GcRootKind) String() string {
+	switch k {
+	case JniGlobalRoot:
+		return "JNI global"
+	case JniLocalRoot:
+		return "JNI local"
+	case JavaFrameRoot:
+		return "Java frame"
+	case StickyClassRoot:
+		return "sticky class"
+	case ThreadObjectRoot:
+		return "thread object"
+	case NativeStackRoot:
+		return "native stack"
+	case ThreadBlockRoot:
+		return "thread block"
+	case MonitorUsedRoot:
+		return "monitor"
+	case InternedStringRoot:
+		return "interned string"
+	case FinalizingRoot:
+		return "finalizing"
+	case DebuggerRoot:
+		return "debugger"
+	case ReferenceCleanupRoot:
+		return "reference cleanup"
+	case VmInternalRoot:
+		return "VM internal"
+	case JniMonitorRoot:
+		return "JNI monitor"
+	}
+	return "unknown"
+}
+
+// GcRoot is the object that is alive no matter who references it.
+// ThreadSerialNumber and FrameNumber are set only for
+// roots related to threads.
+type GcRoot struct {
+	ObjectId           core.Identifier
+	Kind               GcRootKind
+	ThreadSerialNumber uint32
+	FrameNumber        int
+}
+
+// CollectionKind tells how the elements
+// of the collection should be shown.
+type CollectionKind int
+
+const (
+	ListCollection CollectionKind = iota
+	SetCollection
+	MapCollection
+)
+
+func (k CollectionKind) String() string {
+	switch k {
+	case ListCollection:
+		return "list"
+	case SetCollection:
+		return "set"
+	case MapCollection:
+		return "map"
+	}
+	return "unknown"
+}
+
+func (k CollectionKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// Entry is the element of the collection. Key
+// is set only for the entries of the maps.
+type Entry struct {
+	Key   core.JavaValue
+	Value core.JavaValue
+}
+
+// Collection is the content of JDK collection or map
+// in the order the collection iterates over it.
+type Collection struct {
+	ClassName string
+	Kind      CollectionKind
+	Entries   []Entry
+}
+
+// Fill is the number of elements of the collection and the length
+// of its backing array. Capacity is 0 if the array is not allocated yet.
+type Fill struct {
+	Size     int
+	Capacity int
+}
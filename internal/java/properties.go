@@ -0,0 +1,121 @@
+package java
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/danielleontiev/neojhat/internal/core"
+)
+
+const javaLangSystemClassName = "java/lang/System"
+
+// SystemProperties reads the system properties from the static field
+// "props" of java.lang.System. The entries of java.util.Properties are
+// read with ParseCollection, so both JDK 8 (Hashtable) and JDK 9+
+// (ConcurrentHashMap) layouts are supported.
+func (h *Heap) SystemProperties() (map[string]string, error) {
+	classId, ok := h.classByName(javaLangSystemClassName)
+	if !ok {
+		return nil, fmt.Errorf("class %s not found", javaLangSystemClassName)
+	}
+	class, err := h.ParseClass(classId)
+	if err != nil {
+		return nil, err
+	}
+	var propsObjectId core.Identifier
+	for _, field := range class.StaticFields {
+		if field.Name == "props" {
+			id, err := field.Value.ToObject()
+			if err != nil {
+				return nil, err
+			}
+			propsObjectId = id
+			break
+		}
+	}
+	props, err := h.ParseCollection(propsObjectId)
+	if err != nil {
+		return nil, err
+	}
+	properties := make(map[string]string)
+	for _, entry := range props.Entries {
+		key, err := h.ParseJavaString(entry.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := h.ParseJavaString(entry.Value)
+		if err != nil {
+			return nil, err
+		}
+		properties[key] = value
+	}
+	return properties, nil
+}
+
+// DetectHeapLayout starts with the default layout for the identifier
+// size and corrects it with what is known from the system properties:
+// since JDK 9 HotSpot sets java.vm.compressedOopsMode only when oops are
+// compressed, class pointers are compressed only together with oops
+// before JDK 15, and -XX options may be found among the values (e.g.
+// when the command line is saved by the application). The default
+// layout is returned when the properties cannot be read.
+func (h *Heap) DetectHeapLayout() core.HeapLayout {
+	layout := core.DefaultHeapLayout(h.parsedAccessor.IdentifierSize)
+	properties, err := h.SystemProperties()
+	if err != nil {
+		return layout
+	}
+	version := featureVersion(properties["java.specification.version"])
+	if _, ok := properties["java.vm.compressedOopsMode"]; layout.Is64Bit && version >= 9 && !ok {
+		layout.CompressedOops = false
+		if version < 15 {
+			layout.CompressedClassPointers = false
+		}
+	}
+	for _, value := range properties {
+		for _, option := range strings.Fields(value) {
+			if strings.HasPrefix(option, "-XX:") {
+				// options that are not about the layout are ignored
+				_ = layout.Apply(option)
+			}
+		}
+	}
+	return layout
+}
+
+// featureVersion returns the major version of the JDK from
+// java.specification.version, e.g. 8 for "1.8" and 17 for "17",
+// or 0 if the version is unknown.
+func featureVersion(specificationVersion string) int {
+	version := strings.TrimPrefix(specificationVersion, "1.")
+	major, _, _ := strings.Cut(version, ".")
+	n, err := strconv.Atoi(major)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+func (h *Heap) classByName(className string) (core.Identifier, bool) {
+	for _, loadClass := range h.parsedAccessor.ListHprofLoadClass() {
+		utf8, err := h.parsedAccessor.GetHprofUtf8(loadClass.ClassNameId)
+		if err != nil {
+			continue
+		}
+		if utf8.Characters == className {
+			return loadClass.ClassObjectId, true
+		}
+	}
+	return 0, false
+}
+
+// SizeInfo calculates shallow sizes for the detected heap layout
+// changed by the comma-separated JVM options, see core.HeapLayout.Apply.
+func (h *Heap) SizeInfo(layoutOptions string) (*core.SizeInfo, error) {
+	layout := h.DetectHeapLayout()
+	if err := layout.Apply(layoutOptions); err != nil {
+		return nil, err
+	}
+	return core.NewSizeInfo(h.parsedAccessor.IdentifierSize).WithLayout(layout), nil
+}
@@ -0,0 +1,197 @@
+package java
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/danielleontiev/neojhat/internal/core"
+	td "github.com/danielleontiev/neojhat/internal/testdump"
+)
+
+// propertiesSample is the dump with java.lang.System.props of JDK 8
+// layout: java.util.Properties 300 extends Hashtable, its table 301
+// has the entry 400+i in the bin i, keys and values are the strings
+// 500+2i and 501+2i with byte[] values 600+2i and 601+2i.
+func propertiesSample(properties map[string]string) []byte {
+	var keys []string
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	// objects are dumped in the order of identifiers
+	var bins []uint64
+	var entries, strings, arrays [][]byte
+	for i, key := range keys {
+		entry, keyId, valueId := uint64(400+i), uint64(500+2*i), uint64(501+2*i)
+		bins = append(bins, entry)
+		entries = append(entries, td.InstanceDump(entry, 105, td.Id(keyId), td.Id(valueId), td.Id(0)))
+		strings = append(strings,
+			td.InstanceDump(keyId, 101, td.Id(keyId+100), []byte{0}),
+			td.InstanceDump(valueId, 101, td.Id(valueId+100), []byte{0}),
+		)
+		arrays = append(arrays,
+			td.PrimArrayDump(keyId+100, core.Byte, uint32(len(key)), []byte(key)),
+			td.PrimArrayDump(valueId+100, core.Byte, uint32(len(properties[key])), []byte(properties[key])),
+		)
+	}
+	subRecords := append([][]byte{
+		td.ClassDump(100, 0, 0, nil, nil),
+		td.ClassDump(101, 100, 9, nil, []td.Field{objField(10), {NameId: 11, Type: core.Byte}}),
+		td.ClassDump(102, 100, 0, []td.Field{{NameId: 12, Type: core.Object, Value: td.Id(300)}}, nil),
+		td.ClassDump(103, 100, 8, nil, []td.Field{objField(13)}),
+		td.ClassDump(104, 103, 0, nil, nil),
+		td.ClassDump(105, 100, 24, nil, []td.Field{objField(14), objField(15), objField(16)}),
+		td.ClassDump(106, 100, 0, nil, nil),
+		td.InstanceDump(300, 104, td.Id(301)),
+		td.ObjArrayDump(301, 106, bins...),
+	}, append(append(entries, strings...), arrays...)...)
+	return td.Dump(
+		[][]byte{
+			td.Utf8(1, "java/lang/Object"),
+			td.Utf8(2, "java/lang/String"),
+			td.Utf8(3, "java/lang/System"),
+			td.Utf8(4, "java/util/Hashtable"),
+			td.Utf8(5, "java/util/Properties"),
+			td.Utf8(6, "java/util/Hashtable$Entry"),
+			td.Utf8(7, "[Ljava/lang/Object;"),
+			td.Utf8(10, "value"),
+			td.Utf8(11, "coder"),
+			td.Utf8(12, "props"),
+			td.Utf8(13, "table"),
+			td.Utf8(14, "key"),
+			td.Utf8(15, "value"),
+			td.Utf8(16, "next"),
+			td.LoadClass(1, 100, 1),
+			td.LoadClass(2, 101, 2),
+			td.LoadClass(3, 102, 3),
+			td.LoadClass(4, 103, 4),
+			td.LoadClass(5, 104, 5),
+			td.LoadClass(6, 105, 6),
+			td.LoadClass(7, 106, 7),
+		},
+		subRecords...,
+	)
+}
+
+// noSystemSample has no java.lang.System to read the properties from
+var noSystemSample = td.Dump(
+	[][]byte{td.Utf8(1, "java/lang/Object"), td.LoadClass(1, 100, 1)},
+	td.ClassDump(100, 0, 0, nil, nil),
+)
+
+func TestHeap_SystemProperties(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      []byte
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "properties",
+			in: propertiesSample(map[string]string{
+				"java.specification.version": "17",
+				"sun.java.command":           "app -XX:+UseG1GC",
+			}),
+			want: map[string]string{
+				"java.specification.version": "17",
+				"sun.java.command":           "app -XX:+UseG1GC",
+			},
+		},
+		{
+			name: "no properties",
+			in:   propertiesSample(nil),
+			want: map[string]string{},
+		},
+		{
+			name:    "no java.lang.System",
+			in:      noSystemSample,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsedAccessor, err := td.NewParsedAccessor(tt.in, false)
+			if err != nil {
+				t.Fatalf("cannot parse sample: %v", err)
+			}
+			got, err := NewHeap(parsedAccessor).SystemProperties()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SystemProperties() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SystemProperties() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHeap_DetectHeapLayout(t *testing.T) {
+	compressed := core.DefaultHeapLayout(8)
+	uncompressed := core.HeapLayout{Is64Bit: true, ObjectAlignment: 8}
+	compressedClassPointers := core.HeapLayout{Is64Bit: true, CompressedClassPointers: true, ObjectAlignment: 8}
+	tests := []struct {
+		name       string
+		properties map[string]string
+		want       core.HeapLayout
+	}{
+		{
+			name: "JDK 17 with compressed oops",
+			properties: map[string]string{
+				"java.specification.version": "17",
+				"java.vm.compressedOopsMode": "Zero based",
+			},
+			want: compressed,
+		},
+		{
+			name:       "JDK 17 without compressed oops",
+			properties: map[string]string{"java.specification.version": "17"},
+			want:       compressedClassPointers,
+		},
+		{
+			name:       "JDK 11 without compressed oops",
+			properties: map[string]string{"java.specification.version": "11"},
+			want:       uncompressed,
+		},
+		{
+			// JDK 8 does not set java.vm.compressedOopsMode
+			name:       "JDK 8",
+			properties: map[string]string{"java.specification.version": "1.8"},
+			want:       compressed,
+		},
+		{
+			name:       "unknown version",
+			properties: map[string]string{},
+			want:       compressed,
+		},
+		{
+			name: "options in values",
+			properties: map[string]string{
+				"java.specification.version": "11",
+				"java.vm.compressedOopsMode": "Zero based",
+				"sun.java.command":           "app -XX:ObjectAlignmentInBytes=16 -XX:-UseCompressedClassPointers -XX:+UseG1GC",
+			},
+			want: core.HeapLayout{Is64Bit: true, CompressedOops: true, ObjectAlignment: 16},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsedAccessor, err := td.NewParsedAccessor(propertiesSample(tt.properties), false)
+			if err != nil {
+				t.Fatalf("cannot parse sample: %v", err)
+			}
+			if got := NewHeap(parsedAccessor).DetectHeapLayout(); got != tt.want {
+				t.Errorf("DetectHeapLayout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	t.Run("no properties", func(t *testing.T) {
+		parsedAccessor, err := td.NewParsedAccessor(noSystemSample, false)
+		if err != nil {
+			t.Fatalf("cannot parse sample: %v", err)
+		}
+		if got := NewHeap(parsedAccessor).DetectHeapLayout(); got != compressed {
+			t.Errorf("DetectHeapLayout() = %v, want %v", got, compressed)
+		}
+	})
+}
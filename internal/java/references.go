@@ -0,0 +1,199 @@
+package java
+
+import (
+	"fmt"
+
+	"github.com/danielleontiev/neojhat/internal/core"
+	"github.com/danielleontiev/neojhat/internal/format"
+)
+
+// KindOf looks up the object by its identifier in all the
+// indexes and tells which kind of heap record it is.
+func (h *Heap) KindOf(objectId core.Identifier) ObjectKind {
+	if _, err := h.parsedAccessor.GetHprofGcInstanceDump(objectId); err == nil {
+		return InstanceObject
+	}
+	if _, err := h.parsedAccessor.GetHprofGcObjArray(objectId); err == nil {
+		return ObjectArrayObject
+	}
+	if _, err := h.parsedAccessor.GetHprofGcPrimArray(objectId); err == nil {
+		return PrimitiveArrayObject
+	}
+	if _, err := h.parsedAccessor.GetHprofGcClassDump(objectId); err == nil {
+		return ClassObject
+	}
+	return UnknownObject
+}
+
+// OutboundReferences returns all non-null references the object holds.
+// For instances, fields of the whole class hierarchy are inspected, for
+// object arrays - all elements and for classes - static fields declared
+// by the class itself. Primitive arrays do not refer to anything.
+func (h *Heap) OutboundReferences(objectId core.Identifier) ([]Reference, error) {
+	switch h.KindOf(objectId) {
+	case InstanceObject:
+		return h.instanceReferences(objectId)
+	case ObjectArrayObject:
+		return h.objectArrayReferences(objectId)
+	case ClassObject:
+		return h.staticReferences(objectId)
+	case PrimitiveArrayObject:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("object with id %v not found", objectId)
+}
+
+func (h *Heap) instanceReferences(objectId core.Identifier) ([]Reference, error) {
+	object, err := h.ParseNormalObject(objectId)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := object.Fields()
+	if err != nil {
+		return nil, fmt.Errorf("error reading fields of instance with id %v: %w", objectId, err)
+	}
+	var references []Reference
+	for _, field := range fields {
+		if field.Value.Type != core.Object {
+			continue
+		}
+		to, err := field.Value.ToObject()
+		if err != nil {
+			return nil, fmt.Errorf("error reading field %v of instance with id %v: %w", field.Name, objectId, err)
+		}
+		if to == 0 {
+			continue
+		}
+		references = append(references, Reference{
+			Kind:  InstanceFieldReference,
+			From:  objectId,
+			To:    to,
+			Field: field.Name,
+		})
+	}
+	return references, nil
+}
+
+func (h *Heap) objectArrayReferences(arrayObjectId core.Identifier) ([]Reference, error) {
+	array, err := h.ParseObjectArrayFull(arrayObjectId)
+	if err != nil {
+		return nil, err
+	}
+	var references []Reference
+	for i, to := range array.Elements {
+		if to == 0 {
+			continue
+		}
+		references = append(references, Reference{
+			Kind:  ArrayElementReference,
+			From:  arrayObjectId,
+			To:    to,
+			Index: i,
+		})
+	}
+	return references, nil
+}
+
+func (h *Heap) staticReferences(classId core.Identifier) ([]Reference, error) {
+	class, err := h.ParseClass(classId)
+	if err != nil {
+		return nil, err
+	}
+	var references []Reference
+	for _, field := range class.StaticFields {
+		if field.Type != core.Object {
+			continue
+		}
+		to, err := field.Value.ToObject()
+		if err != nil {
+			return nil, fmt.Errorf("error reading static field %v of class with id %v: %w", field.Name, classId, err)
+		}
+		if to == 0 {
+			continue
+		}
+		references = append(references, Reference{
+			Kind:  StaticFieldReference,
+			From:  classId,
+			To:    to,
+			Field: field.Name,
+		})
+	}
+	return references, nil
+}
+
+// Referrers returns references pointing to the given object. It requires
+// references index built during parsing. The index stores only identifiers
+// of referrers, so the referrers are inspected to recover field names.
+func (h *Heap) Referrers(objectId core.Identifier) ([]Reference, error) {
+	referrerIds, err := h.parsedAccessor.GetReferrers(objectId)
+	if err != nil {
+		return nil, err
+	}
+	var references []Reference
+	for i, referrerId := range referrerIds {
+		// the same referrer is repeated for every reference it holds
+		if i > 0 && referrerIds[i-1] == referrerId {
+			continue
+		}
+		outbound, err := h.OutboundReferences(referrerId)
+		if err != nil {
+			return nil, fmt.Errorf("error reading references of referrer %v: %w", referrerId, err)
+		}
+		for _, reference := range outbound {
+			if reference.To == objectId {
+				references = append(references, reference)
+			}
+		}
+	}
+	return references, nil
+}
+
+// ObjectTypeName returns the name of the object type: class name
+// for instances, type of the array for arrays and "class <name>"
+// for class objects.
+func (h *Heap) ObjectTypeName(objectId core.Identifier) (string, error) {
+	switch h.KindOf(objectId) {
+	case InstanceObject:
+		header, err := h.parsedAccessor.GetHprofGcInstanceDump(objectId)
+		if err != nil {
+			return "", err
+		}
+		return h.className(header.ClassObjectId)
+	case ObjectArrayObject:
+		header, err := h.parsedAccessor.GetHprofGcObjArray(objectId)
+		if err != nil {
+			return "", err
+		}
+		arrayClassName, err := h.className(header.ArrayClassId)
+		if err != nil {
+			return "", err
+		}
+		name, _ := format.Signature(arrayClassName)
+		return name, nil
+	case PrimitiveArrayObject:
+		header, err := h.parsedAccessor.GetHprofGcPrimArray(objectId)
+		if err != nil {
+			return "", err
+		}
+		return header.ElementType.String() + "[]", nil
+	case ClassObject:
+		name, err := h.className(objectId)
+		if err != nil {
+			return "", err
+		}
+		return "class " + name, nil
+	}
+	return "", fmt.Errorf("object with id %v not found", objectId)
+}
+
+func (h *Heap) className(classId core.Identifier) (string, error) {
+	loadClass, err := h.parsedAccessor.GetHprofLoadClassByClassObjectId(classId)
+	if err != nil {
+		return "", fmt.Errorf("error reading HprofLoadClass by id %v: %w", classId, err)
+	}
+	className, err := h.parsedAccessor.GetHprofUtf8(loadClass.ClassNameId)
+	if err != nil {
+		return "", fmt.Errorf("error reading class name: %w", err)
+	}
+	return className.Characters, nil
+}
@@ -0,0 +1,269 @@
+package java
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/danielleontiev/neojhat/internal/core"
+	td "github.com/danielleontiev/neojhat/internal/testdump"
+)
+
+// class Base { Object parent; }
+// class Node extends Base { static Node INSTANCE; int count; Node next; }
+var referencesSample = td.Dump(
+	[][]byte{
+		td.Utf8(1, "Node"),
+		td.Utf8(2, "java/lang/Object"),
+		td.Utf8(3, "next"),
+		td.Utf8(4, "Base"),
+		td.Utf8(5, "INSTANCE"),
+		td.Utf8(6, "count"),
+		td.Utf8(7, "parent"),
+		td.Utf8(8, "[Ljava/lang/Object;"),
+		td.LoadClass(1, 100, 2),
+		td.LoadClass(2, 101, 4),
+		td.LoadClass(3, 102, 1),
+		td.LoadClass(4, 103, 8),
+	},
+	td.ClassDump(100, 0, 0, nil, nil),
+	td.ClassDump(101, 100, 8, nil, []td.Field{{NameId: 7, Type: core.Object}}),
+	td.ClassDump(102, 101, 12,
+		[]td.Field{{NameId: 5, Type: core.Object, Value: td.Id(200)}},
+		[]td.Field{{NameId: 6, Type: core.Int}, {NameId: 3, Type: core.Object}},
+	),
+	td.ClassDump(103, 100, 0, nil, nil),
+	td.InstanceDump(200, 102, td.U4(1), td.Id(201), td.Id(300)),
+	td.InstanceDump(201, 102, td.U4(2), td.Id(0), td.Id(200)),
+	td.ObjArrayDump(300, 103, 200, 0, 201),
+	td.PrimArrayDump(400, core.Byte, 2, []byte{0x01, 0x02}),
+)
+
+func createHeap(in []byte, withReferences bool, t *testing.T) *Heap {
+	parsedAccessor, err := td.NewParsedAccessor(in, withReferences)
+	if err != nil {
+		t.Fatalf("cannot parse sample: %v", err)
+	}
+	return NewHeap(parsedAccessor)
+}
+
+func TestHeap_KindOf(t *testing.T) {
+	heap := createHeap(referencesSample, false, t)
+	tests := []struct {
+		id   core.Identifier
+		want ObjectKind
+	}{
+		{id: 200, want: InstanceObject},
+		{id: 300, want: ObjectArrayObject},
+		{id: 400, want: PrimitiveArrayObject},
+		{id: 102, want: ClassObject},
+		{id: 999, want: UnknownObject},
+	}
+	for _, tt := range tests {
+		if got := heap.KindOf(tt.id); got != tt.want {
+			t.Errorf("KindOf(%v) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestHeap_OutboundReferences(t *testing.T) {
+	heap := createHeap(referencesSample, false, t)
+	tests := []struct {
+		name string
+		id   core.Identifier
+		want []Reference
+	}{
+		{
+			name: "instance with superclass fields",
+			id:   200,
+			want: []Reference{
+				{Kind: InstanceFieldReference, From: 200, To: 201, Field: "next"},
+				{Kind: InstanceFieldReference, From: 200, To: 300, Field: "parent"},
+			},
+		},
+		{
+			name: "null fields are skipped",
+			id:   201,
+			want: []Reference{
+				{Kind: InstanceFieldReference, From: 201, To: 200, Field: "parent"},
+			},
+		},
+		{
+			name: "object array",
+			id:   300,
+			want: []Reference{
+				{Kind: ArrayElementReference, From: 300, To: 200, Index: 0},
+				{Kind: ArrayElementReference, From: 300, To: 201, Index: 2},
+			},
+		},
+		{
+			name: "static fields",
+			id:   102,
+			want: []Reference{
+				{Kind: StaticFieldReference, From: 102, To: 200, Field: "INSTANCE"},
+			},
+		},
+		{
+			name: "primitive array",
+			id:   400,
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := heap.OutboundReferences(tt.id)
+			if err != nil {
+				t.Errorf("OutboundReferences() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("OutboundReferences() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+	if _, err := heap.OutboundReferences(999); err == nil {
+		t.Errorf("OutboundReferences() of unknown object, error expected")
+	}
+}
+
+func TestReference_Name(t *testing.T) {
+	tests := []struct {
+		reference Reference
+		want      string
+	}{
+		{reference: Reference{Kind: InstanceFieldReference, Field: "next"}, want: "next"},
+		{reference: Reference{Kind: ArrayElementReference, Index: 3}, want: "[3]"},
+		{reference: Reference{Kind: StaticFieldReference, Field: "INSTANCE"}, want: "static INSTANCE"},
+	}
+	for _, tt := range tests {
+		if got := tt.reference.Name(); got != tt.want {
+			t.Errorf("Reference.Name() = %v, want %v", got, tt.want)
+		}
+	}
+}
+
+func TestHeap_Referrers(t *testing.T) {
+	heap := createHeap(referencesSample, true, t)
+	tests := []struct {
+		name string
+		id   core.Identifier
+		want []Reference
+	}{
+		{
+			name: "referenced by instance, array and static field",
+			id:   200,
+			want: []Reference{
+				{Kind: StaticFieldReference, From: 102, To: 200, Field: "INSTANCE"},
+				{Kind: InstanceFieldReference, From: 201, To: 200, Field: "parent"},
+				{Kind: ArrayElementReference, From: 300, To: 200, Index: 0},
+			},
+		},
+		{
+			name: "referenced twice by the same array",
+			id:   201,
+			want: []Reference{
+				{Kind: InstanceFieldReference, From: 200, To: 201, Field: "next"},
+				{Kind: ArrayElementReference, From: 300, To: 201, Index: 2},
+			},
+		},
+		{
+			name: "not referenced",
+			id:   400,
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := heap.Referrers(tt.id)
+			if err != nil {
+				t.Errorf("Referrers() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Referrers() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHeap_ObjectTypeName(t *testing.T) {
+	heap := createHeap(referencesSample, false, t)
+	tests := []struct {
+		id   core.Identifier
+		want string
+	}{
+		{id: 200, want: "Node"},
+		{id: 300, want: "java.lang.Object[]"},
+		{id: 400, want: "byte[]"},
+		{id: 102, want: "class Node"},
+	}
+	for _, tt := range tests {
+		got, err := heap.ObjectTypeName(tt.id)
+		if err != nil {
+			t.Errorf("ObjectTypeName(%v) error = %v", tt.id, err)
+		}
+		if got != tt.want {
+			t.Errorf("ObjectTypeName(%v) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+	if _, err := heap.ObjectTypeName(999); err == nil {
+		t.Errorf("ObjectTypeName() of unknown object, error expected")
+	}
+}
+
+// TestHeap_Concurrent walks the sample from many goroutines at once
+// and compares the results with the sequential walk. The heap is
+// created for every run, so the class cache is filled concurrently
+// as well. Run it with -race.
+func TestHeap_Concurrent(t *testing.T) {
+	objectIds := []core.Identifier{100, 101, 102, 103, 200, 201, 300, 400}
+	walk := func(heap *Heap) (string, error) {
+		var result strings.Builder
+		for _, objectId := range objectIds {
+			references, err := heap.OutboundReferences(objectId)
+			if err != nil {
+				return "", err
+			}
+			referrers, err := heap.Referrers(objectId)
+			if err != nil {
+				return "", err
+			}
+			typeName, err := heap.ObjectTypeName(objectId)
+			if err != nil {
+				return "", err
+			}
+			fmt.Fprintf(&result, "%v %s %v %v\n", objectId, typeName, references, referrers)
+		}
+		err := heap.ScanInstances(func(core.Identifier) bool { return true }, func(objectId core.Identifier, object NormalObject) error {
+			fields, err := object.Fields()
+			fmt.Fprintf(&result, "%v %v\n", objectId, fields)
+			return err
+		})
+		return result.String(), err
+	}
+	want, err := walk(createHeap(referencesSample, true, t))
+	if err != nil {
+		t.Fatalf("walk error = %v", err)
+	}
+
+	heap := createHeap(referencesSample, true, t)
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := walk(heap)
+			if err != nil {
+				errs <- err
+			} else if got != want {
+				errs <- fmt.Errorf("concurrent walk = %s, want %s", got, want)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
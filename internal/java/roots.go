@@ -0,0 +1,135 @@
+package java
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/danielleontiev/neojhat/internal/core"
+)
+
+// GcRoots lists all GC roots of the heap dump. The same object
+// can be presented several times if it is a root for several reasons.
+func (h *Heap) GcRoots() []GcRoot {
+	var roots []GcRoot
+	for _, r := range h.parsedAccessor.ListHprofGcRootJniGlobal() {
+		roots = append(roots, GcRoot{ObjectId: r.ObjectId, Kind: JniGlobalRoot})
+	}
+	for _, r := range h.parsedAccessor.ListHprofGcRootJniLocal() {
+		roots = append(roots, GcRoot{
+			ObjectId:           r.ObjectId,
+			Kind:               JniLocalRoot,
+			ThreadSerialNumber: r.ThreadSerialNumber,
+			FrameNumber:        int(r.FrameNumberInStackTrace),
+		})
+	}
+	for _, r := range h.parsedAccessor.ListHprofGcRootJavaFrame() {
+		roots = append(roots, GcRoot{
+			ObjectId:           r.ObjectId,
+			Kind:               JavaFrameRoot,
+			ThreadSerialNumber: r.ThreadSerialNumber,
+			FrameNumber:        int(r.FrameNumberInStackTrace),
+		})
+	}
+	for _, r := range h.parsedAccessor.ListHprofGcRootStickyClass() {
+		roots = append(roots, GcRoot{ObjectId: r.ObjectId, Kind: StickyClassRoot})
+	}
+	for _, r := range h.parsedAccessor.ListHprofGcRootThreadObj() {
+		roots = append(roots, GcRoot{
+			ObjectId:           r.ThreadObjectId,
+			Kind:               ThreadObjectRoot,
+			ThreadSerialNumber: r.ThreadSequenceNumber,
+		})
+	}
+	for _, r := range h.parsedAccessor.ListHprofGcRootNativeStack() {
+		roots = append(roots, GcRoot{
+			ObjectId:           r.ObjectId,
+			Kind:               NativeStackRoot,
+			ThreadSerialNumber: r.ThreadSerialNumber,
+		})
+	}
+	for _, r := range h.parsedAccessor.ListHprofGcRootThreadBlock() {
+		roots = append(roots, GcRoot{
+			ObjectId:           r.ObjectId,
+			Kind:               ThreadBlockRoot,
+			ThreadSerialNumber: r.ThreadSerialNumber,
+		})
+	}
+	for _, r := range h.parsedAccessor.ListHprofGcRootMonitorUsed() {
+		roots = append(roots, GcRoot{ObjectId: r.ObjectId, Kind: MonitorUsedRoot})
+	}
+	for _, r := range h.parsedAccessor.ListHprofGcRootInternedString() {
+		roots = append(roots, GcRoot{ObjectId: r.ObjectId, Kind: InternedStringRoot})
+	}
+	for _, r := range h.parsedAccessor.ListHprofGcRootFinalizing() {
+		roots = append(roots, GcRoot{ObjectId: r.ObjectId, Kind: FinalizingRoot})
+	}
+	for _, r := range h.parsedAccessor.ListHprofGcRootDebugger() {
+		roots = append(roots, GcRoot{ObjectId: r.ObjectId, Kind: DebuggerRoot})
+	}
+	for _, r := range h.parsedAccessor.ListHprofGcRootReferenceCleanup() {
+		roots = append(roots, GcRoot{ObjectId: r.ObjectId, Kind: ReferenceCleanupRoot})
+	}
+	for _, r := range h.parsedAccessor.ListHprofGcRootVmInternal() {
+		roots = append(roots, GcRoot{ObjectId: r.ObjectId, Kind: VmInternalRoot})
+	}
+	for _, r := range h.parsedAccessor.ListHprofGcRootJniMonitor() {
+		roots = append(roots, GcRoot{
+			ObjectId:           r.ObjectId,
+			Kind:               JniMonitorRoot,
+			ThreadSerialNumber: r.ThreadSerialNumber,
+		})
+	}
+	for _, r := range h.parsedAccessor.ListHprofGcRootUnknown() {
+		roots = append(roots, GcRoot{ObjectId: r.ObjectId, Kind: UnknownRoot})
+	}
+	return roots
+}
+
+// GcRootIds returns sorted identifiers of all
+// GC roots without duplicates.
+func (h *Heap) GcRootIds() []core.Identifier {
+	seen := make(map[core.Identifier]bool)
+	var ids []core.Identifier
+	for _, r := range h.GcRoots() {
+		if r.ObjectId == 0 || seen[r.ObjectId] {
+			continue
+		}
+		seen[r.ObjectId] = true
+		ids = append(ids, r.ObjectId)
+	}
+	sortIdentifiers(ids)
+	return ids
+}
+
+// ThreadName returns the name of the thread with the given serial
+// number read from the name field of java.lang.Thread object. Dumps
+// written by profiling agents have no thread objects, the name from
+// HPROF_START_THREAD record is used for them.
+func (h *Heap) ThreadName(threadSerialNumber uint32) (string, error) {
+	for _, threadObj := range h.parsedAccessor.ListHprofGcRootThreadObj() {
+		if threadObj.ThreadSequenceNumber != threadSerialNumber {
+			continue
+		}
+		thread, err := h.ParseNormalObject(threadObj.ThreadObjectId)
+		if err != nil {
+			return "", err
+		}
+		name, err := thread.GetFieldValueByName("name")
+		if err != nil {
+			return "", err
+		}
+		return h.ParseJavaString(name.Value)
+	}
+	if startThread, err := h.parsedAccessor.GetHprofStartThread(threadSerialNumber); err == nil {
+		name, err := h.parsedAccessor.GetHprofUtf8(startThread.ThreadNameId)
+		if err != nil {
+			return "", err
+		}
+		return name.Characters, nil
+	}
+	return "", fmt.Errorf("thread with serial number %v not found", threadSerialNumber)
+}
+
+func sortIdentifiers(ids []core.Identifier) {
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+}
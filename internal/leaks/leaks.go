@@ -0,0 +1,174 @@
+// leaks looks for the objects that are likely to be memory leaks the same
+// way as leak suspects report of Eclipse MAT does. The analysis is based on
+// the dominator tree. Objects dominated only by the virtual root (top-level
+// dominators) are inspected:
+//
+//  1. Every top-level dominator retaining more than the threshold is
+//     the suspect.
+//  2. The rest of top-level dominators are grouped by class. Group
+//     retaining more than the threshold is the suspect as well, e.g.
+//     many small caches that are not big enough separately.
+//
+// Retained memory of the suspect is usually held by some collection deep
+// inside. The accumulation point is found by descending into the biggest
+// dominated object while it retains the most of the memory of its parent.
+package leaks
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/danielleontiev/neojhat/internal/core"
+	"github.com/danielleontiev/neojhat/internal/dump"
+	"github.com/danielleontiev/neojhat/internal/java"
+	"github.com/danielleontiev/neojhat/internal/paths"
+	"github.com/danielleontiev/neojhat/internal/storage"
+)
+
+// accumulationShare is the share of the parent retained size in
+// percents the biggest child should retain to continue descending
+// when looking for the accumulation point.
+const accumulationShare = 80
+
+// GetLeaks returns suspects retaining more than threshold
+// percents of the heap ordered by retained size.
+func GetLeaks(parsedAccessor *dump.ParsedAccessor, threshold int) (Leaks, error) {
+	dominators, err := parsedAccessor.Dominators()
+	if err != nil {
+		return Leaks{}, err
+	}
+	heap := java.NewHeap(parsedAccessor)
+	topLevel, err := dominators.GetDominated(0)
+	if err != nil {
+		return Leaks{}, fmt.Errorf("cannot read top-level dominators: %w", err)
+	}
+
+	type object struct {
+		id        core.Identifier
+		className string
+		retained  int
+	}
+	var objects []object
+	totalSize := 0
+	for _, id := range topLevel {
+		retained, err := dominators.GetRetainedSize(id)
+		if err != nil {
+			return Leaks{}, fmt.Errorf("cannot read retained size of %v: %w", id, err)
+		}
+		totalSize += retained
+		objects = append(objects, object{id: id, retained: retained})
+	}
+	isSuspect := func(retained int) bool {
+		return totalSize > 0 && retained*100 > totalSize*threshold
+	}
+
+	var suspects []Suspect
+	type group struct {
+		biggest   object
+		instances int
+		retained  int
+	}
+	groups := make(map[string]*group)
+	for _, o := range objects {
+		className, err := heap.ObjectTypeName(o.id)
+		if err != nil {
+			return Leaks{}, err
+		}
+		o.className = className
+		if isSuspect(o.retained) {
+			suspects = append(suspects, Suspect{
+				ObjectId:     o.id,
+				ClassName:    o.className,
+				Instances:    1,
+				RetainedSize: o.retained,
+			})
+			continue
+		}
+		g, ok := groups[className]
+		if !ok {
+			g = &group{biggest: o}
+			groups[className] = g
+		}
+		if o.retained > g.biggest.retained {
+			g.biggest = o
+		}
+		g.instances++
+		g.retained += o.retained
+	}
+	for className, g := range groups {
+		if isSuspect(g.retained) {
+			suspects = append(suspects, Suspect{
+				ObjectId:     g.biggest.id,
+				ClassName:    className,
+				Instances:    g.instances,
+				RetainedSize: g.retained,
+			})
+		}
+	}
+	sort.Slice(suspects, func(i, j int) bool {
+		if suspects[i].RetainedSize == suspects[j].RetainedSize {
+			return suspects[i].ObjectId < suspects[j].ObjectId
+		}
+		return suspects[i].RetainedSize > suspects[j].RetainedSize
+	})
+
+	for i := range suspects {
+		accumulationPoint, err := findAccumulationPoint(heap, dominators, suspects[i].ObjectId)
+		if err != nil {
+			return Leaks{}, err
+		}
+		suspects[i].AccumulationPoint = accumulationPoint
+		// every path to the accumulation point goes through the
+		// suspect since the suspect dominates it
+		p, err := paths.GetPaths(parsedAccessor, accumulationPoint.ObjectId, true, 1)
+		if err != nil {
+			return Leaks{}, err
+		}
+		if len(p.Items) > 0 {
+			suspects[i].Path = p.Items[0]
+		}
+	}
+	return Leaks{
+		TotalSize: totalSize,
+		Threshold: threshold,
+		Suspects:  suspects,
+	}, nil
+}
+
+func findAccumulationPoint(heap *java.Heap, dominators *storage.DominatorsReadStorage, objectId core.Identifier) (AccumulationPoint, error) {
+	current := objectId
+	currentRetained, err := dominators.GetRetainedSize(current)
+	if err != nil {
+		return AccumulationPoint{}, fmt.Errorf("cannot read retained size of %v: %w", current, err)
+	}
+	for {
+		dominated, err := dominators.GetDominated(current)
+		if err != nil {
+			return AccumulationPoint{}, fmt.Errorf("cannot read objects dominated by %v: %w", current, err)
+		}
+		var biggest core.Identifier
+		biggestRetained := 0
+		for _, id := range dominated {
+			retained, err := dominators.GetRetainedSize(id)
+			if err != nil {
+				return AccumulationPoint{}, fmt.Errorf("cannot read retained size of %v: %w", id, err)
+			}
+			if retained > biggestRetained {
+				biggest, biggestRetained = id, retained
+			}
+		}
+		if biggestRetained == 0 || biggestRetained*100 < currentRetained*accumulationShare {
+			break
+		}
+		current, currentRetained = biggest, biggestRetained
+	}
+	className, err := heap.ObjectTypeName(current)
+	if err != nil {
+		return AccumulationPoint{}, err
+	}
+	return AccumulationPoint{
+		ObjectId:     current,
+		ClassName:    className,
+		RetainedSize: currentRetained,
+	}, nil
+}
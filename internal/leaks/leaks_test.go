@@ -0,0 +1,130 @@
+package leaks
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/danielleontiev/neojhat/internal/core"
+	"github.com/danielleontiev/neojhat/internal/dominator"
+	"github.com/danielleontiev/neojhat/internal/dump"
+	"github.com/danielleontiev/neojhat/internal/storage"
+	td "github.com/danielleontiev/neojhat/internal/testdump"
+)
+
+// class Holder { Object a; }
+//
+//	JNI global → 1000 → 1001 (Object[]) → 1002..1005 → byte[100]
+//	JNI global → 3000, 3001, 3002 → byte[20]
+var leaksSample = td.Dump(
+	[][]byte{
+		td.Utf8(1, "Holder"),
+		td.Utf8(2, "java/lang/Object"),
+		td.Utf8(3, "a"),
+		td.Utf8(4, "[Ljava/lang/Object;"),
+		td.LoadClass(1, 100, 1),
+		td.LoadClass(2, 101, 2),
+		td.LoadClass(3, 102, 4),
+	},
+	td.RootJniGlobal(1000),
+	td.RootJniGlobal(3000),
+	td.RootJniGlobal(3001),
+	td.RootJniGlobal(3002),
+	td.ClassDump(101, 0, 0, nil, nil),
+	td.ClassDump(100, 101, 8, nil, []td.Field{{NameId: 3, Type: core.Object}}),
+	td.ClassDump(102, 101, 0, nil, nil),
+	td.InstanceDump(1000, 100, td.Id(1001)),
+	td.ObjArrayDump(1001, 102, 1002, 1003, 1004, 1005),
+	td.InstanceDump(1002, 100, td.Id(1102)),
+	td.InstanceDump(1003, 100, td.Id(1103)),
+	td.InstanceDump(1004, 100, td.Id(1104)),
+	td.InstanceDump(1005, 100, td.Id(1105)),
+	td.PrimArrayDump(1102, core.Byte, 100, make([]byte, 100)),
+	td.PrimArrayDump(1103, core.Byte, 100, make([]byte, 100)),
+	td.PrimArrayDump(1104, core.Byte, 100, make([]byte, 100)),
+	td.PrimArrayDump(1105, core.Byte, 100, make([]byte, 100)),
+	td.InstanceDump(3000, 100, td.Id(3100)),
+	td.InstanceDump(3001, 100, td.Id(3101)),
+	td.InstanceDump(3002, 100, td.Id(3102)),
+	td.PrimArrayDump(3100, core.Byte, 20, make([]byte, 20)),
+	td.PrimArrayDump(3101, core.Byte, 20, make([]byte, 20)),
+	td.PrimArrayDump(3102, core.Byte, 20, make([]byte, 20)),
+)
+
+func createParsedAccessor(in []byte, t *testing.T) *dump.ParsedAccessor {
+	parsedAccessor, err := td.NewParsedAccessor(in, true)
+	if err != nil {
+		t.Fatalf("cannot parse sample: %v", err)
+	}
+	dominatorsVolume := storage.NewRamWriteVolume()
+	retainedVolume := storage.NewRamWriteVolume()
+	dominatedVolume := storage.NewRamWriteVolume()
+	writer := storage.NewDominatorsWriteStorage(dominatorsVolume, retainedVolume, dominatedVolume, td.NewRun)
+	if err := dominator.NewBuilder(parsedAccessor, td.NewRun, td.NewArray).Build(writer); err != nil {
+		t.Fatalf("cannot build dominators: %v", err)
+	}
+	retainedSizes := bytes.NewBuffer(nil)
+	if err := writer.SerializeTo(retainedSizes); err != nil {
+		t.Fatalf("cannot serialize retained sizes: %v", err)
+	}
+	reader, err := storage.NewDominatorsReadStorage(
+		storage.NewRamReadVolume(dominatorsVolume.Bytes()), dominatorsVolume.Len(),
+		storage.NewRamReadVolume(retainedVolume.Bytes()), retainedVolume.Len(),
+		storage.NewRamReadVolume(dominatedVolume.Bytes()), dominatedVolume.Len(),
+	)
+	if err != nil {
+		t.Fatalf("cannot create dominators reader: %v", err)
+	}
+	if err := reader.RestoreFrom(retainedSizes); err != nil {
+		t.Fatalf("cannot restore retained sizes: %v", err)
+	}
+	return parsedAccessor.WithDominators(reader)
+}
+
+func TestGetLeaks(t *testing.T) {
+	parsedAccessor := createParsedAccessor(leaksSample, t)
+	leaks, err := GetLeaks(parsedAccessor, 10)
+	if err != nil {
+		t.Fatalf("GetLeaks() error = %v", err)
+	}
+	// Holder is 16 bytes, Object[4] - 32, byte[100] - 120, byte[20] - 40
+	if leaks.TotalSize != 760 {
+		t.Errorf("TotalSize = %v, want 760", leaks.TotalSize)
+	}
+	if len(leaks.Suspects) != 2 {
+		t.Fatalf("len(Suspects) = %v, want 2: %+v", len(leaks.Suspects), leaks.Suspects)
+	}
+	tests := []struct {
+		suspect      Suspect
+		objectId     core.Identifier
+		instances    int
+		retained     int
+		accumulation core.Identifier
+		path         []core.Identifier
+	}{
+		{
+			suspect: leaks.Suspects[0], objectId: 1000, instances: 1, retained: 592,
+			accumulation: 1001, path: []core.Identifier{1000, 1001},
+		},
+		{
+			suspect: leaks.Suspects[1], objectId: 3000, instances: 3, retained: 168,
+			accumulation: 3000, path: []core.Identifier{3000},
+		},
+	}
+	for _, tt := range tests {
+		s := tt.suspect
+		if s.ObjectId != tt.objectId || s.ClassName != "Holder" || s.Instances != tt.instances || s.RetainedSize != tt.retained {
+			t.Errorf("suspect = %+v, want object %v with %v instances retaining %v", s, tt.objectId, tt.instances, tt.retained)
+		}
+		if s.AccumulationPoint.ObjectId != tt.accumulation {
+			t.Errorf("accumulation point of %v = %v, want %v", tt.objectId, s.AccumulationPoint.ObjectId, tt.accumulation)
+		}
+		var path []core.Identifier
+		for _, step := range s.Path.Steps {
+			path = append(path, step.ObjectId)
+		}
+		if !reflect.DeepEqual(path, tt.path) {
+			t.Errorf("path of %v = %v, want %v", tt.objectId, path, tt.path)
+		}
+	}
+}
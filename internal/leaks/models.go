@@ -0,0 +1,36 @@
+package leaks
+
+import (
+	"github.com/danielleontiev/neojhat/internal/core"
+	"github.com/danielleontiev/neojhat/internal/paths"
+)
+
+// Suspect is the single object or the group of objects of the same
+// class that retain too much memory. For the group ObjectId is the
+// biggest object of the group, accumulation point and path are
+// computed for it.
+type Suspect struct {
+	ObjectId          core.Identifier   `json:"objectId"`
+	ClassName         string            `json:"className"`
+	Instances         int               `json:"instances"`
+	RetainedSize      int               `json:"retainedSize"`
+	AccumulationPoint AccumulationPoint `json:"accumulationPoint"`
+	Path              paths.Path        `json:"path"`
+}
+
+// AccumulationPoint is the object where the retained memory
+// spreads among many children.
+type AccumulationPoint struct {
+	ObjectId     core.Identifier `json:"objectId"`
+	ClassName    string          `json:"className"`
+	RetainedSize int             `json:"retainedSize"`
+}
+
+// Leaks is the result of the analysis. TotalSize is the size
+// of all reachable objects, Threshold is the share of the heap
+// in percents the suspect retains at least.
+type Leaks struct {
+	TotalSize int       `json:"totalSize"`
+	Threshold int       `json:"threshold"`
+	Suspects  []Suspect `json:"suspects"`
+}
@@ -0,0 +1,273 @@
+package objects
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/danielleontiev/neojhat/internal/core"
+	"github.com/danielleontiev/neojhat/internal/dump"
+	"github.com/danielleontiev/neojhat/internal/format"
+	"github.com/danielleontiev/neojhat/internal/java"
+)
+
+// GetDiff compares the objects of the base heap dump with the objects
+// of the current one class by class. Classes are matched by name since
+// the identifiers of the same class differ from dump to dump. Retained
+// sizes are compared when both dumps have the dominator tree. When byId
+// is set, the objects are matched by identifiers too to tell how many of
+// them are new and how many are gone. It makes sense only for the dumps
+// of the same process when the objects are not moved in between, e.g.
+// by the collector that doesn't compact the heap. top limits the number
+// of classes to show.
+func GetDiff(base, current *dump.ParsedAccessor, filter Filter, top int, byId bool) (Diff, error) {
+	_, baseErr := base.Dominators()
+	_, currentErr := current.Dominators()
+	withRetained := baseErr == nil && currentErr == nil
+	sortBy := Count
+	if withRetained {
+		sortBy = Retained
+	}
+	baseObjects, err := GetObjects(base, sortBy, NoGroups, 0, filter, 0, "", "")
+	if err != nil {
+		return Diff{}, fmt.Errorf("cannot count objects of base dump: %w", err)
+	}
+	currentObjects, err := GetObjects(current, sortBy, NoGroups, 0, filter, 0, "", "")
+	if err != nil {
+		return Diff{}, fmt.Errorf("cannot count objects of current dump: %w", err)
+	}
+
+	diffs := make(map[string]*ClassDiff)
+	for _, item := range baseObjects.Items {
+		diff := classDiff(diffs, format.ClassName(item.Name))
+		diff.BaseCount += item.InstancesCount
+		diff.BaseSize += item.TotalSize
+		diff.BaseRetained += item.RetainedSize
+	}
+	for _, item := range currentObjects.Items {
+		diff := classDiff(diffs, format.ClassName(item.Name))
+		diff.Count += item.InstancesCount
+		diff.Size += item.TotalSize
+		diff.Retained += item.RetainedSize
+	}
+	if byId {
+		if err := diffIds(base, current, filter, diffs); err != nil {
+			return Diff{}, err
+		}
+	}
+
+	var items []ClassDiff
+	for _, diff := range diffs {
+		if diff.CountDelta() == 0 && diff.SizeDelta() == 0 && diff.RetainedDelta() == 0 && diff.NewCount == 0 && diff.GoneCount == 0 {
+			continue
+		}
+		items = append(items, *diff)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		left, right := items[i], items[j]
+		if left.SizeDelta() != right.SizeDelta() {
+			return left.SizeDelta() > right.SizeDelta()
+		}
+		if left.CountDelta() != right.CountDelta() {
+			return left.CountDelta() > right.CountDelta()
+		}
+		return left.Name < right.Name
+	})
+	return Diff{
+		Items:        items,
+		BaseCount:    baseObjects.FilteredCount,
+		Count:        currentObjects.FilteredCount,
+		BaseSize:     baseObjects.FilteredSize,
+		Size:         currentObjects.FilteredSize,
+		Filtered:     !filter.empty(),
+		WithRetained: withRetained,
+		ById:         byId,
+		Top:          top,
+		BaseLayout:   baseObjects.Layout,
+		Layout:       currentObjects.Layout,
+	}, nil
+}
+
+func classDiff(diffs map[string]*ClassDiff, name string) *ClassDiff {
+	diff, ok := diffs[name]
+	if !ok {
+		diff = &ClassDiff{Name: name}
+		diffs[name] = diff
+	}
+	return diff
+}
+
+// diffIds looks up every object of the current dump in the base one.
+// The objects not found there (or found with another class) are new,
+// the rest of the objects of the base dump are gone.
+func diffIds(base, current *dump.ParsedAccessor, filter Filter, diffs map[string]*ClassDiff) error {
+	baseObjects, err := newObjectIdentities(base)
+	if err != nil {
+		return err
+	}
+	currentObjects, err := newObjectIdentities(current)
+	if err != nil {
+		return err
+	}
+	keptCount := make(map[string]int)
+	keptSize := make(map[string]int)
+	compare := func(name string, size int, find func() (string, int, bool, error)) error {
+		if !filter.accept(name) {
+			return nil
+		}
+		baseName, baseSize, found, err := find()
+		if err != nil {
+			return err
+		}
+		if found && baseName == name {
+			keptCount[name]++
+			keptSize[name] += baseSize
+			return nil
+		}
+		diff := classDiff(diffs, name)
+		diff.NewCount++
+		diff.NewSize += size
+		return nil
+	}
+
+	err = current.ScanHprofGcInstanceDumps(func(header core.HprofGcClassDumpInstanceDumpHeader) error {
+		name, size, err := currentObjects.instance(header)
+		if err != nil {
+			return err
+		}
+		return compare(name, size, func() (string, int, bool, error) {
+			return baseObjects.findInstance(header.ObjectId)
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("cannot compare instances: %w", err)
+	}
+	err = current.ScanHprofGcObjArrays(func(header core.HprofGcObjArrayDumpHeader) error {
+		name, size, err := currentObjects.objArray(header)
+		if err != nil {
+			return err
+		}
+		return compare(name, size, func() (string, int, bool, error) {
+			return baseObjects.findObjArray(header.ArrayObjectId)
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("cannot compare object arrays: %w", err)
+	}
+	err = current.ScanHprofGcPrimArrays(func(header core.HprofGcPrimArrayDumpHeader) error {
+		name, size := currentObjects.primArray(header)
+		return compare(name, size, func() (string, int, bool, error) {
+			return baseObjects.findPrimArray(header.ArrayObjectId)
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("cannot compare primitive arrays: %w", err)
+	}
+
+	for name, diff := range diffs {
+		diff.GoneCount = diff.BaseCount - keptCount[name]
+		diff.GoneSize = diff.BaseSize - keptSize[name]
+	}
+	return nil
+}
+
+// objectIdentities tells the class names (in the printed form) and
+// the shallow sizes of the objects of one dump.
+type objectIdentities struct {
+	parsedAccessor *dump.ParsedAccessor
+	heap           *java.Heap
+	size           *core.SizeInfo
+	names          map[core.Identifier]string
+	instanceSizes  map[core.Identifier]int
+}
+
+func newObjectIdentities(parsedAccessor *dump.ParsedAccessor) (*objectIdentities, error) {
+	heap := java.NewHeap(parsedAccessor)
+	size, err := heap.SizeInfo("")
+	if err != nil {
+		return nil, err
+	}
+	return &objectIdentities{
+		parsedAccessor: parsedAccessor,
+		heap:           heap,
+		size:           size,
+		names:          make(map[core.Identifier]string),
+		instanceSizes:  make(map[core.Identifier]int),
+	}, nil
+}
+
+func (o *objectIdentities) className(classId core.Identifier, array bool) (string, error) {
+	if name, ok := o.names[classId]; ok {
+		return name, nil
+	}
+	loadClass, err := o.parsedAccessor.GetHprofLoadClassByClassObjectId(classId)
+	if err != nil {
+		return "", err
+	}
+	className, err := o.parsedAccessor.GetHprofUtf8(loadClass.ClassNameId)
+	if err != nil {
+		return "", err
+	}
+	name := className.Characters
+	if array {
+		name, _ = format.Signature(name)
+	}
+	name = format.ClassName(name)
+	o.names[classId] = name
+	return name, nil
+}
+
+func (o *objectIdentities) instance(header core.HprofGcClassDumpInstanceDumpHeader) (string, int, error) {
+	name, err := o.className(header.ClassObjectId, false)
+	if err != nil {
+		return "", 0, err
+	}
+	size, ok := o.instanceSizes[header.ClassObjectId]
+	if !ok {
+		if size, err = o.heap.InstanceSize(header.ClassObjectId, o.size); err != nil {
+			return "", 0, err
+		}
+		o.instanceSizes[header.ClassObjectId] = size
+	}
+	return name, size, nil
+}
+
+func (o *objectIdentities) objArray(header core.HprofGcObjArrayDumpHeader) (string, int, error) {
+	name, err := o.className(header.ArrayClassId, true)
+	if err != nil {
+		return "", 0, err
+	}
+	return name, o.size.OfArray(core.Object, int(header.NumberOfElements)), nil
+}
+
+func (o *objectIdentities) primArray(header core.HprofGcPrimArrayDumpHeader) (string, int) {
+	return header.ElementType.String() + "[]", o.size.OfArray(header.ElementType, int(header.NumberOfElements))
+}
+
+// findInstance looks up the instance by identifier, the lookup error
+// means there is no such instance in the dump.
+func (o *objectIdentities) findInstance(objectId core.Identifier) (string, int, bool, error) {
+	header, err := o.parsedAccessor.GetHprofGcInstanceDump(objectId)
+	if err != nil {
+		return "", 0, false, nil
+	}
+	name, size, err := o.instance(header)
+	return name, size, true, err
+}
+
+func (o *objectIdentities) findObjArray(arrayObjectId core.Identifier) (string, int, bool, error) {
+	header, err := o.parsedAccessor.GetHprofGcObjArray(arrayObjectId)
+	if err != nil {
+		return "", 0, false, nil
+	}
+	name, size, err := o.objArray(header)
+	return name, size, true, err
+}
+
+func (o *objectIdentities) findPrimArray(arrayObjectId core.Identifier) (string, int, bool, error) {
+	header, err := o.parsedAccessor.GetHprofGcPrimArray(arrayObjectId)
+	if err != nil {
+		return "", 0, false, nil
+	}
+	name, size := o.primArray(header)
+	return name, size, true, nil
+}
@@ -0,0 +1,107 @@
+package objects
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/danielleontiev/neojhat/internal/core"
+	td "github.com/danielleontiev/neojhat/internal/testdump"
+)
+
+var diffClasses = [][]byte{
+	td.Utf8(1, "java/lang/Object"),
+	td.Utf8(2, "java/util/HashMap"),
+	td.Utf8(3, "java/util/TreeMap"),
+	td.LoadClass(1, 100, 1),
+	td.LoadClass(2, 101, 2),
+	td.LoadClass(3, 102, 3),
+}
+
+var diffBase = td.Dump(
+	diffClasses,
+	td.ClassDump(100, 0, 0, nil, nil),
+	td.ClassDump(101, 100, 0, nil, nil),
+	td.ClassDump(102, 100, 0, nil, nil),
+	td.InstanceDump(200, 101),
+	td.InstanceDump(201, 101),
+	td.InstanceDump(203, 102),
+	td.PrimArrayDump(300, core.Byte, 3, []byte("abc")),
+)
+
+// 201 is the TreeMap now, so it's counted as new
+// TreeMap and gone HashMap when comparing by ids
+var diffCurrent = td.Dump(
+	diffClasses,
+	td.ClassDump(100, 0, 0, nil, nil),
+	td.ClassDump(101, 100, 0, nil, nil),
+	td.ClassDump(102, 100, 0, nil, nil),
+	td.InstanceDump(200, 101),
+	td.InstanceDump(201, 102),
+	td.InstanceDump(202, 101),
+	td.InstanceDump(205, 101),
+	td.PrimArrayDump(300, core.Byte, 3, []byte("abc")),
+	td.PrimArrayDump(301, core.Byte, 5, []byte("abcde")),
+)
+
+func TestGetDiff(t *testing.T) {
+	base, err := td.NewParsedAccessor(diffBase, false)
+	if err != nil {
+		t.Fatalf("cannot parse base: %v", err)
+	}
+	current, err := td.NewParsedAccessor(diffCurrent, false)
+	if err != nil {
+		t.Fatalf("cannot parse current: %v", err)
+	}
+	tests := []struct {
+		name    string
+		byId    bool
+		include string
+		want    []ClassDiff
+	}{
+		{
+			name: "by class name",
+			want: []ClassDiff{
+				{Name: "byte[]", BaseCount: 1, Count: 2, BaseSize: 24, Size: 48},
+				{Name: "java.util.HashMap", BaseCount: 2, Count: 3, BaseSize: 32, Size: 48},
+			},
+		},
+		{
+			name: "by id",
+			byId: true,
+			want: []ClassDiff{
+				{Name: "byte[]", BaseCount: 1, Count: 2, BaseSize: 24, Size: 48, NewCount: 1, NewSize: 24},
+				{Name: "java.util.HashMap", BaseCount: 2, Count: 3, BaseSize: 32, Size: 48, NewCount: 2, NewSize: 32, GoneCount: 1, GoneSize: 16},
+				{Name: "java.util.TreeMap", BaseCount: 1, Count: 1, BaseSize: 16, Size: 16, NewCount: 1, NewSize: 16, GoneCount: 1, GoneSize: 16},
+			},
+		},
+		{
+			name:    "filtered",
+			byId:    true,
+			include: "java.util.*",
+			want: []ClassDiff{
+				{Name: "java.util.HashMap", BaseCount: 2, Count: 3, BaseSize: 32, Size: 48, NewCount: 2, NewSize: 32, GoneCount: 1, GoneSize: 16},
+				{Name: "java.util.TreeMap", BaseCount: 1, Count: 1, BaseSize: 16, Size: 16, NewCount: 1, NewSize: 16, GoneCount: 1, GoneSize: 16},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var filter Filter
+			if tt.include != "" {
+				if err := filter.Include.Set(tt.include); err != nil {
+					t.Fatalf("Set(%q) error = %v", tt.include, err)
+				}
+			}
+			diff, err := GetDiff(base, current, filter, 0, tt.byId)
+			if err != nil {
+				t.Fatalf("GetDiff() error = %v", err)
+			}
+			if !reflect.DeepEqual(diff.Items, tt.want) {
+				t.Errorf("GetDiff() = %+v, want %+v", diff.Items, tt.want)
+			}
+			if diff.WithRetained {
+				t.Errorf("retained sizes are compared without dominator trees")
+			}
+		})
+	}
+}
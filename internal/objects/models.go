@@ -1,12 +1,19 @@
 package objects
 
-import "fmt"
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/danielleontiev/neojhat/internal/core"
+)
 
 type SortBy int
 
 const (
 	Count SortBy = iota
 	Size
+	Retained
 )
 
 func (s *SortBy) String() string {
@@ -15,6 +22,8 @@ func (s *SortBy) String() string {
 		return "count"
 	case Size:
 		return "size"
+	case Retained:
+		return "retained"
 	}
 	return "unknown"
 }
@@ -27,22 +36,243 @@ func (s *SortBy) Set(value string) error {
 	case "size":
 		*s = Size
 		return nil
+	case "retained":
+		*s = Retained
+		return nil
 	case "":
 		*s = Count
 		return nil
 	}
-	return fmt.Errorf("Use \"count\" or \"size\" instead")
+	return fmt.Errorf("Use \"count\", \"size\" or \"retained\" instead")
+}
+
+func (s SortBy) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// GroupBy is the way classes are rolled up into groups.
+type GroupBy int
+
+const (
+	NoGroups GroupBy = iota
+	Package
+	ClassLoader
+	Superclass
+)
+
+func (g *GroupBy) String() string {
+	switch *g {
+	case NoGroups:
+		return ""
+	case Package:
+		return "package"
+	case ClassLoader:
+		return "classloader"
+	case Superclass:
+		return "superclass"
+	}
+	return "unknown"
+}
+
+func (g *GroupBy) Set(value string) error {
+	switch value {
+	case "package":
+		*g = Package
+		return nil
+	case "classloader":
+		*g = ClassLoader
+		return nil
+	case "superclass":
+		*g = Superclass
+		return nil
+	case "":
+		*g = NoGroups
+		return nil
+	}
+	return fmt.Errorf("Use \"package\", \"classloader\" or \"superclass\" instead")
+}
+
+func (g GroupBy) MarshalText() ([]byte, error) {
+	return []byte(g.String()), nil
+}
+
+// Patterns are the patterns of class names. The pattern in slashes is
+// the regular expression, e.g. /^java\.util\..*Map$/, otherwise it's
+// glob matching the whole name, where * matches any characters and ?
+// matches one character, e.g. java.util.* or byte[]. Names are
+// matched in the form they are printed, i.e. java.lang.String[].
+type Patterns []*regexp.Regexp
+
+func (p *Patterns) String() string {
+	var patterns []string
+	for _, re := range *p {
+		patterns = append(patterns, re.String())
+	}
+	return strings.Join(patterns, " ")
+}
+
+// Set adds the pattern, so the flag could be repeated.
+func (p *Patterns) Set(value string) error {
+	expr := globToRegexp(value)
+	if len(value) > 1 && strings.HasPrefix(value, "/") && strings.HasSuffix(value, "/") {
+		expr = value[1 : len(value)-1]
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return fmt.Errorf("invalid pattern '%s': %w", value, err)
+	}
+	*p = append(*p, re)
+	return nil
+}
+
+func (p Patterns) match(name string) bool {
+	for _, re := range p {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func globToRegexp(glob string) string {
+	var expr strings.Builder
+	expr.WriteString("^")
+	for _, r := range glob {
+		switch r {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString("$")
+	return expr.String()
+}
+
+// Filter keeps the classes that match any of Include patterns (all
+// the classes if there are no such patterns) and none of Exclude.
+type Filter struct {
+	Include Patterns
+	Exclude Patterns
+}
+
+func (f Filter) empty() bool {
+	return len(f.Include) == 0 && len(f.Exclude) == 0
+}
+
+func (f Filter) accept(name string) bool {
+	if len(f.Include) > 0 && !f.Include.match(name) {
+		return false
+	}
+	return !f.Exclude.match(name)
 }
 
+// ObjectItem is the group of objects of the same class. RetainedSize
+// is filled only when sorting by retained size since it requires
+// the dominator tree.
 type ObjectItem struct {
-	Name           string
-	TotalSize      int
-	InstancesCount int
+	Name           string `json:"name"`
+	TotalSize      int    `json:"totalSize"`
+	InstancesCount int    `json:"instancesCount"`
+	RetainedSize   int    `json:"retainedSize,omitempty"`
+
+	// class object of instances and arrays, 0 for primitive arrays
+	classId core.Identifier
 }
 
+// ClassId is the class object of the instances or arrays of the item,
+// it's 0 for primitive arrays which have no class in the dump.
+func (i ObjectItem) ClassId() core.Identifier {
+	return i.classId
+}
+
+// ObjectGroup sums up the classes of the group, the classes
+// themselves are listed in Items.
+type ObjectGroup struct {
+	ObjectItem
+	Items []ObjectItem `json:"items"`
+}
+
+// HeapItem has the totals of one of the heaps of Android heap dump.
+type HeapItem struct {
+	Name       string `json:"name"`
+	TotalSize  int    `json:"totalSize"`
+	TotalCount int    `json:"totalCount"`
+}
+
+// Objects lists the groups of objects. Heap is the name of the
+// heap the objects are limited to, empty for the whole dump.
+// Sizes are calculated for Layout. Groups are set unless GroupBy
+// is NoGroups, they overlap when grouping by superclass since the
+// class belongs to the groups of all its superclasses. When the
+// classes are Filtered, TotalSize and TotalCount are still of the
+// whole heap (or of the Heap) and FilteredSize and FilteredCount
+// are of the classes left. Top limits the number of classes (or
+// groups) to show, 0 is for all.
 type Objects struct {
-	Items      []ObjectItem
-	TotalSize  int
-	TotalCount int
-	SortBy     SortBy
+	Items         []ObjectItem  `json:"items"`
+	GroupBy       GroupBy       `json:"groupBy,omitempty"`
+	Groups        []ObjectGroup `json:"groups,omitempty"`
+	TotalSize     int           `json:"totalSize"`
+	TotalCount    int           `json:"totalCount"`
+	Filtered      bool          `json:"filtered"`
+	FilteredSize  int           `json:"filteredSize"`
+	FilteredCount int           `json:"filteredCount"`
+	Top           int           `json:"top"`
+	SortBy        SortBy        `json:"sortBy"`
+	Heap          string        `json:"heap,omitempty"`
+	Heaps         []HeapItem    `json:"heaps,omitempty"`
+	Layout        string        `json:"layout"`
+}
+
+// ClassDiff is the change of the objects of the class between two
+// heap dumps, the numbers of the base dump are prefixed with Base.
+// Retained sizes are set only when both dumps have the dominator
+// tree. New and Gone are set only when comparing by identifiers:
+// the object is new if the base dump has no object of the same class
+// with the same identifier and it's gone if the other dump has none.
+type ClassDiff struct {
+	Name         string `json:"name"`
+	BaseCount    int    `json:"baseCount"`
+	Count        int    `json:"count"`
+	BaseSize     int    `json:"baseSize"`
+	Size         int    `json:"size"`
+	BaseRetained int    `json:"baseRetained"`
+	Retained     int    `json:"retained"`
+	NewCount     int    `json:"newCount"`
+	NewSize      int    `json:"newSize"`
+	GoneCount    int    `json:"goneCount"`
+	GoneSize     int    `json:"goneSize"`
+}
+
+func (c ClassDiff) CountDelta() int {
+	return c.Count - c.BaseCount
+}
+
+func (c ClassDiff) SizeDelta() int {
+	return c.Size - c.BaseSize
+}
+
+func (c ClassDiff) RetainedDelta() int {
+	return c.Retained - c.BaseRetained
+}
+
+// Diff lists the classes which objects changed between the base heap
+// dump and the current one, sorted by growth of the shallow size. The
+// totals are of the classes accepted by the filter. Sizes of every
+// dump are calculated for its own layout.
+type Diff struct {
+	Items        []ClassDiff `json:"items"`
+	BaseCount    int         `json:"baseCount"`
+	Count        int         `json:"count"`
+	BaseSize     int         `json:"baseSize"`
+	Size         int         `json:"size"`
+	Filtered     bool        `json:"filtered"`
+	WithRetained bool        `json:"withRetained"`
+	ById         bool        `json:"byId"`
+	Top          int         `json:"top"`
+	BaseLayout   string      `json:"baseLayout"`
+	Layout       string      `json:"layout"`
 }
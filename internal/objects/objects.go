@@ -1,68 +1,279 @@
 package objects
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+
 	"github.com/danielleontiev/neojhat/internal/core"
 	"github.com/danielleontiev/neojhat/internal/dump"
 	"github.com/danielleontiev/neojhat/internal/format"
+	"github.com/danielleontiev/neojhat/internal/java"
+	"github.com/danielleontiev/neojhat/internal/storage"
 )
 
-func GetObjects(parserAccessor *dump.ParsedAccessor, sortBy SortBy) (Objects, error) {
-	meta := parserAccessor.MetaStorage
-	sizeInfo := core.NewSizeInfo(parserAccessor.IdSize)
+// ErrLayoutWithRetained is returned when the heap layout is changed
+// for sorting by retained size, retained sizes are calculated for the
+// detected layout.
+var ErrLayoutWithRetained = errors.New("heap layout cannot be changed when sorting by retained size")
+
+// GetObjects groups objects of the heap dump by class. For Android
+// heap dumps the totals of every heap are given too and heapName
+// limits the objects to the given heap. Retained sizes are
+// calculated for the whole dump, they are not split by heap. Shallow
+// sizes are calculated for the detected heap layout changed by
+// layoutOptions, see core.HeapLayout.Apply. Retained sizes are stored
+// in the index for the detected layout, so the layout cannot be changed
+// when sorting by retained size. The classes are filtered
+// by filter and then rolled up into groups by groupBy, packageDepth is
+// the number of leading parts of the package name kept when grouping
+// by package. top limits the number of classes or groups to show.
+func GetObjects(parserAccessor *dump.ParsedAccessor, sortBy SortBy, groupBy GroupBy, packageDepth int, filter Filter, top int, heapName string, layoutOptions string) (Objects, error) {
+	if sortBy == Retained && layoutOptions != "" {
+		return Objects{}, ErrLayoutWithRetained
+	}
+	size, err := java.NewHeap(parserAccessor).SizeInfo(layoutOptions)
+	if err != nil {
+		return Objects{}, err
+	}
+
+	var retainedSizes storage.RetainedSizes
+	if sortBy == Retained {
+		dominators, err := parserAccessor.Dominators()
+		if err != nil {
+			return Objects{}, err
+		}
+		retainedSizes = dominators.RetainedSizes
+	}
 
+	var heaps []HeapItem
+	counters := parserAccessor.Counters
+	found := heapName == ""
+	var names []string
+	for _, heap := range parserAccessor.ListHeaps() {
+		_, totalSize, totalCount, err := countObjects(parserAccessor, size, heap.Counters, retainedSizes)
+		if err != nil {
+			return Objects{}, err
+		}
+		heaps = append(heaps, HeapItem{Name: heap.Name, TotalSize: totalSize, TotalCount: totalCount})
+		names = append(names, heap.Name)
+		if heap.Name == heapName {
+			counters = heap.Counters
+			found = true
+		}
+	}
+	if !found {
+		if len(names) == 0 {
+			return Objects{}, fmt.Errorf("heap %s not found, the dump has no heaps", heapName)
+		}
+		return Objects{}, fmt.Errorf("heap %s not found, available heaps: %s", heapName, strings.Join(names, ", "))
+	}
+
+	items, totalSize, totalCount, err := countObjects(parserAccessor, size, counters, retainedSizes)
+	if err != nil {
+		return Objects{}, err
+	}
+	filteredSize, filteredCount := totalSize, totalCount
+	if !filter.empty() {
+		items, filteredSize, filteredCount = filterItems(items, filter)
+	}
+	var groups []ObjectGroup
+	if groupBy != NoGroups {
+		if groups, err = groupObjects(parserAccessor, items, groupBy, packageDepth); err != nil {
+			return Objects{}, err
+		}
+	}
+	return Objects{
+		Items:         items,
+		GroupBy:       groupBy,
+		Groups:        groups,
+		TotalSize:     totalSize,
+		TotalCount:    totalCount,
+		Filtered:      !filter.empty(),
+		FilteredSize:  filteredSize,
+		FilteredCount: filteredCount,
+		Top:           top,
+		SortBy:        sortBy,
+		Heap:          heapName,
+		Heaps:         heaps,
+		Layout:        size.Layout().String(),
+	}, nil
+}
+
+func countObjects(parserAccessor *dump.ParsedAccessor, sizeInfo *core.SizeInfo, counters storage.Counters, retainedSizes storage.RetainedSizes) ([]ObjectItem, int, int, error) {
+	heap := java.NewHeap(parserAccessor)
 	var totalSize, totalCount int
 	var items []ObjectItem
-	for arrType, instancesCount := range meta.Counters.PrimArraysCount {
-		elementsCount := meta.Counters.PrimArrayElementsCount[arrType]
+	for arrType, instancesCount := range counters.PrimArraysCount {
+		elementsCount := counters.PrimArrayElementsCount[arrType]
 		name := arrType.String() + "[]"
-		size := sizeInfo.OfType(arrType) * elementsCount
+		size := sizeInfo.OfArrays(arrType, instancesCount, elementsCount, counters.PrimArrayLengths[arrType])
 		totalSize += size
 		totalCount += instancesCount
 		items = append(items,
-			ObjectItem{Name: name, InstancesCount: instancesCount, TotalSize: size})
+			ObjectItem{Name: name, InstancesCount: instancesCount, TotalSize: size, RetainedSize: retainedSizes.PrimArrays[arrType]})
 	}
-	for classId, instancesCount := range meta.Counters.ObjArraysCount {
-		elementsCount := meta.Counters.ObjArrayElementsCount[classId]
+	for classId, instancesCount := range counters.ObjArraysCount {
+		elementsCount := counters.ObjArrayElementsCount[classId]
 		loadClass, err := parserAccessor.GetHprofLoadClassByClassObjectId(classId)
 		if err != nil {
-			return Objects{}, err
+			return nil, 0, 0, err
 		}
 		className, err := parserAccessor.GetHprofUtf8(loadClass.ClassNameId)
 		if err != nil {
-			return Objects{}, err
+			return nil, 0, 0, err
 		}
 		name, _ := format.Signature(className.Characters)
-		size := sizeInfo.OfType(core.Object) * elementsCount
+		size := sizeInfo.OfArrays(core.Object, instancesCount, elementsCount, counters.ObjArrayLengths[classId])
 		totalSize += size
 		totalCount += instancesCount
 		items = append(items,
-			ObjectItem{Name: name, InstancesCount: instancesCount, TotalSize: size})
+			ObjectItem{Name: name, InstancesCount: instancesCount, TotalSize: size, RetainedSize: retainedSizes.Classes[classId], classId: classId})
 	}
-	for classId, instancesCount := range meta.Counters.InstancesCount {
+	for classId, instancesCount := range counters.InstancesCount {
 		loadClass, err := parserAccessor.GetHprofLoadClassByClassObjectId(classId)
 		if err != nil {
-			return Objects{}, err
+			return nil, 0, 0, err
 		}
 		className, err := parserAccessor.GetHprofUtf8(loadClass.ClassNameId)
 		if err != nil {
-			return Objects{}, err
+			return nil, 0, 0, err
 		}
-		classDump, err := parserAccessor.GetHprofGcClassDump(classId)
+		instanceSize, err := heap.InstanceSize(classId, sizeInfo)
 		if err != nil {
-			return Objects{}, err
+			return nil, 0, 0, err
 		}
 		name := className.Characters
-		size := int(classDump.InstanceSize) * instancesCount
+		size := instanceSize * instancesCount
 		totalSize += size
 		totalCount += instancesCount
 		items = append(items,
-			ObjectItem{Name: name, InstancesCount: instancesCount, TotalSize: size})
+			ObjectItem{Name: name, InstancesCount: instancesCount, TotalSize: size, RetainedSize: retainedSizes.Classes[classId], classId: classId})
 	}
+	return items, totalSize, totalCount, nil
+}
 
-	return Objects{
-		Items:      items,
-		TotalSize:  totalSize,
-		TotalCount: totalCount,
-		SortBy:     sortBy,
-	}, nil
+// filterItems keeps the classes accepted by the filter, the names are
+// matched in the form they are printed, e.g. java.lang.String[].
+func filterItems(items []ObjectItem, filter Filter) ([]ObjectItem, int, int) {
+	var filtered []ObjectItem
+	var totalSize, totalCount int
+	for _, item := range items {
+		if !filter.accept(format.ClassName(item.Name)) {
+			continue
+		}
+		filtered = append(filtered, item)
+		totalSize += item.TotalSize
+		totalCount += item.InstancesCount
+	}
+	return filtered, totalSize, totalCount
+}
+
+const (
+	primitiveArraysGroup = "(primitive arrays)"
+	defaultPackageGroup  = "(default package)"
+	bootstrapLoaderGroup = "(bootstrap)"
+)
+
+// groupObjects rolls up the classes into groups. Every class belongs
+// to exactly one group except for grouping by superclass where the
+// class is counted in the groups of all its superclasses, so all
+// the maps are found in the java.util.AbstractMap group no matter
+// how deep in the hierarchy they are.
+func groupObjects(parserAccessor *dump.ParsedAccessor, items []ObjectItem, groupBy GroupBy, packageDepth int) ([]ObjectGroup, error) {
+	heap := java.NewHeap(parserAccessor)
+	groups := make(map[string]*ObjectGroup)
+	for _, item := range items {
+		var names []string
+		switch groupBy {
+		case Package:
+			names = []string{packageOf(item, packageDepth)}
+		case ClassLoader:
+			name, err := classLoaderOf(parserAccessor, heap, item)
+			if err != nil {
+				return nil, err
+			}
+			names = []string{name}
+		case Superclass:
+			superclasses, err := superclassesOf(heap, item)
+			if err != nil {
+				return nil, err
+			}
+			names = superclasses
+		}
+		for _, name := range names {
+			group, ok := groups[name]
+			if !ok {
+				group = &ObjectGroup{ObjectItem: ObjectItem{Name: name}}
+				groups[name] = group
+			}
+			group.TotalSize += item.TotalSize
+			group.InstancesCount += item.InstancesCount
+			group.RetainedSize += item.RetainedSize
+			group.Items = append(group.Items, item)
+		}
+	}
+	result := make([]ObjectGroup, 0, len(groups))
+	for _, group := range groups {
+		result = append(result, *group)
+	}
+	return result, nil
+}
+
+// packageOf is the package of the class (of the element class for
+// arrays) cut to the given number of parts, e.g. java.util for
+// java.util.concurrent.ConcurrentHashMap with depth 2.
+func packageOf(item ObjectItem, depth int) string {
+	if item.classId == 0 {
+		return primitiveArraysGroup
+	}
+	name := strings.TrimRight(format.ClassName(item.Name), "[]")
+	i := strings.LastIndex(name, ".")
+	if i < 0 {
+		return defaultPackageGroup
+	}
+	parts := strings.Split(name[:i], ".")
+	if len(parts) > depth {
+		parts = parts[:depth]
+	}
+	return strings.Join(parts, ".")
+}
+
+// classLoaderOf is the class name and the identifier of the class loader
+// object which loaded the class. Primitive arrays and the classes of the
+// JDK are loaded by the bootstrap loader which has no object.
+func classLoaderOf(parserAccessor *dump.ParsedAccessor, heap *java.Heap, item ObjectItem) (string, error) {
+	if item.classId == 0 {
+		return bootstrapLoaderGroup, nil
+	}
+	classDump, err := parserAccessor.GetHprofGcClassDump(item.classId)
+	if err != nil {
+		return "", err
+	}
+	loaderId := classDump.ClassloaderObjectId
+	if loaderId == 0 {
+		return bootstrapLoaderGroup, nil
+	}
+	name, err := heap.ObjectTypeName(loaderId)
+	if err != nil {
+		return "", fmt.Errorf("cannot read class loader of %s: %w", item.Name, err)
+	}
+	return fmt.Sprintf("%s@%s", format.ClassName(name), format.ObjectId(uint64(loaderId))), nil
+}
+
+// superclassesOf lists all the superclasses of the class up to
+// java.lang.Object which is the only superclass of arrays.
+func superclassesOf(heap *java.Heap, item ObjectItem) ([]string, error) {
+	if item.classId == 0 {
+		return []string{"java.lang.Object"}, nil
+	}
+	class, err := heap.ParseClass(item.classId)
+	if err != nil {
+		return nil, err
+	}
+	var superclasses []string
+	for c := class.Superclass; c != nil; c = c.Superclass {
+		superclasses = append(superclasses, format.ClassName(c.Name))
+	}
+	return superclasses, nil
 }
@@ -0,0 +1,219 @@
+package objects
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/danielleontiev/neojhat/internal/core"
+	td "github.com/danielleontiev/neojhat/internal/testdump"
+)
+
+// LinkedHashMap → HashMap → AbstractMap → Object
+// TreeMap → AbstractMap
+// com.example.app.Foo is loaded by com.example.Loader (900)
+var groupsSample = td.Dump(
+	[][]byte{
+		td.Utf8(1, "java/lang/Object"),
+		td.Utf8(2, "java/util/AbstractMap"),
+		td.Utf8(3, "java/util/HashMap"),
+		td.Utf8(4, "java/util/LinkedHashMap"),
+		td.Utf8(5, "java/util/TreeMap"),
+		td.Utf8(6, "com/example/app/Foo"),
+		td.Utf8(7, "com/example/Loader"),
+		td.Utf8(8, "[Ljava/lang/String;"),
+		td.LoadClass(1, 100, 1),
+		td.LoadClass(2, 101, 2),
+		td.LoadClass(3, 102, 3),
+		td.LoadClass(4, 103, 4),
+		td.LoadClass(5, 104, 5),
+		td.LoadClass(6, 105, 6),
+		td.LoadClass(7, 106, 7),
+		td.LoadClass(8, 107, 8),
+	},
+	td.ClassDump(100, 0, 0, nil, nil),
+	td.ClassDump(101, 100, 0, nil, nil),
+	td.ClassDump(102, 101, 0, nil, nil),
+	td.ClassDump(103, 102, 0, nil, nil),
+	td.ClassDump(104, 101, 0, nil, nil),
+	td.LoadedClassDump(105, 100, 900, 0, nil, nil),
+	td.ClassDump(106, 100, 0, nil, nil),
+	td.ClassDump(107, 100, 0, nil, nil),
+	td.InstanceDump(200, 102),
+	td.InstanceDump(201, 102),
+	td.InstanceDump(202, 103),
+	td.InstanceDump(203, 104),
+	td.InstanceDump(204, 105),
+	td.InstanceDump(900, 106),
+	td.ObjArrayDump(301, 107, 0, 0),
+	td.PrimArrayDump(300, core.Byte, 3, []byte("abc")),
+)
+
+func TestGetObjects_Groups(t *testing.T) {
+	parsedAccessor, err := td.NewParsedAccessor(groupsSample, false)
+	if err != nil {
+		t.Fatalf("cannot parse sample: %v", err)
+	}
+	tests := []struct {
+		name    string
+		groupBy GroupBy
+		depth   int
+		want    map[string]int
+	}{
+		{
+			name:    "package",
+			groupBy: Package,
+			depth:   2,
+			want:    map[string]int{"java.util": 4, "java.lang": 1, "com.example": 2, "(primitive arrays)": 1},
+		},
+		{
+			name:    "deep package",
+			groupBy: Package,
+			depth:   3,
+			want:    map[string]int{"java.util": 4, "java.lang": 1, "com.example.app": 1, "com.example": 1, "(primitive arrays)": 1},
+		},
+		{
+			name:    "class loader",
+			groupBy: ClassLoader,
+			want:    map[string]int{"(bootstrap)": 7, "com.example.Loader@0x384": 1},
+		},
+		{
+			name:    "superclass",
+			groupBy: Superclass,
+			want:    map[string]int{"java.lang.Object": 8, "java.util.AbstractMap": 4, "java.util.HashMap": 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects, err := GetObjects(parsedAccessor, Count, tt.groupBy, tt.depth, Filter{}, 0, "", "")
+			if err != nil {
+				t.Fatalf("GetObjects() error = %v", err)
+			}
+			got := make(map[string]int)
+			for _, group := range objects.Groups {
+				got[group.Name] = group.InstancesCount
+				var members int
+				for _, item := range group.Items {
+					members += item.InstancesCount
+				}
+				if members != group.InstancesCount {
+					t.Errorf("group %s has %v instances, members have %v", group.Name, group.InstancesCount, members)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("groups = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetObjects_SuperclassMembers(t *testing.T) {
+	parsedAccessor, err := td.NewParsedAccessor(groupsSample, false)
+	if err != nil {
+		t.Fatalf("cannot parse sample: %v", err)
+	}
+	objects, err := GetObjects(parsedAccessor, Count, Superclass, 2, Filter{}, 0, "", "")
+	if err != nil {
+		t.Fatalf("GetObjects() error = %v", err)
+	}
+	for _, group := range objects.Groups {
+		if group.Name != "java.util.AbstractMap" {
+			continue
+		}
+		var members []string
+		for _, item := range group.Items {
+			members = append(members, item.Name)
+		}
+		sort.Strings(members)
+		if want := []string{"java/util/HashMap", "java/util/LinkedHashMap", "java/util/TreeMap"}; !reflect.DeepEqual(members, want) {
+			t.Errorf("members of AbstractMap = %v, want %v", members, want)
+		}
+		return
+	}
+	t.Errorf("AbstractMap group not found")
+}
+
+func TestGetObjects_Filter(t *testing.T) {
+	parsedAccessor, err := td.NewParsedAccessor(groupsSample, false)
+	if err != nil {
+		t.Fatalf("cannot parse sample: %v", err)
+	}
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		want    []string
+	}{
+		{
+			name:    "glob",
+			include: []string{"java.util.*"},
+			exclude: []string{"*Linked*"},
+			want:    []string{"java/util/HashMap", "java/util/TreeMap"},
+		},
+		{
+			name:    "arrays",
+			include: []string{"byte[]", "java.lang.String[]"},
+			want:    []string{"byte[]", "java.lang.String[]"},
+		},
+		{
+			name:    "regex",
+			include: []string{`/\.(Tree|Linked)/`},
+			want:    []string{"java/util/LinkedHashMap", "java/util/TreeMap"},
+		},
+		{
+			name:    "exclude only",
+			exclude: []string{"java.*", "com.*"},
+			want:    []string{"byte[]"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var filter Filter
+			for _, p := range tt.include {
+				if err := filter.Include.Set(p); err != nil {
+					t.Fatalf("Set(%q) error = %v", p, err)
+				}
+			}
+			for _, p := range tt.exclude {
+				if err := filter.Exclude.Set(p); err != nil {
+					t.Fatalf("Set(%q) error = %v", p, err)
+				}
+			}
+			objects, err := GetObjects(parsedAccessor, Count, NoGroups, 2, filter, 0, "", "")
+			if err != nil {
+				t.Fatalf("GetObjects() error = %v", err)
+			}
+			var names []string
+			var size, count int
+			for _, item := range objects.Items {
+				names = append(names, item.Name)
+				size += item.TotalSize
+				count += item.InstancesCount
+			}
+			sort.Strings(names)
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("classes = %v, want %v", names, tt.want)
+			}
+			if !objects.Filtered || objects.FilteredSize != size || objects.FilteredCount != count {
+				t.Errorf("filtered totals = (%v, %v), want (%v, %v)", objects.FilteredSize, objects.FilteredCount, size, count)
+			}
+			if objects.TotalCount != 8 {
+				t.Errorf("TotalCount = %v, want 8", objects.TotalCount)
+			}
+		})
+	}
+}
+
+func TestGetObjects_RetainedLayout(t *testing.T) {
+	parsedAccessor, err := td.NewParsedAccessor(groupsSample, false)
+	if err != nil {
+		t.Fatalf("cannot parse sample: %v", err)
+	}
+	if _, err := GetObjects(parsedAccessor, Retained, NoGroups, 0, Filter{}, 0, "", "-XX:-UseCompressedOops"); !errors.Is(err, ErrLayoutWithRetained) {
+		t.Errorf("GetObjects() sorted by retained size with changed layout error = %v, want %v", err, ErrLayoutWithRetained)
+	}
+	if _, err := GetObjects(parsedAccessor, Size, NoGroups, 0, Filter{}, 0, "", "-XX:-UseCompressedOops"); err != nil {
+		t.Errorf("GetObjects() sorted by size with changed layout error = %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package output
+
+import (
+	_ "embed"
+
+	"html/template"
+	"io"
+
+	"github.com/danielleontiev/neojhat/internal/core"
+	"github.com/danielleontiev/neojhat/internal/format"
+	"github.com/danielleontiev/neojhat/internal/threads"
+)
+
+// Page is the page of the web UI served by serve command. Unlike
+// the static HTML output, the cells of its tables are links to
+// other pages when Href is set.
+type Page struct {
+	Summary  []PageSummary
+	Sections []PageSection
+}
+
+// PageSummary is the row of the table at the top of the page.
+type PageSummary struct {
+	Key string
+	Val Cell
+}
+
+// PageSection is the titled table of the page. Links are shown above
+// the table (e.g. sorting) and Pages - below it (e.g. next page).
+type PageSection struct {
+	Title   string
+	Note    string
+	Links   []Cell
+	Headers []string
+	Rows    [][]Cell
+	Pages   []Cell
+}
+
+// Cell is the text of the table cell, it's the link when Href is set.
+type Cell struct {
+	Text string
+	Href string
+}
+
+var (
+	//go:embed templates/browse.html
+	browseHtml string
+)
+
+// BrowseHtml prints the page of the web UI
+func BrowseHtml(title string, page Page, destination io.Writer) error {
+	coreTemplate, err := template.New("core").Parse(coreHtml)
+	if err != nil {
+		return err
+	}
+	browseTemplate, err := coreTemplate.Parse(browseHtml)
+	if err != nil {
+		return err
+	}
+	return browseTemplate.Execute(destination, data{
+		Title:   title,
+		Favicon: faviconBase64,
+		Payload: page,
+	})
+}
+
+// ThreadsPage lists the stacks of all threads with their local
+// variables, objectHref is the link to the page of the variable.
+func ThreadsPage(threadDump threads.ThreadDump, objectHref func(core.Identifier) string) Page {
+	var page Page
+	for _, stackTrace := range getSortedStackTraces(threadDump) {
+		section := PageSection{
+			Title:   createPrettyThread(stackTrace),
+			Headers: []string{"Frame", "Local Variable"},
+		}
+		if len(stackTrace.Frames) == 0 {
+			section.Note = "No frames"
+		}
+		for _, frame := range stackTrace.Frames {
+			section.Rows = append(section.Rows, []Cell{{Text: createPrettyFrame(frame)}, {}})
+			for _, local := range frame.LocalFrames {
+				section.Rows = append(section.Rows, []Cell{{}, {
+					Text: createPrettyStackVariable(local) + " " + format.ObjectId(uint64(local.ObjectId)),
+					Href: objectHref(local.ObjectId),
+				}})
+			}
+		}
+		page.Sections = append(page.Sections, section)
+	}
+	return page
+}
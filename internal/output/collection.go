@@ -0,0 +1,70 @@
+package output
+
+import (
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/danielleontiev/neojhat/internal/collection"
+	"github.com/danielleontiev/neojhat/internal/format"
+	"github.com/danielleontiev/neojhat/internal/java"
+)
+
+// CollectionPlain prints the result of collection command
+// without colors
+func CollectionPlain(c collection.Collection, destination io.Writer) {
+	printTable(getCollectionTable(c), identity, identity, identity, identity, destination)
+}
+
+// CollectionPlainColor is the same as CollectionPlain but
+// with colorful output
+func CollectionPlainColor(c collection.Collection) {
+	printTable(getCollectionTable(c), Bold, Cyan, Yellow, Blue, os.Stdout)
+}
+
+// CollectionHtml prints the output of collection command as HTML
+func CollectionHtml(c collection.Collection, destination io.Writer) error {
+	return tableToHtml("Collection", getCollectionTable(c), destination)
+}
+
+// CollectionJson prints the output of collection command as JSON
+func CollectionJson(c collection.Collection, destination io.Writer) error {
+	c.ClassName = format.ClassName(c.ClassName)
+	if c.Elements == nil {
+		c.Elements = []collection.Element{}
+	}
+	return printJson("collection", collectionSchemaVersion, c, destination)
+}
+
+// CollectionCsv prints the output of collection command as
+// delimiter-separated values, key is empty unless it's a map
+func CollectionCsv(c collection.Collection, comma rune, destination io.Writer) error {
+	var rows [][]string
+	for i, element := range c.Elements {
+		rows = append(rows, []string{strconv.Itoa(i), element.Key, element.Value})
+	}
+	return printRecords([]string{"index", "key", "value"}, rows, comma, destination)
+}
+
+func getCollectionTable(c collection.Collection) table {
+	t := table{
+		Summary: []tableSummary{
+			{Key: "Object", Val: format.ClassName(c.ClassName) + " " + format.ObjectId(uint64(c.ObjectId))},
+			{Key: "Kind", Val: c.Kind.String()},
+			{Key: "Size", Val: strconv.Itoa(len(c.Elements))},
+		},
+		Headers: []string{"#", "Value"},
+	}
+	isMap := c.Kind == java.MapCollection
+	if isMap {
+		t.Headers = []string{"#", "Key", "Value"}
+	}
+	for i, element := range c.Elements {
+		row := []string{strconv.Itoa(i)}
+		if isMap {
+			row = append(row, element.Key)
+		}
+		t.Rows = append(t.Rows, append(row, element.Value))
+	}
+	return t
+}
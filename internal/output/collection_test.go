@@ -0,0 +1,70 @@
+package output_test
+
+import (
+	_ "embed"
+
+	"strings"
+	"testing"
+
+	"github.com/danielleontiev/neojhat/internal/collection"
+	"github.com/danielleontiev/neojhat/internal/java"
+	"github.com/danielleontiev/neojhat/internal/output"
+)
+
+var collection1 = collection.Collection{
+	ObjectId:  0x7ff0012a8,
+	ClassName: "java/util/HashMap",
+	Kind:      java.MapCollection,
+	Elements: []collection.Element{
+		{Key: `"user.home"`, Value: `"/home/user"`},
+		{Key: `"timeout"`, Value: "30"},
+		{Key: `"cache"`, Value: "java.util.ArrayList 0x7ff001040"},
+		{Key: `"parent"`, Value: "null"},
+	},
+}
+
+var collection2 = collection.Collection{
+	ObjectId:  0x7ff001040,
+	ClassName: "java/util/ArrayList",
+	Kind:      java.ListCollection,
+	Elements: []collection.Element{
+		{Value: `"first"`},
+		{Value: "byte[] 0x7ff0019c0"},
+	},
+}
+
+var (
+	//go:embed test-data/collection1.txt
+	collection1txt string
+	//go:embed test-data/collection1.html
+	collection1html string
+	//go:embed test-data/collection2.txt
+	collection2txt string
+)
+
+func TestCollectionPlain1(t *testing.T) {
+	builder := &strings.Builder{}
+	output.CollectionPlain(collection1, builder)
+	result := builder.String()
+	if result != collection1txt {
+		compareLineByLine(t, result, collection1txt)
+	}
+}
+
+func TestCollectionHtml1(t *testing.T) {
+	builder := &strings.Builder{}
+	output.CollectionHtml(collection1, builder)
+	result := builder.String()
+	if result != collection1html {
+		compareLineByLine(t, result, collection1html)
+	}
+}
+
+func TestCollectionPlain2(t *testing.T) {
+	builder := &strings.Builder{}
+	output.CollectionPlain(collection2, builder)
+	result := builder.String()
+	if result != collection2txt {
+		compareLineByLine(t, result, collection2txt)
+	}
+}
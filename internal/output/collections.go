@@ -0,0 +1,92 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/danielleontiev/neojhat/internal/collections"
+	"github.com/danielleontiev/neojhat/internal/format"
+)
+
+// CollectionsPlain prints the result of collections command
+// without colors
+func CollectionsPlain(c collections.Collections, destination io.Writer) {
+	printTable(getCollectionsTable(c), identity, identity, identity, identity, destination)
+}
+
+// CollectionsPlainColor is the same as CollectionsPlain but
+// with colorful output
+func CollectionsPlainColor(c collections.Collections) {
+	printTable(getCollectionsTable(c), Bold, Cyan, Yellow, Blue, os.Stdout)
+}
+
+// CollectionsHtml prints the output of collections command as HTML
+func CollectionsHtml(c collections.Collections, destination io.Writer) error {
+	return tableToHtml("Collections", getCollectionsTable(c), destination)
+}
+
+// CollectionsJson prints the output of collections command as JSON
+func CollectionsJson(c collections.Collections, destination io.Writer) error {
+	classes := []collections.ClassStats{}
+	for _, class := range c.Classes {
+		class.ClassName = format.ClassName(class.ClassName)
+		classes = append(classes, class)
+	}
+	c.Classes = classes
+	return printJson("collections", collectionsSchemaVersion, c, destination)
+}
+
+// CollectionsCsv prints the output of collections command as
+// delimiter-separated values with wasted sizes in bytes
+func CollectionsCsv(c collections.Collections, comma rune, destination io.Writer) error {
+	header := []string{"class_name", "instances", "empty"}
+	for _, r := range collections.SizeRanges {
+		header = append(header, "sizes_"+r.String())
+	}
+	header = append(header, "fill_ratio", "wasted_size")
+	var rows [][]string
+	for _, class := range c.Classes {
+		row := []string{format.ClassName(class.ClassName), strconv.Itoa(class.Instances), strconv.Itoa(class.Empty)}
+		for _, count := range class.Histogram {
+			row = append(row, strconv.Itoa(count))
+		}
+		row = append(row, strconv.FormatFloat(class.FillRatio, 'f', 2, 64), strconv.Itoa(class.WastedSize))
+		rows = append(rows, row)
+	}
+	return printRecords(header, rows, comma, destination)
+}
+
+// getCollectionsTable has a column for every bucket
+// of the histogram of sizes after the empty ones.
+func getCollectionsTable(c collections.Collections) table {
+	instances := 0
+	for _, class := range c.Classes {
+		instances += class.Instances
+	}
+	t := table{
+		Summary: []tableSummary{
+			{Key: "Collections", Val: strconv.Itoa(instances)},
+			{Key: "Wasted", Val: format.Size(c.WastedSize)},
+		},
+		Headers: []string{"Class Name", "Instances", "Empty"},
+	}
+	for _, r := range collections.SizeRanges {
+		t.Headers = append(t.Headers, r.String())
+	}
+	t.Headers = append(t.Headers, "Avg Fill", "Wasted")
+	for _, class := range c.Classes {
+		row := []string{
+			format.ClassName(class.ClassName),
+			strconv.Itoa(class.Instances),
+			strconv.Itoa(class.Empty),
+		}
+		for _, n := range class.Histogram {
+			row = append(row, strconv.Itoa(n))
+		}
+		row = append(row, fmt.Sprintf("%.1f%%", 100*class.FillRatio), format.Size(class.WastedSize))
+		t.Rows = append(t.Rows, row)
+	}
+	return t
+}
@@ -0,0 +1,66 @@
+package output_test
+
+import (
+	_ "embed"
+
+	"strings"
+	"testing"
+
+	"github.com/danielleontiev/neojhat/internal/collections"
+	"github.com/danielleontiev/neojhat/internal/output"
+)
+
+var collections1 = collections.Collections{
+	Classes: []collections.ClassStats{
+		{
+			ClassName:  "java/util/HashMap",
+			Instances:  12040,
+			Empty:      4012,
+			Histogram:  []int{2011, 3560, 1984, 402, 60, 11},
+			FillRatio:  0.3125,
+			WastedSize: 2516582,
+		},
+		{
+			ClassName:  "java/util/ArrayList",
+			Instances:  8400,
+			Empty:      6120,
+			Histogram:  []int{1200, 780, 250, 50, 0, 0},
+			FillRatio:  0.4,
+			WastedSize: 1048576,
+		},
+		{
+			ClassName:  "java/util/ArrayDeque",
+			Instances:  3,
+			Empty:      3,
+			Histogram:  []int{0, 0, 0, 0, 0, 0},
+			FillRatio:  0,
+			WastedSize: 408,
+		},
+	},
+	WastedSize: 3565566,
+}
+
+var (
+	//go:embed test-data/collections1.txt
+	collections1txt string
+	//go:embed test-data/collections1.html
+	collections1html string
+)
+
+func TestCollectionsPlain1(t *testing.T) {
+	builder := &strings.Builder{}
+	output.CollectionsPlain(collections1, builder)
+	result := builder.String()
+	if result != collections1txt {
+		compareLineByLine(t, result, collections1txt)
+	}
+}
+
+func TestCollectionsHtml1(t *testing.T) {
+	builder := &strings.Builder{}
+	output.CollectionsHtml(collections1, builder)
+	result := builder.String()
+	if result != collections1html {
+		compareLineByLine(t, result, collections1html)
+	}
+}
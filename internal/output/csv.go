@@ -0,0 +1,28 @@
+package output
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+)
+
+// printRecords prints the header and the rows as delimiter-separated
+// values, comma is ',' for CSV and '\t' for TSV. The values containing
+// the delimiter, quotes or line breaks are quoted.
+func printRecords(header []string, rows [][]string, comma rune, destination io.Writer) error {
+	writer := csv.NewWriter(destination)
+	writer.Comma = comma
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	return writer.WriteAll(rows)
+}
+
+// rawPercent is the share of value in total with two decimals,
+// spreadsheets parse it as a number unlike "12%"
+func rawPercent(value, total int) string {
+	if total == 0 {
+		return "0.00"
+	}
+	return fmt.Sprintf("%.2f", 100*float64(value)/float64(total))
+}
@@ -0,0 +1,147 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/danielleontiev/neojhat/internal/format"
+	"github.com/danielleontiev/neojhat/internal/objects"
+)
+
+// DiffPlain prints the result of diff command
+// without colors
+func DiffPlain(d objects.Diff, destination io.Writer) {
+	printTable(getDiffTable(d), identity, identity, identity, identity, destination)
+}
+
+// DiffPlainColor is the same as DiffPlain but
+// with colorful output
+func DiffPlainColor(d objects.Diff) {
+	printTable(getDiffTable(d), Bold, Cyan, Yellow, Blue, os.Stdout)
+}
+
+// DiffHtml prints the output of diff command as HTML
+func DiffHtml(d objects.Diff, destination io.Writer) error {
+	return tableToHtml("Diff", getDiffTable(d), destination)
+}
+
+// DiffJson prints the output of diff command as JSON, the classes
+// are cut to the same top as in the plain output
+func DiffJson(d objects.Diff, destination io.Writer) error {
+	if d.Top > 0 && len(d.Items) > d.Top {
+		d.Items = d.Items[:d.Top]
+	}
+	if d.Items == nil {
+		d.Items = []objects.ClassDiff{}
+	}
+	return printJson("diff", diffSchemaVersion, d, destination)
+}
+
+// DiffCsv prints the output of diff command as delimiter-separated
+// values with sizes in bytes
+func DiffCsv(d objects.Diff, comma rune, destination io.Writer) error {
+	header := []string{"class_name", "base_count", "count", "count_change", "base_size", "size", "size_change"}
+	if d.WithRetained {
+		header = append(header, "base_retained", "retained", "retained_change")
+	}
+	if d.ById {
+		header = append(header, "new_count", "new_size", "gone_count", "gone_size")
+	}
+	items := d.Items
+	if d.Top > 0 && len(items) > d.Top {
+		items = items[:d.Top]
+	}
+	var rows [][]string
+	for _, item := range items {
+		row := []string{item.Name,
+			strconv.Itoa(item.BaseCount), strconv.Itoa(item.Count), strconv.Itoa(item.CountDelta()),
+			strconv.Itoa(item.BaseSize), strconv.Itoa(item.Size), strconv.Itoa(item.SizeDelta())}
+		if d.WithRetained {
+			row = append(row, strconv.Itoa(item.BaseRetained), strconv.Itoa(item.Retained), strconv.Itoa(item.RetainedDelta()))
+		}
+		if d.ById {
+			row = append(row, strconv.Itoa(item.NewCount), strconv.Itoa(item.NewSize), strconv.Itoa(item.GoneCount), strconv.Itoa(item.GoneSize))
+		}
+		rows = append(rows, row)
+	}
+	return printRecords(header, rows, comma, destination)
+}
+
+func getDiffTable(d objects.Diff) table {
+	instances, totalSize := "Instances", "Total Size"
+	if d.Filtered {
+		instances, totalSize = "Filtered Instances", "Filtered Size"
+	}
+	comparedBy := "class names"
+	if d.ById {
+		comparedBy = "class names and object ids"
+	}
+	t := table{
+		Summary: []tableSummary{
+			{Key: instances, Val: fmt.Sprintf("%s (%s)", change(strconv.Itoa(d.BaseCount), strconv.Itoa(d.Count)), signed(d.Count-d.BaseCount))},
+			{Key: totalSize, Val: fmt.Sprintf("%s (%s)", change(format.Size(d.BaseSize), format.Size(d.Size)), signedSize(d.Size-d.BaseSize))},
+			{Key: "Classes Changed", Val: strconv.Itoa(len(d.Items))},
+			{Key: "Compared By", Val: comparedBy},
+		},
+		Headers: []string{"Class Name", "Count Change", "Count", "Size Change", "Size"},
+	}
+	if d.BaseLayout != d.Layout {
+		t.Summary = append(t.Summary,
+			tableSummary{Key: "Base Layout", Val: d.BaseLayout},
+			tableSummary{Key: "Layout", Val: d.Layout})
+	}
+	if d.WithRetained {
+		t.Headers = append(t.Headers, "Retained Change", "Retained")
+	}
+	if d.ById {
+		t.Headers = append(t.Headers, "New", "Gone")
+	}
+	items := d.Items
+	if d.Top > 0 && len(items) > d.Top {
+		items = items[:d.Top]
+	}
+	for _, item := range items {
+		row := []string{
+			item.Name,
+			signed(item.CountDelta()),
+			change(strconv.Itoa(item.BaseCount), strconv.Itoa(item.Count)),
+			signedSize(item.SizeDelta()),
+			change(format.Size(item.BaseSize), format.Size(item.Size)),
+		}
+		if d.WithRetained {
+			row = append(row,
+				signedSize(item.RetainedDelta()),
+				change(format.Size(item.BaseRetained), format.Size(item.Retained)))
+		}
+		if d.ById {
+			row = append(row,
+				fmt.Sprintf("%d (%s)", item.NewCount, format.Size(item.NewSize)),
+				fmt.Sprintf("%d (%s)", item.GoneCount, format.Size(item.GoneSize)))
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	return t
+}
+
+func change(from, to string) string {
+	return from + " → " + to
+}
+
+func signed(n int) string {
+	if n > 0 {
+		return "+" + strconv.Itoa(n)
+	}
+	return strconv.Itoa(n)
+}
+
+func signedSize(n int) string {
+	if n > 0 {
+		return "+" + format.Size(n)
+	}
+	if n < 0 {
+		return "-" + format.Size(-n)
+	}
+	return format.Size(n)
+}
@@ -0,0 +1,82 @@
+package output_test
+
+import (
+	_ "embed"
+
+	"strings"
+	"testing"
+
+	"github.com/danielleontiev/neojhat/internal/objects"
+	"github.com/danielleontiev/neojhat/internal/output"
+)
+
+var diff1 = objects.Diff{
+	Items: []objects.ClassDiff{
+		{Name: "byte[]", BaseCount: 12000, Count: 18000, BaseSize: 4194304, Size: 7340032, BaseRetained: 4194304, Retained: 7340032, NewCount: 6500, NewSize: 3355443, GoneCount: 500, GoneSize: 209715},
+		{Name: "java.util.HashMap$Node", BaseCount: 30000, Count: 45000, BaseSize: 960000, Size: 1440000, BaseRetained: 5242880, Retained: 8388608, NewCount: 15000, NewSize: 480000},
+		{Name: "java.lang.Thread", BaseCount: 40, Count: 40, BaseSize: 4800, Size: 4800, BaseRetained: 81920, Retained: 61440},
+		{Name: "java.lang.String", BaseCount: 20000, Count: 19000, BaseSize: 480000, Size: 456000, BaseRetained: 1048576, Retained: 996147, GoneCount: 1000, GoneSize: 24000},
+	},
+	BaseCount:    98000,
+	Count:        118000,
+	BaseSize:     6291456,
+	Size:         9437184,
+	WithRetained: true,
+	ById:         true,
+	Top:          3,
+	BaseLayout:   "64-bit, compressed oops, compressed class pointers, 8-byte alignment",
+	Layout:       "64-bit, compressed oops, compressed class pointers, 8-byte alignment",
+}
+
+var (
+	//go:embed test-data/diff1.txt
+	diff1txt string
+	//go:embed test-data/diff1.html
+	diff1html string
+)
+
+func TestDiffPlain1(t *testing.T) {
+	builder := &strings.Builder{}
+	output.DiffPlain(diff1, builder)
+	result := builder.String()
+	if result != diff1txt {
+		compareLineByLine(t, result, diff1txt)
+	}
+}
+
+func TestDiffHtml1(t *testing.T) {
+	builder := &strings.Builder{}
+	output.DiffHtml(diff1, builder)
+	result := builder.String()
+	if result != diff1html {
+		compareLineByLine(t, result, diff1html)
+	}
+}
+
+var (
+	//go:embed test-data/diff1.json
+	diff1json string
+)
+
+func TestDiffJson1(t *testing.T) {
+	builder := &strings.Builder{}
+	output.DiffJson(diff1, builder)
+	result := builder.String()
+	if result != diff1json {
+		compareLineByLine(t, result, diff1json)
+	}
+}
+
+var (
+	//go:embed test-data/diff1.csv
+	diff1csv string
+)
+
+func TestDiffCsv1(t *testing.T) {
+	builder := &strings.Builder{}
+	output.DiffCsv(diff1, ',', builder)
+	result := builder.String()
+	if result != diff1csv {
+		compareLineByLine(t, result, diff1csv)
+	}
+}
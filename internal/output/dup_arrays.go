@@ -0,0 +1,67 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/danielleontiev/neojhat/internal/duplicates"
+	"github.com/danielleontiev/neojhat/internal/format"
+)
+
+// DupArraysPlain prints the result of dup-arrays command
+// without colors
+func DupArraysPlain(a duplicates.DuplicateArrays, destination io.Writer) {
+	printTable(getDupArraysTable(a), identity, identity, identity, identity, destination)
+}
+
+// DupArraysPlainColor is the same as DupArraysPlain but
+// with colorful output
+func DupArraysPlainColor(a duplicates.DuplicateArrays) {
+	printTable(getDupArraysTable(a), Bold, Cyan, Yellow, Blue, os.Stdout)
+}
+
+// DupArraysHtml prints the output of dup-arrays command as HTML
+func DupArraysHtml(a duplicates.DuplicateArrays, destination io.Writer) error {
+	return tableToHtml("Duplicate Arrays", getDupArraysTable(a), destination)
+}
+
+// DupArraysJson prints the output of dup-arrays command as JSON
+func DupArraysJson(a duplicates.DuplicateArrays, destination io.Writer) error {
+	if a.Items == nil {
+		a.Items = []duplicates.DuplicateArray{}
+	}
+	return printJson("dup-arrays", dupArraysSchemaVersion, a, destination)
+}
+
+// DupArraysCsv prints the output of dup-arrays command
+// as delimiter-separated values
+func DupArraysCsv(a duplicates.DuplicateArrays, comma rune, destination io.Writer) error {
+	var rows [][]string
+	for _, item := range a.Items {
+		rows = append(rows, []string{item.ElementType.String(), strconv.Itoa(item.Length), strconv.Itoa(item.Count),
+			strconv.Itoa(item.WastedSize), format.ObjectId(uint64(item.SampleId))})
+	}
+	return printRecords([]string{"element_type", "length", "count", "wasted_size", "sample_id"}, rows, comma, destination)
+}
+
+func getDupArraysTable(a duplicates.DuplicateArrays) table {
+	t := table{
+		Summary: []tableSummary{
+			{Key: "Arrays", Val: strconv.Itoa(a.TotalCount)},
+			{Key: "Duplicated", Val: strconv.Itoa(a.DuplicatedCount)},
+			{Key: "Wasted", Val: format.Size(a.WastedSize)},
+		},
+		Headers: []string{"Array", "Count", "Wasted", "Sample Id"},
+	}
+	for _, item := range a.Items {
+		t.Rows = append(t.Rows, []string{
+			fmt.Sprintf("%s[%d]", item.ElementType, item.Length),
+			strconv.Itoa(item.Count),
+			format.Size(item.WastedSize),
+			format.ObjectId(uint64(item.SampleId)),
+		})
+	}
+	return t
+}
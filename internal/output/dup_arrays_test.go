@@ -0,0 +1,48 @@
+package output_test
+
+import (
+	_ "embed"
+
+	"strings"
+	"testing"
+
+	"github.com/danielleontiev/neojhat/internal/core"
+	"github.com/danielleontiev/neojhat/internal/duplicates"
+	"github.com/danielleontiev/neojhat/internal/output"
+)
+
+var dupArrays1 = duplicates.DuplicateArrays{
+	Items: []duplicates.DuplicateArray{
+		{ElementType: core.Byte, Length: 65536, Count: 120, WastedSize: 7800423, SampleId: 0x7ff0012a8},
+		{ElementType: core.Int, Length: 256, Count: 300, WastedSize: 306912, SampleId: 0x7ff001040},
+		{ElementType: core.Char, Length: 0, Count: 1200, WastedSize: 19183, SampleId: 0x7ff0019c0},
+	},
+	TotalCount:      524288,
+	DuplicatedCount: 1620,
+	WastedSize:      8126518,
+}
+
+var (
+	//go:embed test-data/dup-arrays1.txt
+	dupArrays1txt string
+	//go:embed test-data/dup-arrays1.html
+	dupArrays1html string
+)
+
+func TestDupArraysPlain1(t *testing.T) {
+	builder := &strings.Builder{}
+	output.DupArraysPlain(dupArrays1, builder)
+	result := builder.String()
+	if result != dupArrays1txt {
+		compareLineByLine(t, result, dupArrays1txt)
+	}
+}
+
+func TestDupArraysHtml1(t *testing.T) {
+	builder := &strings.Builder{}
+	output.DupArraysHtml(dupArrays1, builder)
+	result := builder.String()
+	if result != dupArrays1html {
+		compareLineByLine(t, result, dupArrays1html)
+	}
+}
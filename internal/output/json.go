@@ -0,0 +1,50 @@
+package output
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// Versions of the schemas of JSON output. The version of the command
+// is increased on every incompatible change of its result: when the
+// field is removed or renamed or its type is changed. New fields are
+// added without changing the version.
+const (
+	threadsSchemaVersion     = 1
+	summarySchemaVersion     = 1
+	objectsSchemaVersion     = 1
+	referrersSchemaVersion   = 1
+	pathsSchemaVersion       = 1
+	leaksSchemaVersion       = 1
+	querySchemaVersion       = 1
+	collectionSchemaVersion  = 1
+	collectionsSchemaVersion = 1
+	stringsSchemaVersion     = 1
+	dupArraysSchemaVersion   = 1
+	diffSchemaVersion        = 1
+)
+
+// jsonDocument wraps the result of every command, so the consumer
+// can check what it's reading before reading it.
+type jsonDocument struct {
+	Command       string `json:"command"`
+	SchemaVersion int    `json:"schemaVersion"`
+	Result        any    `json:"result"`
+}
+
+// ResourceJson prints the resource of HTTP API of serve command in the
+// same envelope as the output of commands, command is the resource name.
+func ResourceJson(resource string, schemaVersion int, result any, destination io.Writer) error {
+	return printJson(resource, schemaVersion, result, destination)
+}
+
+func printJson(command string, schemaVersion int, result any, destination io.Writer) error {
+	encoder := json.NewEncoder(destination)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(jsonDocument{
+		Command:       command,
+		SchemaVersion: schemaVersion,
+		Result:        result,
+	})
+}
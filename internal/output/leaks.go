@@ -0,0 +1,133 @@
+package output
+
+import (
+	_ "embed"
+
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/danielleontiev/neojhat/internal/format"
+	"github.com/danielleontiev/neojhat/internal/leaks"
+)
+
+type printLeaks struct {
+	Summary  []tableSummary
+	Suspects []table
+}
+
+// LeaksPlain prints the result of leaks command
+// without colors
+func LeaksPlain(l leaks.Leaks, destination io.Writer) {
+	printLeakTables(l, identity, identity, identity, identity, destination)
+}
+
+// LeaksPlainColor is the same as LeaksPlain but
+// with colorful output
+func LeaksPlainColor(l leaks.Leaks) {
+	printLeakTables(l, Bold, Cyan, Yellow, Blue, os.Stdout)
+}
+
+func printLeakTables(l leaks.Leaks, headerColor, summaryColor, classNameColor, columnColor func(s string) string, destination io.Writer) {
+	printObj := getPrintLeaks(l)
+	for _, s := range printObj.Summary {
+		fmt.Fprintln(destination, summaryColor(fmt.Sprintf("%s: %s", s.Key, s.Val)))
+	}
+	for _, t := range printObj.Suspects {
+		fmt.Fprintln(destination)
+		printTable(t, headerColor, summaryColor, classNameColor, columnColor, destination)
+	}
+}
+
+// getPrintLeaks describes every suspect in the summary of the
+// table with the path from GC root to the accumulation point.
+func getPrintLeaks(l leaks.Leaks) printLeaks {
+	share := func(size int) string {
+		if l.TotalSize == 0 {
+			return format.Size(size)
+		}
+		return fmt.Sprintf("%v (%v%%)", format.Size(size), 100*size/l.TotalSize)
+	}
+	printObj := printLeaks{
+		Summary: []tableSummary{
+			{Key: "Total Size", Val: format.Size(l.TotalSize)},
+			{Key: "Threshold", Val: fmt.Sprintf("%v%%", l.Threshold)},
+			{Key: "Suspects", Val: strconv.Itoa(len(l.Suspects))},
+		},
+	}
+	for i, suspect := range l.Suspects {
+		var description string
+		if suspect.Instances == 1 {
+			description = fmt.Sprintf("one instance of %s %s retains %s",
+				format.ClassName(suspect.ClassName), format.ObjectId(uint64(suspect.ObjectId)), share(suspect.RetainedSize))
+		} else {
+			description = fmt.Sprintf("%v instances of %s retain %s",
+				suspect.Instances, format.ClassName(suspect.ClassName), share(suspect.RetainedSize))
+		}
+		point := suspect.AccumulationPoint
+		summary := []tableSummary{
+			{Key: fmt.Sprintf("Suspect %d", i+1), Val: description},
+			{Key: "Accumulation Point", Val: fmt.Sprintf("%s %s retains %s",
+				format.ClassName(point.ClassName), format.ObjectId(uint64(point.ObjectId)), share(point.RetainedSize))},
+			{Key: "Path", Val: suspect.Path.Root},
+		}
+		printObj.Suspects = append(printObj.Suspects, getPathTable(suspect.Path, summary))
+	}
+	return printObj
+}
+
+var (
+	//go:embed templates/leaks.html
+	leaksHtml string
+)
+
+// LeaksHtml prints the output of leaks command as HTML
+func LeaksHtml(l leaks.Leaks, destination io.Writer) error {
+	coreTemplate, err := template.New("core").Parse(coreHtml)
+	if err != nil {
+		return err
+	}
+	leaksTemplate, err := coreTemplate.Parse(leaksHtml)
+	if err != nil {
+		return err
+	}
+	return leaksTemplate.Execute(destination, data{
+		Title:   "Leak Suspects",
+		Favicon: faviconBase64,
+		Payload: getPrintLeaks(l),
+	})
+}
+
+// LeaksJson prints the output of leaks command as JSON
+func LeaksJson(l leaks.Leaks, destination io.Writer) error {
+	suspects := []leaks.Suspect{}
+	for _, suspect := range l.Suspects {
+		suspect.ClassName = format.ClassName(suspect.ClassName)
+		suspect.AccumulationPoint.ClassName = format.ClassName(suspect.AccumulationPoint.ClassName)
+		suspect.Path = jsonPath(suspect.Path)
+		suspects = append(suspects, suspect)
+	}
+	l.Suspects = suspects
+	return printJson("leaks", leaksSchemaVersion, l, destination)
+}
+
+// LeaksCsv prints the output of leaks command as delimiter-separated
+// values, one row per suspect with its accumulation point, the paths
+// are left out, see PathsCsv
+func LeaksCsv(l leaks.Leaks, comma rune, destination io.Writer) error {
+	header := []string{"class_name", "object_id", "instances", "retained_size", "retained_percent",
+		"accumulation_class_name", "accumulation_object_id", "accumulation_retained_size", "root"}
+	var rows [][]string
+	for _, suspect := range l.Suspects {
+		point := suspect.AccumulationPoint
+		rows = append(rows, []string{
+			format.ClassName(suspect.ClassName), format.ObjectId(uint64(suspect.ObjectId)),
+			strconv.Itoa(suspect.Instances), strconv.Itoa(suspect.RetainedSize), rawPercent(suspect.RetainedSize, l.TotalSize),
+			format.ClassName(point.ClassName), format.ObjectId(uint64(point.ObjectId)), strconv.Itoa(point.RetainedSize),
+			suspect.Path.Root,
+		})
+	}
+	return printRecords(header, rows, comma, destination)
+}
@@ -0,0 +1,79 @@
+package output_test
+
+import (
+	_ "embed"
+
+	"strings"
+	"testing"
+
+	"github.com/danielleontiev/neojhat/internal/leaks"
+	"github.com/danielleontiev/neojhat/internal/output"
+	"github.com/danielleontiev/neojhat/internal/paths"
+)
+
+var leaks1 = leaks.Leaks{
+	TotalSize: 2100000,
+	Threshold: 10,
+	Suspects: []leaks.Suspect{
+		{
+			ObjectId:     0x7ff000e10,
+			ClassName:    "Main$Worker",
+			Instances:    1,
+			RetainedSize: 1050000,
+			AccumulationPoint: leaks.AccumulationPoint{
+				ObjectId:     0x7ff0012a8,
+				ClassName:    "java/util/HashMap",
+				RetainedSize: 1048000,
+			},
+			Path: paths.Path{
+				Root: "Java frame of thread main, frame 3",
+				Steps: []paths.Step{
+					{ObjectId: 0x7ff000e10, ClassName: "Main$Worker"},
+					{ObjectId: 0x7ff0012a8, ClassName: "java/util/HashMap", Field: "cache"},
+				},
+			},
+		},
+		{
+			ObjectId:     0x7ff003000,
+			ClassName:    "java/lang/Thread",
+			Instances:    24,
+			RetainedSize: 315000,
+			AccumulationPoint: leaks.AccumulationPoint{
+				ObjectId:     0x7ff003000,
+				ClassName:    "java/lang/Thread",
+				RetainedSize: 21000,
+			},
+			Path: paths.Path{
+				Root: "thread object of thread pool-1-thread-1",
+				Steps: []paths.Step{
+					{ObjectId: 0x7ff003000, ClassName: "java/lang/Thread"},
+				},
+			},
+		},
+	},
+}
+
+var (
+	//go:embed test-data/leaks1.txt
+	leaks1txt string
+	//go:embed test-data/leaks1.html
+	leaks1html string
+)
+
+func TestLeaksPlain1(t *testing.T) {
+	builder := &strings.Builder{}
+	output.LeaksPlain(leaks1, builder)
+	result := builder.String()
+	if result != leaks1txt {
+		compareLineByLine(t, result, leaks1txt)
+	}
+}
+
+func TestLeaksHtml1(t *testing.T) {
+	builder := &strings.Builder{}
+	output.LeaksHtml(leaks1, builder)
+	result := builder.String()
+	if result != leaks1html {
+		compareLineByLine(t, result, leaks1html)
+	}
+}
@@ -20,14 +20,26 @@ type printObject struct {
 	NameHeader           string
 	InstancesCountHeader string
 	TotalSizeHeader      string
+	RetainedSizeHeader   string
 	TotalCount           string
 	TotalSize            string
+	Layout               string
+	Heap                 string
+	Heaps                []printHeap
 	Items                []printItem
+	Groups               []printItem
+}
+type printHeap struct {
+	Name       string
+	TotalCount string
+	TotalSize  string
 }
 type printItem struct {
 	Name           string
 	TotalSize      string
 	InstancesCount string
+	RetainedSize   string
+	Members        []printItem
 }
 
 // ObjectsPlain print the result of objects command
@@ -46,8 +58,24 @@ func ObjectsPlainColor(o objects.Objects) {
 func print(o objects.Objects, headerColor, summaryColor, classNameColor, numColor func(s string) string, destination io.Writer) {
 	printObj := getPrintItems(o)
 	const gap = 10
-	var maxName, maxCount, maxSize int
-	for _, item := range append(printObj.Items, printItem{Name: printObj.NameHeader, TotalSize: printObj.TotalSizeHeader, InstancesCount: printObj.InstancesCountHeader}) {
+	var maxName, maxCount, maxSize, maxRetained int
+	headerItem := printItem{
+		Name: printObj.NameHeader, TotalSize: printObj.TotalSizeHeader,
+		InstancesCount: printObj.InstancesCountHeader, RetainedSize: printObj.RetainedSizeHeader,
+	}
+	rows := printObj.Items
+	if printObj.Groups != nil {
+		// members are printed below the group and indented
+		rows = nil
+		for _, group := range printObj.Groups {
+			rows = append(rows, group)
+			for _, member := range group.Members {
+				member.Name = "  " + member.Name
+				rows = append(rows, member)
+			}
+		}
+	}
+	for _, item := range append(rows, headerItem) {
 		if len(item.Name) > maxName {
 			maxName = len(item.Name)
 		}
@@ -57,7 +85,11 @@ func print(o objects.Objects, headerColor, summaryColor, classNameColor, numColo
 		if len(item.TotalSize) > maxSize {
 			maxSize = len(item.TotalSize)
 		}
+		if len(item.RetainedSize) > maxRetained {
+			maxRetained = len(item.RetainedSize)
+		}
 	}
+	withRetained := printObj.RetainedSizeHeader != ""
 
 	alignRight := func(s string, max int) string {
 		return strings.Repeat(" ", max+gap-utf8.RuneCountInString(s)) + s
@@ -69,60 +101,168 @@ func print(o objects.Objects, headerColor, summaryColor, classNameColor, numColo
 		name := alignLeft(i.Name, maxName)
 		count := alignRight(i.InstancesCount, maxCount)
 		size := alignRight(i.TotalSize, maxSize)
-		return classNameColor(name) + " |" + numColor(count) + " |" + numColor(size) + " |"
+		nameColor := classNameColor
+		if i.Members != nil {
+			nameColor = headerColor
+		}
+		line := nameColor(name) + " |" + numColor(count) + " |" + numColor(size) + " |"
+		if withRetained {
+			line += numColor(alignRight(i.RetainedSize, maxRetained)) + " |"
+		}
+		return line
 	}
 
+	if printObj.Heap != "" {
+		fmt.Fprintln(destination, summaryColor(fmt.Sprintf("Heap: %v", printObj.Heap)))
+	}
 	fmt.Fprintln(destination, summaryColor(fmt.Sprintf("Instances: %v", printObj.TotalCount)))
 	fmt.Fprintln(destination, summaryColor(fmt.Sprintf("Total Size: %v", printObj.TotalSize)))
+	if printObj.Layout != "" {
+		fmt.Fprintln(destination, summaryColor(fmt.Sprintf("Layout: %v", printObj.Layout)))
+	}
+	for _, heap := range printObj.Heaps {
+		fmt.Fprintln(destination, summaryColor(fmt.Sprintf("Heap %v: %v instances, %v", heap.Name, heap.TotalCount, heap.TotalSize)))
+	}
 	fmt.Fprintln(destination)
 
 	header := alignLeft(printObj.NameHeader, maxName) + " |" + alignRight(printObj.InstancesCountHeader, maxCount) +
 		" |" + alignRight(printObj.TotalSizeHeader, maxSize) + " |"
+	width := gap*3 + maxName + maxCount + maxSize + 6
+	if withRetained {
+		header += alignRight(printObj.RetainedSizeHeader, maxRetained) + " |"
+		width += gap + maxRetained + 2
+	}
 	fmt.Fprintln(destination, headerColor(header))
-	fmt.Fprintln(destination, strings.Repeat("-", gap*3+maxName+maxCount+maxSize+6))
-	for _, item := range printObj.Items {
+	fmt.Fprintln(destination, strings.Repeat("-", width))
+	for _, item := range rows {
 		fmt.Fprintln(destination, stringifyItem(item))
 	}
 }
 
+// arrangeObjects sorts the classes (and the groups with their members)
+// in the order they are shown and cuts them to the top ones.
+func arrangeObjects(o objects.Objects) objects.Objects {
+	var key func(item objects.ObjectItem) int
+	switch o.SortBy {
+	case objects.Size:
+		key = func(item objects.ObjectItem) int { return item.TotalSize }
+	case objects.Count:
+		key = func(item objects.ObjectItem) int { return item.InstancesCount }
+	case objects.Retained:
+		key = func(item objects.ObjectItem) int { return item.RetainedSize }
+	}
+	sortItems := func(items []objects.ObjectItem) {
+		sort.Slice(items, func(i, j int) bool {
+			if key(items[i]) == key(items[j]) {
+				return items[i].Name < items[j].Name
+			}
+			return key(items[i]) > key(items[j])
+		})
+	}
+
+	sortItems(o.Items)
+	if o.Top > 0 && o.GroupBy == objects.NoGroups && len(o.Items) > o.Top {
+		o.Items = o.Items[:o.Top]
+	}
+	if o.GroupBy != objects.NoGroups {
+		sort.Slice(o.Groups, func(i, j int) bool {
+			if key(o.Groups[i].ObjectItem) == key(o.Groups[j].ObjectItem) {
+				return o.Groups[i].Name < o.Groups[j].Name
+			}
+			return key(o.Groups[i].ObjectItem) > key(o.Groups[j].ObjectItem)
+		})
+		if o.Top > 0 && len(o.Groups) > o.Top {
+			o.Groups = o.Groups[:o.Top]
+		}
+		for _, group := range o.Groups {
+			sortItems(group.Items)
+		}
+	}
+	return o
+}
+
 func getPrintItems(o objects.Objects) printObject {
 	var printObj printObject
-	var printItems []printItem
 	switch o.SortBy {
 	case objects.Size:
 		printObj = printObject{
 			NameHeader: "Class Name", InstancesCountHeader: "Count", TotalSizeHeader: "Size ↓",
 		}
-		sort.Slice(o.Items, func(i, j int) bool {
-			if o.Items[i].TotalSize == o.Items[j].TotalSize {
-				return o.Items[i].Name < o.Items[j].Name
-			}
-			return o.Items[i].TotalSize > o.Items[j].TotalSize
-		})
 	case objects.Count:
 		printObj = printObject{
 			NameHeader: "Class Name", InstancesCountHeader: "Count ↓", TotalSizeHeader: "Size",
 		}
-		sort.Slice(o.Items, func(i, j int) bool {
-			if o.Items[i].InstancesCount == o.Items[j].InstancesCount {
-				return o.Items[i].Name < o.Items[j].Name
-			}
-			return o.Items[i].InstancesCount > o.Items[j].InstancesCount
-		})
+	case objects.Retained:
+		printObj = printObject{
+			NameHeader: "Class Name", InstancesCountHeader: "Count", TotalSizeHeader: "Size", RetainedSizeHeader: "Retained ↓",
+		}
 	}
-	for _, item := range o.Items {
-		printItems = append(printItems, printItem{
+	// percents of the filtered classes go first when they are filtered
+	percents := func(value, filteredTotal, total int) string {
+		if o.Filtered {
+			return fmt.Sprintf("%v%% / %v%%", percent(value, filteredTotal), percent(value, total))
+		}
+		return fmt.Sprintf("%v%%", percent(value, total))
+	}
+	printItemOf := func(item objects.ObjectItem) printItem {
+		pItem := printItem{
 			Name:           format.ClassName(item.Name),
-			InstancesCount: fmt.Sprintf("%v (%v%%)", item.InstancesCount, 100*item.InstancesCount/o.TotalCount),
-			TotalSize:      fmt.Sprintf("%v (%v%%)", format.Size(item.TotalSize), 100*item.TotalSize/o.TotalSize),
-		})
+			InstancesCount: fmt.Sprintf("%v (%v)", item.InstancesCount, percents(item.InstancesCount, o.FilteredCount, o.TotalCount)),
+			TotalSize:      fmt.Sprintf("%v (%v)", format.Size(item.TotalSize), percents(item.TotalSize, o.FilteredSize, o.TotalSize)),
+		}
+		if o.SortBy == objects.Retained {
+			pItem.RetainedSize = fmt.Sprintf("%v (%v%%)", format.Size(item.RetainedSize), percent(item.RetainedSize, o.TotalSize))
+		}
+		return pItem
+	}
+
+	o = arrangeObjects(o)
+	for _, item := range o.Items {
+		printObj.Items = append(printObj.Items, printItemOf(item))
+	}
+	if o.GroupBy != objects.NoGroups {
+		printObj.NameHeader = groupHeaders[o.GroupBy]
+		printObj.Groups = []printItem{}
+		for _, group := range o.Groups {
+			pGroup := printItemOf(group.ObjectItem)
+			pGroup.Members = []printItem{}
+			for _, item := range group.Items {
+				pGroup.Members = append(pGroup.Members, printItemOf(item))
+			}
+			printObj.Groups = append(printObj.Groups, pGroup)
+		}
 	}
 	printObj.TotalCount = strconv.Itoa(o.TotalCount)
 	printObj.TotalSize = format.Size(o.TotalSize)
-	printObj.Items = printItems
+	if o.Filtered {
+		printObj.TotalCount = fmt.Sprintf("%v of %v", o.FilteredCount, o.TotalCount)
+		printObj.TotalSize = fmt.Sprintf("%v of %v", format.Size(o.FilteredSize), format.Size(o.TotalSize))
+	}
+	printObj.Layout = o.Layout
+	printObj.Heap = o.Heap
+	for _, heap := range o.Heaps {
+		printObj.Heaps = append(printObj.Heaps, printHeap{
+			Name:       heap.Name,
+			TotalCount: strconv.Itoa(heap.TotalCount),
+			TotalSize:  format.Size(heap.TotalSize),
+		})
+	}
 	return printObj
 }
 
+func percent(value, total int) int {
+	if total == 0 {
+		return 0
+	}
+	return 100 * value / total
+}
+
+var groupHeaders = map[objects.GroupBy]string{
+	objects.Package:     "Package / Class Name",
+	objects.ClassLoader: "Class Loader / Class Name",
+	objects.Superclass:  "Superclass / Class Name",
+}
+
 var (
 	//go:embed templates/objects.html
 	objectsHtml string
@@ -147,3 +287,71 @@ func ObjectsHtml(o objects.Objects, destination io.Writer) error {
 		Payload: printObj,
 	})
 }
+
+// ObjectsJson prints the output of objects command as JSON, the classes
+// and groups are in the same order and cut to the same top as in the
+// plain output
+func ObjectsJson(o objects.Objects, destination io.Writer) error {
+	o = arrangeObjects(o)
+	jsonItems := func(items []objects.ObjectItem) []objects.ObjectItem {
+		result := []objects.ObjectItem{}
+		for _, item := range items {
+			item.Name = format.ClassName(item.Name)
+			result = append(result, item)
+		}
+		return result
+	}
+	o.Items = jsonItems(o.Items)
+	var groups []objects.ObjectGroup
+	for _, group := range o.Groups {
+		group.Items = jsonItems(group.Items)
+		groups = append(groups, group)
+	}
+	o.Groups = groups
+	return printJson("objects", objectsSchemaVersion, o, destination)
+}
+
+// ObjectsCsv prints the output of objects command as delimiter-separated
+// values with sizes in bytes. count_percent and size_percent are of the
+// whole heap (or of the heap given). When the classes are filtered,
+// count_filtered_percent and size_filtered_percent are added with the
+// percentages of the filtered classes only. When the classes are grouped,
+// every class of the group has a row with the group in the first column.
+func ObjectsCsv(o objects.Objects, comma rune, destination io.Writer) error {
+	o = arrangeObjects(o)
+	header := []string{"class_name", "count", "count_percent", "size", "size_percent"}
+	if o.Filtered {
+		header = []string{"class_name", "count", "count_percent", "count_filtered_percent", "size", "size_percent", "size_filtered_percent"}
+	}
+	if o.SortBy == objects.Retained {
+		header = append(header, "retained_size", "retained_percent")
+	}
+	recordOf := func(item objects.ObjectItem) []string {
+		record := []string{format.ClassName(item.Name), strconv.Itoa(item.InstancesCount), rawPercent(item.InstancesCount, o.TotalCount)}
+		if o.Filtered {
+			record = append(record, rawPercent(item.InstancesCount, o.FilteredCount))
+		}
+		record = append(record, strconv.Itoa(item.TotalSize), rawPercent(item.TotalSize, o.TotalSize))
+		if o.Filtered {
+			record = append(record, rawPercent(item.TotalSize, o.FilteredSize))
+		}
+		if o.SortBy == objects.Retained {
+			record = append(record, strconv.Itoa(item.RetainedSize), rawPercent(item.RetainedSize, o.TotalSize))
+		}
+		return record
+	}
+	var rows [][]string
+	if o.GroupBy == objects.NoGroups {
+		for _, item := range o.Items {
+			rows = append(rows, recordOf(item))
+		}
+		return printRecords(header, rows, comma, destination)
+	}
+	header = append([]string{o.GroupBy.String()}, header...)
+	for _, group := range o.Groups {
+		for _, item := range group.Items {
+			rows = append(rows, append([]string{group.Name}, recordOf(item)...))
+		}
+	}
+	return printRecords(header, rows, comma, destination)
+}
@@ -14,6 +14,7 @@ var objects1 = objects.Objects{
 	TotalSize:  2100000,
 	TotalCount: 73224,
 	SortBy:     objects.Size,
+	Layout:     "64-bit, compressed oops, compressed class pointers, 8-byte alignment",
 	Items: []objects.ObjectItem{
 		{
 			Name:           "byte[]",
@@ -67,3 +68,267 @@ func TestObjectsHtml1(t *testing.T) {
 		compareLineByLine(t, result, objects1html)
 	}
 }
+
+var objects2 = objects.Objects{
+	TotalSize:  2100000,
+	TotalCount: 73224,
+	SortBy:     objects.Retained,
+	Items: []objects.ObjectItem{
+		{
+			Name:           "byte[]",
+			TotalSize:      571000,
+			InstancesCount: 16558,
+			RetainedSize:   571000,
+		},
+		{
+			Name:           "java.lang.String",
+			TotalSize:      203000,
+			InstancesCount: 16004,
+			RetainedSize:   602000,
+		},
+		{
+			Name:           "java.util.HashMap",
+			TotalSize:      2400,
+			InstancesCount: 50,
+			RetainedSize:   1050000,
+		},
+	},
+}
+
+var (
+	//go:embed test-data/objects2.txt
+	objects2txt string
+	//go:embed test-data/objects2.html
+	objects2html string
+)
+
+func TestObjectsPlain2(t *testing.T) {
+	builder := &strings.Builder{}
+	output.ObjectsPlain(objects2, builder)
+	result := builder.String()
+	if result != objects2txt {
+		compareLineByLine(t, result, objects2txt)
+	}
+}
+
+func TestObjectsHtml2(t *testing.T) {
+	builder := &strings.Builder{}
+	output.ObjectsHtml(objects2, builder)
+	result := builder.String()
+	if result != objects2html {
+		compareLineByLine(t, result, objects2html)
+	}
+}
+
+var objects3 = objects.Objects{
+	TotalSize:  760000,
+	TotalCount: 20210,
+	SortBy:     objects.Count,
+	Heap:       "app",
+	Heaps: []objects.HeapItem{
+		{Name: "app", TotalSize: 760000, TotalCount: 20210},
+		{Name: "image", TotalSize: 3400000, TotalCount: 51002},
+		{Name: "zygote", TotalSize: 1200000, TotalCount: 17340},
+	},
+	Items: []objects.ObjectItem{
+		{
+			Name:           "java.lang.String",
+			TotalSize:      160000,
+			InstancesCount: 10000,
+		},
+		{
+			Name:           "byte[]",
+			TotalSize:      600000,
+			InstancesCount: 10210,
+		},
+	},
+}
+
+var (
+	//go:embed test-data/objects3.txt
+	objects3txt string
+	//go:embed test-data/objects3.html
+	objects3html string
+)
+
+func TestObjectsPlain3(t *testing.T) {
+	builder := &strings.Builder{}
+	output.ObjectsPlain(objects3, builder)
+	result := builder.String()
+	if result != objects3txt {
+		compareLineByLine(t, result, objects3txt)
+	}
+}
+
+func TestObjectsHtml3(t *testing.T) {
+	builder := &strings.Builder{}
+	output.ObjectsHtml(objects3, builder)
+	result := builder.String()
+	if result != objects3html {
+		compareLineByLine(t, result, objects3html)
+	}
+}
+
+var objects4 = objects.Objects{
+	TotalSize:  1000000,
+	TotalCount: 30000,
+	SortBy:     objects.Size,
+	GroupBy:    objects.Superclass,
+	Items: []objects.ObjectItem{
+		{Name: "java.util.HashMap", TotalSize: 100000, InstancesCount: 2000},
+		{Name: "java.util.LinkedHashMap", TotalSize: 50000, InstancesCount: 1000},
+		{Name: "java.util.TreeMap", TotalSize: 20000, InstancesCount: 500},
+		{Name: "byte[]", TotalSize: 500000, InstancesCount: 10000},
+	},
+	Groups: []objects.ObjectGroup{
+		{
+			ObjectItem: objects.ObjectItem{Name: "java.util.HashMap", TotalSize: 50000, InstancesCount: 1000},
+			Items: []objects.ObjectItem{
+				{Name: "java.util.LinkedHashMap", TotalSize: 50000, InstancesCount: 1000},
+			},
+		},
+		{
+			ObjectItem: objects.ObjectItem{Name: "java.util.AbstractMap", TotalSize: 170000, InstancesCount: 3500},
+			Items: []objects.ObjectItem{
+				{Name: "java.util.TreeMap", TotalSize: 20000, InstancesCount: 500},
+				{Name: "java.util.HashMap", TotalSize: 100000, InstancesCount: 2000},
+				{Name: "java.util.LinkedHashMap", TotalSize: 50000, InstancesCount: 1000},
+			},
+		},
+	},
+}
+
+var (
+	//go:embed test-data/objects4.txt
+	objects4txt string
+	//go:embed test-data/objects4.html
+	objects4html string
+)
+
+func TestObjectsPlain4(t *testing.T) {
+	builder := &strings.Builder{}
+	output.ObjectsPlain(objects4, builder)
+	result := builder.String()
+	if result != objects4txt {
+		compareLineByLine(t, result, objects4txt)
+	}
+}
+
+func TestObjectsHtml4(t *testing.T) {
+	builder := &strings.Builder{}
+	output.ObjectsHtml(objects4, builder)
+	result := builder.String()
+	if result != objects4html {
+		compareLineByLine(t, result, objects4html)
+	}
+}
+
+var objects5 = objects.Objects{
+	TotalSize:     2100000,
+	TotalCount:    73224,
+	Filtered:      true,
+	FilteredSize:  400000,
+	FilteredCount: 10000,
+	Top:           2,
+	SortBy:        objects.Size,
+	Items: []objects.ObjectItem{
+		{Name: "java.util.HashMap", TotalSize: 100000, InstancesCount: 2000},
+		{Name: "java.util.HashMap$Node", TotalSize: 250000, InstancesCount: 7500},
+		{Name: "java.util.HashMap$Node[]", TotalSize: 50000, InstancesCount: 500},
+	},
+}
+
+var (
+	//go:embed test-data/objects5.txt
+	objects5txt string
+	//go:embed test-data/objects5.html
+	objects5html string
+)
+
+func TestObjectsPlain5(t *testing.T) {
+	builder := &strings.Builder{}
+	output.ObjectsPlain(objects5, builder)
+	result := builder.String()
+	if result != objects5txt {
+		compareLineByLine(t, result, objects5txt)
+	}
+}
+
+func TestObjectsHtml5(t *testing.T) {
+	builder := &strings.Builder{}
+	output.ObjectsHtml(objects5, builder)
+	result := builder.String()
+	if result != objects5html {
+		compareLineByLine(t, result, objects5html)
+	}
+}
+
+var (
+	//go:embed test-data/objects4.json
+	objects4json string
+)
+
+func TestObjectsJson4(t *testing.T) {
+	builder := &strings.Builder{}
+	output.ObjectsJson(objects4, builder)
+	result := builder.String()
+	if result != objects4json {
+		compareLineByLine(t, result, objects4json)
+	}
+}
+
+var (
+	//go:embed test-data/objects5.json
+	objects5json string
+)
+
+func TestObjectsJson5(t *testing.T) {
+	builder := &strings.Builder{}
+	output.ObjectsJson(objects5, builder)
+	result := builder.String()
+	if result != objects5json {
+		compareLineByLine(t, result, objects5json)
+	}
+}
+
+var (
+	//go:embed test-data/objects1.csv
+	objects1csv string
+)
+
+func TestObjectsCsv1(t *testing.T) {
+	builder := &strings.Builder{}
+	output.ObjectsCsv(objects1, ',', builder)
+	result := builder.String()
+	if result != objects1csv {
+		compareLineByLine(t, result, objects1csv)
+	}
+}
+
+var (
+	//go:embed test-data/objects4.tsv
+	objects4tsv string
+)
+
+func TestObjectsTsv4(t *testing.T) {
+	builder := &strings.Builder{}
+	output.ObjectsCsv(objects4, '\t', builder)
+	result := builder.String()
+	if result != objects4tsv {
+		compareLineByLine(t, result, objects4tsv)
+	}
+}
+
+var (
+	//go:embed test-data/objects5.csv
+	objects5csv string
+)
+
+func TestObjectsCsv5(t *testing.T) {
+	builder := &strings.Builder{}
+	output.ObjectsCsv(objects5, ',', builder)
+	result := builder.String()
+	if result != objects5csv {
+		compareLineByLine(t, result, objects5csv)
+	}
+}
@@ -0,0 +1,130 @@
+package output
+
+import (
+	_ "embed"
+
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/danielleontiev/neojhat/internal/format"
+	"github.com/danielleontiev/neojhat/internal/paths"
+)
+
+type printPaths struct {
+	Summary []tableSummary
+	Paths   []table
+}
+
+// PathsPlain prints the result of path-to-root command
+// without colors
+func PathsPlain(p paths.Paths, destination io.Writer) {
+	printPathTables(p, identity, identity, identity, identity, destination)
+}
+
+// PathsPlainColor is the same as PathsPlain but
+// with colorful output
+func PathsPlainColor(p paths.Paths) {
+	printPathTables(p, Bold, Cyan, Yellow, Blue, os.Stdout)
+}
+
+func printPathTables(p paths.Paths, headerColor, summaryColor, classNameColor, columnColor func(s string) string, destination io.Writer) {
+	printObj := getPrintPaths(p)
+	for _, s := range printObj.Summary {
+		fmt.Fprintln(destination, summaryColor(fmt.Sprintf("%s: %s", s.Key, s.Val)))
+	}
+	for _, t := range printObj.Paths {
+		fmt.Fprintln(destination)
+		printTable(t, headerColor, summaryColor, classNameColor, columnColor, destination)
+	}
+}
+
+// getPrintPaths converts every path to the table with objects
+// from GC root to the inspected one. Field column has the field
+// of the previous object that holds the reference.
+func getPrintPaths(p paths.Paths) printPaths {
+	printObj := printPaths{
+		Summary: []tableSummary{
+			{Key: "Object", Val: format.ClassName(p.ClassName) + " " + format.ObjectId(uint64(p.ObjectId))},
+			{Key: "Paths", Val: strconv.Itoa(len(p.Items))},
+		},
+	}
+	for i, path := range p.Items {
+		summary := []tableSummary{{Key: fmt.Sprintf("Path %d", i+1), Val: path.Root}}
+		printObj.Paths = append(printObj.Paths, getPathTable(path, summary))
+	}
+	return printObj
+}
+
+func getPathTable(path paths.Path, summary []tableSummary) table {
+	t := table{
+		Summary: summary,
+		Headers: []string{"Class Name", "Object Id", "Field"},
+	}
+	for _, step := range path.Steps {
+		t.Rows = append(t.Rows, []string{
+			format.ClassName(step.ClassName),
+			format.ObjectId(uint64(step.ObjectId)),
+			step.Field,
+		})
+	}
+	return t
+}
+
+var (
+	//go:embed templates/paths.html
+	pathsHtml string
+)
+
+// PathsHtml prints the output of path-to-root command as HTML
+func PathsHtml(p paths.Paths, destination io.Writer) error {
+	coreTemplate, err := template.New("core").Parse(coreHtml)
+	if err != nil {
+		return err
+	}
+	pathsTemplate, err := coreTemplate.Parse(pathsHtml)
+	if err != nil {
+		return err
+	}
+	return pathsTemplate.Execute(destination, data{
+		Title:   "Paths to GC Roots",
+		Favicon: faviconBase64,
+		Payload: getPrintPaths(p),
+	})
+}
+
+// PathsJson prints the output of path-to-root command as JSON
+func PathsJson(p paths.Paths, destination io.Writer) error {
+	p.ClassName = format.ClassName(p.ClassName)
+	items := []paths.Path{}
+	for _, path := range p.Items {
+		items = append(items, jsonPath(path))
+	}
+	p.Items = items
+	return printJson("path-to-root", pathsSchemaVersion, p, destination)
+}
+
+// PathsCsv prints the output of path-to-root command as delimiter-separated
+// values, one row per step of every path, paths are numbered from 1
+func PathsCsv(p paths.Paths, comma rune, destination io.Writer) error {
+	var rows [][]string
+	for i, path := range p.Items {
+		for _, step := range path.Steps {
+			rows = append(rows, []string{strconv.Itoa(i + 1), path.Root,
+				format.ClassName(step.ClassName), format.ObjectId(uint64(step.ObjectId)), step.Field})
+		}
+	}
+	return printRecords([]string{"path", "root", "class_name", "object_id", "field"}, rows, comma, destination)
+}
+
+func jsonPath(path paths.Path) paths.Path {
+	steps := []paths.Step{}
+	for _, step := range path.Steps {
+		step.ClassName = format.ClassName(step.ClassName)
+		steps = append(steps, step)
+	}
+	path.Steps = steps
+	return path
+}
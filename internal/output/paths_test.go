@@ -0,0 +1,58 @@
+package output_test
+
+import (
+	_ "embed"
+
+	"strings"
+	"testing"
+
+	"github.com/danielleontiev/neojhat/internal/output"
+	"github.com/danielleontiev/neojhat/internal/paths"
+)
+
+var paths1 = paths.Paths{
+	ObjectId:  0x7ff0012a8,
+	ClassName: "java/util/HashMap",
+	Items: []paths.Path{
+		{
+			Root: "Java frame of thread main, frame 3",
+			Steps: []paths.Step{
+				{ObjectId: 0x7ff000e10, ClassName: "Main$Worker"},
+				{ObjectId: 0x7ff001040, ClassName: "java/util/Collections$UnmodifiableMap", Field: "cache"},
+				{ObjectId: 0x7ff0012a8, ClassName: "java/util/HashMap", Field: "m"},
+			},
+		},
+		{
+			Root: "sticky class",
+			Steps: []paths.Step{
+				{ObjectId: 0x7ff000230, ClassName: "class Main"},
+				{ObjectId: 0x7ff0012a8, ClassName: "java/util/HashMap", Field: "static CACHE"},
+			},
+		},
+	},
+}
+
+var (
+	//go:embed test-data/paths1.txt
+	paths1txt string
+	//go:embed test-data/paths1.html
+	paths1html string
+)
+
+func TestPathsPlain1(t *testing.T) {
+	builder := &strings.Builder{}
+	output.PathsPlain(paths1, builder)
+	result := builder.String()
+	if result != paths1txt {
+		compareLineByLine(t, result, paths1txt)
+	}
+}
+
+func TestPathsHtml1(t *testing.T) {
+	builder := &strings.Builder{}
+	output.PathsHtml(paths1, builder)
+	result := builder.String()
+	if result != paths1html {
+		compareLineByLine(t, result, paths1html)
+	}
+}
@@ -0,0 +1,51 @@
+package output
+
+import (
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/danielleontiev/neojhat/internal/query"
+)
+
+// QueryPlain prints the result of query command
+// without colors
+func QueryPlain(r query.Result, destination io.Writer) {
+	printTable(getQueryTable(r), identity, identity, identity, identity, destination)
+}
+
+// QueryPlainColor is the same as QueryPlain but
+// with colorful output
+func QueryPlainColor(r query.Result) {
+	printTable(getQueryTable(r), Bold, Cyan, Yellow, Blue, os.Stdout)
+}
+
+// QueryHtml prints the output of query command as HTML
+func QueryHtml(r query.Result, destination io.Writer) error {
+	return tableToHtml("Query", getQueryTable(r), destination)
+}
+
+// QueryJson prints the output of query command as JSON
+func QueryJson(r query.Result, destination io.Writer) error {
+	if r.Rows == nil {
+		r.Rows = [][]string{}
+	}
+	return printJson("query", querySchemaVersion, r, destination)
+}
+
+// QueryCsv prints the output of query command as delimiter-separated
+// values with the selected columns
+func QueryCsv(r query.Result, comma rune, destination io.Writer) error {
+	return printRecords(r.Columns, r.Rows, comma, destination)
+}
+
+func getQueryTable(r query.Result) table {
+	return table{
+		Summary: []tableSummary{
+			{Key: "Query", Val: r.Query},
+			{Key: "Rows", Val: strconv.Itoa(len(r.Rows))},
+		},
+		Headers: r.Columns,
+		Rows:    r.Rows,
+	}
+}
@@ -0,0 +1,46 @@
+package output_test
+
+import (
+	_ "embed"
+
+	"strings"
+	"testing"
+
+	"github.com/danielleontiev/neojhat/internal/output"
+	"github.com/danielleontiev/neojhat/internal/query"
+)
+
+var query1 = query.Result{
+	Query:   "SELECT @id, size, name FROM java.util.HashMap WHERE size > 10000 ORDER BY size DESC LIMIT 3",
+	Columns: []string{"@id", "size", "name"},
+	Rows: [][]string{
+		{"0x7ff0012a8", "65536", "users"},
+		{"0x7ff003f10", "20480", "null"},
+		{"0x7ff0051c0", "10240", "orders"},
+	},
+}
+
+var (
+	//go:embed test-data/query1.txt
+	query1txt string
+	//go:embed test-data/query1.html
+	query1html string
+)
+
+func TestQueryPlain1(t *testing.T) {
+	builder := &strings.Builder{}
+	output.QueryPlain(query1, builder)
+	result := builder.String()
+	if result != query1txt {
+		compareLineByLine(t, result, query1txt)
+	}
+}
+
+func TestQueryHtml1(t *testing.T) {
+	builder := &strings.Builder{}
+	output.QueryHtml(query1, builder)
+	result := builder.String()
+	if result != query1html {
+		compareLineByLine(t, result, query1html)
+	}
+}
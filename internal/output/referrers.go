@@ -0,0 +1,67 @@
+package output
+
+import (
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/danielleontiev/neojhat/internal/format"
+	"github.com/danielleontiev/neojhat/internal/referrers"
+)
+
+// ReferrersPlain prints the result of referrers command
+// without colors
+func ReferrersPlain(r referrers.Referrers, destination io.Writer) {
+	printTable(getReferrersTable(r), identity, identity, identity, identity, destination)
+}
+
+// ReferrersPlainColor is the same as ReferrersPlain but
+// with colorful output
+func ReferrersPlainColor(r referrers.Referrers) {
+	printTable(getReferrersTable(r), Bold, Cyan, Yellow, Blue, os.Stdout)
+}
+
+// ReferrersHtml prints the output of referrers command as HTML
+func ReferrersHtml(r referrers.Referrers, destination io.Writer) error {
+	return tableToHtml("Referrers", getReferrersTable(r), destination)
+}
+
+// ReferrersJson prints the output of referrers command as JSON
+func ReferrersJson(r referrers.Referrers, destination io.Writer) error {
+	r.ClassName = format.ClassName(r.ClassName)
+	items := []referrers.Referrer{}
+	for _, item := range r.Items {
+		item.ClassName = format.ClassName(item.ClassName)
+		items = append(items, item)
+	}
+	r.Items = items
+	return printJson("referrers", referrersSchemaVersion, r, destination)
+}
+
+// ReferrersCsv prints the output of referrers command
+// as delimiter-separated values
+func ReferrersCsv(r referrers.Referrers, comma rune, destination io.Writer) error {
+	var rows [][]string
+	for _, item := range r.Items {
+		rows = append(rows, []string{format.ClassName(item.ClassName), format.ObjectId(uint64(item.ObjectId)), item.Field})
+	}
+	return printRecords([]string{"class_name", "object_id", "field"}, rows, comma, destination)
+}
+
+func getReferrersTable(r referrers.Referrers) table {
+	t := table{
+		Summary: []tableSummary{
+			{Key: "Object", Val: format.ClassName(r.ClassName) + " " + format.ObjectId(uint64(r.ObjectId))},
+			{Key: "Referrers", Val: strconv.Itoa(len(r.Items))},
+		},
+		Headers: []string{"Class Name", "Object Id", "Field"},
+	}
+	for _, item := range r.Items {
+		t.Rows = append(t.Rows, []string{
+			format.ClassName(item.ClassName),
+			format.ObjectId(uint64(item.ObjectId)),
+			item.Field,
+		})
+	}
+	return t
+}
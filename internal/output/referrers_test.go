@@ -0,0 +1,58 @@
+package output_test
+
+import (
+	_ "embed"
+
+	"strings"
+	"testing"
+
+	"github.com/danielleontiev/neojhat/internal/output"
+	"github.com/danielleontiev/neojhat/internal/referrers"
+)
+
+var referrers1 = referrers.Referrers{
+	ObjectId:  0x7ff0012a8,
+	ClassName: "java/util/HashMap",
+	Items: []referrers.Referrer{
+		{
+			ObjectId:  0x7ff001040,
+			ClassName: "java/util/Collections$UnmodifiableMap",
+			Field:     "m",
+		},
+		{
+			ObjectId:  0x7ff0019c0,
+			ClassName: "java.lang.Object[]",
+			Field:     "[12]",
+		},
+		{
+			ObjectId:  0x7ff000230,
+			ClassName: "class Main",
+			Field:     "static CACHE",
+		},
+	},
+}
+
+var (
+	//go:embed test-data/referrers1.txt
+	referrers1txt string
+	//go:embed test-data/referrers1.html
+	referrers1html string
+)
+
+func TestReferrersPlain1(t *testing.T) {
+	builder := &strings.Builder{}
+	output.ReferrersPlain(referrers1, builder)
+	result := builder.String()
+	if result != referrers1txt {
+		compareLineByLine(t, result, referrers1txt)
+	}
+}
+
+func TestReferrersHtml1(t *testing.T) {
+	builder := &strings.Builder{}
+	output.ReferrersHtml(referrers1, builder)
+	result := builder.String()
+	if result != referrers1html {
+		compareLineByLine(t, result, referrers1html)
+	}
+}
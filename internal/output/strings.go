@@ -0,0 +1,81 @@
+package output
+
+import (
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/danielleontiev/neojhat/internal/duplicates"
+	"github.com/danielleontiev/neojhat/internal/format"
+)
+
+// maxStringLength is the number of characters of the
+// string value to show, the rest is cut off.
+const maxStringLength = 60
+
+// StringsPlain prints the result of strings command
+// without colors
+func StringsPlain(s duplicates.DuplicateStrings, destination io.Writer) {
+	printTable(getStringsTable(s), identity, identity, identity, identity, destination)
+}
+
+// StringsPlainColor is the same as StringsPlain but
+// with colorful output
+func StringsPlainColor(s duplicates.DuplicateStrings) {
+	printTable(getStringsTable(s), Bold, Cyan, Yellow, Blue, os.Stdout)
+}
+
+// StringsHtml prints the output of strings command as HTML
+func StringsHtml(s duplicates.DuplicateStrings, destination io.Writer) error {
+	return tableToHtml("Duplicate Strings", getStringsTable(s), destination)
+}
+
+// StringsJson prints the output of strings command as JSON
+func StringsJson(s duplicates.DuplicateStrings, destination io.Writer) error {
+	items := []duplicates.DuplicateString{}
+	for _, item := range s.Items {
+		item.SampleReferrer = format.ClassName(item.SampleReferrer)
+		items = append(items, item)
+	}
+	s.Items = items
+	return printJson("strings", stringsSchemaVersion, s, destination)
+}
+
+// StringsCsv prints the output of strings command as delimiter-separated
+// values, the values of the strings are not cut unlike in the table
+func StringsCsv(s duplicates.DuplicateStrings, comma rune, destination io.Writer) error {
+	var rows [][]string
+	for _, item := range s.Items {
+		rows = append(rows, []string{item.Value, strconv.Itoa(item.Count), strconv.Itoa(item.WastedSize),
+			format.ObjectId(uint64(item.SampleId)), format.ClassName(item.SampleReferrer)})
+	}
+	return printRecords([]string{"value", "count", "wasted_size", "sample_id", "sample_referrer"}, rows, comma, destination)
+}
+
+// getStringsTable shows values quoted, so line breaks
+// and other special characters do not break the table.
+func getStringsTable(s duplicates.DuplicateStrings) table {
+	t := table{
+		Summary: []tableSummary{
+			{Key: "Strings", Val: strconv.Itoa(s.TotalCount)},
+			{Key: "Duplicated", Val: strconv.Itoa(s.DuplicatedCount)},
+			{Key: "Wasted", Val: format.Size(s.WastedSize)},
+		},
+		Headers: []string{"Value", "Count", "Wasted", "Sample Id", "Sample Referrer"},
+	}
+	for _, item := range s.Items {
+		value := []rune(item.Value)
+		quoted := strconv.Quote(string(value))
+		if len(value) > maxStringLength {
+			quoted = strconv.Quote(string(value[:maxStringLength])) + "..."
+		}
+		t.Rows = append(t.Rows, []string{
+			quoted,
+			strconv.Itoa(item.Count),
+			format.Size(item.WastedSize),
+			format.ObjectId(uint64(item.SampleId)),
+			format.ClassName(item.SampleReferrer),
+		})
+	}
+	return t
+}
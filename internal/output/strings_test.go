@@ -0,0 +1,64 @@
+package output_test
+
+import (
+	_ "embed"
+
+	"strings"
+	"testing"
+
+	"github.com/danielleontiev/neojhat/internal/duplicates"
+	"github.com/danielleontiev/neojhat/internal/output"
+)
+
+var strings1 = duplicates.DuplicateStrings{
+	Items: []duplicates.DuplicateString{
+		{
+			Value:          "application/json",
+			Count:          120400,
+			WastedSize:     6742344,
+			SampleId:       0x7ff0012a8,
+			SampleReferrer: "java/util/HashMap$Node",
+		},
+		{
+			Value:          "SELECT id, name, email, created_at FROM users WHERE tenant_id = ? AND deleted = false",
+			Count:          2100,
+			WastedSize:     436800,
+			SampleId:       0x7ff001040,
+			SampleReferrer: "com/example/Query",
+		},
+		{
+			Value:      "\n",
+			Count:      40,
+			WastedSize: 1520,
+			SampleId:   0x7ff0019c0,
+		},
+	},
+	TotalCount:      1048576,
+	DuplicatedCount: 122540,
+	WastedSize:      7180664,
+}
+
+var (
+	//go:embed test-data/strings1.txt
+	strings1txt string
+	//go:embed test-data/strings1.html
+	strings1html string
+)
+
+func TestStringsPlain1(t *testing.T) {
+	builder := &strings.Builder{}
+	output.StringsPlain(strings1, builder)
+	result := builder.String()
+	if result != strings1txt {
+		compareLineByLine(t, result, strings1txt)
+	}
+}
+
+func TestStringsHtml1(t *testing.T) {
+	builder := &strings.Builder{}
+	output.StringsHtml(strings1, builder)
+	result := builder.String()
+	if result != strings1html {
+		compareLineByLine(t, result, strings1html)
+	}
+}
@@ -54,8 +54,22 @@ func getPropertiesList(s summary.Summary) []Properties {
 	heap := []summary.Kv{
 		{Key: "Classes", Val: strconv.Itoa(s.Heap.Classes)},
 		{Key: "GC Roots", Val: strconv.Itoa(s.Heap.GcRoots)},
-		{Key: "Instances", Val: strconv.Itoa(s.Heap.Instances)},
-		{Key: "Heap Size", Val: format.Size(s.Heap.HeapSize)},
+	}
+	for _, kv := range s.Heap.GcRootsByKind {
+		heap = append(heap, summary.Kv{Key: "  " + kv.Key, Val: kv.Val})
+	}
+	heap = append(heap,
+		summary.Kv{Key: "Instances", Val: strconv.Itoa(s.Heap.Instances)},
+		summary.Kv{Key: "Heap Size", Val: format.Size(s.Heap.HeapSize)},
+	)
+	for _, h := range s.Heap.Heaps {
+		heap = append(heap, summary.Kv{
+			Key: "  " + h.Name,
+			Val: fmt.Sprintf("%v, %v instances", format.Size(h.HeapSize), h.Instances),
+		})
+	}
+	if s.Heap.Layout != "" {
+		heap = append(heap, summary.Kv{Key: "Layout", Val: s.Heap.Layout})
 	}
 	system := []summary.Kv{
 		{Key: "JVM Uptime", Val: s.System.JvmUptime},
@@ -121,3 +135,46 @@ func SummaryHtml(s summary.Summary, destination io.Writer) error {
 		Payload: props,
 	})
 }
+
+// SummaryJson prints the output of summary command as JSON
+func SummaryJson(s summary.Summary, destination io.Writer) error {
+	return printJson("summary", summarySchemaVersion, s, destination)
+}
+
+// SummaryCsv prints the output of summary command as delimiter-separated
+// values, one row per property with the section it belongs to. Sizes
+// are in bytes.
+func SummaryCsv(s summary.Summary, comma rune, destination io.Writer) error {
+	header := []string{"section", "key", "value"}
+	var rows [][]string
+	add := func(section string, properties []summary.Kv) {
+		for _, kv := range properties {
+			rows = append(rows, []string{section, kv.Key, kv.Val})
+		}
+	}
+	add("Environment", []summary.Kv{
+		{Key: "Architecture", Val: s.Env.Architecture},
+		{Key: "JavaHome", Val: s.Env.JavaHome},
+		{Key: "JavaName", Val: s.Env.JavaName},
+		{Key: "JavaVendor", Val: s.Env.JavaVendor},
+		{Key: "JavaVersion", Val: s.Env.JavaVersion},
+		{Key: "System", Val: s.Env.System},
+	})
+	add("Heap", []summary.Kv{
+		{Key: "Classes", Val: strconv.Itoa(s.Heap.Classes)},
+		{Key: "GC Roots", Val: strconv.Itoa(s.Heap.GcRoots)},
+		{Key: "Instances", Val: strconv.Itoa(s.Heap.Instances)},
+		{Key: "Heap Size", Val: strconv.Itoa(s.Heap.HeapSize)},
+		{Key: "Layout", Val: s.Heap.Layout},
+	})
+	add("GC Roots", s.Heap.GcRootsByKind)
+	for _, h := range s.Heap.Heaps {
+		add("Heaps", []summary.Kv{
+			{Key: h.Name + " Instances", Val: strconv.Itoa(h.Instances)},
+			{Key: h.Name + " Heap Size", Val: strconv.Itoa(h.HeapSize)},
+		})
+	}
+	add("System", []summary.Kv{{Key: "JVM Uptime", Val: s.System.JvmUptime}})
+	add("Properties", s.Properties)
+	return printRecords(header, rows, comma, destination)
+}
@@ -23,10 +23,18 @@ var summary1 = summary.Summary{
 		System:       "Mac OS X",
 	},
 	Heap: summary.HeapProperties{
-		Classes:   42,
-		GcRoots:   43,
+		Classes: 42,
+		GcRoots: 43,
+		GcRootsByKind: []summary.Kv{
+			{Key: "JNI global", Val: "12"},
+			{Key: "Java frame", Val: "20"},
+			{Key: "Sticky class", Val: "7"},
+			{Key: "Thread object", Val: "3"},
+			{Key: "Monitor", Val: "1"},
+		},
 		HeapSize:  44,
 		Instances: 45,
+		Layout:    "64-bit, compressed oops, compressed class pointers, 8-byte alignment",
 	},
 	System: summary.SystemProperties{
 		JvmUptime: "40s",
@@ -55,3 +63,69 @@ func TestSummaryHtml1(t *testing.T) {
 	result := builder.String()
 	compareLineByLine(t, summary1html, result)
 }
+
+var summary2 = summary.Summary{
+	Env: summary.EnvProperties{
+		Architecture: "aarch64",
+		JavaHome:     "/system",
+		JavaName:     "Dalvik (2.1.0, )",
+		JavaVendor:   "The Android Project",
+		JavaVersion:  "0",
+		System:       "Linux",
+	},
+	Heap: summary.HeapProperties{
+		Classes: 4210,
+		GcRoots: 2,
+		GcRootsByKind: []summary.Kv{
+			{Key: "Interned string", Val: "1"},
+			{Key: "VM internal", Val: "1"},
+		},
+		HeapSize:  5360000,
+		Instances: 88552,
+		Heaps: []summary.HeapSize{
+			{Name: "app", HeapSize: 760000, Instances: 20210},
+			{Name: "image", HeapSize: 3400000, Instances: 51002},
+			{Name: "zygote", HeapSize: 1200000, Instances: 17340},
+		},
+	},
+	System: summary.SystemProperties{
+		JvmUptime: "1m5s",
+	},
+}
+
+var (
+	//go:embed test-data/summary2.txt
+	summary2txt string
+	//go:embed test-data/summary2.html
+	summary2html string
+)
+
+func TestSummaryPlain2(t *testing.T) {
+	builder := &strings.Builder{}
+	output.SummaryPlain(summary2, builder)
+	result := builder.String()
+	if result != summary2txt {
+		compareLineByLine(t, result, summary2txt)
+	}
+}
+
+func TestSummaryHtml2(t *testing.T) {
+	builder := &strings.Builder{}
+	output.SummaryHtml(summary2, builder)
+	result := builder.String()
+	compareLineByLine(t, summary2html, result)
+}
+
+var (
+	//go:embed test-data/summary1.json
+	summary1json string
+)
+
+func TestSummaryJson1(t *testing.T) {
+	builder := &strings.Builder{}
+	output.SummaryJson(summary1, builder)
+	result := builder.String()
+	if result != summary1json {
+		compareLineByLine(t, result, summary1json)
+	}
+}
@@ -0,0 +1,102 @@
+package output
+
+import (
+	_ "embed"
+
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// table is the common shape of command results that are lists
+// of objects. Summary lines are printed above the table. The first
+// column is aligned left and the rest are aligned right like in the
+// output of objects command.
+type table struct {
+	Summary []tableSummary
+	Headers []string
+	Rows    [][]string
+}
+
+type tableSummary struct {
+	Key string
+	Val string
+}
+
+// printTable prints the table as plain text. firstColumnColor is applied
+// to the first column (usually class names), columnColor - to the rest.
+func printTable(t table, headerColor, summaryColor, firstColumnColor, columnColor func(s string) string, destination io.Writer) {
+	const gap = 10
+	widths := make([]int, len(t.Headers))
+	for _, row := range append([][]string{t.Headers}, t.Rows...) {
+		for i, cell := range row {
+			if l := utf8.RuneCountInString(cell); l > widths[i] {
+				widths[i] = l
+			}
+		}
+	}
+	align := func(s string, i int) string {
+		padding := strings.Repeat(" ", widths[i]+gap-utf8.RuneCountInString(s))
+		if i == 0 {
+			return s + padding
+		}
+		return padding + s
+	}
+	stringifyRow := func(row []string, firstColor, color func(s string) string) string {
+		var b strings.Builder
+		for i, cell := range row {
+			if i == 0 {
+				b.WriteString(firstColor(align(cell, i)))
+			} else {
+				b.WriteString(color(align(cell, i)))
+			}
+			b.WriteString(" |")
+		}
+		return b.String()
+	}
+
+	for _, s := range t.Summary {
+		fmt.Fprintln(destination, summaryColor(fmt.Sprintf("%s: %s", s.Key, s.Val)))
+	}
+	if len(t.Summary) > 0 {
+		fmt.Fprintln(destination)
+	}
+	fmt.Fprintln(destination, headerColor(stringifyRow(t.Headers, identity, identity)))
+	lineLength := 0
+	for _, w := range widths {
+		lineLength += w + gap + 2
+	}
+	fmt.Fprintln(destination, strings.Repeat("-", lineLength))
+	for _, row := range t.Rows {
+		fmt.Fprintln(destination, stringifyRow(row, firstColumnColor, columnColor))
+	}
+}
+
+func identity(s string) string {
+	return s
+}
+
+var (
+	//go:embed templates/table.html
+	tableHtml string
+)
+
+// tableToHtml renders the table with the given title
+// using the common table template.
+func tableToHtml(title string, t table, destination io.Writer) error {
+	coreTemplate, err := template.New("core").Parse(coreHtml)
+	if err != nil {
+		return err
+	}
+	tableTemplate, err := coreTemplate.Parse(tableHtml)
+	if err != nil {
+		return err
+	}
+	return tableTemplate.Execute(destination, data{
+		Title:   title,
+		Favicon: faviconBase64,
+		Payload: t,
+	})
+}
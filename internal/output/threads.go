@@ -7,6 +7,7 @@ import (
 	"html/template"
 	"io"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/danielleontiev/neojhat/internal/core"
@@ -183,3 +184,44 @@ func ThreadsHtml(threadDump threads.ThreadDump, localVars bool, destination io.W
 		},
 	})
 }
+
+// ThreadsJson prints the output of threads command as JSON, local
+// variables are included only when localVars is set
+func ThreadsJson(threadDump threads.ThreadDump, localVars bool, destination io.Writer) error {
+	traces := []threads.StackTrace{}
+	for _, t := range getSortedStackTraces(threadDump) {
+		frames := []threads.StackFrame{}
+		for _, f := range t.Frames {
+			f.ClassName = format.ClassName(f.ClassName)
+			if !localVars {
+				f.LocalFrames = nil
+			}
+			frames = append(frames, f)
+		}
+		t.Frames = frames
+		traces = append(traces, t)
+	}
+	return printJson("threads", threadsSchemaVersion, threads.ThreadDump{StackTraces: traces}, destination)
+}
+
+// ThreadsCsv prints the output of threads command as delimiter-separated
+// values, one row per frame. The thread without frames still has a row
+// with empty frame columns.
+func ThreadsCsv(threadDump threads.ThreadDump, comma rune, destination io.Writer) error {
+	header := []string{"thread_id", "thread_name", "thread_status", "thread_daemon", "thread_priority",
+		"frame", "class_name", "method_name", "method_signature", "file_name", "line_number"}
+	var rows [][]string
+	for _, t := range getSortedStackTraces(threadDump) {
+		thread := []string{strconv.Itoa(t.ThreadId), t.ThreadName, t.ThreadStatus.String(),
+			strconv.FormatBool(t.ThreadDaemon), strconv.Itoa(t.ThreadPriority)}
+		if len(t.Frames) == 0 {
+			rows = append(rows, append(thread, "", "", "", "", "", ""))
+		}
+		for i, f := range t.Frames {
+			row := append([]string{}, thread...)
+			rows = append(rows, append(row, strconv.Itoa(i), format.ClassName(f.ClassName),
+				f.MethodName, f.MethodSignature, f.FileName, f.LineNumber))
+		}
+	}
+	return printRecords(header, rows, comma, destination)
+}
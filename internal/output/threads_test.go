@@ -98,3 +98,31 @@ func TestThreadHtml2(t *testing.T) {
 		compareLineByLine(t, result, threads2html)
 	}
 }
+
+var (
+	//go:embed test-data/threads1.json
+	threads1json string
+)
+
+func TestThreadJson1(t *testing.T) {
+	builder := &strings.Builder{}
+	output.ThreadsJson(threads1, true, builder)
+	result := builder.String()
+	if result != threads1json {
+		compareLineByLine(t, result, threads1json)
+	}
+}
+
+var (
+	//go:embed test-data/threads1.csv
+	threads1csv string
+)
+
+func TestThreadCsv1(t *testing.T) {
+	builder := &strings.Builder{}
+	output.ThreadsCsv(threads1, ',', builder)
+	result := builder.String()
+	if result != threads1csv {
+		compareLineByLine(t, result, threads1csv)
+	}
+}
@@ -0,0 +1,25 @@
+package paths
+
+import "github.com/danielleontiev/neojhat/internal/core"
+
+// Step is the object on the path. Field is the field (or array
+// element) of the previous object on the path that holds
+// the reference to this one, it's empty for GC root.
+type Step struct {
+	ObjectId  core.Identifier `json:"objectId"`
+	ClassName string          `json:"className"`
+	Field     string          `json:"field,omitempty"`
+}
+
+// Path is the chain of references starting at GC
+// root and ending at the inspected object.
+type Path struct {
+	Root  string `json:"root"`
+	Steps []Step `json:"steps"`
+}
+
+type Paths struct {
+	ObjectId  core.Identifier `json:"objectId"`
+	ClassName string          `json:"className"`
+	Items     []Path          `json:"items"`
+}
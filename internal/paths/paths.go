@@ -0,0 +1,146 @@
+// paths answers the question "why the object is alive". It walks the
+// references index backwards from the object with breadth-first search
+// until GC roots are met, so every found path is the shortest path from
+// its root. Roots are not expanded further: the path through one GC
+// root to another one does not tell anything new.
+package paths
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/danielleontiev/neojhat/internal/core"
+	"github.com/danielleontiev/neojhat/internal/dump"
+	"github.com/danielleontiev/neojhat/internal/java"
+)
+
+// weakReferences are classes whose referent field does
+// not prevent the referenced object from being collected.
+var weakReferences = []string{
+	"java/lang/ref/WeakReference",
+	"java/lang/ref/SoftReference",
+	"java/lang/ref/PhantomReference",
+}
+
+// GetPaths returns at most maxPaths shortest paths from GC roots to
+// the object. When excludeWeak is set, referent fields of weak, soft
+// and phantom references are not followed.
+func GetPaths(parsedAccessor *dump.ParsedAccessor, objectId core.Identifier, excludeWeak bool, maxPaths int) (Paths, error) {
+	heap := java.NewHeap(parsedAccessor)
+	className, err := heap.ObjectTypeName(objectId)
+	if err != nil {
+		return Paths{}, err
+	}
+	roots := make(map[core.Identifier][]java.GcRoot)
+	for _, root := range heap.GcRoots() {
+		roots[root.ObjectId] = append(roots[root.ObjectId], root)
+	}
+
+	// next is the edge from the visited object towards the inspected one
+	type edge struct {
+		next  core.Identifier
+		field string
+	}
+	visited := map[core.Identifier]edge{objectId: {}}
+	queue := []core.Identifier{objectId}
+	var found []core.Identifier
+	for len(queue) > 0 && len(found) < maxPaths {
+		current := queue[0]
+		queue = queue[1:]
+		if _, ok := roots[current]; ok {
+			found = append(found, current)
+			continue
+		}
+		references, err := heap.Referrers(current)
+		if err != nil {
+			return Paths{}, fmt.Errorf("cannot find referrers of %v: %w", current, err)
+		}
+		for _, reference := range references {
+			if _, ok := visited[reference.From]; ok {
+				continue
+			}
+			if excludeWeak {
+				weak, err := isWeakReferent(heap, reference)
+				if err != nil {
+					return Paths{}, err
+				}
+				if weak {
+					continue
+				}
+			}
+			visited[reference.From] = edge{next: current, field: reference.Name()}
+			queue = append(queue, reference.From)
+		}
+	}
+
+	var items []Path
+	for _, rootId := range found {
+		root := describeRoots(heap, roots[rootId])
+		var steps []Step
+		field := ""
+		for id := rootId; ; id = visited[id].next {
+			stepClassName, err := heap.ObjectTypeName(id)
+			if err != nil {
+				return Paths{}, err
+			}
+			steps = append(steps, Step{ObjectId: id, ClassName: stepClassName, Field: field})
+			if id == objectId {
+				break
+			}
+			field = visited[id].field
+		}
+		items = append(items, Path{Root: root, Steps: steps})
+	}
+	return Paths{
+		ObjectId:  objectId,
+		ClassName: className,
+		Items:     items,
+	}, nil
+}
+
+func isWeakReferent(heap *java.Heap, reference java.Reference) (bool, error) {
+	if reference.Kind != java.InstanceFieldReference || reference.Field != "referent" {
+		return false, nil
+	}
+	for _, className := range weakReferences {
+		weak, err := heap.InstanceOf(reference.From, className)
+		if err != nil {
+			return false, err
+		}
+		if weak {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// describeRoots explains why the object is a GC root, e.g.
+// "Java frame of thread main, frame 3". The object can be
+// a root for several reasons at once.
+func describeRoots(heap *java.Heap, roots []java.GcRoot) string {
+	var descriptions []string
+	for _, root := range roots {
+		switch root.Kind {
+		case java.JniLocalRoot, java.JavaFrameRoot:
+			descriptions = append(descriptions, fmt.Sprintf("%s of thread %s, frame %d",
+				root.Kind, threadName(heap, root.ThreadSerialNumber), root.FrameNumber))
+		case java.ThreadObjectRoot, java.NativeStackRoot, java.ThreadBlockRoot, java.JniMonitorRoot:
+			descriptions = append(descriptions, fmt.Sprintf("%s of thread %s",
+				root.Kind, threadName(heap, root.ThreadSerialNumber)))
+		default:
+			descriptions = append(descriptions, root.Kind.String())
+		}
+	}
+	return strings.Join(descriptions, ", ")
+}
+
+// threadName falls back to the serial number of the thread
+// when its name cannot be read, e.g. when the thread object
+// is missing in the dump.
+func threadName(heap *java.Heap, threadSerialNumber uint32) string {
+	name, err := heap.ThreadName(threadSerialNumber)
+	if err != nil {
+		return fmt.Sprintf("#%d", threadSerialNumber)
+	}
+	return name
+}
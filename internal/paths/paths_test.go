@@ -0,0 +1,102 @@
+package paths
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/danielleontiev/neojhat/internal/core"
+	td "github.com/danielleontiev/neojhat/internal/testdump"
+)
+
+// Thread "main" (serial 1) has Node 1000 in frame 3,
+// WeakReference 2000 is JNI global.
+//
+//	frame → 1000 → 1001 → 1002
+//	JNI global → 2000 (WeakReference) ⇢ 1002
+var pathsSample = td.Dump(
+	[][]byte{
+		td.Utf8(1, "java/lang/Object"),
+		td.Utf8(2, "java/lang/String"),
+		td.Utf8(3, "java/lang/Thread"),
+		td.Utf8(4, "Node"),
+		td.Utf8(5, "java/lang/ref/Reference"),
+		td.Utf8(6, "java/lang/ref/WeakReference"),
+		td.Utf8(7, "value"),
+		td.Utf8(8, "name"),
+		td.Utf8(9, "next"),
+		td.Utf8(10, "referent"),
+		td.LoadClass(1, 100, 1),
+		td.LoadClass(2, 101, 2),
+		td.LoadClass(3, 102, 3),
+		td.LoadClass(4, 103, 4),
+		td.LoadClass(5, 104, 5),
+		td.LoadClass(6, 105, 6),
+	},
+	td.RootThreadObj(600, 1, 0),
+	td.RootJavaFrame(1000, 1, 3),
+	td.RootJniGlobal(2000),
+	td.ClassDump(100, 0, 0, nil, nil),
+	td.ClassDump(101, 100, 8, nil, []td.Field{{NameId: 7, Type: core.Object}}),
+	td.ClassDump(102, 100, 8, nil, []td.Field{{NameId: 8, Type: core.Object}}),
+	td.ClassDump(103, 100, 8, nil, []td.Field{{NameId: 9, Type: core.Object}}),
+	td.ClassDump(104, 100, 8, nil, []td.Field{{NameId: 10, Type: core.Object}}),
+	td.ClassDump(105, 104, 8, nil, nil),
+	td.InstanceDump(500, 101, td.Id(501)),
+	td.PrimArrayDump(501, core.Byte, 4, []byte("main")),
+	td.InstanceDump(600, 102, td.Id(500)),
+	td.InstanceDump(1000, 103, td.Id(1001)),
+	td.InstanceDump(1001, 103, td.Id(1002)),
+	td.InstanceDump(1002, 103, td.Id(0)),
+	td.InstanceDump(2000, 105, td.Id(1002)),
+)
+
+var (
+	weakPath = Path{
+		Root: "JNI global",
+		Steps: []Step{
+			{ObjectId: 2000, ClassName: "java/lang/ref/WeakReference"},
+			{ObjectId: 1002, ClassName: "Node", Field: "referent"},
+		},
+	}
+	framePath = Path{
+		Root: "Java frame of thread main, frame 3",
+		Steps: []Step{
+			{ObjectId: 1000, ClassName: "Node"},
+			{ObjectId: 1001, ClassName: "Node", Field: "next"},
+			{ObjectId: 1002, ClassName: "Node", Field: "next"},
+		},
+	}
+)
+
+func TestGetPaths(t *testing.T) {
+	parsedAccessor, err := td.NewParsedAccessor(pathsSample, true)
+	if err != nil {
+		t.Fatalf("cannot parse sample: %v", err)
+	}
+	tests := []struct {
+		name        string
+		objectId    core.Identifier
+		excludeWeak bool
+		maxPaths    int
+		want        []Path
+	}{
+		{name: "all paths", objectId: 1002, maxPaths: 10, want: []Path{weakPath, framePath}},
+		{name: "exclude weak", objectId: 1002, excludeWeak: true, maxPaths: 10, want: []Path{framePath}},
+		{name: "max paths", objectId: 1002, maxPaths: 1, want: []Path{weakPath}},
+		{
+			name: "root itself", objectId: 600, maxPaths: 10,
+			want: []Path{{Root: "thread object of thread main", Steps: []Step{{ObjectId: 600, ClassName: "java/lang/Thread"}}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetPaths(parsedAccessor, tt.objectId, tt.excludeWeak, tt.maxPaths)
+			if err != nil {
+				t.Fatalf("GetPaths() error = %v", err)
+			}
+			if !reflect.DeepEqual(got.Items, tt.want) {
+				t.Errorf("GetPaths() = %+v, want %+v", got.Items, tt.want)
+			}
+		})
+	}
+}
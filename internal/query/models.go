@@ -0,0 +1,72 @@
+package query
+
+// Query is the parsed query:
+//
+//	SELECT <columns> FROM [INSTANCEOF] <class> [WHERE <condition>]
+//	[ORDER BY <operand> [ASC|DESC], ...] [LIMIT <number>]
+//
+// Columns is nil for SELECT *. Limit is 0 when there is no limit.
+type Query struct {
+	Columns    []FieldPath
+	Class      string
+	InstanceOf bool
+	Where      Expr
+	OrderBy    []Order
+	Limit      int
+}
+
+// FieldPath is the chain of field names, e.g. "name.value" is
+// the field "value" of the object referenced by the field "name".
+// Pseudo-fields @id, @class and @size are the identifier, class
+// name and shallow size of the object.
+type FieldPath []string
+
+// Order is the single key of ORDER BY clause.
+type Order struct {
+	Operand Operand
+	Desc    bool
+}
+
+// Expr is the node of WHERE condition, one of And, Or,
+// Not and Comparison.
+type Expr interface {
+	expr()
+}
+
+type And struct {
+	Left, Right Expr
+}
+
+type Or struct {
+	Left, Right Expr
+}
+
+type Not struct {
+	Expr Expr
+}
+
+// Comparison compares two operands with one of =, !=, <, <=, >,
+// >= and LIKE. IS NULL and IS NOT NULL are comparisons with null.
+type Comparison struct {
+	Op          string
+	Left, Right Operand
+}
+
+func (And) expr()        {}
+func (Or) expr()         {}
+func (Not) expr()        {}
+func (Comparison) expr() {}
+
+// Operand is either the field of the object or the literal. Literal
+// is int64, float64, string, bool or nil for null.
+type Operand struct {
+	Field   FieldPath
+	Literal any
+}
+
+// Result is the table with the values of the selected columns.
+type Result struct {
+	Query   string     `json:"query"`
+	Columns []string   `json:"columns"`
+	Rows    [][]string `json:"rows"`
+}
@@ -0,0 +1,372 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	endToken tokenKind = iota
+	identToken
+	numberToken
+	stringToken
+	symbolToken
+)
+
+type token struct {
+	kind     tokenKind
+	text     string
+	position int
+}
+
+func (t token) String() string {
+	if t.kind == endToken {
+		return "end of query"
+	}
+	return fmt.Sprintf("%q at position %d", t.text, t.position)
+}
+
+// tokenize splits the query into identifiers (class and field names and
+// keywords), numbers, quoted strings and symbols of comparisons.
+func tokenize(query string) ([]token, error) {
+	var tokens []token
+	runes := []rune(query)
+	isIdent := func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_$.@", r)
+	}
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			i++
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: numberToken, text: string(runes[start:i]), position: start})
+		case unicode.IsLetter(r) || r == '_' || r == '$' || r == '@':
+			start := i
+			for i < len(runes) && isIdent(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: identToken, text: string(runes[start:i]), position: start})
+		case r == '\'' || r == '"':
+			start := i
+			var b strings.Builder
+			i++
+			for ; i < len(runes) && runes[i] != r; i++ {
+				b.WriteRune(runes[i])
+			}
+			if i == len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			i++
+			tokens = append(tokens, token{kind: stringToken, text: b.String(), position: start})
+		default:
+			start := i
+			symbol := string(r)
+			if i+1 < len(runes) {
+				if two := string(runes[i : i+2]); two == "<=" || two == ">=" || two == "!=" || two == "<>" {
+					symbol = two
+				}
+			}
+			if !symbols[symbol] {
+				return nil, fmt.Errorf("unexpected %q at position %d", symbol, start)
+			}
+			i += len([]rune(symbol))
+			tokens = append(tokens, token{kind: symbolToken, text: symbol, position: start})
+		}
+	}
+	return append(tokens, token{kind: endToken, position: len(runes)}), nil
+}
+
+var symbols = map[string]bool{
+	"=": true, "!=": true, "<>": true, "<": true, "<=": true, ">": true, ">=": true,
+	"(": true, ")": true, ",": true, "*": true,
+}
+
+var comparisons = map[string]bool{
+	"=": true, "!=": true, "<>": true, "<": true, "<=": true, ">": true, ">=": true,
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// Parse parses the query. Keywords are case-insensitive, class names
+// are accepted both as java.util.HashMap and java/util/HashMap.
+func Parse(query string) (Query, error) {
+	tokens, err := tokenize(query)
+	if err != nil {
+		return Query{}, err
+	}
+	p := &parser{tokens: tokens}
+	return p.query()
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != endToken {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isKeyword(keyword string) bool {
+	t := p.peek()
+	return t.kind == identToken && strings.EqualFold(t.text, keyword)
+}
+
+func (p *parser) isSymbol(symbol string) bool {
+	t := p.peek()
+	return t.kind == symbolToken && t.text == symbol
+}
+
+func (p *parser) expectKeyword(keyword string) error {
+	if !p.isKeyword(keyword) {
+		return fmt.Errorf("unexpected %v, expected %s", p.peek(), keyword)
+	}
+	p.next()
+	return nil
+}
+
+func (p *parser) query() (Query, error) {
+	var q Query
+	if err := p.expectKeyword("SELECT"); err != nil {
+		return Query{}, err
+	}
+	if p.isSymbol("*") {
+		p.next()
+	} else {
+		for {
+			field, err := p.fieldPath()
+			if err != nil {
+				return Query{}, err
+			}
+			q.Columns = append(q.Columns, field)
+			if !p.isSymbol(",") {
+				break
+			}
+			p.next()
+		}
+	}
+	if err := p.expectKeyword("FROM"); err != nil {
+		return Query{}, err
+	}
+	if p.isKeyword("INSTANCEOF") {
+		p.next()
+		q.InstanceOf = true
+	}
+	class := p.next()
+	if class.kind != identToken && class.kind != stringToken {
+		return Query{}, fmt.Errorf("unexpected %v, expected class name", class)
+	}
+	q.Class = strings.ReplaceAll(class.text, ".", "/")
+	if p.isKeyword("WHERE") {
+		p.next()
+		where, err := p.or()
+		if err != nil {
+			return Query{}, err
+		}
+		q.Where = where
+	}
+	if p.isKeyword("ORDER") {
+		p.next()
+		if err := p.expectKeyword("BY"); err != nil {
+			return Query{}, err
+		}
+		for {
+			operand, err := p.operand()
+			if err != nil {
+				return Query{}, err
+			}
+			order := Order{Operand: operand}
+			if p.isKeyword("DESC") {
+				p.next()
+				order.Desc = true
+			} else if p.isKeyword("ASC") {
+				p.next()
+			}
+			q.OrderBy = append(q.OrderBy, order)
+			if !p.isSymbol(",") {
+				break
+			}
+			p.next()
+		}
+	}
+	if p.isKeyword("LIMIT") {
+		p.next()
+		t := p.next()
+		limit, err := strconv.Atoi(t.text)
+		if t.kind != numberToken || err != nil || limit <= 0 {
+			return Query{}, fmt.Errorf("unexpected %v, expected positive number", t)
+		}
+		q.Limit = limit
+	}
+	if t := p.peek(); t.kind != endToken {
+		return Query{}, fmt.Errorf("unexpected %v", t)
+	}
+	return q, nil
+}
+
+func (p *parser) fieldPath() (FieldPath, error) {
+	t := p.next()
+	if t.kind != identToken || isReserved(t.text) {
+		return nil, fmt.Errorf("unexpected %v, expected field name", t)
+	}
+	return FieldPath(strings.Split(t.text, ".")), nil
+}
+
+func (p *parser) or() (Expr, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("OR") {
+		p.next()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = Or{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (Expr, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("AND") {
+		p.next()
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		left = And{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) not() (Expr, error) {
+	if p.isKeyword("NOT") {
+		p.next()
+		e, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return Not{Expr: e}, nil
+	}
+	if p.isSymbol("(") {
+		p.next()
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.isSymbol(")") {
+			return nil, fmt.Errorf("unexpected %v, expected )", p.peek())
+		}
+		p.next()
+		return e, nil
+	}
+	return p.comparison()
+}
+
+func (p *parser) comparison() (Expr, error) {
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	if p.isKeyword("IS") {
+		p.next()
+		op := "="
+		if p.isKeyword("NOT") {
+			p.next()
+			op = "!="
+		}
+		if err := p.expectKeyword("NULL"); err != nil {
+			return nil, err
+		}
+		return Comparison{Op: op, Left: left, Right: Operand{}}, nil
+	}
+	t := p.next()
+	var op string
+	switch {
+	case t.kind == symbolToken && comparisons[t.text]:
+		op = t.text
+		if op == "<>" {
+			op = "!="
+		}
+	case t.kind == identToken && strings.EqualFold(t.text, "LIKE"):
+		op = "LIKE"
+	default:
+		return nil, fmt.Errorf("unexpected %v, expected comparison", t)
+	}
+	right, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	return Comparison{Op: op, Left: left, Right: right}, nil
+}
+
+func (p *parser) operand() (Operand, error) {
+	t := p.peek()
+	switch t.kind {
+	case numberToken:
+		p.next()
+		if i, err := strconv.ParseInt(t.text, 0, 64); err == nil {
+			return Operand{Literal: i}, nil
+		}
+		if f, err := strconv.ParseFloat(t.text, 64); err == nil {
+			return Operand{Literal: f}, nil
+		}
+		return Operand{}, fmt.Errorf("malformed number %v", t)
+	case stringToken:
+		p.next()
+		return Operand{Literal: t.text}, nil
+	case identToken:
+		switch strings.ToUpper(t.text) {
+		case "TRUE":
+			p.next()
+			return Operand{Literal: true}, nil
+		case "FALSE":
+			p.next()
+			return Operand{Literal: false}, nil
+		case "NULL":
+			p.next()
+			return Operand{}, nil
+		}
+		field, err := p.fieldPath()
+		if err != nil {
+			return Operand{}, err
+		}
+		return Operand{Field: field}, nil
+	}
+	return Operand{}, fmt.Errorf("unexpected %v, expected field or value", t)
+}
+
+var reserved = []string{
+	"SELECT", "FROM", "INSTANCEOF", "WHERE", "ORDER", "BY", "ASC", "DESC", "LIMIT",
+	"AND", "OR", "NOT", "IS", "NULL", "LIKE", "TRUE", "FALSE",
+}
+
+func isReserved(word string) bool {
+	for _, r := range reserved {
+		if strings.EqualFold(word, r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,473 @@
+// query evaluates small SQL-like queries over the instances of the heap:
+//
+//	SELECT @id, size FROM java.util.HashMap WHERE size > 10000 ORDER BY size DESC LIMIT 10
+//	SELECT * FROM INSTANCEOF java.util.AbstractMap
+//	SELECT @id, name FROM java.lang.Thread WHERE name LIKE 'pool-%' AND daemon = false
+//
+// Instances are streamed from the instance index and only instances of the
+// requested class (or its subclasses with INSTANCEOF) are parsed. Fields
+// are resolved through the class metadata, references to java.lang.String
+// are shown and compared as strings. Without ORDER BY the scan stops as
+// soon as LIMIT rows are found, with ORDER BY only LIMIT best rows are
+// kept in memory.
+package query
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/danielleontiev/neojhat/internal/core"
+	"github.com/danielleontiev/neojhat/internal/dump"
+	"github.com/danielleontiev/neojhat/internal/format"
+	"github.com/danielleontiev/neojhat/internal/java"
+)
+
+// reference is the value of the object field
+// that is not resolved to string.
+type reference core.Identifier
+
+var errLimitReached = errors.New("limit reached")
+
+type evaluator struct {
+	heap     *java.Heap
+	size     *core.SizeInfo
+	patterns map[string]*regexp.Regexp
+}
+
+type object struct {
+	id       core.Identifier
+	instance java.NormalObject
+}
+
+type row struct {
+	values []any
+	keys   []any
+}
+
+// Run parses and evaluates the query.
+func Run(parsedAccessor *dump.ParsedAccessor, query string) (Result, error) {
+	q, err := Parse(query)
+	if err != nil {
+		return Result{}, fmt.Errorf("cannot parse query: %w", err)
+	}
+	heap := java.NewHeap(parsedAccessor)
+	classes, fromClass, err := matchingClasses(parsedAccessor, heap, q)
+	if err != nil {
+		return Result{}, err
+	}
+	columns := q.Columns
+	if columns == nil {
+		columns = []FieldPath{{"@id"}}
+		for class := &fromClass; class != nil; class = class.Superclass {
+			for _, field := range class.InstanceFields {
+				columns = append(columns, FieldPath{field.Name})
+			}
+		}
+	}
+
+	size, err := heap.SizeInfo("")
+	if err != nil {
+		return Result{}, err
+	}
+	e := &evaluator{heap: heap, size: size, patterns: make(map[string]*regexp.Regexp)}
+	var rows []row
+	err = heap.ScanInstances(
+		func(classId core.Identifier) bool { return classes[classId] },
+		func(objectId core.Identifier, instance java.NormalObject) error {
+			o := object{id: objectId, instance: instance}
+			if q.Where != nil {
+				matches, err := e.condition(o, q.Where)
+				if err != nil || !matches {
+					return err
+				}
+			}
+			var r row
+			for _, column := range columns {
+				v, err := e.field(o, column)
+				if err != nil {
+					return err
+				}
+				r.values = append(r.values, v)
+			}
+			for _, order := range q.OrderBy {
+				v, err := e.operand(o, order.Operand)
+				if err != nil {
+					return err
+				}
+				r.keys = append(r.keys, v)
+			}
+			rows = append(rows, r)
+			if q.Limit > 0 {
+				if len(q.OrderBy) == 0 && len(rows) == q.Limit {
+					return errLimitReached
+				}
+				if len(rows) >= 2*q.Limit {
+					rows = sortRows(rows, q.OrderBy)[:q.Limit]
+				}
+			}
+			return nil
+		},
+	)
+	if err != nil && !errors.Is(err, errLimitReached) {
+		return Result{}, err
+	}
+	rows = sortRows(rows, q.OrderBy)
+	if q.Limit > 0 && len(rows) > q.Limit {
+		rows = rows[:q.Limit]
+	}
+
+	result := Result{Query: query}
+	for _, column := range columns {
+		result.Columns = append(result.Columns, strings.Join(column, "."))
+	}
+	for _, r := range rows {
+		var values []string
+		for _, v := range r.values {
+			values = append(values, formatValue(v))
+		}
+		result.Rows = append(result.Rows, values)
+	}
+	return result, nil
+}
+
+// matchingClasses returns identifiers of classes whose instances are
+// inspected and the class from FROM clause itself.
+func matchingClasses(parsedAccessor *dump.ParsedAccessor, heap *java.Heap, q Query) (map[core.Identifier]bool, java.Class, error) {
+	classes := make(map[core.Identifier]bool)
+	var fromClass *java.Class
+	for _, loadClass := range parsedAccessor.ListHprofLoadClass() {
+		class, err := heap.ParseClass(loadClass.ClassObjectId)
+		if err != nil {
+			// loaded classes without class dump have no instances
+			continue
+		}
+		for c := &class; c != nil; c = c.Superclass {
+			if c.Name != q.Class {
+				continue
+			}
+			if fromClass == nil {
+				fromClass = c
+			}
+			if c == &class || q.InstanceOf {
+				classes[loadClass.ClassObjectId] = true
+			}
+			break
+		}
+	}
+	if fromClass == nil {
+		return nil, java.Class{}, fmt.Errorf("class %s not found", format.ClassName(q.Class))
+	}
+	return classes, *fromClass, nil
+}
+
+func sortRows(rows []row, orderBy []Order) []row {
+	if len(orderBy) == 0 {
+		return rows
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		for k, order := range orderBy {
+			c := compareForOrder(rows[i].keys[k], rows[j].keys[k])
+			if c == 0 {
+				continue
+			}
+			if order.Desc {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+	return rows
+}
+
+// field returns the value of the field path, following references for
+// every name but the last one. Null in the middle of the path gives null.
+func (e *evaluator) field(o object, path FieldPath) (any, error) {
+	for i, name := range path {
+		var v any
+		switch name {
+		case "@id":
+			v = reference(o.id)
+		case "@class":
+			v = format.ClassName(o.instance.Class.Name)
+		case "@size":
+			v = int64(o.instance.Class.InstanceSize(e.size))
+		default:
+			fieldValue, err := o.instance.GetFieldValueByName(name)
+			if err != nil {
+				return nil, fmt.Errorf("cannot read %s of %s: %w",
+					strings.Join(path, "."), format.ClassName(o.instance.Class.Name), err)
+			}
+			v = toValue(fieldValue.Value)
+			if i == len(path)-1 {
+				return e.resolveString(v), nil
+			}
+		}
+		if i == len(path)-1 {
+			return v, nil
+		}
+		ref, ok := v.(reference)
+		if !ok {
+			if v == nil {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("cannot read %s: %s is not an object", strings.Join(path, "."), name)
+		}
+		instance, err := e.heap.ParseNormalObject(core.Identifier(ref))
+		if err != nil {
+			return nil, fmt.Errorf("cannot read %s: %s is not an instance", strings.Join(path, "."), name)
+		}
+		o = object{id: core.Identifier(ref), instance: instance}
+	}
+	return nil, nil
+}
+
+func (e *evaluator) operand(o object, operand Operand) (any, error) {
+	if operand.Field != nil {
+		return e.field(o, operand.Field)
+	}
+	return operand.Literal, nil
+}
+
+func (e *evaluator) condition(o object, expr Expr) (bool, error) {
+	switch expr := expr.(type) {
+	case And:
+		left, err := e.condition(o, expr.Left)
+		if err != nil || !left {
+			return false, err
+		}
+		return e.condition(o, expr.Right)
+	case Or:
+		left, err := e.condition(o, expr.Left)
+		if err != nil || left {
+			return left, err
+		}
+		return e.condition(o, expr.Right)
+	case Not:
+		inner, err := e.condition(o, expr.Expr)
+		return !inner, err
+	case Comparison:
+		left, err := e.operand(o, expr.Left)
+		if err != nil {
+			return false, err
+		}
+		right, err := e.operand(o, expr.Right)
+		if err != nil {
+			return false, err
+		}
+		return e.compare(expr.Op, left, right)
+	}
+	return false, fmt.Errorf("unknown expression %T", expr)
+}
+
+func (e *evaluator) compare(op string, left, right any) (bool, error) {
+	if op == "LIKE" {
+		s, ok := left.(string)
+		pattern, isPattern := right.(string)
+		if !ok || !isPattern {
+			return false, nil
+		}
+		re, err := e.pattern(pattern)
+		if err != nil {
+			return false, err
+		}
+		return re.MatchString(s), nil
+	}
+	if left == nil || right == nil {
+		switch op {
+		case "=":
+			return left == nil && right == nil, nil
+		case "!=":
+			return left != nil || right != nil, nil
+		}
+		return false, nil
+	}
+	c, ok := compareValues(left, right)
+	switch op {
+	case "=":
+		return ok && c == 0, nil
+	case "!=":
+		return !ok || c != 0, nil
+	case "<":
+		return ok && c < 0, nil
+	case "<=":
+		return ok && c <= 0, nil
+	case ">":
+		return ok && c > 0, nil
+	case ">=":
+		return ok && c >= 0, nil
+	}
+	return false, fmt.Errorf("unknown comparison %s", op)
+}
+
+// pattern converts LIKE pattern to regular expression:
+// % matches any number of characters, _ - single one.
+func (e *evaluator) pattern(like string) (*regexp.Regexp, error) {
+	if re, ok := e.patterns[like]; ok {
+		return re, nil
+	}
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range like {
+		switch r {
+		case '%':
+			b.WriteString(".*")
+		case '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("malformed pattern %q: %w", like, err)
+	}
+	e.patterns[like] = re
+	return re, nil
+}
+
+// resolveString converts the reference to java.lang.String to Go string.
+// Other references and strings that cannot be decoded stay references.
+func (e *evaluator) resolveString(v any) any {
+	ref, ok := v.(reference)
+	if !ok {
+		return v
+	}
+	s, err := e.heap.ParseJavaString(core.JavaValue{Type: core.Object, Value: core.Identifier(ref)})
+	if err != nil {
+		return v
+	}
+	return s
+}
+
+func toValue(v core.JavaValue) any {
+	switch value := v.Value.(type) {
+	case core.Identifier:
+		if value == 0 {
+			return nil
+		}
+		return reference(value)
+	case bool:
+		return value
+	case string:
+		if v.Type == core.Char && len(value) == 2 {
+			// char is kept as two raw bytes of UTF-16 code unit
+			return string(rune(value[0])<<8 | rune(value[1]))
+		}
+		return value
+	case float32:
+		return float64(value)
+	case float64:
+		return value
+	case int8:
+		return int64(value)
+	case int16:
+		return int64(value)
+	case int32:
+		return int64(value)
+	case int64:
+		return value
+	}
+	return nil
+}
+
+// compareValues compares values of compatible types: numbers
+// (references are compared as numbers too), strings and booleans.
+func compareValues(left, right any) (int, bool) {
+	if l, ok := left.(string); ok {
+		r, ok := right.(string)
+		return strings.Compare(l, r), ok
+	}
+	if l, ok := left.(bool); ok {
+		r, ok := right.(bool)
+		return boolToInt(l) - boolToInt(r), ok
+	}
+	if l, ok := toInteger(left); ok {
+		if r, ok := toInteger(right); ok {
+			return compareNumbers(l, r), true
+		}
+	}
+	l, lok := toNumber(left)
+	r, rok := toNumber(right)
+	if !lok || !rok {
+		return 0, false
+	}
+	return compareNumbers(l, r), true
+}
+
+func compareNumbers[T int64 | float64](l, r T) int {
+	switch {
+	case l < r:
+		return -1
+	case l > r:
+		return 1
+	}
+	return 0
+}
+
+// compareForOrder orders null before any other value.
+func compareForOrder(left, right any) int {
+	if left == nil || right == nil {
+		switch {
+		case left == nil && right == nil:
+			return 0
+		case left == nil:
+			return -1
+		}
+		return 1
+	}
+	c, _ := compareValues(left, right)
+	return c
+}
+
+func toInteger(v any) (int64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	case reference:
+		return int64(n), true
+	}
+	return 0, false
+}
+
+func toNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	case reference:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func formatValue(v any) string {
+	switch value := v.(type) {
+	case nil:
+		return "null"
+	case reference:
+		return format.ObjectId(uint64(value))
+	case string:
+		return value
+	case bool:
+		return strconv.FormatBool(value)
+	case int64:
+		return strconv.FormatInt(value, 10)
+	case float64:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,200 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/danielleontiev/neojhat/internal/core"
+	td "github.com/danielleontiev/neojhat/internal/testdump"
+)
+
+// class Base { int size; }
+// class Map extends Base { String name; Map next; boolean cached; }
+// class Other { int size; }
+// class Letter { char c; }
+var querySample = td.Dump(
+	[][]byte{
+		td.Utf8(1, "java/lang/Object"),
+		td.Utf8(2, "java/lang/String"),
+		td.Utf8(3, "Base"),
+		td.Utf8(4, "Map"),
+		td.Utf8(5, "Other"),
+		td.Utf8(6, "value"),
+		td.Utf8(7, "size"),
+		td.Utf8(8, "name"),
+		td.Utf8(9, "next"),
+		td.Utf8(10, "cached"),
+		td.Utf8(11, "Letter"),
+		td.Utf8(12, "c"),
+		td.LoadClass(1, 100, 1),
+		td.LoadClass(2, 101, 2),
+		td.LoadClass(3, 102, 3),
+		td.LoadClass(4, 103, 4),
+		td.LoadClass(5, 104, 5),
+		td.LoadClass(6, 105, 11),
+	},
+	td.ClassDump(100, 0, 0, nil, nil),
+	td.ClassDump(101, 100, 8, nil, []td.Field{{NameId: 6, Type: core.Object}}),
+	td.ClassDump(102, 100, 4, nil, []td.Field{{NameId: 7, Type: core.Int}}),
+	td.ClassDump(103, 102, 21, nil, []td.Field{
+		{NameId: 8, Type: core.Object}, {NameId: 9, Type: core.Object}, {NameId: 10, Type: core.Boolean},
+	}),
+	td.ClassDump(104, 100, 4, nil, []td.Field{{NameId: 7, Type: core.Int}}),
+	td.ClassDump(105, 100, 2, nil, []td.Field{{NameId: 12, Type: core.Char}}),
+	td.InstanceDump(200, 101, td.Id(201)),
+	td.PrimArrayDump(201, core.Byte, 5, []byte("users")),
+	td.InstanceDump(210, 101, td.Id(211)),
+	td.PrimArrayDump(211, core.Byte, 6, []byte("orders")),
+	td.InstanceDump(1000, 103, td.Id(200), td.Id(1001), []byte{1}, td.U4(10)),
+	td.InstanceDump(1001, 103, td.Id(210), td.Id(0), []byte{0}, td.U4(30000)),
+	td.InstanceDump(1002, 103, td.Id(0), td.Id(1000), []byte{0}, td.U4(20000)),
+	td.InstanceDump(1003, 102, td.U4(50000)),
+	td.InstanceDump(1004, 104, td.U4(70000)),
+	td.InstanceDump(1005, 105, []byte{0x00, 'A'}),
+	td.InstanceDump(1006, 105, []byte{0x04, 0x16}), // Ж
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    Query
+		wantErr bool
+	}{
+		{
+			query: "select * from java.util.HashMap",
+			want:  Query{Class: "java/util/HashMap"},
+		},
+		{
+			query: "SELECT @id, name.value FROM INSTANCEOF Base WHERE NOT (size >= 10 OR name IS NOT NULL) AND name LIKE 'a%' ORDER BY size DESC, @id LIMIT 5",
+			want: Query{
+				Columns:    []FieldPath{{"@id"}, {"name", "value"}},
+				Class:      "Base",
+				InstanceOf: true,
+				Where: And{
+					Left: Not{Expr: Or{
+						Left:  Comparison{Op: ">=", Left: Operand{Field: FieldPath{"size"}}, Right: Operand{Literal: int64(10)}},
+						Right: Comparison{Op: "!=", Left: Operand{Field: FieldPath{"name"}}},
+					}},
+					Right: Comparison{Op: "LIKE", Left: Operand{Field: FieldPath{"name"}}, Right: Operand{Literal: "a%"}},
+				},
+				OrderBy: []Order{{Operand: Operand{Field: FieldPath{"size"}}, Desc: true}, {Operand: Operand{Field: FieldPath{"@id"}}}},
+				Limit:   5,
+			},
+		},
+		{
+			query: "SELECT x FROM A WHERE x <> 0x10 AND y = 1.5 AND z = true",
+			want: Query{
+				Columns: []FieldPath{{"x"}},
+				Class:   "A",
+				Where: And{
+					Left: And{
+						Left:  Comparison{Op: "!=", Left: Operand{Field: FieldPath{"x"}}, Right: Operand{Literal: int64(16)}},
+						Right: Comparison{Op: "=", Left: Operand{Field: FieldPath{"y"}}, Right: Operand{Literal: 1.5}},
+					},
+					Right: Comparison{Op: "=", Left: Operand{Field: FieldPath{"z"}}, Right: Operand{Literal: true}},
+				},
+			},
+		},
+		{query: "SELECT FROM A", wantErr: true},
+		{query: "SELECT x FROM", wantErr: true},
+		{query: "SELECT x FROM A WHERE x", wantErr: true},
+		{query: "SELECT x FROM A WHERE x = 'a", wantErr: true},
+		{query: "SELECT x FROM A LIMIT 0", wantErr: true},
+		{query: "SELECT x FROM A B", wantErr: true},
+		{query: "SELECT x FROM A WHERE x ! 1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, err := Parse(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	parsedAccessor, err := td.NewParsedAccessor(querySample, false)
+	if err != nil {
+		t.Fatalf("cannot parse sample: %v", err)
+	}
+	tests := []struct {
+		query   string
+		columns []string
+		rows    [][]string
+		wantErr bool
+	}{
+		{
+			query:   "SELECT * FROM Map",
+			columns: []string{"@id", "name", "next", "cached", "size"},
+			rows: [][]string{
+				{"0x3e8", "users", "0x3e9", "true", "10"},
+				{"0x3e9", "orders", "null", "false", "30000"},
+				{"0x3ea", "null", "0x3e8", "false", "20000"},
+			},
+		},
+		{
+			query:   "SELECT @id, size FROM INSTANCEOF Base WHERE size > 10000 ORDER BY size DESC",
+			columns: []string{"@id", "size"},
+			rows:    [][]string{{"0x3eb", "50000"}, {"0x3e9", "30000"}, {"0x3ea", "20000"}},
+		},
+		{
+			query:   "SELECT @id FROM Base",
+			columns: []string{"@id"},
+			rows:    [][]string{{"0x3eb"}},
+		},
+		{
+			query:   "select @id, next.name from Map where next is not null and next.name like 'us%'",
+			columns: []string{"@id", "next.name"},
+			rows:    [][]string{{"0x3ea", "users"}},
+		},
+		{
+			query:   "SELECT @id FROM Map WHERE name = 'orders' OR cached = true ORDER BY @id DESC LIMIT 1",
+			columns: []string{"@id"},
+			rows:    [][]string{{"0x3e9"}},
+		},
+		{
+			query:   "SELECT @id FROM Map LIMIT 2",
+			columns: []string{"@id"},
+			rows:    [][]string{{"0x3e8"}, {"0x3e9"}},
+		},
+		{
+			query:   "SELECT @id, @size FROM INSTANCEOF Base",
+			columns: []string{"@id", "@size"},
+			rows:    [][]string{{"0x3e8", "32"}, {"0x3e9", "32"}, {"0x3ea", "32"}, {"0x3eb", "16"}},
+		},
+		{
+			query:   "SELECT @id, c FROM Letter",
+			columns: []string{"@id", "c"},
+			rows:    [][]string{{"0x3ed", "A"}, {"0x3ee", "Ж"}},
+		},
+		{
+			query:   "SELECT @id FROM Letter WHERE c = 'A'",
+			columns: []string{"@id"},
+			rows:    [][]string{{"0x3ed"}},
+		},
+		{query: "SELECT missing FROM Map", wantErr: true},
+		{query: "SELECT size FROM NoSuchClass", wantErr: true},
+		{query: "SELECT size.value FROM Map", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, err := Run(parsedAccessor, tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got.Columns, tt.columns) {
+				t.Errorf("Run() columns = %v, want %v", got.Columns, tt.columns)
+			}
+			if !reflect.DeepEqual(got.Rows, tt.rows) {
+				t.Errorf("Run() rows = %v, want %v", got.Rows, tt.rows)
+			}
+		})
+	}
+}
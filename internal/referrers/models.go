@@ -0,0 +1,17 @@
+package referrers
+
+import "github.com/danielleontiev/neojhat/internal/core"
+
+// Referrer is the object holding the reference
+// to the inspected object.
+type Referrer struct {
+	ObjectId  core.Identifier `json:"objectId"`
+	ClassName string          `json:"className"`
+	Field     string          `json:"field"`
+}
+
+type Referrers struct {
+	ObjectId  core.Identifier `json:"objectId"`
+	ClassName string          `json:"className"`
+	Items     []Referrer      `json:"items"`
+}
@@ -0,0 +1,43 @@
+// referrers answers the question "who references the object".
+// It relies on the references index built during parsing which
+// maps every object to the objects pointing to it. The index has only
+// identifiers, so the referrers are parsed to find which field
+// (or array element) holds the reference.
+package referrers
+
+import (
+	"fmt"
+
+	"github.com/danielleontiev/neojhat/internal/core"
+	"github.com/danielleontiev/neojhat/internal/dump"
+	"github.com/danielleontiev/neojhat/internal/java"
+)
+
+func GetReferrers(parsedAccessor *dump.ParsedAccessor, objectId core.Identifier) (Referrers, error) {
+	heap := java.NewHeap(parsedAccessor)
+	className, err := heap.ObjectTypeName(objectId)
+	if err != nil {
+		return Referrers{}, err
+	}
+	references, err := heap.Referrers(objectId)
+	if err != nil {
+		return Referrers{}, fmt.Errorf("cannot find referrers of %v: %w", objectId, err)
+	}
+	var items []Referrer
+	for _, reference := range references {
+		referrerClassName, err := heap.ObjectTypeName(reference.From)
+		if err != nil {
+			return Referrers{}, err
+		}
+		items = append(items, Referrer{
+			ObjectId:  reference.From,
+			ClassName: referrerClassName,
+			Field:     reference.Name(),
+		})
+	}
+	return Referrers{
+		ObjectId:  objectId,
+		ClassName: className,
+		Items:     items,
+	}, nil
+}
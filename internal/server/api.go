@@ -0,0 +1,341 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/danielleontiev/neojhat/internal/core"
+	"github.com/danielleontiev/neojhat/internal/format"
+	"github.com/danielleontiev/neojhat/internal/java"
+	"github.com/danielleontiev/neojhat/internal/output"
+	"github.com/danielleontiev/neojhat/internal/referrers"
+	"github.com/danielleontiev/neojhat/internal/summary"
+	"github.com/danielleontiev/neojhat/internal/threads"
+)
+
+// Versions of the schemas of the API resources, they are changed
+// the same way as the versions of the output of commands. Threads
+// and summary are the same documents as the output of commands.
+const (
+	classesSchemaVersion   = 1
+	instancesSchemaVersion = 1
+	objectSchemaVersion    = 1
+	referrersSchemaVersion = 1
+)
+
+// apiHandler finds the resource for the request, the returned
+// function prints it.
+type apiHandler func(r *http.Request) (func(io.Writer) error, error)
+
+func resource(name string, schemaVersion int, result any) func(io.Writer) error {
+	return func(w io.Writer) error {
+		return output.ResourceJson(name, schemaVersion, result, w)
+	}
+}
+
+func (s *Server) api(handler apiHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		write, err := handler(r)
+		if err == nil {
+			err = write(&buf)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err != nil {
+			status := http.StatusInternalServerError
+			var e httpError
+			if errors.As(err, &e) {
+				status = e.status
+			}
+			w.WriteHeader(status)
+			json.NewEncoder(w).Encode(apiError{Error: err.Error()})
+			return
+		}
+		buf.WriteTo(w)
+	}
+}
+
+// apiClasses is the class histogram, sorted the same way as in the UI.
+func (s *Server) apiClasses(r *http.Request) (func(io.Writer) error, error) {
+	pages, err := parsePagination(r)
+	if err != nil {
+		return nil, err
+	}
+	o, _, _, err := s.histogram(r)
+	if err != nil {
+		return nil, err
+	}
+	list := ClassList{Window: pages.window(len(o.Items)), Items: []ClassEntry{}}
+	from, to := pages.bounds(len(o.Items))
+	for _, item := range o.Items[from:to] {
+		list.Items = append(list.Items, ClassEntry{
+			Id:             item.ClassId(),
+			Name:           format.ClassName(item.Name),
+			InstancesCount: item.InstancesCount,
+			TotalSize:      item.TotalSize,
+			RetainedSize:   item.RetainedSize,
+		})
+	}
+	return resource("classes", classesSchemaVersion, list), nil
+}
+
+// apiInstances lists the instances of the class,
+// or the arrays of the array class.
+func (s *Server) apiInstances(r *http.Request) (func(io.Writer) error, error) {
+	classId, err := pathId(r)
+	if err != nil {
+		return nil, err
+	}
+	pages, err := parsePagination(r)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.parsedAccessor.GetHprofGcClassDump(classId); err != nil {
+		return nil, notFound("class %s not found", format.ObjectId(uint64(classId)))
+	}
+	class, err := s.heap.ParseClass(classId)
+	if err != nil {
+		return nil, err
+	}
+	list := InstanceList{ClassId: classId, ClassName: className(class.Name), Items: []ObjectEntry{}}
+	if strings.HasPrefix(class.Name, "[") {
+		list.Window = pages.window(s.parsedAccessor.Counters.ObjArraysCount[classId])
+		headers, err := s.pageOfObjArrays(classId, pages)
+		if err != nil {
+			return nil, err
+		}
+		for _, header := range headers {
+			list.Items = append(list.Items, ObjectEntry{
+				Id:   header.ArrayObjectId,
+				Size: s.size.OfArray(core.Object, int(header.NumberOfElements)),
+			})
+		}
+	} else {
+		list.Window = pages.window(s.parsedAccessor.Counters.InstancesCount[classId])
+		instanceSize, err := s.heap.InstanceSize(classId, s.size)
+		if err != nil {
+			return nil, err
+		}
+		ids, err := s.pageOfInstances(classId, pages)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			list.Items = append(list.Items, ObjectEntry{Id: id, Size: instanceSize})
+		}
+	}
+	return resource("instances", instancesSchemaVersion, list), nil
+}
+
+func (s *Server) apiObject(r *http.Request) (func(io.Writer) error, error) {
+	objectId, err := pathId(r)
+	if err != nil {
+		return nil, err
+	}
+	pages, err := parsePagination(r)
+	if err != nil {
+		return nil, err
+	}
+	object := Object{Id: objectId, Kind: s.heap.KindOf(objectId)}
+	switch object.Kind {
+	case java.InstanceObject:
+		err = s.describeInstance(&object)
+	case java.ObjectArrayObject:
+		err = s.describeObjArray(&object, pages)
+	case java.PrimitiveArrayObject:
+		err = s.describePrimArray(&object)
+	case java.ClassObject:
+		err = s.describeClass(&object)
+	default:
+		return nil, notFound("object %s not found", format.ObjectId(uint64(objectId)))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resource("object", objectSchemaVersion, object), nil
+}
+
+func (s *Server) describeInstance(object *Object) error {
+	header, err := s.parsedAccessor.GetHprofGcInstanceDump(object.Id)
+	if err != nil {
+		return err
+	}
+	instance, err := s.heap.ParseNormalObject(object.Id)
+	if err != nil {
+		return err
+	}
+	fields, err := instance.Fields()
+	if err != nil {
+		return fmt.Errorf("cannot read fields of %s: %w", format.ObjectId(uint64(object.Id)), err)
+	}
+	if object.Size, err = s.heap.InstanceSize(header.ClassObjectId, s.size); err != nil {
+		return err
+	}
+	object.ClassId = header.ClassObjectId
+	object.ClassName = format.ClassName(instance.Class.Name)
+	for _, field := range fields {
+		object.Fields = append(object.Fields, FieldValue{
+			Name:       field.Name,
+			DeclaredBy: format.ClassName(field.Origin),
+			Type:       field.Value.Type,
+			Value:      jsonValue(field.Value),
+		})
+	}
+	return nil
+}
+
+func (s *Server) describeObjArray(object *Object, pages pagination) error {
+	array, err := s.heap.ParseObjectArrayFull(object.Id)
+	if err != nil {
+		return err
+	}
+	arrayClassName, err := s.heap.ObjectTypeName(object.Id)
+	if err != nil {
+		return err
+	}
+	length := len(array.Elements)
+	window := pages.window(length)
+	object.ClassId = array.ArrayClassId
+	object.ClassName = format.ClassName(arrayClassName)
+	object.Size = s.size.OfArray(core.Object, length)
+	object.Length = &length
+	object.Window = &window
+	from, to := pages.bounds(length)
+	for i := from; i < to; i++ {
+		element := Element{Index: i}
+		if array.Elements[i] != 0 {
+			element.Value = &array.Elements[i]
+		}
+		object.Elements = append(object.Elements, element)
+	}
+	return nil
+}
+
+func (s *Server) describePrimArray(object *Object) error {
+	header, err := s.parsedAccessor.GetHprofGcPrimArray(object.Id)
+	if err != nil {
+		return err
+	}
+	length := int(header.NumberOfElements)
+	object.ClassName = header.ElementType.String() + "[]"
+	object.Size = s.size.OfArray(header.ElementType, length)
+	object.Length = &length
+	return nil
+}
+
+func (s *Server) describeClass(object *Object) error {
+	class, err := s.heap.ParseClass(object.Id)
+	if err != nil {
+		return err
+	}
+	object.ClassName = className(class.Name)
+	if !strings.HasPrefix(class.Name, "[") {
+		if object.InstanceSize, err = s.heap.InstanceSize(object.Id, s.size); err != nil {
+			return err
+		}
+	}
+	for _, field := range class.StaticFields {
+		object.Fields = append(object.Fields, FieldValue{
+			Name:       field.Name,
+			DeclaredBy: object.ClassName,
+			Type:       field.Type,
+			Value:      jsonValue(field.Value),
+		})
+	}
+	return nil
+}
+
+func (s *Server) apiReferrers(r *http.Request) (func(io.Writer) error, error) {
+	objectId, err := pathId(r)
+	if err != nil {
+		return nil, err
+	}
+	pages, err := parsePagination(r)
+	if err != nil {
+		return nil, err
+	}
+	if s.heap.KindOf(objectId) == java.UnknownObject {
+		return nil, notFound("object %s not found", format.ObjectId(uint64(objectId)))
+	}
+	// the index repeats the referrer for every reference it holds,
+	// so the page is counted in references like the items
+	referrerIds, err := s.parsedAccessor.GetReferrers(objectId)
+	if err != nil {
+		return nil, err
+	}
+	from, to := pages.bounds(len(referrerIds))
+	references, err := s.referencesAt(objectId, referrerIds, from, to)
+	if err != nil {
+		return nil, err
+	}
+	list := ReferrerList{ObjectId: objectId, Window: pages.window(len(referrerIds)), Items: []referrers.Referrer{}}
+	for _, reference := range references {
+		referrerClassName, err := s.heap.ObjectTypeName(reference.From)
+		if err != nil {
+			return nil, err
+		}
+		list.Items = append(list.Items, referrers.Referrer{
+			ObjectId:  reference.From,
+			ClassName: format.ClassName(referrerClassName),
+			Field:     reference.Name(),
+		})
+	}
+	return resource("referrers", referrersSchemaVersion, list), nil
+}
+
+// apiThreads is the same document as the output of threads
+// command with local variables.
+func (s *Server) apiThreads(r *http.Request) (func(io.Writer) error, error) {
+	threadDump, err := threads.GetThreadDump(s.parsedAccessor)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse thread dump: %w", err)
+	}
+	return func(w io.Writer) error {
+		return output.ThreadsJson(threadDump, true, w)
+	}, nil
+}
+
+// apiSummary is the same document as the output of summary command.
+func (s *Server) apiSummary(r *http.Request) (func(io.Writer) error, error) {
+	sum, err := summary.GetSummary(s.parsedAccessor, false, "")
+	if err != nil {
+		return nil, fmt.Errorf("can't parse summary: %w", err)
+	}
+	return func(w io.Writer) error {
+		return output.SummaryJson(sum, w)
+	}, nil
+}
+
+// jsonValue converts the value of the field to the JSON value: the
+// identifier (or null) for references, the character as the string
+// and the number otherwise. JSON has no NaN and infinities, so they
+// are strings.
+func jsonValue(value core.JavaValue) any {
+	switch value.Type {
+	case core.Object:
+		objectId, err := value.ToObject()
+		if err != nil || objectId == 0 {
+			return nil
+		}
+		return objectId
+	case core.Char:
+		if c, ok := value.Value.(string); ok && len(c) == 2 {
+			return string(rune(c[0])<<8 | rune(c[1]))
+		}
+	case core.Float:
+		if f, ok := value.Value.(float32); ok && (math.IsNaN(float64(f)) || math.IsInf(float64(f), 0)) {
+			return fmt.Sprint(f)
+		}
+	case core.Double:
+		if f, ok := value.Value.(float64); ok && (math.IsNaN(f) || math.IsInf(f, 0)) {
+			return fmt.Sprint(f)
+		}
+	}
+	return value.Value
+}
@@ -0,0 +1,335 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	td "github.com/danielleontiev/neojhat/internal/testdump"
+)
+
+func TestApi(t *testing.T) {
+	parsedAccessor, err := td.NewParsedAccessor(serverSample, true)
+	if err != nil {
+		t.Fatalf("cannot parse dump: %v", err)
+	}
+	s, err := New(parsedAccessor, td.NewRun)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	tests := []struct {
+		path     string
+		status   int
+		resource string
+		// result of the resource or the error document
+		want string
+	}{
+		{
+			path:     "/api/classes?sort=size&limit=2",
+			status:   http.StatusOK,
+			resource: "classes",
+			want: `{"total": 3, "offset": 0, "limit": 2, "items": [
+				{"id": "0x65", "name": "com.example.Node", "instancesCount": 2, "totalSize": 48},
+				{"name": "byte[]", "instancesCount": 1, "totalSize": 24}]}`,
+		},
+		{
+			path:     "/api/classes/0x65/instances?offset=1",
+			status:   http.StatusOK,
+			resource: "instances",
+			want: `{"classId": "0x65", "className": "com.example.Node", "total": 2, "offset": 1, "limit": 100,
+				"items": [{"id": "0xc9", "size": 24}]}`,
+		},
+		{
+			path:     "/api/classes/0x66/instances",
+			status:   http.StatusOK,
+			resource: "instances",
+			want: `{"classId": "0x66", "className": "com.example.Node[]", "total": 1, "offset": 0, "limit": 100,
+				"items": [{"id": "0x12c", "size": 24}]}`,
+		},
+		{
+			path:   "/api/classes/0xc8/instances",
+			status: http.StatusNotFound,
+			want:   `{"error": "class 0xc8 not found"}`,
+		},
+		{
+			path:     "/api/objects/0xc8",
+			status:   http.StatusOK,
+			resource: "object",
+			want: `{"id": "0xc8", "kind": "instance", "classId": "0x65", "className": "com.example.Node", "size": 24,
+				"fields": [
+					{"name": "next", "declaredBy": "com.example.Node", "type": "object", "value": "0xc9"},
+					{"name": "count", "declaredBy": "com.example.Node", "type": "int", "value": 7}]}`,
+		},
+		{
+			path:     "/api/objects/0x12c?offset=1&limit=1",
+			status:   http.StatusOK,
+			resource: "object",
+			want: `{"id": "0x12c", "kind": "object-array", "classId": "0x66", "className": "com.example.Node[]", "size": 24,
+				"length": 2, "elements": [{"index": 1, "value": "0xc9"}], "window": {"total": 2, "offset": 1, "limit": 1}}`,
+		},
+		{
+			path:     "/api/objects/400",
+			status:   http.StatusOK,
+			resource: "object",
+			want:     `{"id": "0x190", "kind": "primitive-array", "className": "byte[]", "size": 24, "length": 3}`,
+		},
+		{
+			path:     "/api/objects/0x65",
+			status:   http.StatusOK,
+			resource: "object",
+			want: `{"id": "0x65", "kind": "class", "className": "com.example.Node", "instanceSize": 24,
+				"fields": [{"name": "INSTANCE", "declaredBy": "com.example.Node", "type": "object", "value": "0xc8"}]}`,
+		},
+		{
+			path:   "/api/objects/0x1",
+			status: http.StatusNotFound,
+			want:   `{"error": "object 0x1 not found"}`,
+		},
+		{
+			path:     "/api/objects/0xc9/referrers",
+			status:   http.StatusOK,
+			resource: "referrers",
+			want: `{"objectId": "0xc9", "total": 2, "offset": 0, "limit": 100, "items": [
+				{"objectId": "0xc8", "className": "com.example.Node", "field": "next"},
+				{"objectId": "0x12c", "className": "com.example.Node[]", "field": "[1]"}]}`,
+		},
+		{
+			path:     "/api/objects/0xc9/referrers?offset=1",
+			status:   http.StatusOK,
+			resource: "referrers",
+			want: `{"objectId": "0xc9", "total": 2, "offset": 1, "limit": 100, "items": [
+				{"objectId": "0x12c", "className": "com.example.Node[]", "field": "[1]"}]}`,
+		},
+		{
+			path:     "/api/threads",
+			status:   http.StatusOK,
+			resource: "threads",
+			want:     `{"stackTraces": []}`,
+		},
+		{
+			// the sample has no system properties
+			path:   "/api/summary",
+			status: http.StatusInternalServerError,
+			want:   `{"error": "can't parse summary: class java/lang/System not found"}`,
+		},
+		{
+			path:   "/api/objects/0xc8?limit=0",
+			status: http.StatusBadRequest,
+			want:   `{"error": "invalid limit '0'"}`,
+		},
+		{
+			path:   "/api/classes?offset=9223372036854775807",
+			status: http.StatusBadRequest,
+			want:   `{"error": "invalid offset '9223372036854775807'"}`,
+		},
+		{
+			// the largest offset, the end of the page does not overflow
+			path:     "/api/classes?offset=9223372036854774807",
+			status:   http.StatusOK,
+			resource: "classes",
+			want:     `{"total": 3, "offset": 9223372036854774807, "limit": 100, "items": []}`,
+		},
+		{
+			path:     "/api/classes/0x65/instances?offset=9223372036854774807",
+			status:   http.StatusOK,
+			resource: "instances",
+			want: `{"classId": "0x65", "className": "com.example.Node", "total": 2, "offset": 9223372036854774807,
+				"limit": 100, "items": []}`,
+		},
+		{
+			path:     "/api/objects/0xc9/referrers?offset=9223372036854774807",
+			status:   http.StatusOK,
+			resource: "referrers",
+			want:     `{"objectId": "0xc9", "total": 2, "offset": 9223372036854774807, "limit": 100, "items": []}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			s.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			response := recorder.Result()
+			if response.StatusCode != tt.status {
+				t.Fatalf("GET %s status = %d, want %d", tt.path, response.StatusCode, tt.status)
+			}
+			if contentType := response.Header.Get("Content-Type"); contentType != "application/json" {
+				t.Errorf("GET %s Content-Type = %s", tt.path, contentType)
+			}
+			var document struct {
+				Command       string          `json:"command"`
+				SchemaVersion int             `json:"schemaVersion"`
+				Result        json.RawMessage `json:"result"`
+			}
+			body, err := io.ReadAll(response.Body)
+			if err != nil {
+				t.Fatalf("cannot read body: %v", err)
+			}
+			got := body
+			if tt.resource != "" {
+				if err := json.Unmarshal(body, &document); err != nil {
+					t.Fatalf("GET %s is not JSON: %v", tt.path, err)
+				}
+				if document.Command != tt.resource || document.SchemaVersion != 1 {
+					t.Errorf("GET %s is %s version %d, want %s version 1", tt.path, document.Command, document.SchemaVersion, tt.resource)
+				}
+				got = document.Result
+			}
+			assertJsonEqual(t, got, tt.want)
+		})
+	}
+}
+
+// TestApiReferrersPages checks that the pages of referrers are counted
+// in references when the referrer holds many references to the object:
+// the array 300 (0x12c) has the object 200 (0xc8) three times.
+func TestApiReferrersPages(t *testing.T) {
+	sample := td.Dump(
+		[][]byte{
+			td.Utf8(1, "java/lang/Object"),
+			td.Utf8(2, "[Ljava/lang/Object;"),
+			td.LoadClass(1, 100, 1),
+			td.LoadClass(2, 102, 2),
+		},
+		td.ClassDump(100, 0, 0, nil, nil),
+		td.ClassDump(102, 100, 0, nil, nil),
+		td.InstanceDump(200, 100),
+		td.ObjArrayDump(300, 102, 200, 200, 200),
+		td.ObjArrayDump(301, 102, 200),
+	)
+	parsedAccessor, err := td.NewParsedAccessor(sample, true)
+	if err != nil {
+		t.Fatalf("cannot parse dump: %v", err)
+	}
+	s, err := New(parsedAccessor, td.NewRun)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	tests := []struct {
+		path string
+		want string
+	}{
+		{
+			path: "/api/objects/0xc8/referrers?limit=2",
+			want: `{"objectId": "0xc8", "total": 4, "offset": 0, "limit": 2, "items": [
+				{"objectId": "0x12c", "className": "java.lang.Object[]", "field": "[0]"},
+				{"objectId": "0x12c", "className": "java.lang.Object[]", "field": "[1]"}]}`,
+		},
+		{
+			path: "/api/objects/0xc8/referrers?offset=1&limit=2",
+			want: `{"objectId": "0xc8", "total": 4, "offset": 1, "limit": 2, "items": [
+				{"objectId": "0x12c", "className": "java.lang.Object[]", "field": "[1]"},
+				{"objectId": "0x12c", "className": "java.lang.Object[]", "field": "[2]"}]}`,
+		},
+		{
+			path: "/api/objects/0xc8/referrers?offset=2&limit=2",
+			want: `{"objectId": "0xc8", "total": 4, "offset": 2, "limit": 2, "items": [
+				{"objectId": "0x12c", "className": "java.lang.Object[]", "field": "[2]"},
+				{"objectId": "0x12d", "className": "java.lang.Object[]", "field": "[0]"}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			s.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			response := recorder.Result()
+			if response.StatusCode != http.StatusOK {
+				t.Fatalf("GET %s status = %d, want %d", tt.path, response.StatusCode, http.StatusOK)
+			}
+			var document struct {
+				Result json.RawMessage `json:"result"`
+			}
+			if err := json.NewDecoder(response.Body).Decode(&document); err != nil {
+				t.Fatalf("GET %s is not JSON: %v", tt.path, err)
+			}
+			assertJsonEqual(t, document.Result, tt.want)
+		})
+	}
+}
+
+func assertJsonEqual(t *testing.T, got []byte, want string) {
+	t.Helper()
+	var gotValue, wantValue any
+	if err := json.Unmarshal(got, &gotValue); err != nil {
+		t.Fatalf("cannot decode %s: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &wantValue); err != nil {
+		t.Fatalf("cannot decode %s: %v", want, err)
+	}
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+// TestApiConcurrentRequests checks that the answers to the requests
+// sent at the same time are the same as to the requests sent one by one.
+func TestApiConcurrentRequests(t *testing.T) {
+	parsedAccessor, err := td.NewParsedAccessor(serverSample, true)
+	if err != nil {
+		t.Fatalf("cannot parse dump: %v", err)
+	}
+	s, err := New(parsedAccessor, td.NewRun)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	paths := []string{
+		"/api/classes",
+		"/api/classes/0x65/instances",
+		"/api/classes/0x65/instances?offset=1&limit=1",
+		"/api/objects/0xc8",
+		"/api/objects/0x12c",
+		"/api/objects/0xc9/referrers",
+		"/api/threads",
+		"/objects/0xc8",
+	}
+	get := func(path string) (string, error) {
+		recorder := httptest.NewRecorder()
+		s.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		response := recorder.Result()
+		body, err := io.ReadAll(response.Body)
+		if err != nil {
+			return "", err
+		}
+		if response.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("GET %s status = %d: %s", path, response.StatusCode, body)
+		}
+		return string(body), nil
+	}
+	want := make(map[string]string)
+	for _, path := range paths {
+		body, err := get(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want[path] = body
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 16*len(paths))
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := range paths {
+				path := paths[(i+j)%len(paths)]
+				body, err := get(path)
+				if err != nil {
+					errs <- err
+					continue
+				}
+				if body != want[path] {
+					errs <- fmt.Errorf("GET %s = %s, want %s", path, body, want[path])
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
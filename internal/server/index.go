@@ -0,0 +1,115 @@
+package server
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/danielleontiev/neojhat/internal/core"
+	"github.com/danielleontiev/neojhat/internal/dump"
+	"github.com/danielleontiev/neojhat/internal/storage"
+)
+
+// classIndex has the identifiers of the objects sorted by their class,
+// so the page of the instances of the class is read starting from the
+// position of the class instead of scanning the dump up to the page.
+// Instances and object arrays are keyed by the class, primitive arrays
+// by the element type. The index is built on the first request in the
+// temporary storage provided by newRun.
+type classIndex struct {
+	newRun  func() (storage.RunVolume, error)
+	once    sync.Once
+	err     error
+	byClass *storage.IndexRecordsReadStorage
+	byType  *storage.IndexRecordsReadStorage
+}
+
+func newClassIndex(newRun func() (storage.RunVolume, error)) *classIndex {
+	return &classIndex{newRun: newRun}
+}
+
+// build sorts the objects of the dump by class once,
+// the error is returned on every call if it failed.
+func (c *classIndex) build(parsedAccessor *dump.ParsedAccessor) error {
+	c.once.Do(func() {
+		c.byClass, c.err = c.sort(func(put func(key uint64, objectId core.Identifier) error) error {
+			err := parsedAccessor.ScanHprofGcInstanceDumps(func(header core.HprofGcClassDumpInstanceDumpHeader) error {
+				return put(uint64(header.ClassObjectId), header.ObjectId)
+			})
+			if err != nil {
+				return fmt.Errorf("cannot scan instances: %w", err)
+			}
+			err = parsedAccessor.ScanHprofGcObjArrays(func(header core.HprofGcObjArrayDumpHeader) error {
+				return put(uint64(header.ArrayClassId), header.ArrayObjectId)
+			})
+			if err != nil {
+				return fmt.Errorf("cannot scan object arrays: %w", err)
+			}
+			return nil
+		})
+		if c.err != nil {
+			return
+		}
+		c.byType, c.err = c.sort(func(put func(key uint64, objectId core.Identifier) error) error {
+			err := parsedAccessor.ScanHprofGcPrimArrays(func(header core.HprofGcPrimArrayDumpHeader) error {
+				return put(uint64(header.ElementType), header.ArrayObjectId)
+			})
+			if err != nil {
+				return fmt.Errorf("cannot scan primitive arrays: %w", err)
+			}
+			return nil
+		})
+	})
+	return c.err
+}
+
+// sort collects the objects put by scan and returns
+// them sorted by the key and then by identifier.
+func (c *classIndex) sort(scan func(put func(key uint64, objectId core.Identifier) error) error) (*storage.IndexRecordsReadStorage, error) {
+	index := storage.NewTempIndex(c.newRun)
+	writer := storage.NewSortingIndexWriteStorage(index, c.newRun, storage.DefaultBatchSize)
+	err := scan(func(key uint64, objectId core.Identifier) error {
+		return writer.Put(key, uint64(objectId))
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("cannot sort objects by class: %w", err)
+	}
+	return index.Reader()
+}
+
+// page returns the identifiers of the page of the objects with the key.
+func page(index *storage.IndexRecordsReadStorage, key uint64, pages pagination) ([]core.Identifier, error) {
+	first, err := index.Find(key)
+	if err != nil {
+		return nil, err
+	}
+	var ids []core.Identifier
+	// first+offset could overflow for the offsets far beyond the index
+	for position := first + min(pages.offset, index.Len()-first); position < index.Len() && len(ids) < pages.limit; position++ {
+		k, objectId, err := index.At(position)
+		if err != nil {
+			return nil, err
+		}
+		if k != key {
+			break
+		}
+		ids = append(ids, core.Identifier(objectId))
+	}
+	return ids, nil
+}
+
+// Close removes the temporary storage of the index.
+func (c *classIndex) Close() error {
+	var err error
+	for _, index := range []*storage.IndexRecordsReadStorage{c.byClass, c.byType} {
+		if index == nil {
+			continue
+		}
+		if closeErr := index.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/danielleontiev/neojhat/internal/core"
+	td "github.com/danielleontiev/neojhat/internal/testdump"
+)
+
+func TestPage(t *testing.T) {
+	c := newClassIndex(td.NewRun)
+	// objects of the classes 2 and 5 interleave in the dump
+	index, err := c.sort(func(put func(key uint64, objectId core.Identifier) error) error {
+		for id := core.Identifier(10); id < 20; id++ {
+			if err := put(uint64(2+3*(id%2)), id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("sort() error = %v", err)
+	}
+	defer index.Close()
+	tests := []struct {
+		name  string
+		key   uint64
+		pages pagination
+		want  []core.Identifier
+	}{
+		{name: "first page", key: 2, pages: pagination{limit: 3}, want: []core.Identifier{10, 12, 14}},
+		{name: "last page", key: 2, pages: pagination{offset: 3, limit: 3}, want: []core.Identifier{16, 18}},
+		{name: "last class", key: 5, pages: pagination{offset: 1, limit: 2}, want: []core.Identifier{13, 15}},
+		{name: "beyond the class", key: 2, pages: pagination{offset: 5, limit: 3}},
+		{name: "beyond the index", key: 5, pages: pagination{offset: 10, limit: 3}},
+		{name: "no objects", key: 3, pages: pagination{limit: 3}},
+		{name: "no objects at the end", key: 7, pages: pagination{limit: 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := page(index, tt.key, tt.pages)
+			if err != nil {
+				t.Fatalf("page() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("page() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"github.com/danielleontiev/neojhat/internal/core"
+	"github.com/danielleontiev/neojhat/internal/java"
+	"github.com/danielleontiev/neojhat/internal/referrers"
+)
+
+// Window is the part of the long list returned by the API, Total
+// is the length of the whole list.
+type Window struct {
+	Total  int `json:"total"`
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+// ClassEntry is the row of the class histogram, Id is omitted for
+// primitive arrays since they have no class in the dump.
+type ClassEntry struct {
+	Id             core.Identifier `json:"id,omitempty"`
+	Name           string          `json:"name"`
+	InstancesCount int             `json:"instancesCount"`
+	TotalSize      int             `json:"totalSize"`
+	RetainedSize   int             `json:"retainedSize,omitempty"`
+}
+
+type ClassList struct {
+	Window
+	Items []ClassEntry `json:"items"`
+}
+
+// ObjectEntry is the instance of the class (or the array of the
+// array class) with its shallow size.
+type ObjectEntry struct {
+	Id   core.Identifier `json:"id"`
+	Size int             `json:"size"`
+}
+
+type InstanceList struct {
+	ClassId   core.Identifier `json:"classId"`
+	ClassName string          `json:"className"`
+	Window
+	Items []ObjectEntry `json:"items"`
+}
+
+// FieldValue is the field of the instance or the static field of the
+// class. Value is the identifier of the object for references (null
+// for null references) and the number, boolean or character otherwise.
+type FieldValue struct {
+	Name       string        `json:"name"`
+	DeclaredBy string        `json:"declaredBy"`
+	Type       core.JavaType `json:"type"`
+	Value      any           `json:"value"`
+}
+
+type Element struct {
+	Index int              `json:"index"`
+	Value *core.Identifier `json:"value"`
+}
+
+// Object is the heap object of any kind. Size is the shallow size of
+// instances and arrays, classes have InstanceSize of their instances
+// instead. Fields are the instance fields for instances and the static
+// fields for classes. Elements are the page of the elements of object
+// arrays, Window tells which page it is.
+type Object struct {
+	Id           core.Identifier `json:"id"`
+	Kind         java.ObjectKind `json:"kind"`
+	ClassId      core.Identifier `json:"classId,omitempty"`
+	ClassName    string          `json:"className"`
+	Size         int             `json:"size,omitempty"`
+	InstanceSize int             `json:"instanceSize,omitempty"`
+	Length       *int            `json:"length,omitempty"`
+	Fields       []FieldValue    `json:"fields,omitempty"`
+	Elements     []Element       `json:"elements,omitempty"`
+	Window       *Window         `json:"window,omitempty"`
+}
+
+type ReferrerList struct {
+	ObjectId core.Identifier `json:"objectId"`
+	Window
+	Items []referrers.Referrer `json:"items"`
+}
+
+type apiError struct {
+	Error string `json:"error"`
+}
@@ -0,0 +1,773 @@
+// server keeps the parsed heap dump open and serves the web UI to browse
+// it: the class histogram, the instances of every class, the fields and
+// references of every object and the stacks of threads. Every object is
+// the link to its page, so the heap graph can be walked in both directions
+// by clicking. Pages are rendered on the server and need nothing from the
+// internet, so the UI works offline. The same data is served as JSON under
+// /api/ for the tools.
+package server
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/danielleontiev/neojhat/internal/core"
+	"github.com/danielleontiev/neojhat/internal/dump"
+	"github.com/danielleontiev/neojhat/internal/format"
+	"github.com/danielleontiev/neojhat/internal/java"
+	"github.com/danielleontiev/neojhat/internal/objects"
+	"github.com/danielleontiev/neojhat/internal/output"
+	"github.com/danielleontiev/neojhat/internal/storage"
+	"github.com/danielleontiev/neojhat/internal/threads"
+)
+
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+	// the end of the page is offset+limit, it should not overflow
+	maxOffset = math.MaxInt - maxLimit
+	// popular objects (e.g. empty strings) have millions of
+	// referrers, only the first ones are inspected
+	maxReferrers = 100
+)
+
+// Server is http.Handler serving the pages of the heap dump. Both
+// ParsedAccessor and Heap are safe for concurrent use, so requests
+// are served in parallel.
+type Server struct {
+	parsedAccessor *dump.ParsedAccessor
+	heap           *java.Heap
+	size           *core.SizeInfo
+	byClass        *classIndex
+	mux            *http.ServeMux
+}
+
+// New creates the server for the parsed heap dump. Referrers are
+// shown only if the accessor has the references index. newRun
+// provides temporary storage for the objects sorted by class,
+// it's removed by Close.
+func New(parsedAccessor *dump.ParsedAccessor, newRun func() (storage.RunVolume, error)) (*Server, error) {
+	heap := java.NewHeap(parsedAccessor)
+	size, err := heap.SizeInfo("")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		parsedAccessor: parsedAccessor,
+		heap:           heap,
+		size:           size,
+		byClass:        newClassIndex(newRun),
+		mux:            http.NewServeMux(),
+	}
+	s.mux.HandleFunc("GET /{$}", s.page(s.classes))
+	s.mux.HandleFunc("GET /classes/{id}", s.page(s.class))
+	s.mux.HandleFunc("GET /arrays/{type}", s.page(s.primArrays))
+	s.mux.HandleFunc("GET /objects/{id}", s.page(s.object))
+	s.mux.HandleFunc("GET /threads", s.page(s.threads))
+	s.mux.HandleFunc("GET /api/classes", s.api(s.apiClasses))
+	s.mux.HandleFunc("GET /api/classes/{id}/instances", s.api(s.apiInstances))
+	s.mux.HandleFunc("GET /api/objects/{id}", s.api(s.apiObject))
+	s.mux.HandleFunc("GET /api/objects/{id}/referrers", s.api(s.apiReferrers))
+	s.mux.HandleFunc("GET /api/threads", s.api(s.apiThreads))
+	s.mux.HandleFunc("GET /api/summary", s.api(s.apiSummary))
+	return s, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Close removes the temporary storage of the server.
+func (s *Server) Close() error {
+	return s.byClass.Close()
+}
+
+// pageHandler builds the page for the request, the page is titled
+// with the returned title.
+type pageHandler func(r *http.Request) (string, output.Page, error)
+
+// httpError is the error caused by the request itself,
+// e.g. malformed identifier or missing object.
+type httpError struct {
+	status  int
+	message string
+}
+
+func (e httpError) Error() string {
+	return e.message
+}
+
+func notFound(format string, args ...any) error {
+	return httpError{status: http.StatusNotFound, message: fmt.Sprintf(format, args...)}
+}
+
+func badRequest(format string, args ...any) error {
+	return httpError{status: http.StatusBadRequest, message: fmt.Sprintf(format, args...)}
+}
+
+func (s *Server) page(handler pageHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		title, page, err := handler(r)
+		if err != nil {
+			status := http.StatusInternalServerError
+			var e httpError
+			if errors.As(err, &e) {
+				status = e.status
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		// rendered to the buffer first, so the template
+		// error is not mixed with the half-written page
+		var buf bytes.Buffer
+		if err := output.BrowseHtml(title, page, &buf); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		buf.WriteTo(w)
+	}
+}
+
+// histogram counts the objects of every class sorted as requested
+// by sort query parameter, withRetained tells whether the objects
+// could be sorted by retained size.
+func (s *Server) histogram(r *http.Request) (o objects.Objects, sortBy objects.SortBy, withRetained bool, err error) {
+	if err := sortBy.Set(r.URL.Query().Get("sort")); err != nil {
+		return objects.Objects{}, 0, false, badRequest("invalid sort: %v", err)
+	}
+	_, err = s.parsedAccessor.Dominators()
+	withRetained = err == nil
+	if sortBy == objects.Retained && !withRetained {
+		return objects.Objects{}, 0, false, badRequest("retained sizes are not available, run leaks command to build the dominator tree")
+	}
+	o, err = objects.GetObjects(s.parsedAccessor, sortBy, objects.NoGroups, 0, objects.Filter{}, 0, "", "")
+	if err != nil {
+		return objects.Objects{}, 0, false, fmt.Errorf("cannot count objects: %w", err)
+	}
+	sort.Slice(o.Items, func(i, j int) bool {
+		left, right := sortKey(o.Items[i], sortBy), sortKey(o.Items[j], sortBy)
+		if left != right {
+			return left > right
+		}
+		return o.Items[i].Name < o.Items[j].Name
+	})
+	return o, sortBy, withRetained, nil
+}
+
+func (s *Server) classes(r *http.Request) (string, output.Page, error) {
+	o, sortBy, withRetained, err := s.histogram(r)
+	if err != nil {
+		return "", output.Page{}, err
+	}
+	items := o.Items
+
+	links := []output.Cell{{Text: "Sort by:"}, {Text: "count", Href: "/?sort=count"}, {Text: "size", Href: "/?sort=size"}}
+	if withRetained {
+		links = append(links, output.Cell{Text: "retained", Href: "/?sort=retained"})
+	}
+	section := output.PageSection{
+		Links:   links,
+		Headers: []string{"Class Name", "Instances", "Total Size"},
+	}
+	if sortBy == objects.Retained {
+		section.Headers = append(section.Headers, "Retained Size")
+	}
+	for _, item := range items {
+		href := classHref(item.ClassId())
+		if item.ClassId() == 0 {
+			href = "/arrays/" + strings.TrimSuffix(item.Name, "[]")
+		}
+		row := []output.Cell{
+			{Text: format.ClassName(item.Name), Href: href},
+			{Text: strconv.Itoa(item.InstancesCount)},
+			{Text: format.Size(item.TotalSize)},
+		}
+		if sortBy == objects.Retained {
+			row = append(row, output.Cell{Text: format.Size(item.RetainedSize)})
+		}
+		section.Rows = append(section.Rows, row)
+	}
+	return "Classes", output.Page{
+		Summary: []output.PageSummary{
+			{Key: "Instances", Val: output.Cell{Text: strconv.Itoa(o.TotalCount)}},
+			{Key: "Total Size", Val: output.Cell{Text: format.Size(o.TotalSize)}},
+			{Key: "Classes", Val: output.Cell{Text: strconv.Itoa(len(items))}},
+			{Key: "Layout", Val: output.Cell{Text: o.Layout}},
+		},
+		Sections: []output.PageSection{section},
+	}, nil
+}
+
+func sortKey(item objects.ObjectItem, sortBy objects.SortBy) int {
+	switch sortBy {
+	case objects.Size:
+		return item.TotalSize
+	case objects.Retained:
+		return item.RetainedSize
+	}
+	return item.InstancesCount
+}
+
+func (s *Server) class(r *http.Request) (string, output.Page, error) {
+	classId, err := pathId(r)
+	if err != nil {
+		return "", output.Page{}, err
+	}
+	return s.classPage(classId, r)
+}
+
+// classPage shows the class with its fields and the page of its
+// instances, the instances of array classes are arrays.
+func (s *Server) classPage(classId core.Identifier, r *http.Request) (string, output.Page, error) {
+	classDump, err := s.parsedAccessor.GetHprofGcClassDump(classId)
+	if err != nil {
+		return "", output.Page{}, notFound("class %s not found", format.ObjectId(uint64(classId)))
+	}
+	pages, err := parsePagination(r)
+	if err != nil {
+		return "", output.Page{}, err
+	}
+	class, err := s.heap.ParseClass(classId)
+	if err != nil {
+		return "", output.Page{}, err
+	}
+	name := className(class.Name)
+	isArray := strings.HasPrefix(class.Name, "[")
+
+	var page output.Page
+	page.Summary = append(page.Summary,
+		output.PageSummary{Key: "Class", Val: output.Cell{Text: name}},
+		output.PageSummary{Key: "Id", Val: output.Cell{Text: format.ObjectId(uint64(classId))}})
+	if class.Superclass != nil {
+		page.Summary = append(page.Summary, output.PageSummary{Key: "Superclass", Val: output.Cell{
+			Text: className(class.Superclass.Name),
+			Href: classHref(classDump.SuperclassObjectId),
+		}})
+	}
+	loader := output.Cell{Text: "bootstrap"}
+	if classDump.ClassloaderObjectId != 0 {
+		loader = s.objectCell(classDump.ClassloaderObjectId)
+	}
+	page.Summary = append(page.Summary, output.PageSummary{Key: "Class Loader", Val: loader})
+
+	var total int
+	var instances output.PageSection
+	if isArray {
+		total = s.parsedAccessor.Counters.ObjArraysCount[classId]
+		instances, err = s.objArrays(classId, pages, total)
+	} else {
+		instanceSize, err := s.heap.InstanceSize(classId, s.size)
+		if err != nil {
+			return "", output.Page{}, err
+		}
+		page.Summary = append(page.Summary, output.PageSummary{Key: "Instance Size", Val: output.Cell{Text: format.Size(instanceSize)}})
+		total = s.parsedAccessor.Counters.InstancesCount[classId]
+		instances, err = s.instances(classId, instanceSize, pages, total)
+	}
+	if err != nil {
+		return "", output.Page{}, err
+	}
+	page.Summary = append(page.Summary, output.PageSummary{Key: "Instances", Val: output.Cell{Text: strconv.Itoa(total)}})
+
+	if len(class.StaticFields) > 0 {
+		statics := output.PageSection{Title: "Static Fields", Headers: []string{"Name", "Type", "Value"}}
+		for _, field := range class.StaticFields {
+			statics.Rows = append(statics.Rows, []output.Cell{{Text: field.Name}, {Text: field.Type.String()}, s.valueCell(field.Value)})
+		}
+		page.Sections = append(page.Sections, statics)
+	}
+	if len(class.InstanceFields) > 0 {
+		fields := output.PageSection{Title: "Instance Fields", Headers: []string{"Name", "Type"}}
+		for _, field := range class.InstanceFields {
+			fields.Rows = append(fields.Rows, []output.Cell{{Text: field.Name}, {Text: field.Type.String()}})
+		}
+		page.Sections = append(page.Sections, fields)
+	}
+	page.Sections = append(page.Sections, instances)
+	return name, page, nil
+}
+
+// pageOfInstances finds the page of the instances of the class
+// in the index of the objects sorted by class, see classIndex.
+func (s *Server) pageOfInstances(classId core.Identifier, pages pagination) ([]core.Identifier, error) {
+	if err := s.byClass.build(s.parsedAccessor); err != nil {
+		return nil, err
+	}
+	return page(s.byClass.byClass, uint64(classId), pages)
+}
+
+// pageOfObjArrays is pageOfInstances for the arrays of the array class.
+func (s *Server) pageOfObjArrays(classId core.Identifier, pages pagination) ([]core.HprofGcObjArrayDumpHeader, error) {
+	ids, err := s.pageOfInstances(classId, pages)
+	if err != nil {
+		return nil, err
+	}
+	var headers []core.HprofGcObjArrayDumpHeader
+	for _, id := range ids {
+		header, err := s.parsedAccessor.GetHprofGcObjArray(id)
+		if err != nil {
+			return nil, err
+		}
+		headers = append(headers, header)
+	}
+	return headers, nil
+}
+
+// pageOfPrimArrays is pageOfInstances for the primitive arrays of the type.
+func (s *Server) pageOfPrimArrays(elementType core.JavaType, pages pagination) ([]core.HprofGcPrimArrayDumpHeader, error) {
+	if err := s.byClass.build(s.parsedAccessor); err != nil {
+		return nil, err
+	}
+	ids, err := page(s.byClass.byType, uint64(elementType), pages)
+	if err != nil {
+		return nil, err
+	}
+	var headers []core.HprofGcPrimArrayDumpHeader
+	for _, id := range ids {
+		header, err := s.parsedAccessor.GetHprofGcPrimArray(id)
+		if err != nil {
+			return nil, err
+		}
+		headers = append(headers, header)
+	}
+	return headers, nil
+}
+
+func (s *Server) instances(classId core.Identifier, instanceSize int, pages pagination, total int) (output.PageSection, error) {
+	section := pages.section("Instances", "/classes/"+format.ObjectId(uint64(classId)), total)
+	section.Headers = []string{"Object", "Size"}
+	ids, err := s.pageOfInstances(classId, pages)
+	if err != nil {
+		return output.PageSection{}, err
+	}
+	for _, id := range ids {
+		section.Rows = append(section.Rows, []output.Cell{s.objectCell(id), {Text: format.Size(instanceSize)}})
+	}
+	return section, nil
+}
+
+func (s *Server) objArrays(classId core.Identifier, pages pagination, total int) (output.PageSection, error) {
+	section := pages.section("Arrays", "/classes/"+format.ObjectId(uint64(classId)), total)
+	section.Headers = []string{"Array", "Length", "Size"}
+	headers, err := s.pageOfObjArrays(classId, pages)
+	if err != nil {
+		return output.PageSection{}, err
+	}
+	for _, header := range headers {
+		section.Rows = append(section.Rows, []output.Cell{
+			s.objectCell(header.ArrayObjectId),
+			{Text: strconv.Itoa(int(header.NumberOfElements))},
+			{Text: format.Size(s.size.OfArray(core.Object, int(header.NumberOfElements)))},
+		})
+	}
+	return section, nil
+}
+
+// primArrays lists the primitive arrays of the type, they
+// have no class in the dump, so the type is in the path.
+func (s *Server) primArrays(r *http.Request) (string, output.Page, error) {
+	elementType, ok := primitiveType(r.PathValue("type"))
+	if !ok {
+		return "", output.Page{}, notFound("unknown primitive type '%s'", r.PathValue("type"))
+	}
+	pages, err := parsePagination(r)
+	if err != nil {
+		return "", output.Page{}, err
+	}
+	total := s.parsedAccessor.Counters.PrimArraysCount[elementType]
+	section := pages.section("Arrays", "/arrays/"+elementType.String(), total)
+	section.Headers = []string{"Array", "Length", "Size"}
+	headers, err := s.pageOfPrimArrays(elementType, pages)
+	if err != nil {
+		return "", output.Page{}, err
+	}
+	for _, header := range headers {
+		section.Rows = append(section.Rows, []output.Cell{
+			{Text: format.ObjectId(uint64(header.ArrayObjectId)), Href: objectHref(header.ArrayObjectId)},
+			{Text: strconv.Itoa(int(header.NumberOfElements))},
+			{Text: format.Size(s.size.OfArray(elementType, int(header.NumberOfElements)))},
+		})
+	}
+	name := elementType.String() + "[]"
+	return name, output.Page{
+		Summary: []output.PageSummary{
+			{Key: "Type", Val: output.Cell{Text: name}},
+			{Key: "Arrays", Val: output.Cell{Text: strconv.Itoa(total)}},
+		},
+		Sections: []output.PageSection{section},
+	}, nil
+}
+
+func primitiveType(name string) (core.JavaType, bool) {
+	for _, t := range []core.JavaType{core.Boolean, core.Char, core.Float, core.Double, core.Byte, core.Short, core.Int, core.Long} {
+		if t.String() == name {
+			return t, true
+		}
+	}
+	return 0, false
+}
+
+func (s *Server) object(r *http.Request) (string, output.Page, error) {
+	objectId, err := pathId(r)
+	if err != nil {
+		return "", output.Page{}, err
+	}
+	switch s.heap.KindOf(objectId) {
+	case java.InstanceObject:
+		return s.instancePage(objectId)
+	case java.ObjectArrayObject:
+		return s.objArrayPage(objectId, r)
+	case java.PrimitiveArrayObject:
+		return s.primArrayPage(objectId)
+	case java.ClassObject:
+		return s.classPage(objectId, r)
+	}
+	return "", output.Page{}, notFound("object %s not found", format.ObjectId(uint64(objectId)))
+}
+
+func (s *Server) instancePage(objectId core.Identifier) (string, output.Page, error) {
+	header, err := s.parsedAccessor.GetHprofGcInstanceDump(objectId)
+	if err != nil {
+		return "", output.Page{}, err
+	}
+	object, err := s.heap.ParseNormalObject(objectId)
+	if err != nil {
+		return "", output.Page{}, err
+	}
+	fields, err := object.Fields()
+	if err != nil {
+		return "", output.Page{}, fmt.Errorf("cannot read fields of %s: %w", format.ObjectId(uint64(objectId)), err)
+	}
+	instanceSize, err := s.heap.InstanceSize(header.ClassObjectId, s.size)
+	if err != nil {
+		return "", output.Page{}, err
+	}
+	name := format.ClassName(object.Class.Name)
+	page := output.Page{Summary: []output.PageSummary{
+		{Key: "Object", Val: output.Cell{Text: format.ObjectId(uint64(objectId))}},
+		{Key: "Class", Val: output.Cell{Text: name, Href: classHref(header.ClassObjectId)}},
+		{Key: "Size", Val: output.Cell{Text: format.Size(instanceSize)}},
+	}}
+	if object.Class.Name == "java/lang/String" {
+		value, err := s.heap.ParseJavaString(core.JavaValue{Type: core.Object, Value: objectId})
+		if err != nil {
+			return "", output.Page{}, err
+		}
+		page.Summary = append(page.Summary, output.PageSummary{Key: "Value", Val: output.Cell{Text: strconv.Quote(value)}})
+	}
+
+	fieldsSection := output.PageSection{Title: "Fields", Headers: []string{"Name", "Declared By", "Type", "Value"}}
+	for _, field := range fields {
+		fieldsSection.Rows = append(fieldsSection.Rows, []output.Cell{
+			{Text: field.Name},
+			{Text: format.ClassName(field.Origin)},
+			{Text: field.Value.Type.String()},
+			s.valueCell(field.Value),
+		})
+	}
+	references, err := s.heap.OutboundReferences(objectId)
+	if err != nil {
+		return "", output.Page{}, err
+	}
+	referencesSection := output.PageSection{Title: "References", Headers: []string{"Field", "Object"}}
+	if len(references) == 0 {
+		referencesSection.Note = "The object doesn't reference other objects"
+	}
+	for _, reference := range references {
+		referencesSection.Rows = append(referencesSection.Rows, []output.Cell{{Text: reference.Name()}, s.objectCell(reference.To)})
+	}
+	referrersSection, err := s.referrers(objectId)
+	if err != nil {
+		return "", output.Page{}, err
+	}
+	page.Sections = []output.PageSection{fieldsSection, referencesSection, referrersSection}
+	return name + " " + format.ObjectId(uint64(objectId)), page, nil
+}
+
+func (s *Server) objArrayPage(arrayObjectId core.Identifier, r *http.Request) (string, output.Page, error) {
+	pages, err := parsePagination(r)
+	if err != nil {
+		return "", output.Page{}, err
+	}
+	array, err := s.heap.ParseObjectArrayFull(arrayObjectId)
+	if err != nil {
+		return "", output.Page{}, err
+	}
+	arrayClassName, err := s.heap.ObjectTypeName(arrayObjectId)
+	if err != nil {
+		return "", output.Page{}, err
+	}
+	name := format.ClassName(arrayClassName)
+	page := output.Page{Summary: []output.PageSummary{
+		{Key: "Object", Val: output.Cell{Text: format.ObjectId(uint64(arrayObjectId))}},
+		{Key: "Class", Val: output.Cell{Text: name, Href: classHref(array.ArrayClassId)}},
+		{Key: "Length", Val: output.Cell{Text: strconv.Itoa(len(array.Elements))}},
+		{Key: "Size", Val: output.Cell{Text: format.Size(s.size.OfArray(core.Object, len(array.Elements)))}},
+	}}
+	elements := pages.section("Elements", objectHref(arrayObjectId), len(array.Elements))
+	elements.Headers = []string{"Index", "Value"}
+	from, to := pages.bounds(len(array.Elements))
+	for i := from; i < to; i++ {
+		elements.Rows = append(elements.Rows, []output.Cell{
+			{Text: strconv.Itoa(i)},
+			s.valueCell(core.JavaValue{Type: core.Object, Value: array.Elements[i]}),
+		})
+	}
+	referrersSection, err := s.referrers(arrayObjectId)
+	if err != nil {
+		return "", output.Page{}, err
+	}
+	page.Sections = []output.PageSection{elements, referrersSection}
+	return name + " " + format.ObjectId(uint64(arrayObjectId)), page, nil
+}
+
+func (s *Server) primArrayPage(arrayObjectId core.Identifier) (string, output.Page, error) {
+	header, err := s.parsedAccessor.GetHprofGcPrimArray(arrayObjectId)
+	if err != nil {
+		return "", output.Page{}, err
+	}
+	name := header.ElementType.String() + "[]"
+	page := output.Page{Summary: []output.PageSummary{
+		{Key: "Object", Val: output.Cell{Text: format.ObjectId(uint64(arrayObjectId))}},
+		{Key: "Type", Val: output.Cell{Text: name, Href: "/arrays/" + header.ElementType.String()}},
+		{Key: "Length", Val: output.Cell{Text: strconv.Itoa(int(header.NumberOfElements))}},
+		{Key: "Size", Val: output.Cell{Text: format.Size(s.size.OfArray(header.ElementType, int(header.NumberOfElements)))}},
+	}}
+	referrersSection, err := s.referrers(arrayObjectId)
+	if err != nil {
+		return "", output.Page{}, err
+	}
+	page.Sections = []output.PageSection{referrersSection}
+	return name + " " + format.ObjectId(uint64(arrayObjectId)), page, nil
+}
+
+// referrers lists the objects referencing the object, the references
+// index has only identifiers, so the referrers are inspected to tell
+// the field (or the element) holding the reference.
+func (s *Server) referrers(objectId core.Identifier) (output.PageSection, error) {
+	section := output.PageSection{Title: "Referrers", Headers: []string{"Object", "Field"}}
+	referrerIds, err := s.referrerIds(objectId)
+	if err != nil {
+		section.Note = "Referrers are not available: " + err.Error()
+		return section, nil
+	}
+	switch {
+	case len(referrerIds) == 0:
+		section.Note = "The object is not referenced by other objects"
+	case len(referrerIds) > maxReferrers:
+		section.Note = fmt.Sprintf("Showing the first %d of %d referrers", maxReferrers, len(referrerIds))
+		referrerIds = referrerIds[:maxReferrers]
+	}
+	references, err := s.referencesTo(objectId, referrerIds)
+	if err != nil {
+		return output.PageSection{}, err
+	}
+	for _, reference := range references {
+		section.Rows = append(section.Rows, []output.Cell{s.objectCell(reference.From), {Text: reference.Name()}})
+	}
+	return section, nil
+}
+
+// referrerIds are the objects referencing the object, each one once.
+func (s *Server) referrerIds(objectId core.Identifier) ([]core.Identifier, error) {
+	referrerIds, err := s.parsedAccessor.GetReferrers(objectId)
+	if err != nil {
+		return nil, err
+	}
+	var unique []core.Identifier
+	for i, referrerId := range referrerIds {
+		// the same referrer is repeated for every reference it holds
+		if i > 0 && referrerIds[i-1] == referrerId {
+			continue
+		}
+		unique = append(unique, referrerId)
+	}
+	return unique, nil
+}
+
+// referencesTo inspects the referrers to find the references to the
+// object, the references index has only identifiers of referrers.
+func (s *Server) referencesTo(objectId core.Identifier, referrerIds []core.Identifier) ([]java.Reference, error) {
+	var references []java.Reference
+	for _, referrerId := range referrerIds {
+		outbound, err := s.heap.OutboundReferences(referrerId)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read references of referrer %s: %w", format.ObjectId(uint64(referrerId)), err)
+		}
+		for _, reference := range outbound {
+			if reference.To == objectId {
+				references = append(references, reference)
+			}
+		}
+	}
+	return references, nil
+}
+
+// referencesAt returns the references to the object at the positions
+// from..to of the references index. The positions of the referrer in
+// the index are its references in the order of OutboundReferences.
+func (s *Server) referencesAt(objectId core.Identifier, referrerIds []core.Identifier, from, to int) ([]java.Reference, error) {
+	var page []java.Reference
+	for position := from; position < to; {
+		referrerId := referrerIds[position]
+		first, end := position, position
+		for first > 0 && referrerIds[first-1] == referrerId {
+			first--
+		}
+		for end < to && referrerIds[end] == referrerId {
+			end++
+		}
+		references, err := s.referencesTo(objectId, []core.Identifier{referrerId})
+		if err != nil {
+			return nil, err
+		}
+		page = append(page, references[min(position-first, len(references)):min(end-first, len(references))]...)
+		position = end
+	}
+	return page, nil
+}
+
+func (s *Server) threads(r *http.Request) (string, output.Page, error) {
+	threadDump, err := threads.GetThreadDump(s.parsedAccessor)
+	if err != nil {
+		return "", output.Page{}, fmt.Errorf("can't parse thread dump: %w", err)
+	}
+	page := output.ThreadsPage(threadDump, objectHref)
+	if len(page.Sections) == 0 {
+		page.Sections = []output.PageSection{{Note: "The dump has no thread stacks"}}
+	}
+	return "Threads", page, nil
+}
+
+// objectCell is the link to the object labelled with its type,
+// objects missing in the dump are shown without the link.
+func (s *Server) objectCell(objectId core.Identifier) output.Cell {
+	id := format.ObjectId(uint64(objectId))
+	typeName, err := s.heap.ObjectTypeName(objectId)
+	if err != nil {
+		return output.Cell{Text: id}
+	}
+	return output.Cell{Text: format.ClassName(typeName) + " " + id, Href: objectHref(objectId)}
+}
+
+func (s *Server) valueCell(value core.JavaValue) output.Cell {
+	if value.Type != core.Object {
+		return output.Cell{Text: renderPrimitive(value)}
+	}
+	objectId, err := value.ToObject()
+	if err != nil {
+		return output.Cell{Text: fmt.Sprint(value.Value)}
+	}
+	if objectId == 0 {
+		return output.Cell{Text: "null"}
+	}
+	return s.objectCell(objectId)
+}
+
+func renderPrimitive(value core.JavaValue) string {
+	if value.Type == core.Char {
+		// char is kept as two raw bytes of UTF-16 code unit
+		if c, ok := value.Value.(string); ok && len(c) == 2 {
+			return strconv.QuoteRune(rune(c[0])<<8 | rune(c[1]))
+		}
+	}
+	return fmt.Sprint(value.Value)
+}
+
+// className prints the name of the class, array classes
+// are named by signature, e.g. [Ljava/lang/String;
+func className(name string) string {
+	if strings.HasPrefix(name, "[") {
+		name, _ = format.Signature(name)
+	}
+	return format.ClassName(name)
+}
+
+func objectHref(objectId core.Identifier) string {
+	return "/objects/" + format.ObjectId(uint64(objectId))
+}
+
+func classHref(classId core.Identifier) string {
+	return "/classes/" + format.ObjectId(uint64(classId))
+}
+
+// pathId parses the identifier in the path, hex
+// with 0x prefix and decimal are accepted.
+func pathId(r *http.Request) (core.Identifier, error) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 0, 64)
+	if err != nil {
+		return 0, badRequest("invalid identifier '%s', use hex (0x7ff0012a8) or decimal number", r.PathValue("id"))
+	}
+	return core.Identifier(id), nil
+}
+
+// pagination is the window of the long list, e.g. the instances of the
+// class, set by offset and limit query parameters.
+type pagination struct {
+	offset int
+	limit  int
+}
+
+func parsePagination(r *http.Request) (pagination, error) {
+	p := pagination{limit: defaultLimit}
+	query := r.URL.Query()
+	if value := query.Get("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 || offset > maxOffset {
+			return pagination{}, badRequest("invalid offset '%s'", value)
+		}
+		p.offset = offset
+	}
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return pagination{}, badRequest("invalid limit '%s'", value)
+		}
+		p.limit = min(limit, maxLimit)
+	}
+	return p, nil
+}
+
+// section is the section of the page with the links
+// to the previous and the next pages of the list.
+func (p pagination) section(title, path string, total int) output.PageSection {
+	section := output.PageSection{Title: title}
+	if total == 0 {
+		section.Note = "None"
+		return section
+	}
+	last := min(p.offset+p.limit, total)
+	if p.offset < total {
+		section.Note = fmt.Sprintf("Showing %d-%d of %d", p.offset+1, last, total)
+	} else {
+		section.Note = fmt.Sprintf("Nothing to show, there are %d in total", total)
+	}
+	if p.offset > 0 {
+		section.Pages = append(section.Pages, output.Cell{Text: "← previous", Href: p.href(path, max(p.offset-p.limit, 0))})
+	}
+	if last < total {
+		section.Pages = append(section.Pages, output.Cell{Text: "next →", Href: p.href(path, last)})
+	}
+	return section
+}
+
+// bounds are the indexes of the first and after the last
+// items of the page in the list of total items.
+func (p pagination) bounds(total int) (int, int) {
+	return min(p.offset, total), min(p.offset+p.limit, total)
+}
+
+func (p pagination) window(total int) Window {
+	return Window{Total: total, Offset: p.offset, Limit: p.limit}
+}
+
+func (p pagination) href(path string, offset int) string {
+	return fmt.Sprintf("%s?offset=%d&limit=%d", path, offset, p.limit)
+}
@@ -0,0 +1,197 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/danielleontiev/neojhat/internal/core"
+	td "github.com/danielleontiev/neojhat/internal/testdump"
+)
+
+// Node 200 (0xc8) links to Node 201 (0xc9), both are in the
+// array 300 (0x12c) and 200 is kept in the static field.
+var serverSample = td.Dump(
+	[][]byte{
+		td.Utf8(1, "java/lang/Object"),
+		td.Utf8(2, "com/example/Node"),
+		td.Utf8(3, "next"),
+		td.Utf8(4, "count"),
+		td.Utf8(5, "[Lcom/example/Node;"),
+		td.Utf8(6, "INSTANCE"),
+		td.LoadClass(1, 100, 1),
+		td.LoadClass(2, 101, 2),
+		td.LoadClass(3, 102, 5),
+	},
+	td.ClassDump(100, 0, 0, nil, nil),
+	td.ClassDump(101, 100, 12,
+		[]td.Field{{NameId: 6, Type: core.Object, Value: td.Id(200)}},
+		[]td.Field{{NameId: 3, Type: core.Object}, {NameId: 4, Type: core.Int}}),
+	td.ClassDump(102, 100, 0, nil, nil),
+	td.InstanceDump(200, 101, td.Id(201), td.U4(7)),
+	td.InstanceDump(201, 101, td.Id(0), td.U4(8)),
+	td.ObjArrayDump(300, 102, 200, 201),
+	td.PrimArrayDump(400, core.Byte, 3, []byte("abc")),
+)
+
+func TestServer(t *testing.T) {
+	parsedAccessor, err := td.NewParsedAccessor(serverSample, true)
+	if err != nil {
+		t.Fatalf("cannot parse dump: %v", err)
+	}
+	s, err := New(parsedAccessor, td.NewRun)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+		want   []string
+	}{
+		{
+			name:   "histogram",
+			path:   "/",
+			status: http.StatusOK,
+			want:   []string{`<a href="/classes/0x65">com.example.Node</a>`, `<a href="/classes/0x66">com.example.Node[]</a>`, `<a href="/arrays/byte">byte[]</a>`},
+		},
+		{
+			name:   "histogram sorted by size",
+			path:   "/?sort=size",
+			status: http.StatusOK,
+			want:   []string{"com.example.Node"},
+		},
+		{
+			name:   "retained sizes without dominator tree",
+			path:   "/?sort=retained",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "unknown sorting",
+			path:   "/?sort=name",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "class",
+			path:   "/classes/0x65",
+			status: http.StatusOK,
+			want: []string{
+				`<a href="/classes/0x64">java.lang.Object</a>`,
+				"INSTANCE", "Instance Fields", "Showing 1-2 of 2",
+				`<a href="/objects/0xc8">com.example.Node 0xc8</a>`,
+				`<a href="/objects/0xc9">com.example.Node 0xc9</a>`,
+			},
+		},
+		{
+			name:   "second page of instances",
+			path:   "/classes/101?offset=1&limit=1",
+			status: http.StatusOK,
+			want:   []string{"Showing 2-2 of 2", `<a href="/classes/0x65?offset=0&amp;limit=1">← previous</a>`},
+		},
+		{
+			name:   "array class",
+			path:   "/classes/0x66",
+			status: http.StatusOK,
+			want:   []string{"com.example.Node[]", `<a href="/objects/0x12c">com.example.Node[] 0x12c</a>`},
+		},
+		{
+			name:   "primitive arrays",
+			path:   "/arrays/byte",
+			status: http.StatusOK,
+			want:   []string{`<a href="/objects/0x190">0x190</a>`, "Showing 1-1 of 1"},
+		},
+		{
+			name:   "unknown primitive type",
+			path:   "/arrays/string",
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "instance",
+			path:   "/objects/0xc8",
+			status: http.StatusOK,
+			want: []string{
+				`<a href="/classes/0x65">com.example.Node</a>`,
+				"<td>count</td>", "<td>7</td>",
+				`<a href="/objects/0xc9">com.example.Node 0xc9</a>`,
+				`<a href="/objects/0x12c">com.example.Node[] 0x12c</a>`, "<td>[0]</td>",
+			},
+		},
+		{
+			name:   "referrers of instance",
+			path:   "/objects/0xc9",
+			status: http.StatusOK,
+			want:   []string{`<a href="/objects/0xc8">com.example.Node 0xc8</a>`, "<td>next</td>", "<td>[1]</td>"},
+		},
+		{
+			name:   "object array",
+			path:   "/objects/0x12c",
+			status: http.StatusOK,
+			want:   []string{"Showing 1-2 of 2", `<a href="/objects/0xc9">com.example.Node 0xc9</a>`},
+		},
+		{
+			name:   "primitive array",
+			path:   "/objects/400",
+			status: http.StatusOK,
+			want:   []string{`<a href="/arrays/byte">byte[]</a>`, "The object is not referenced by other objects"},
+		},
+		{
+			name:   "class object",
+			path:   "/objects/0x64",
+			status: http.StatusOK,
+			want:   []string{"<h1>java.lang.Object</h1>"},
+		},
+		{
+			name:   "missing object",
+			path:   "/objects/0x999",
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "malformed identifier",
+			path:   "/objects/node",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "malformed limit",
+			path:   "/classes/0x65?limit=-1",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "threads",
+			path:   "/threads",
+			status: http.StatusOK,
+			want:   []string{"The dump has no thread stacks"},
+		},
+		{
+			name:   "read-only",
+			method: http.MethodPost,
+			path:   "/",
+			status: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			recorder := httptest.NewRecorder()
+			s.ServeHTTP(recorder, httptest.NewRequest(method, tt.path, nil))
+			response := recorder.Result()
+			body, err := io.ReadAll(response.Body)
+			if err != nil {
+				t.Fatalf("cannot read body: %v", err)
+			}
+			if response.StatusCode != tt.status {
+				t.Fatalf("%s %s status = %d, want %d: %s", method, tt.path, response.StatusCode, tt.status, body)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(body), want) {
+					t.Errorf("%s %s doesn't contain %q:\n%s", method, tt.path, want, body)
+				}
+			}
+		})
+	}
+}
@@ -77,6 +77,30 @@ func (r *BigRecordsReadStorage) HprofGcPrimArrayDumpGetOffset(arrayObjectId core
 	return int(offset), err
 }
 
+// HprofGcInstanceDumpScanOffsets calls fn for every instance
+// in increasing order of identifiers.
+func (r *BigRecordsReadStorage) HprofGcInstanceDumpScanOffsets(fn func(objectId core.Identifier, offset int) error) error {
+	return r.instanceDumpPersistent.Scan(func(key, val uint64) error {
+		return fn(core.Identifier(key), int(val))
+	})
+}
+
+// HprofGcObjArrayDumpScanOffsets calls fn for every object array
+// in increasing order of identifiers.
+func (r *BigRecordsReadStorage) HprofGcObjArrayDumpScanOffsets(fn func(arrayObjectId core.Identifier, offset int) error) error {
+	return r.objArrayDumpPersistent.Scan(func(key, val uint64) error {
+		return fn(core.Identifier(key), int(val))
+	})
+}
+
+// HprofGcPrimArrayDumpScanOffsets calls fn for every primitive array
+// in increasing order of identifiers.
+func (r *BigRecordsReadStorage) HprofGcPrimArrayDumpScanOffsets(fn func(arrayObjectId core.Identifier, offset int) error) error {
+	return r.primArrayDumpPersistent.Scan(func(key, val uint64) error {
+		return fn(core.Identifier(key), int(val))
+	})
+}
+
 func (r *BigRecordsReadStorage) Close() error {
 	err1 := r.instanceDumpPersistent.Close()
 	err2 := r.objArrayDumpPersistent.Close()
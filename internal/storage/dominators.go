@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+
+	"github.com/danielleontiev/neojhat/internal/core"
+)
+
+// DominatorsWriteStorage persists the dominator tree of the heap: the
+// immediate dominator and the retained size of every reachable object,
+// dominated objects (children in the tree) of every object and retained
+// sizes summed up by classes. Objects dominated only by the virtual root
+// of the tree (GC roots and everything reachable from several of them)
+// have immediate dominator 0.
+type DominatorsWriteStorage struct {
+	dominatorsPersistent *IndexRecordsWriteStorage
+	retainedPersistent   *IndexRecordsWriteStorage
+	dominatedPersistent  *SortingIndexWriteStorage
+	RetainedSizes
+}
+
+// RetainedSizes are retained sizes of all instances of the class (or all
+// arrays of the type). Objects dominated by objects of the same class are
+// not counted twice, so the value is the amount of memory that would be
+// freed if all the objects of the class were gone.
+type RetainedSizes struct {
+	Classes    map[core.Identifier]int
+	PrimArrays map[core.JavaType]int
+}
+
+func NewDominatorsWriteStorage(
+	dominatorsPersistent io.WriteCloser,
+	retainedPersistent io.WriteCloser,
+	dominatedPersistent io.WriteCloser,
+	newRun func() (RunVolume, error),
+) *DominatorsWriteStorage {
+	return &DominatorsWriteStorage{
+		dominatorsPersistent: NewIndexRecordsWriteStorage(dominatorsPersistent, DefaultBatchSize),
+		retainedPersistent:   NewIndexRecordsWriteStorage(retainedPersistent, DefaultBatchSize),
+		dominatedPersistent:  NewSortingIndexWriteStorage(dominatedPersistent, newRun, DefaultBatchSize),
+		RetainedSizes: RetainedSizes{
+			Classes:    make(map[core.Identifier]int),
+			PrimArrays: make(map[core.JavaType]int),
+		},
+	}
+}
+
+// PutDominator stores the immediate dominator and the retained size of
+// the object. Objects should be put in increasing order of identifiers.
+func (w *DominatorsWriteStorage) PutDominator(objectId, immediateDominator core.Identifier, retainedSize int) error {
+	if err := w.dominatorsPersistent.Put(uint64(objectId), uint64(immediateDominator)); err != nil {
+		return err
+	}
+	if err := w.retainedPersistent.Put(uint64(objectId), uint64(retainedSize)); err != nil {
+		return err
+	}
+	return w.dominatedPersistent.Put(uint64(immediateDominator), uint64(objectId))
+}
+
+func (w *DominatorsWriteStorage) SerializeTo(destination io.Writer) error {
+	encoder := gob.NewEncoder(destination)
+	if err := encoder.Encode(w.RetainedSizes); err != nil {
+		return fmt.Errorf("cannot serialize: %w", err)
+	}
+	return nil
+}
+
+func (w *DominatorsWriteStorage) Close() error {
+	err1 := w.dominatorsPersistent.Close()
+	err2 := w.retainedPersistent.Close()
+	err3 := w.dominatedPersistent.Close()
+	return combineErrors("Cannot close DominatorsWriteStorage", err1, err2, err3)
+}
+
+type DominatorsReadStorage struct {
+	dominatorsPersistent *IndexRecordsReadStorage
+	retainedPersistent   *IndexRecordsReadStorage
+	dominatedPersistent  *IndexRecordsReadStorage
+	RetainedSizes
+}
+
+func NewDominatorsReadStorage(
+	dominatorsPersistent IndexRecordsReaderAtCloser,
+	dominatorsPersistentSize int,
+	retainedPersistent IndexRecordsReaderAtCloser,
+	retainedPersistentSize int,
+	dominatedPersistent IndexRecordsReaderAtCloser,
+	dominatedPersistentSize int,
+) (*DominatorsReadStorage, error) {
+	dominatorsStorage, err := NewIndexRecordsReadStorage(dominatorsPersistent, dominatorsPersistentSize)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create dominators storage: %w", err)
+	}
+	retainedStorage, err := NewIndexRecordsReadStorage(retainedPersistent, retainedPersistentSize)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create retained sizes storage: %w", err)
+	}
+	dominatedStorage, err := NewIndexRecordsReadStorage(dominatedPersistent, dominatedPersistentSize)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create dominated objects storage: %w", err)
+	}
+	return &DominatorsReadStorage{
+		dominatorsPersistent: dominatorsStorage,
+		retainedPersistent:   retainedStorage,
+		dominatedPersistent:  dominatedStorage,
+	}, nil
+}
+
+func (r *DominatorsReadStorage) RestoreFrom(source io.Reader) error {
+	var retainedSizes RetainedSizes
+	decoder := gob.NewDecoder(source)
+	if err := decoder.Decode(&retainedSizes); err != nil {
+		return fmt.Errorf("cannot deserialize: %w", err)
+	}
+	r.RetainedSizes = retainedSizes
+	return nil
+}
+
+// GetImmediateDominator returns the immediate dominator of the object,
+// 0 means the object is dominated by the virtual root only. Unreachable
+// objects are not presented in the dominator tree.
+func (r *DominatorsReadStorage) GetImmediateDominator(objectId core.Identifier) (core.Identifier, error) {
+	immediateDominator, err := r.dominatorsPersistent.Get(uint64(objectId))
+	return core.Identifier(immediateDominator), err
+}
+
+func (r *DominatorsReadStorage) GetRetainedSize(objectId core.Identifier) (int, error) {
+	retainedSize, err := r.retainedPersistent.Get(uint64(objectId))
+	return int(retainedSize), err
+}
+
+// GetDominated returns objects immediately dominated by the
+// given one. Use 0 to get children of the virtual root.
+func (r *DominatorsReadStorage) GetDominated(objectId core.Identifier) ([]core.Identifier, error) {
+	values, err := r.dominatedPersistent.GetAll(uint64(objectId))
+	if err != nil {
+		return nil, err
+	}
+	dominated := make([]core.Identifier, 0, len(values))
+	for _, v := range values {
+		dominated = append(dominated, core.Identifier(v))
+	}
+	return dominated, nil
+}
+
+func (r *DominatorsReadStorage) Close() error {
+	err1 := r.dominatorsPersistent.Close()
+	err2 := r.retainedPersistent.Close()
+	err3 := r.dominatedPersistent.Close()
+	return combineErrors("Cannot close DominatorsReadStorage", err1, err2, err3)
+}
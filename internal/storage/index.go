@@ -29,6 +29,7 @@
 package storage
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/binary"
 	"fmt"
@@ -179,6 +180,78 @@ func (r *IndexRecordsReadStorage) Get(key uint64) (uint64, error) {
 	return 0, fmt.Errorf("key %v not found", key)
 }
 
+// GetAll returns values of all the records with the given key in the
+// order they are stored. Unlike Get it's suitable for indexes where keys
+// are not unique.
+func (r *IndexRecordsReadStorage) GetAll(key uint64) ([]uint64, error) {
+	position, err := r.Find(key)
+	if err != nil {
+		return nil, err
+	}
+	var values []uint64
+	for i := position; i < r.recordsNumber; i++ {
+		k, v, err := r.At(i)
+		if err != nil {
+			return nil, err
+		}
+		if k != key {
+			break
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// Find returns the position of the first record with the key greater
+// than or equal to the given one. If there are no such records, the
+// number of records is returned.
+func (r *IndexRecordsReadStorage) Find(key uint64) (int, error) {
+	left := 0
+	right := r.recordsNumber
+	for left < right {
+		mid := left + (right-left)/2
+		k, _, err := r.At(mid)
+		if err != nil {
+			return 0, err
+		}
+		if k < key {
+			left = mid + 1
+		} else {
+			right = mid
+		}
+	}
+	return left, nil
+}
+
+// At reads the record at the given position.
+func (r *IndexRecordsReadStorage) At(position int) (key uint64, val uint64, err error) {
+	record := make([]byte, 16)
+	if _, err := r.persistentStorage.ReadAt(record, int64(16*position)); err != nil {
+		return 0, 0, fmt.Errorf("cannot read record at offset %v: %w", position, err)
+	}
+	return binary.BigEndian.Uint64(record[:8]), binary.BigEndian.Uint64(record[8:]), nil
+}
+
+// Len returns the number of records.
+func (r *IndexRecordsReadStorage) Len() int {
+	return r.recordsNumber
+}
+
+// Scan reads all the records sequentially and calls fn for every record.
+func (r *IndexRecordsReadStorage) Scan(fn func(key uint64, val uint64) error) error {
+	reader := bufio.NewReader(io.NewSectionReader(r.persistentStorage, 0, int64(16*r.recordsNumber)))
+	record := make([]byte, 16)
+	for i := 0; i < r.recordsNumber; i++ {
+		if _, err := io.ReadFull(reader, record); err != nil {
+			return fmt.Errorf("cannot read record at offset %v: %w", i, err)
+		}
+		if err := fn(binary.BigEndian.Uint64(record[:8]), binary.BigEndian.Uint64(record[8:])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Close closes the underlying file.
 func (db *IndexRecordsReadStorage) Close() error {
 	return db.persistentStorage.Close()
@@ -211,3 +284,52 @@ func NewRamReadVolume(data []byte) *RamReadVolume {
 func (r *RamReadVolume) Close() error {
 	return nil
 }
+
+// RamRunVolume is in-memory RunVolume for the
+// sorting storage.
+type RamRunVolume struct {
+	data []byte
+}
+
+func NewRamRunVolume() *RamRunVolume {
+	return &RamRunVolume{}
+}
+
+func (v *RamRunVolume) Write(p []byte) (int, error) {
+	v.data = append(v.data, p...)
+	return len(p), nil
+}
+
+func (v *RamRunVolume) ReadAt(p []byte, off int64) (int, error) {
+	return bytes.NewReader(v.data).ReadAt(p, off)
+}
+
+func (v *RamRunVolume) Close() error {
+	return nil
+}
+
+// RamArrayVolume is in-memory ArrayVolume for the
+// paged array.
+type RamArrayVolume struct {
+	data []byte
+}
+
+func NewRamArrayVolume() *RamArrayVolume {
+	return &RamArrayVolume{}
+}
+
+func (v *RamArrayVolume) ReadAt(p []byte, off int64) (int, error) {
+	return bytes.NewReader(v.data).ReadAt(p, off)
+}
+
+func (v *RamArrayVolume) WriteAt(p []byte, off int64) (int, error) {
+	if end := int(off) + len(p); end > len(v.data) {
+		v.data = append(v.data, make([]byte, end-len(v.data))...)
+	}
+	copy(v.data[off:], p)
+	return len(p), nil
+}
+
+func (v *RamArrayVolume) Close() error {
+	return nil
+}
@@ -69,3 +69,43 @@ func Test_GoBackward(t *testing.T) {
 		t.Errorf("putting 999 have not failed but error was expected")
 	}
 }
+
+func TestIndexRecordsReadStorage_GetAll(t *testing.T) {
+	writeVolume := NewRamWriteVolume()
+	writer := NewIndexRecordsWriteStorage(writeVolume, 100)
+	for i := 0; i < 1000; i++ {
+		// key 0 once, key 1 twice, key 2 three times and so on
+		for j := 0; j <= i%10; j++ {
+			if err := writer.Put(uint64(i), uint64(j)); err != nil {
+				t.Errorf("error putting bytes: %v", err)
+			}
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Errorf("cannot close byte storage after writing: %v", err)
+	}
+	reader, err := NewIndexRecordsReadStorage(NewRamReadVolume(writeVolume.Bytes()), writeVolume.Len())
+	if err != nil {
+		t.Errorf("cannot create byte reader: %v", err)
+	}
+	defer reader.Close()
+
+	for _, key := range []uint64{0, 1, 9, 10, 555, 999} {
+		got, err := reader.GetAll(key)
+		if err != nil {
+			t.Errorf("GetAll(%v) error = %v", key, err)
+		}
+		if len(got) != int(key%10)+1 {
+			t.Errorf("GetAll(%v) = %v, want %v values", key, got, key%10+1)
+		}
+		for j, val := range got {
+			if val != uint64(j) {
+				t.Errorf("GetAll(%v)[%v] = %v, want %v", key, j, val, j)
+			}
+		}
+	}
+	got, err := reader.GetAll(1000)
+	if err != nil || len(got) != 0 {
+		t.Errorf("GetAll(1000) = %v, %v, want no values", got, err)
+	}
+}
@@ -15,19 +15,17 @@ func init() {
 
 type MetaWriteStorage struct {
 	MetaStorage
+	heapType *uint32
+	// counters of instances added before the first SetHeap,
+	// nil until SetHeap is called
+	leading *Counters
 }
 
 func NewMetaWriteStorage() *MetaWriteStorage {
 	underlyingMetaStorage := MetaStorage{
-		Counters: Counters{
-			InstancesCount:         make(map[core.Identifier]int),
-			PrimArraysCount:        make(map[core.JavaType]int),
-			PrimArrayElementsCount: make(map[core.JavaType]int),
-			ObjArraysCount:         make(map[core.Identifier]int),
-			ObjArrayElementsCount:  make(map[core.Identifier]int),
-		},
+		Counters: newCounters(),
 	}
-	return &MetaWriteStorage{underlyingMetaStorage}
+	return &MetaWriteStorage{MetaStorage: underlyingMetaStorage}
 }
 
 func (s *MetaWriteStorage) SerializeTo(destination io.Writer) error {
@@ -38,16 +36,62 @@ func (s *MetaWriteStorage) SerializeTo(destination io.Writer) error {
 	return nil
 }
 
+// SetHeap makes all the following instances to be counted
+// for the heap of the given type too. It's called for every
+// HPROF_HEAP_DUMP_INFO sub-record of Android heap dumps.
+func (s *MetaWriteStorage) SetHeap(heapType uint32) {
+	if s.heapType == nil {
+		s.leading = s.copyCounters()
+	}
+	s.heapType = &heapType
+	s.heapCounters(heapType)
+}
+
+func (s *MetaWriteStorage) heapCounters(heapType uint32) Counters {
+	if s.MetaStorage.HeapCounters == nil {
+		s.MetaStorage.HeapCounters = make(map[uint32]Counters)
+	}
+	if _, ok := s.MetaStorage.HeapCounters[heapType]; !ok {
+		s.MetaStorage.HeapCounters[heapType] = newCounters()
+	}
+	return s.MetaStorage.HeapCounters[heapType]
+}
+
+func (s *MetaWriteStorage) copyCounters() *Counters {
+	counters := newCounters()
+	counters.merge(s.MetaStorage.Counters)
+	return &counters
+}
+
 func (s *MetaWriteStorage) AddInstance(obj any) {
-	switch o := obj.(type) {
-	case core.HprofGcClassDumpInstanceDumpHeader:
-		s.MetaStorage.Counters.InstancesCount[o.ClassObjectId]++
-	case core.HprofGcObjArrayDumpHeader:
-		s.MetaStorage.Counters.ObjArraysCount[o.ArrayClassId]++
-		s.MetaStorage.Counters.ObjArrayElementsCount[o.ArrayClassId] += int(o.NumberOfElements)
-	case core.HprofGcPrimArrayDumpHeader:
-		s.MetaStorage.Counters.PrimArraysCount[o.ElementType]++
-		s.MetaStorage.Counters.PrimArrayElementsCount[o.ElementType] += int(o.NumberOfElements)
+	s.MetaStorage.Counters.add(obj)
+	if s.heapType != nil {
+		s.MetaStorage.HeapCounters[*s.heapType].add(obj)
+	}
+}
+
+// Append adds the counters of the storage filled with the following
+// part of the dump, e.g. with the next heap dump segment parsed
+// separately. Instances that the other storage has counted before its
+// first SetHeap belong to the heap set last in this storage, just like
+// they would if the both parts were counted by the same storage.
+func (s *MetaWriteStorage) Append(other *MetaWriteStorage) {
+	leading := other.MetaStorage.Counters
+	if other.leading != nil {
+		leading = *other.leading
+	}
+	if s.heapType != nil {
+		s.MetaStorage.HeapCounters[*s.heapType].merge(leading)
+	} else if other.heapType != nil {
+		s.leading = s.copyCounters()
+		s.leading.merge(leading)
+	}
+	s.MetaStorage.Counters.merge(other.MetaStorage.Counters)
+	for heapType, counters := range other.MetaStorage.HeapCounters {
+		s.heapCounters(heapType).merge(counters)
+	}
+	if other.heapType != nil {
+		s.heapType = other.heapType
 	}
 }
 
@@ -69,14 +113,92 @@ func (s *MetaReadStorage) RestoreFrom(source io.Reader) error {
 	return nil
 }
 
+// MetaStorage keeps the counters of the whole heap and, for Android
+// heap dumps, the counters of every heap (app, image, zygote) by the
+// heap type from HPROF_HEAP_DUMP_INFO. HeapCounters are empty for
+// HotSpot heap dumps.
 type MetaStorage struct {
-	Counters Counters
+	Counters     Counters
+	HeapCounters map[uint32]Counters
 }
 
+// Counters have the number of instances by class and the number of
+// arrays and their elements by type. Array lengths are kept as
+// core.LengthResidues, so the total size of arrays including the
+// padding can be calculated for any heap layout.
 type Counters struct {
 	InstancesCount         map[core.Identifier]int
 	PrimArraysCount        map[core.JavaType]int
 	PrimArrayElementsCount map[core.JavaType]int
+	PrimArrayLengths       map[core.JavaType]core.LengthResidues
 	ObjArraysCount         map[core.Identifier]int
 	ObjArrayElementsCount  map[core.Identifier]int
+	ObjArrayLengths        map[core.Identifier]core.LengthResidues
+}
+
+func newCounters() Counters {
+	return Counters{
+		InstancesCount:         make(map[core.Identifier]int),
+		PrimArraysCount:        make(map[core.JavaType]int),
+		PrimArrayElementsCount: make(map[core.JavaType]int),
+		PrimArrayLengths:       make(map[core.JavaType]core.LengthResidues),
+		ObjArraysCount:         make(map[core.Identifier]int),
+		ObjArrayElementsCount:  make(map[core.Identifier]int),
+		ObjArrayLengths:        make(map[core.Identifier]core.LengthResidues),
+	}
+}
+
+func (c Counters) merge(other Counters) {
+	for classId, count := range other.InstancesCount {
+		c.InstancesCount[classId] += count
+	}
+	for elementType, count := range other.PrimArraysCount {
+		c.PrimArraysCount[elementType] += count
+	}
+	for elementType, count := range other.PrimArrayElementsCount {
+		c.PrimArrayElementsCount[elementType] += count
+	}
+	for elementType, lengths := range other.PrimArrayLengths {
+		if c.PrimArrayLengths[elementType] == nil {
+			c.PrimArrayLengths[elementType] = make(core.LengthResidues)
+		}
+		for residue, count := range lengths {
+			c.PrimArrayLengths[elementType][residue] += count
+		}
+	}
+	for classId, count := range other.ObjArraysCount {
+		c.ObjArraysCount[classId] += count
+	}
+	for classId, count := range other.ObjArrayElementsCount {
+		c.ObjArrayElementsCount[classId] += count
+	}
+	for classId, lengths := range other.ObjArrayLengths {
+		if c.ObjArrayLengths[classId] == nil {
+			c.ObjArrayLengths[classId] = make(core.LengthResidues)
+		}
+		for residue, count := range lengths {
+			c.ObjArrayLengths[classId][residue] += count
+		}
+	}
+}
+
+func (c Counters) add(obj any) {
+	switch o := obj.(type) {
+	case core.HprofGcClassDumpInstanceDumpHeader:
+		c.InstancesCount[o.ClassObjectId]++
+	case core.HprofGcObjArrayDumpHeader:
+		c.ObjArraysCount[o.ArrayClassId]++
+		c.ObjArrayElementsCount[o.ArrayClassId] += int(o.NumberOfElements)
+		if c.ObjArrayLengths[o.ArrayClassId] == nil {
+			c.ObjArrayLengths[o.ArrayClassId] = make(core.LengthResidues)
+		}
+		c.ObjArrayLengths[o.ArrayClassId].Add(int(o.NumberOfElements))
+	case core.HprofGcPrimArrayDumpHeader:
+		c.PrimArraysCount[o.ElementType]++
+		c.PrimArrayElementsCount[o.ElementType] += int(o.NumberOfElements)
+		if c.PrimArrayLengths[o.ElementType] == nil {
+			c.PrimArrayLengths[o.ElementType] = make(core.LengthResidues)
+		}
+		c.PrimArrayLengths[o.ElementType].Add(int(o.NumberOfElements))
+	}
 }
@@ -55,15 +55,100 @@ func TestMetaStorage(t *testing.T) {
 			PrimArrayElementsCount: map[core.JavaType]int{
 				core.Int: 5,
 			},
+			PrimArrayLengths: map[core.JavaType]core.LengthResidues{
+				core.Int: {5: 1},
+			},
 			ObjArraysCount: map[core.Identifier]int{
 				0: 1,
 			},
 			ObjArrayElementsCount: map[core.Identifier]int{
 				0: 10,
 			},
+			ObjArrayLengths: map[core.Identifier]core.LengthResidues{
+				0: {10: 1},
+			},
 		},
 	}
 	if !reflect.DeepEqual(readStorage.MetaStorage, expected) {
 		t.Errorf("Instances = %+v, expected %+v", readStorage.MetaStorage, expected)
 	}
 }
+
+func TestMetaStorage_Heaps(t *testing.T) {
+	writeStorage := NewMetaWriteStorage()
+
+	obj := core.HprofGcClassDumpInstanceDumpHeader{
+		ClassObjectId: 1,
+	}
+	primArr := core.HprofGcPrimArrayDumpHeader{
+		ElementType:      core.Int,
+		NumberOfElements: 5,
+	}
+
+	writeStorage.SetHeap('Z')
+	writeStorage.AddInstance(obj)
+	writeStorage.SetHeap('A')
+	writeStorage.AddInstance(obj)
+	writeStorage.AddInstance(primArr)
+	writeStorage.SetHeap('Z') // the same heap again
+	writeStorage.AddInstance(obj)
+
+	buffer := bytes.NewBuffer(nil)
+	if err := writeStorage.SerializeTo(buffer); err != nil {
+		t.Errorf("Serialize() err = %v", err)
+	}
+	readStorage := NewMetaReadStorage()
+	if err := readStorage.RestoreFrom(buffer); err != nil {
+		t.Errorf("Restore() err = %v", err)
+	}
+
+	if got := readStorage.Counters.InstancesCount[1]; got != 3 {
+		t.Errorf("InstancesCount = %v, expected 3", got)
+	}
+	zygote := readStorage.HeapCounters['Z']
+	if got := zygote.InstancesCount[1]; got != 2 {
+		t.Errorf("zygote InstancesCount = %v, expected 2", got)
+	}
+	if got := zygote.PrimArraysCount[core.Int]; got != 0 {
+		t.Errorf("zygote PrimArraysCount = %v, expected 0", got)
+	}
+	app := readStorage.HeapCounters['A']
+	if got := app.InstancesCount[1]; got != 1 {
+		t.Errorf("app InstancesCount = %v, expected 1", got)
+	}
+	if got := app.PrimArrayElementsCount[core.Int]; got != 5 {
+		t.Errorf("app PrimArrayElementsCount = %v, expected 5", got)
+	}
+}
+
+// TestMetaWriteStorage_Append splits the sequence of instances into
+// three parts in every possible way and checks that the appended
+// storages count the same as the single one.
+func TestMetaWriteStorage_Append(t *testing.T) {
+	obj := core.HprofGcClassDumpInstanceDumpHeader{ClassObjectId: 1}
+	objArr := core.HprofGcObjArrayDumpHeader{ArrayClassId: 2, NumberOfElements: 3}
+	primArr := core.HprofGcPrimArrayDumpHeader{ElementType: core.Int, NumberOfElements: 5}
+	// heap types are applied with SetHeap, the rest are instances
+	sequence := []any{obj, uint32('Z'), obj, objArr, uint32('A'), primArr, obj, uint32('Z'), primArr}
+	fill := func(s *MetaWriteStorage, part []any) *MetaWriteStorage {
+		for _, item := range part {
+			if heapType, ok := item.(uint32); ok {
+				s.SetHeap(heapType)
+			} else {
+				s.AddInstance(item)
+			}
+		}
+		return s
+	}
+	want := fill(NewMetaWriteStorage(), sequence).MetaStorage
+	for i := 0; i <= len(sequence); i++ {
+		for j := i; j <= len(sequence); j++ {
+			got := fill(NewMetaWriteStorage(), sequence[:i])
+			got.Append(fill(NewMetaWriteStorage(), sequence[i:j]))
+			got.Append(fill(NewMetaWriteStorage(), sequence[j:]))
+			if !reflect.DeepEqual(got.MetaStorage, want) {
+				t.Errorf("split at %v and %v: %+v, want %+v", i, j, got.MetaStorage, want)
+			}
+		}
+	}
+}
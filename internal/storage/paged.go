@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"container/list"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// ArrayVolume is the storage behind PagedArray. Reading beyond the
+// written data should return io.EOF, such data is treated as zeros.
+type ArrayVolume interface {
+	io.ReaderAt
+	io.WriterAt
+	io.Closer
+}
+
+const (
+	DefaultPageSize = 1 << 16 // entries, 512 Kb per page
+	DefaultMaxPages = 32
+)
+
+// PagedArray is the array of uint64 that does not have to fit into RAM.
+// Elements are stored in the volume and only the most recently used pages
+// are kept in memory, modified pages are written back when evicted.
+// Elements are zero until set. Errors of the volume are sticky: once an
+// error occurred, Get returns zeros, Set does nothing and Err reports the
+// error, so algorithms can check it once per phase.
+type PagedArray struct {
+	volume   ArrayVolume
+	pageSize int
+	maxPages int
+	pages    map[int64]*list.Element
+	lru      *list.List
+	err      error
+}
+
+type arrayPage struct {
+	index int64
+	data  []uint64
+	dirty bool
+}
+
+func NewPagedArray(volume ArrayVolume, pageSize, maxPages int) *PagedArray {
+	return &PagedArray{
+		volume:   volume,
+		pageSize: pageSize,
+		maxPages: maxPages,
+		pages:    make(map[int64]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+// Get returns the element at position i.
+func (a *PagedArray) Get(i int64) uint64 {
+	p := a.page(i / int64(a.pageSize))
+	if p == nil {
+		return 0
+	}
+	return p.data[i%int64(a.pageSize)]
+}
+
+// Set sets the element at position i.
+func (a *PagedArray) Set(i int64, v uint64) {
+	p := a.page(i / int64(a.pageSize))
+	if p == nil {
+		return
+	}
+	p.data[i%int64(a.pageSize)] = v
+	p.dirty = true
+}
+
+// Err returns the first error occurred while paging.
+func (a *PagedArray) Err() error {
+	return a.err
+}
+
+// Close releases the volume. Pages are not flushed since
+// the array is the scratch space of an algorithm.
+func (a *PagedArray) Close() error {
+	a.pages = nil
+	a.lru = nil
+	return a.volume.Close()
+}
+
+func (a *PagedArray) page(index int64) *arrayPage {
+	if a.err != nil {
+		return nil
+	}
+	if elem, ok := a.pages[index]; ok {
+		a.lru.MoveToFront(elem)
+		return elem.Value.(*arrayPage)
+	}
+	var p *arrayPage
+	if a.lru.Len() >= a.maxPages {
+		oldest := a.lru.Back()
+		p = oldest.Value.(*arrayPage)
+		if err := a.writePage(p); err != nil {
+			a.err = err
+			return nil
+		}
+		a.lru.Remove(oldest)
+		delete(a.pages, p.index)
+		p.index = index
+		p.dirty = false
+	} else {
+		p = &arrayPage{index: index, data: make([]uint64, a.pageSize)}
+	}
+	if err := a.readPage(p); err != nil {
+		a.err = err
+		return nil
+	}
+	a.pages[index] = a.lru.PushFront(p)
+	return p
+}
+
+func (a *PagedArray) readPage(p *arrayPage) error {
+	buf := make([]byte, 8*a.pageSize)
+	n, err := a.volume.ReadAt(buf, p.index*int64(len(buf)))
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("cannot read page %v: %w", p.index, err)
+	}
+	// the tail that has never been written is zeros
+	for i := n; i < len(buf); i++ {
+		buf[i] = 0
+	}
+	for i := range p.data {
+		p.data[i] = binary.BigEndian.Uint64(buf[8*i:])
+	}
+	return nil
+}
+
+func (a *PagedArray) writePage(p *arrayPage) error {
+	if !p.dirty {
+		return nil
+	}
+	buf := make([]byte, 8*a.pageSize)
+	for i, v := range p.data {
+		binary.BigEndian.PutUint64(buf[8*i:], v)
+	}
+	if _, err := a.volume.WriteAt(buf, p.index*int64(len(buf))); err != nil {
+		return fmt.Errorf("cannot write page %v: %w", p.index, err)
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package storage
+
+import "testing"
+
+func TestPagedArray(t *testing.T) {
+	// 4 pages of 8 elements fit into memory, 128 elements do not
+	array := NewPagedArray(NewRamArrayVolume(), 8, 4)
+	defer array.Close()
+	if got := array.Get(100); got != 0 {
+		t.Errorf("Get() of unset element = %v, want 0", got)
+	}
+	for i := int64(0); i < 128; i++ {
+		array.Set(i, uint64(i*i))
+	}
+	// reverse order evicts every page again
+	for i := int64(127); i >= 0; i-- {
+		if got := array.Get(i); got != uint64(i*i) {
+			t.Errorf("Get(%v) = %v, want %v", i, got, i*i)
+		}
+	}
+	if err := array.Err(); err != nil {
+		t.Errorf("Err() = %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/danielleontiev/neojhat/internal/core"
+)
+
+// ReferencesWriteStorage stores reverse edges of the object graph.
+// References are put in the order they are met in the dump which is
+// the order of referrers, so they are resorted by the referenced object
+// before hitting the persistent storage.
+type ReferencesWriteStorage struct {
+	referrersPersistent *SortingIndexWriteStorage
+}
+
+func NewReferencesWriteStorage(referrersPersistent io.WriteCloser, newRun func() (RunVolume, error), batchSize BatchSize) *ReferencesWriteStorage {
+	return &ReferencesWriteStorage{
+		referrersPersistent: NewSortingIndexWriteStorage(referrersPersistent, newRun, batchSize),
+	}
+}
+
+// Fork returns the storage for one of the goroutines putting
+// references at once, see SortingIndexWriteStorage.Fork.
+func (w *ReferencesWriteStorage) Fork() *ReferencesWriteStorage {
+	return &ReferencesWriteStorage{referrersPersistent: w.referrersPersistent.Fork()}
+}
+
+// PutReference records that object "from" holds the reference to object "to".
+func (w *ReferencesWriteStorage) PutReference(from, to core.Identifier) error {
+	return w.referrersPersistent.Put(uint64(to), uint64(from))
+}
+
+func (w *ReferencesWriteStorage) Close() error {
+	return w.referrersPersistent.Close()
+}
+
+type ReferencesReadStorage struct {
+	referrersPersistent *IndexRecordsReadStorage
+}
+
+func NewReferencesReadStorage(referrersPersistent IndexRecordsReaderAtCloser, referrersPersistentSize int) (*ReferencesReadStorage, error) {
+	referrersStorage, err := NewIndexRecordsReadStorage(referrersPersistent, referrersPersistentSize)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create referrers storage: %w", err)
+	}
+	return &ReferencesReadStorage{referrersPersistent: referrersStorage}, nil
+}
+
+// GetReferrers returns identifiers of all objects that hold the reference
+// to the given object. The identifier is repeated if the referrer holds
+// more than one reference to the object.
+func (r *ReferencesReadStorage) GetReferrers(objectId core.Identifier) ([]core.Identifier, error) {
+	values, err := r.referrersPersistent.GetAll(uint64(objectId))
+	if err != nil {
+		return nil, err
+	}
+	referrers := make([]core.Identifier, 0, len(values))
+	for _, v := range values {
+		referrers = append(referrers, core.Identifier(v))
+	}
+	return referrers, nil
+}
+
+// Scan calls fn for every reference in the increasing
+// order of referenced objects.
+func (r *ReferencesReadStorage) Scan(fn func(from, to core.Identifier) error) error {
+	return r.referrersPersistent.Scan(func(key, val uint64) error {
+		return fn(core.Identifier(val), core.Identifier(key))
+	})
+}
+
+func (r *ReferencesReadStorage) Close() error {
+	return r.referrersPersistent.Close()
+}
@@ -14,18 +14,33 @@ func init() {
 }
 
 type underlyingStorage struct {
-	IdSize                 uint32
-	Timestamp              time.Time
-	HprofUtf8              map[core.Identifier]core.HprofUtf8
-	HprofLoadClass         []core.HprofLoadClass
-	HprofFrame             map[core.Identifier]core.HprofFrame
-	HprofTrace             map[uint32]core.HprofTrace
-	HprofGcRootJniGlobal   []core.HprofGcRootJniGlobal
-	HprofGcRootJniLocal    []core.HprofGcRootJniLocal
-	HprofGcRootJavaFrame   []core.HprofGcRootJavaFrame
-	HprofGcRootStickyClass []core.HprofGcRootStickyClass
-	HprofGcRootThreadObj   []core.HprofGcRootThreadObj
-	HprofGcClassDump       map[core.Identifier]core.HprofGcClassDump
+	IdSize                      uint32
+	Timestamp                   time.Time
+	HprofUtf8                   map[core.Identifier]core.HprofUtf8
+	HprofLoadClass              []core.HprofLoadClass
+	HprofFrame                  map[core.Identifier]core.HprofFrame
+	HprofTrace                  map[uint32]core.HprofTrace
+	HprofStartThread            map[uint32]core.HprofStartThread
+	HprofEndThread              []core.HprofEndThread
+	HprofHeapSummary            *core.HprofHeapSummary
+	HprofAllocSites             []core.HprofAllocSites
+	HprofGcRootJniGlobal        []core.HprofGcRootJniGlobal
+	HprofGcRootJniLocal         []core.HprofGcRootJniLocal
+	HprofGcRootJavaFrame        []core.HprofGcRootJavaFrame
+	HprofGcRootStickyClass      []core.HprofGcRootStickyClass
+	HprofGcRootThreadObj        []core.HprofGcRootThreadObj
+	HprofGcRootUnknown          []core.HprofGcRootUnknown
+	HprofGcRootNativeStack      []core.HprofGcRootNativeStack
+	HprofGcRootThreadBlock      []core.HprofGcRootThreadBlock
+	HprofGcRootMonitorUsed      []core.HprofGcRootMonitorUsed
+	HprofGcRootInternedString   []core.HprofGcRootInternedString
+	HprofGcRootFinalizing       []core.HprofGcRootFinalizing
+	HprofGcRootDebugger         []core.HprofGcRootDebugger
+	HprofGcRootReferenceCleanup []core.HprofGcRootReferenceCleanup
+	HprofGcRootVmInternal       []core.HprofGcRootVmInternal
+	HprofGcRootJniMonitor       []core.HprofGcRootJniMonitor
+	HprofHeapDumpInfo           map[uint32]core.HprofHeapDumpInfo
+	HprofGcClassDump            map[core.Identifier]core.HprofGcClassDump
 }
 
 type SmallRecordsWriteStorage struct {
@@ -34,10 +49,12 @@ type SmallRecordsWriteStorage struct {
 
 func NewSmallRecordsWriteStorage() *SmallRecordsWriteStorage {
 	underlyingStorage := underlyingStorage{
-		HprofUtf8:        make(map[core.Identifier]core.HprofUtf8),
-		HprofFrame:       make(map[core.Identifier]core.HprofFrame),
-		HprofTrace:       make(map[uint32]core.HprofTrace),
-		HprofGcClassDump: make(map[core.Identifier]core.HprofGcClassDump),
+		HprofUtf8:         make(map[core.Identifier]core.HprofUtf8),
+		HprofFrame:        make(map[core.Identifier]core.HprofFrame),
+		HprofTrace:        make(map[uint32]core.HprofTrace),
+		HprofStartThread:  make(map[uint32]core.HprofStartThread),
+		HprofHeapDumpInfo: make(map[uint32]core.HprofHeapDumpInfo),
+		HprofGcClassDump:  make(map[core.Identifier]core.HprofGcClassDump),
 	}
 	return &SmallRecordsWriteStorage{underlyingStorage}
 }
@@ -50,6 +67,52 @@ func (s *SmallRecordsWriteStorage) SerializeTo(destination io.Writer) error {
 	return nil
 }
 
+// Append puts all the records of the storage filled with the following
+// part of the dump, e.g. with the next heap dump segment parsed separately.
+// Records are kept in the same order as if the both parts were put to the
+// same storage. Identifier size and timestamp are not touched.
+func (s *SmallRecordsWriteStorage) Append(other *SmallRecordsWriteStorage) {
+	for _, record := range other.HprofUtf8 {
+		s.PutHprofUtf8(record)
+	}
+	s.HprofLoadClass = append(s.HprofLoadClass, other.HprofLoadClass...)
+	for _, record := range other.HprofFrame {
+		s.PutHprofFrame(record)
+	}
+	for _, record := range other.HprofTrace {
+		s.PutHprofTrace(record)
+	}
+	for _, record := range other.HprofStartThread {
+		s.PutHprofStartThread(record)
+	}
+	s.HprofEndThread = append(s.HprofEndThread, other.HprofEndThread...)
+	if other.HprofHeapSummary != nil {
+		s.PutHprofHeapSummary(*other.HprofHeapSummary)
+	}
+	s.HprofAllocSites = append(s.HprofAllocSites, other.HprofAllocSites...)
+	s.HprofGcRootJniGlobal = append(s.HprofGcRootJniGlobal, other.HprofGcRootJniGlobal...)
+	s.HprofGcRootJniLocal = append(s.HprofGcRootJniLocal, other.HprofGcRootJniLocal...)
+	s.HprofGcRootJavaFrame = append(s.HprofGcRootJavaFrame, other.HprofGcRootJavaFrame...)
+	s.HprofGcRootStickyClass = append(s.HprofGcRootStickyClass, other.HprofGcRootStickyClass...)
+	s.HprofGcRootThreadObj = append(s.HprofGcRootThreadObj, other.HprofGcRootThreadObj...)
+	s.HprofGcRootUnknown = append(s.HprofGcRootUnknown, other.HprofGcRootUnknown...)
+	s.HprofGcRootNativeStack = append(s.HprofGcRootNativeStack, other.HprofGcRootNativeStack...)
+	s.HprofGcRootThreadBlock = append(s.HprofGcRootThreadBlock, other.HprofGcRootThreadBlock...)
+	s.HprofGcRootMonitorUsed = append(s.HprofGcRootMonitorUsed, other.HprofGcRootMonitorUsed...)
+	s.HprofGcRootInternedString = append(s.HprofGcRootInternedString, other.HprofGcRootInternedString...)
+	s.HprofGcRootFinalizing = append(s.HprofGcRootFinalizing, other.HprofGcRootFinalizing...)
+	s.HprofGcRootDebugger = append(s.HprofGcRootDebugger, other.HprofGcRootDebugger...)
+	s.HprofGcRootReferenceCleanup = append(s.HprofGcRootReferenceCleanup, other.HprofGcRootReferenceCleanup...)
+	s.HprofGcRootVmInternal = append(s.HprofGcRootVmInternal, other.HprofGcRootVmInternal...)
+	s.HprofGcRootJniMonitor = append(s.HprofGcRootJniMonitor, other.HprofGcRootJniMonitor...)
+	for _, record := range other.HprofHeapDumpInfo {
+		s.PutHprofHeapDumpInfo(record)
+	}
+	for _, record := range other.HprofGcClassDump {
+		s.PutHprofGcClassDump(record)
+	}
+}
+
 func (s *SmallRecordsReadStorage) RestoreFrom(source io.Reader) error {
 	var underlyingStorage underlyingStorage
 	decoder := gob.NewDecoder(source)
@@ -92,6 +155,24 @@ func (s *SmallRecordsWriteStorage) PutHprofTrace(record core.HprofTrace) {
 	s.HprofTrace[record.ThreadSerialNumber] = record
 }
 
+func (s *SmallRecordsWriteStorage) PutHprofStartThread(record core.HprofStartThread) {
+	s.HprofStartThread[record.ThreadSerialNumber] = record
+}
+
+func (s *SmallRecordsWriteStorage) PutHprofEndThread(record core.HprofEndThread) {
+	s.HprofEndThread = append(s.HprofEndThread, record)
+}
+
+// PutHprofHeapSummary keeps only the latest summary
+// since every next one replaces the previous.
+func (s *SmallRecordsWriteStorage) PutHprofHeapSummary(record core.HprofHeapSummary) {
+	s.HprofHeapSummary = &record
+}
+
+func (s *SmallRecordsWriteStorage) PutHprofAllocSites(record core.HprofAllocSites) {
+	s.HprofAllocSites = append(s.HprofAllocSites, record)
+}
+
 func (s *SmallRecordsWriteStorage) PutHprofGcRootJniGlobal(record core.HprofGcRootJniGlobal) {
 	s.HprofGcRootJniGlobal = append(s.HprofGcRootJniGlobal, record)
 }
@@ -112,6 +193,50 @@ func (s *SmallRecordsWriteStorage) PutHprofGcRootThreadObj(record core.HprofGcRo
 	s.HprofGcRootThreadObj = append(s.HprofGcRootThreadObj, record)
 }
 
+func (s *SmallRecordsWriteStorage) PutHprofGcRootUnknown(record core.HprofGcRootUnknown) {
+	s.HprofGcRootUnknown = append(s.HprofGcRootUnknown, record)
+}
+
+func (s *SmallRecordsWriteStorage) PutHprofGcRootNativeStack(record core.HprofGcRootNativeStack) {
+	s.HprofGcRootNativeStack = append(s.HprofGcRootNativeStack, record)
+}
+
+func (s *SmallRecordsWriteStorage) PutHprofGcRootThreadBlock(record core.HprofGcRootThreadBlock) {
+	s.HprofGcRootThreadBlock = append(s.HprofGcRootThreadBlock, record)
+}
+
+func (s *SmallRecordsWriteStorage) PutHprofGcRootMonitorUsed(record core.HprofGcRootMonitorUsed) {
+	s.HprofGcRootMonitorUsed = append(s.HprofGcRootMonitorUsed, record)
+}
+
+func (s *SmallRecordsWriteStorage) PutHprofGcRootInternedString(record core.HprofGcRootInternedString) {
+	s.HprofGcRootInternedString = append(s.HprofGcRootInternedString, record)
+}
+
+func (s *SmallRecordsWriteStorage) PutHprofGcRootFinalizing(record core.HprofGcRootFinalizing) {
+	s.HprofGcRootFinalizing = append(s.HprofGcRootFinalizing, record)
+}
+
+func (s *SmallRecordsWriteStorage) PutHprofGcRootDebugger(record core.HprofGcRootDebugger) {
+	s.HprofGcRootDebugger = append(s.HprofGcRootDebugger, record)
+}
+
+func (s *SmallRecordsWriteStorage) PutHprofGcRootReferenceCleanup(record core.HprofGcRootReferenceCleanup) {
+	s.HprofGcRootReferenceCleanup = append(s.HprofGcRootReferenceCleanup, record)
+}
+
+func (s *SmallRecordsWriteStorage) PutHprofGcRootVmInternal(record core.HprofGcRootVmInternal) {
+	s.HprofGcRootVmInternal = append(s.HprofGcRootVmInternal, record)
+}
+
+func (s *SmallRecordsWriteStorage) PutHprofGcRootJniMonitor(record core.HprofGcRootJniMonitor) {
+	s.HprofGcRootJniMonitor = append(s.HprofGcRootJniMonitor, record)
+}
+
+func (s *SmallRecordsWriteStorage) PutHprofHeapDumpInfo(record core.HprofHeapDumpInfo) {
+	s.HprofHeapDumpInfo[record.HeapType] = record
+}
+
 func (s *SmallRecordsWriteStorage) PutHprofGcClassDump(record core.HprofGcClassDump) {
 	s.HprofGcClassDump[record.ClassObjectId] = record
 }
@@ -162,6 +287,29 @@ func (s *SmallRecordsReadStorage) GetHprofTrace(threadSerialNumber uint32) (core
 	return res, nil
 }
 
+func (s *SmallRecordsReadStorage) GetHprofStartThread(threadSerialNumber uint32) (core.HprofStartThread, error) {
+	res, ok := s.HprofStartThread[threadSerialNumber]
+	if !ok {
+		return core.HprofStartThread{}, fmt.Errorf("Cannot find HprofStartThread record with threadSerialNumber = %v", threadSerialNumber)
+	}
+	return res, nil
+}
+
+func (s *SmallRecordsReadStorage) ListHprofEndThread() []core.HprofEndThread {
+	return s.HprofEndThread
+}
+
+func (s *SmallRecordsReadStorage) GetHprofHeapSummary() (core.HprofHeapSummary, error) {
+	if s.HprofHeapSummary == nil {
+		return core.HprofHeapSummary{}, fmt.Errorf("Cannot find HprofHeapSummary record")
+	}
+	return *s.HprofHeapSummary, nil
+}
+
+func (s *SmallRecordsReadStorage) ListHprofAllocSites() []core.HprofAllocSites {
+	return s.HprofAllocSites
+}
+
 func (s *SmallRecordsReadStorage) ListHprofGcRootJniGlobal() []core.HprofGcRootJniGlobal {
 	return s.HprofGcRootJniGlobal
 }
@@ -182,6 +330,54 @@ func (s *SmallRecordsReadStorage) ListHprofGcRootThreadObj() []core.HprofGcRootT
 	return s.HprofGcRootThreadObj
 }
 
+func (s *SmallRecordsReadStorage) ListHprofGcRootUnknown() []core.HprofGcRootUnknown {
+	return s.HprofGcRootUnknown
+}
+
+func (s *SmallRecordsReadStorage) ListHprofGcRootNativeStack() []core.HprofGcRootNativeStack {
+	return s.HprofGcRootNativeStack
+}
+
+func (s *SmallRecordsReadStorage) ListHprofGcRootThreadBlock() []core.HprofGcRootThreadBlock {
+	return s.HprofGcRootThreadBlock
+}
+
+func (s *SmallRecordsReadStorage) ListHprofGcRootMonitorUsed() []core.HprofGcRootMonitorUsed {
+	return s.HprofGcRootMonitorUsed
+}
+
+func (s *SmallRecordsReadStorage) ListHprofGcRootInternedString() []core.HprofGcRootInternedString {
+	return s.HprofGcRootInternedString
+}
+
+func (s *SmallRecordsReadStorage) ListHprofGcRootFinalizing() []core.HprofGcRootFinalizing {
+	return s.HprofGcRootFinalizing
+}
+
+func (s *SmallRecordsReadStorage) ListHprofGcRootDebugger() []core.HprofGcRootDebugger {
+	return s.HprofGcRootDebugger
+}
+
+func (s *SmallRecordsReadStorage) ListHprofGcRootReferenceCleanup() []core.HprofGcRootReferenceCleanup {
+	return s.HprofGcRootReferenceCleanup
+}
+
+func (s *SmallRecordsReadStorage) ListHprofGcRootVmInternal() []core.HprofGcRootVmInternal {
+	return s.HprofGcRootVmInternal
+}
+
+func (s *SmallRecordsReadStorage) ListHprofGcRootJniMonitor() []core.HprofGcRootJniMonitor {
+	return s.HprofGcRootJniMonitor
+}
+
+func (s *SmallRecordsReadStorage) GetHprofHeapDumpInfo(heapType uint32) (core.HprofHeapDumpInfo, error) {
+	res, ok := s.HprofHeapDumpInfo[heapType]
+	if !ok {
+		return core.HprofHeapDumpInfo{}, fmt.Errorf("Cannot find HprofHeapDumpInfo record with heapType = %v", heapType)
+	}
+	return res, nil
+}
+
 func (s *SmallRecordsReadStorage) GetHprofGcClassDump(classObjectId core.Identifier) (core.HprofGcClassDump, error) {
 	res, ok := s.HprofGcClassDump[classObjectId]
 	if !ok {
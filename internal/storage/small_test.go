@@ -31,6 +31,29 @@ func TestSmallStorage(t *testing.T) {
 		NumberOfFrames:         1,
 		StackFrameIds:          nil,
 	}
+	hprofStartThread := core.HprofStartThread{
+		ThreadSerialNumber:      1,
+		ThreadObjectId:          1,
+		StackTraceSerialNumber:  1,
+		ThreadNameId:            1,
+		ThreadGroupNameId:       1,
+		ThreadParentGroupNameId: 1,
+	}
+	hprofEndThread := core.HprofEndThread{ThreadSerialNumber: 1}
+	hprofHeapSummary := core.HprofHeapSummary{
+		TotalLiveBytes:          1,
+		TotalLiveInstances:      1,
+		TotalBytesAllocated:     1,
+		TotalInstancesAllocated: 1,
+	}
+	hprofAllocSites := core.HprofAllocSites{
+		Flags:         1,
+		CutoffRatio:   0.5,
+		NumberOfSites: 1,
+		Sites: []core.HprofAllocSite{
+			{ClassSerialNumber: 1, StackTraceSerialNumber: 1, BytesAlive: 1, InstancesAlive: 1},
+		},
+	}
 	hprofGcRootJniGlobal := core.HprofGcRootJniGlobal{
 		ObjectId:       1,
 		JniGlobalRefId: 1,
@@ -74,6 +97,10 @@ func TestSmallStorage(t *testing.T) {
 	writeStorage.PutHprofLoadClass(hprofLoadClass)
 	writeStorage.PutHprofFrame(hprofFrame)
 	writeStorage.PutHprofTrace(hprofTrace)
+	writeStorage.PutHprofStartThread(hprofStartThread)
+	writeStorage.PutHprofEndThread(hprofEndThread)
+	writeStorage.PutHprofHeapSummary(hprofHeapSummary)
+	writeStorage.PutHprofAllocSites(hprofAllocSites)
 	writeStorage.PutHprofGcRootJniGlobal(hprofGcRootJniGlobal)
 	writeStorage.PutHprofGcRootJniLocal(hprofGcRootJniLocal)
 	writeStorage.PutHprofGcRootJavaFrame(hprofGcRootJavaFrame)
@@ -148,6 +175,38 @@ func TestSmallStorage(t *testing.T) {
 		t.Errorf("GetHprofTrace err = nil")
 	}
 
+	gotHprofStartThread, err := readStorage.GetHprofStartThread(1)
+	if err != nil {
+		t.Errorf("GetHprofStartThread() error = %v", err)
+	}
+	if !reflect.DeepEqual(gotHprofStartThread, hprofStartThread) {
+		t.Errorf("GetHprofStartThread() = %v, expected %v", gotHprofStartThread, hprofStartThread)
+	}
+	if _, err := readStorage.GetHprofStartThread(2); err == nil {
+		t.Errorf("GetHprofStartThread err = nil")
+	}
+
+	gotHprofEndThreads := readStorage.ListHprofEndThread()
+	if !reflect.DeepEqual(gotHprofEndThreads, []core.HprofEndThread{hprofEndThread}) {
+		t.Errorf("ListHprofEndThread() = %v, expected [%v]", gotHprofEndThreads, hprofEndThread)
+	}
+
+	gotHprofHeapSummary, err := readStorage.GetHprofHeapSummary()
+	if err != nil {
+		t.Errorf("GetHprofHeapSummary() error = %v", err)
+	}
+	if !reflect.DeepEqual(gotHprofHeapSummary, hprofHeapSummary) {
+		t.Errorf("GetHprofHeapSummary() = %v, expected %v", gotHprofHeapSummary, hprofHeapSummary)
+	}
+	if _, err := new(SmallRecordsReadStorage).GetHprofHeapSummary(); err == nil {
+		t.Errorf("GetHprofHeapSummary err = nil")
+	}
+
+	gotHprofAllocSites := readStorage.ListHprofAllocSites()
+	if !reflect.DeepEqual(gotHprofAllocSites, []core.HprofAllocSites{hprofAllocSites}) {
+		t.Errorf("ListHprofAllocSites() = %v, expected [%v]", gotHprofAllocSites, hprofAllocSites)
+	}
+
 	gotHprofGcRootJniGlobals := readStorage.ListHprofGcRootJniGlobal()
 	if !reflect.DeepEqual(gotHprofGcRootJniGlobals, []core.HprofGcRootJniGlobal{hprofGcRootJniGlobal}) {
 		t.Errorf("ListHprofGcRootJniGlobal() = %v, expected [%v]", gotHprofGcRootJniGlobals, hprofGcRootJniGlobal)
@@ -184,3 +243,22 @@ func TestSmallStorage(t *testing.T) {
 		t.Errorf("GetHprofGcClassDump err = nil")
 	}
 }
+
+func TestSmallRecordsWriteStorage_Append(t *testing.T) {
+	want := NewSmallRecordsWriteStorage()
+	first := NewSmallRecordsWriteStorage()
+	second := NewSmallRecordsWriteStorage()
+	for i, s := range []*SmallRecordsWriteStorage{want, first, want, second} {
+		id := core.Identifier(i/2 + 1)
+		s.PutHprofUtf8(core.HprofUtf8{Identifier: id, Characters: "name"})
+		s.PutHprofLoadClass(core.HprofLoadClass{ClassObjectId: id})
+		s.PutHprofGcRootStickyClass(core.HprofGcRootStickyClass{ObjectId: id})
+		s.PutHprofGcRootJniGlobal(core.HprofGcRootJniGlobal{ObjectId: id})
+		s.PutHprofHeapDumpInfo(core.HprofHeapDumpInfo{HeapType: uint32(id), HeapNameId: id})
+		s.PutHprofGcClassDump(core.HprofGcClassDump{ClassObjectId: id})
+	}
+	first.Append(second)
+	if !reflect.DeepEqual(first, want) {
+		t.Errorf("Append() = %+v, want %+v", first, want)
+	}
+}
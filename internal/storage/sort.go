@@ -0,0 +1,371 @@
+package storage
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// RunVolume is temporary storage for one sorted run of index
+// records. It's written once and then read back during merge.
+type RunVolume interface {
+	io.Writer
+	io.ReaderAt
+	io.Closer
+}
+
+// SortingIndexWriteStorage accepts index records in arbitrary order and
+// writes them sorted by key (and by value for equal keys) to the
+// underlying IndexRecordsWriteStorage. It's classic external sort: records
+// are buffered in RAM up to the batch size, then the batch is sorted and
+// spilled to the run volume. On Close all runs are merged. So, the memory
+// consumption is bounded by the batch size no matter how many records
+// are put. Runs are merged at most mergeFanIn at once: when there are
+// that many runs of the same level, they are merged to the single run of
+// the next level, so the number of open run volumes stays bounded too.
+type SortingIndexWriteStorage struct {
+	destination *IndexRecordsWriteStorage
+	newRun      func() (RunVolume, error)
+	batchSize   BatchSize
+	batch       []indexRecord
+	fanIn       int
+	mu          sync.Mutex // guards runs, forks add their runs on Close
+	runs        []sortedRun
+	parent      *SortingIndexWriteStorage
+}
+
+type indexRecord struct {
+	key uint64
+	val uint64
+}
+
+type sortedRun struct {
+	volume RunVolume
+	size   int64
+	// number of merges the records have been through
+	level int
+}
+
+// mergeFanIn is the maximum number of runs merged at once
+const mergeFanIn = 64
+
+// NewSortingIndexWriteStorage creates sorting storage on top of the
+// given persistent storage. newRun is called every time the batch
+// should be spilled, run volumes are closed after merge.
+func NewSortingIndexWriteStorage(persistentStorage io.WriteCloser, newRun func() (RunVolume, error), batchSize BatchSize) *SortingIndexWriteStorage {
+	return &SortingIndexWriteStorage{
+		destination: NewIndexRecordsWriteStorage(persistentStorage, batchSize),
+		newRun:      newRun,
+		batchSize:   batchSize,
+		fanIn:       mergeFanIn,
+	}
+}
+
+// Fork returns the storage that accepts records the same way but hands
+// its sorted runs to w on Close instead of merging them, so records can
+// be put from many goroutines, each with its own fork. All the forks
+// should be closed before w.
+func (w *SortingIndexWriteStorage) Fork() *SortingIndexWriteStorage {
+	return &SortingIndexWriteStorage{
+		newRun:    w.newRun,
+		batchSize: w.batchSize,
+		fanIn:     w.fanIn,
+		parent:    w,
+	}
+}
+
+// Put adds the record. Unlike IndexRecordsWriteStorage.Put
+// keys are allowed to go in any order.
+func (w *SortingIndexWriteStorage) Put(key uint64, val uint64) error {
+	w.batch = append(w.batch, indexRecord{key: key, val: val})
+	if len(w.batch) >= int(w.batchSize) {
+		if err := w.spill(); err != nil {
+			return fmt.Errorf("cannot spill sorted run: %w", err)
+		}
+	}
+	return nil
+}
+
+// Close sorts the records that are left, merges all the runs
+// to the destination and closes it.
+func (w *SortingIndexWriteStorage) Close() error {
+	if w.parent != nil {
+		return w.closeFork()
+	}
+	defer w.closeRuns()
+	if len(w.runs) == 0 {
+		// everything fits into single batch, no need to touch run volumes
+		sortRecords(w.batch)
+		for _, r := range w.batch {
+			if err := w.destination.Put(r.key, r.val); err != nil {
+				return fmt.Errorf("cannot write sorted records: %w", err)
+			}
+		}
+		return w.destination.Close()
+	}
+	if err := w.spill(); err != nil {
+		return fmt.Errorf("cannot spill last sorted run: %w", err)
+	}
+	if err := w.merge(); err != nil {
+		return fmt.Errorf("cannot merge sorted runs: %w", err)
+	}
+	return w.destination.Close()
+}
+
+func (w *SortingIndexWriteStorage) closeFork() error {
+	if err := w.spill(); err != nil {
+		w.closeRuns()
+		return fmt.Errorf("cannot spill last sorted run: %w", err)
+	}
+	w.parent.mu.Lock()
+	defer w.parent.mu.Unlock()
+	w.parent.runs = append(w.parent.runs, w.runs...)
+	w.runs = nil
+	return nil
+}
+
+func (w *SortingIndexWriteStorage) spill() error {
+	if len(w.batch) == 0 {
+		return nil
+	}
+	sortRecords(w.batch)
+	volume, err := w.newRun()
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(volume)
+	record := make([]byte, 16)
+	for _, r := range w.batch {
+		binary.BigEndian.PutUint64(record[:8], r.key)
+		binary.BigEndian.PutUint64(record[8:], r.val)
+		if _, err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	w.runs = append(w.runs, sortedRun{volume: volume, size: int64(16 * len(w.batch))})
+	w.batch = w.batch[:0]
+	return w.compact()
+}
+
+// compact merges the last runs while there are fanIn runs of the
+// same level. Levels of the runs never increase to the end of the
+// list, so the runs of the same level are always at its end.
+func (w *SortingIndexWriteStorage) compact() error {
+	for len(w.runs) >= w.fanIn {
+		tail := w.runs[len(w.runs)-w.fanIn:]
+		level := tail[len(tail)-1].level
+		if tail[0].level != level {
+			return nil
+		}
+		if err := w.mergeTail(w.fanIn, level+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeTail merges n last runs to the single run of the given level
+func (w *SortingIndexWriteStorage) mergeTail(n int, level int) error {
+	tail := w.runs[len(w.runs)-n:]
+	volume, err := w.newRun()
+	if err != nil {
+		return err
+	}
+	merged := sortedRun{volume: volume, level: level}
+	writer := bufio.NewWriter(volume)
+	record := make([]byte, 16)
+	err = mergeRuns(readersOf(tail), func(key uint64, val uint64) error {
+		binary.BigEndian.PutUint64(record[:8], key)
+		binary.BigEndian.PutUint64(record[8:], val)
+		merged.size += 16
+		_, err := writer.Write(record)
+		return err
+	})
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err != nil {
+		volume.Close()
+		return err
+	}
+	for _, run := range tail {
+		run.volume.Close()
+	}
+	w.runs = append(w.runs[:len(w.runs)-n], merged)
+	return nil
+}
+
+func (w *SortingIndexWriteStorage) merge() error {
+	// runs of forks are not compacted together
+	for len(w.runs) > w.fanIn {
+		if err := w.mergeTail(w.fanIn, w.runs[len(w.runs)-1].level+1); err != nil {
+			return err
+		}
+	}
+	return mergeRuns(readersOf(w.runs), w.destination.Put)
+}
+
+func readersOf(runs []sortedRun) []io.Reader {
+	var readers []io.Reader
+	for _, run := range runs {
+		readers = append(readers, io.NewSectionReader(run.volume, 0, run.size))
+	}
+	return readers
+}
+
+// MergeIndexes calls put for the records of all the given indexes
+// in the sorted order. It's useful when the parts of the index are
+// written separately, e.g. by different goroutines.
+func MergeIndexes(indexes []*IndexRecordsReadStorage, put func(key uint64, val uint64) error) error {
+	var runs []io.Reader
+	for _, index := range indexes {
+		runs = append(runs, io.NewSectionReader(index.persistentStorage, 0, int64(16*index.recordsNumber)))
+	}
+	return mergeRuns(runs, put)
+}
+
+// mergeRuns is the k-way merge of sorted runs.
+func mergeRuns(runs []io.Reader, put func(key uint64, val uint64) error) error {
+	h := &runHeap{}
+	for _, run := range runs {
+		cursor := &runCursor{reader: bufio.NewReader(run)}
+		ok, err := cursor.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			h.cursors = append(h.cursors, cursor)
+		}
+	}
+	heap.Init(h)
+	for h.Len() > 0 {
+		cursor := h.cursors[0]
+		if err := put(cursor.current.key, cursor.current.val); err != nil {
+			return err
+		}
+		ok, err := cursor.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(h, 0)
+		} else {
+			heap.Pop(h)
+		}
+	}
+	return nil
+}
+
+func (w *SortingIndexWriteStorage) closeRuns() {
+	for _, run := range w.runs {
+		run.volume.Close()
+	}
+	w.runs = nil
+}
+
+func sortRecords(records []indexRecord) {
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].key == records[j].key {
+			return records[i].val < records[j].val
+		}
+		return records[i].key < records[j].key
+	})
+}
+
+// runCursor reads sorted run record by record
+type runCursor struct {
+	reader  *bufio.Reader
+	current indexRecord
+	buf     [16]byte
+}
+
+func (c *runCursor) next() (bool, error) {
+	if _, err := io.ReadFull(c.reader, c.buf[:]); err != nil {
+		if err == io.EOF {
+			return false, nil
+		}
+		return false, fmt.Errorf("cannot read sorted run: %w", err)
+	}
+	c.current = indexRecord{
+		key: binary.BigEndian.Uint64(c.buf[:8]),
+		val: binary.BigEndian.Uint64(c.buf[8:]),
+	}
+	return true, nil
+}
+
+// runHeap implements heap.Interface to pick
+// the smallest record among all runs
+type runHeap struct {
+	cursors []*runCursor
+}
+
+func (h runHeap) Len() int { return len(h.cursors) }
+
+func (h runHeap) Less(i, j int) bool {
+	left, right := h.cursors[i].current, h.cursors[j].current
+	if left.key == right.key {
+		return left.val < right.val
+	}
+	return left.key < right.key
+}
+
+func (h runHeap) Swap(i, j int) { h.cursors[i], h.cursors[j] = h.cursors[j], h.cursors[i] }
+
+func (h *runHeap) Push(x any) { h.cursors = append(h.cursors, x.(*runCursor)) }
+
+func (h *runHeap) Pop() any {
+	last := h.cursors[len(h.cursors)-1]
+	h.cursors = h.cursors[:len(h.cursors)-1]
+	return last
+}
+
+// TempIndex is the index file that lives only while it's needed, e.g.
+// while the dominator tree is built. It's written as the destination of
+// IndexRecordsWriteStorage (or SortingIndexWriteStorage) and read
+// afterwards, the volume is closed together with the reader.
+type TempIndex struct {
+	newRun func() (RunVolume, error)
+	volume RunVolume
+	size   int
+	err    error
+}
+
+func NewTempIndex(newRun func() (RunVolume, error)) *TempIndex {
+	return &TempIndex{newRun: newRun}
+}
+
+func (t *TempIndex) Write(p []byte) (int, error) {
+	if t.volume == nil {
+		if t.volume, t.err = t.newRun(); t.err != nil {
+			return 0, t.err
+		}
+	}
+	n, err := t.volume.Write(p)
+	t.size += n
+	return n, err
+}
+
+// Close does nothing, the volume is closed together with the reader.
+func (t *TempIndex) Close() error {
+	return nil
+}
+
+func (t *TempIndex) Reader() (*IndexRecordsReadStorage, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	if t.volume == nil {
+		// nothing has been written
+		if t.volume, t.err = t.newRun(); t.err != nil {
+			return nil, t.err
+		}
+	}
+	return NewIndexRecordsReadStorage(t.volume, t.size)
+}
@@ -0,0 +1,191 @@
+package storage
+
+import (
+	"encoding/binary"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestSortingIndexWriteStorage(t *testing.T) {
+	tests := []struct {
+		name      string
+		batchSize BatchSize
+		wantRuns  int
+	}{
+		{name: "single batch", batchSize: 1000, wantRuns: 0},
+		{name: "many runs", batchSize: 7, wantRuns: 15},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeVolume := NewRamWriteVolume()
+			var runs int
+			newRun := func() (RunVolume, error) {
+				runs++
+				return NewRamRunVolume(), nil
+			}
+			writer := NewSortingIndexWriteStorage(writeVolume, newRun, tt.batchSize)
+			// keys go backward, every key is put twice
+			for i := 99; i >= 0; i-- {
+				if err := writer.Put(uint64(i/2), uint64(i)); err != nil {
+					t.Errorf("Put() error = %v", err)
+				}
+			}
+			if err := writer.Close(); err != nil {
+				t.Errorf("Close() error = %v", err)
+			}
+			if runs != tt.wantRuns {
+				t.Errorf("runs = %v, want %v", runs, tt.wantRuns)
+			}
+			data := writeVolume.Bytes()
+			if len(data) != 100*16 {
+				t.Fatalf("len = %v, want %v", len(data), 100*16)
+			}
+			for i := 0; i < 100; i++ {
+				key := binary.BigEndian.Uint64(data[16*i : 16*i+8])
+				val := binary.BigEndian.Uint64(data[16*i+8 : 16*i+16])
+				if key != uint64(i/2) || val != uint64(i) {
+					t.Errorf("record %v = (%v, %v), want (%v, %v)", i, key, val, i/2, i)
+				}
+			}
+		})
+	}
+}
+
+func TestSortingIndexWriteStorage_Fork(t *testing.T) {
+	writeVolume := NewRamWriteVolume()
+	var mu sync.Mutex
+	runs := 0
+	newRun := func() (RunVolume, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		runs++
+		return NewRamRunVolume(), nil
+	}
+	writer := NewSortingIndexWriteStorage(writeVolume, newRun, 7)
+	// every fork puts every fourth key, the writer puts one more
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for f := 0; f < 4; f++ {
+		fork := writer.Fork()
+		wg.Add(1)
+		go func(f int) {
+			defer wg.Done()
+			for i := 99 - f; i >= 0; i -= 4 {
+				if err := fork.Put(uint64(i), uint64(i)); err != nil {
+					errs <- err
+					return
+				}
+			}
+			if err := fork.Close(); err != nil {
+				errs <- err
+			}
+		}(f)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("fork error = %v", err)
+	}
+	if err := writer.Put(100, 100); err != nil {
+		t.Errorf("Put() error = %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	if runs != 4*4+1 {
+		t.Errorf("runs = %v, want %v", runs, 4*4+1)
+	}
+	data := writeVolume.Bytes()
+	if len(data) != 101*16 {
+		t.Fatalf("len = %v, want %v", len(data), 101*16)
+	}
+	for i := 0; i <= 100; i++ {
+		if key := binary.BigEndian.Uint64(data[16*i : 16*i+8]); key != uint64(i) {
+			t.Errorf("record %v has key %v", i, key)
+		}
+	}
+}
+
+func TestMergeIndexes(t *testing.T) {
+	var indexes []*IndexRecordsReadStorage
+	for _, keys := range [][]uint64{{1, 4, 6}, {}, {2, 3, 7}, {5}} {
+		volume := NewRamWriteVolume()
+		writer := NewIndexRecordsWriteStorage(volume, DefaultBatchSize)
+		for _, key := range keys {
+			if err := writer.Put(key, 10*key); err != nil {
+				t.Fatalf("Put() error = %v", err)
+			}
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+		index, err := NewIndexRecordsReadStorage(NewRamReadVolume(volume.Bytes()), volume.Len())
+		if err != nil {
+			t.Fatalf("NewIndexRecordsReadStorage() error = %v", err)
+		}
+		indexes = append(indexes, index)
+	}
+	var got []uint64
+	err := MergeIndexes(indexes, func(key, val uint64) error {
+		if val != 10*key {
+			t.Errorf("value of %v = %v", key, val)
+		}
+		got = append(got, key)
+		return nil
+	})
+	if err != nil {
+		t.Errorf("MergeIndexes() error = %v", err)
+	}
+	if want := []uint64{1, 2, 3, 4, 5, 6, 7}; !reflect.DeepEqual(got, want) {
+		t.Errorf("MergeIndexes() = %v, want %v", got, want)
+	}
+}
+
+// countedRunVolume tracks the number of run volumes that are not closed
+type countedRunVolume struct {
+	*RamRunVolume
+	open *int
+}
+
+func (v countedRunVolume) Close() error {
+	*v.open--
+	return v.RamRunVolume.Close()
+}
+
+func TestSortingIndexWriteStorage_FanIn(t *testing.T) {
+	writeVolume := NewRamWriteVolume()
+	open, maxOpen := 0, 0
+	newRun := func() (RunVolume, error) {
+		open++
+		maxOpen = max(maxOpen, open)
+		return countedRunVolume{RamRunVolume: NewRamRunVolume(), open: &open}, nil
+	}
+	writer := NewSortingIndexWriteStorage(writeVolume, newRun, 2)
+	writer.fanIn = 3
+	// 500 runs of 2 records, keys go backward
+	for i := 999; i >= 0; i-- {
+		if err := writer.Put(uint64(i), uint64(i)); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if open != 0 {
+		t.Errorf("%v run volumes are not closed", open)
+	}
+	// 2 runs of every level up to 3^5 runs and the one being merged
+	if maxOpen > 2*6+1 {
+		t.Errorf("%v run volumes are open at once", maxOpen)
+	}
+	data := writeVolume.Bytes()
+	if len(data) != 1000*16 {
+		t.Fatalf("len = %v, want %v", len(data), 1000*16)
+	}
+	for i := 0; i < 1000; i++ {
+		if key := binary.BigEndian.Uint64(data[16*i : 16*i+8]); key != uint64(i) {
+			t.Fatalf("record %v has key %v", i, key)
+		}
+	}
+}
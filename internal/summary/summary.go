@@ -6,45 +6,60 @@ package summary
 import (
 	"fmt"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/danielleontiev/neojhat/internal/core"
 	"github.com/danielleontiev/neojhat/internal/dump"
 	"github.com/danielleontiev/neojhat/internal/java"
+	"github.com/danielleontiev/neojhat/internal/storage"
 )
 
-const (
-	javaLangSystemClassName          = "java/lang/System"
-	managementFactoryHelperClassName = "sun/management/ManagementFactoryHelper"
-)
+const managementFactoryHelperClassName = "sun/management/ManagementFactoryHelper"
 
 type Properties = map[string]string
 type Kv struct {
-	Key string
-	Val string
+	Key string `json:"key"`
+	Val string `json:"value"`
 }
 type EnvProperties struct {
-	System       string
-	Architecture string
-	JavaHome     string
-	JavaVersion  string
-	JavaName     string
-	JavaVendor   string
+	System       string `json:"system"`
+	Architecture string `json:"architecture"`
+	JavaHome     string `json:"javaHome"`
+	JavaVersion  string `json:"javaVersion"`
+	JavaName     string `json:"javaName"`
+	JavaVendor   string `json:"javaVendor"`
 }
+
+// HeapProperties are the counters of the heap. GcRootsByKind has
+// the number of GC roots of every kind presented in the dump, the
+// object is counted for every reason it is a root. Heaps are set
+// only for Android heap dumps. HeapSize is calculated for Layout.
 type HeapProperties struct {
-	Classes   int
-	GcRoots   int
-	Instances int
-	HeapSize  int
+	Layout        string     `json:"layout"`
+	Classes       int        `json:"classes"`
+	GcRoots       int        `json:"gcRoots"`
+	GcRootsByKind []Kv       `json:"gcRootsByKind"`
+	Instances     int        `json:"instances"`
+	HeapSize      int        `json:"heapSize"`
+	Heaps         []HeapSize `json:"heaps,omitempty"`
+}
+
+// HeapSize is the size of one of the heaps of Android heap dump.
+type HeapSize struct {
+	Name      string `json:"name"`
+	Instances int    `json:"instances"`
+	HeapSize  int    `json:"heapSize"`
 }
 type SystemProperties struct {
-	JvmUptime string
+	JvmUptime string `json:"jvmUptime"`
 }
 type Summary struct {
-	Env        EnvProperties
-	Heap       HeapProperties
-	System     SystemProperties
-	Properties []Kv
+	Env        EnvProperties    `json:"env"`
+	Heap       HeapProperties   `json:"heap"`
+	System     SystemProperties `json:"system"`
+	Properties []Kv             `json:"properties"`
 }
 
 // GetSummary parses given .hprof file and extracts Summary from
@@ -53,17 +68,21 @@ type Summary struct {
 // (private static java.util.Properties props).
 // "props" are set by JVM on startup and contains some info about host
 // system and host JVM. Take a look at java.util.Properties javadoc for
-// more info. java.util.Properties contains ConcurrentHashMap<String, String>
-// which effectiveley the storage with the info. GetSummary takes an attempt
-// to extract the info from hash map by inspecting underlying array with nodes
-// of hash map.
-func GetSummary(parsedAccessor *dump.ParsedAccessor, allProps bool) (Summary, error) {
-	properties, err := getAllProps(parsedAccessor)
+// more info. The properties are read with java.Heap.SystemProperties.
+// Sizes of objects are calculated for the detected heap layout changed
+// by layoutOptions, see core.HeapLayout.Apply.
+func GetSummary(parsedAccessor *dump.ParsedAccessor, allProps bool, layoutOptions string) (Summary, error) {
+	javaHeap := java.NewHeap(parsedAccessor)
+	properties, err := javaHeap.SystemProperties()
 	if err != nil {
 		return Summary{}, err
 	}
 	env := getEnv(properties)
-	heap, err := getHeap(parsedAccessor)
+	size, err := javaHeap.SizeInfo(layoutOptions)
+	if err != nil {
+		return Summary{}, err
+	}
+	heap, err := getHeap(parsedAccessor, javaHeap, size)
 	if err != nil {
 		return Summary{}, err
 	}
@@ -87,83 +106,6 @@ func GetSummary(parsedAccessor *dump.ParsedAccessor, allProps bool) (Summary, er
 	}, nil
 }
 
-// GetAllProps is similar to GetSummary but returns all properties from
-// java.lang.System class
-func getAllProps(parsedAccessor *dump.ParsedAccessor) (Properties, error) {
-	heap := java.NewHeap(parsedAccessor)
-
-	javaLangSystemClass, err := getClassByName(parsedAccessor, javaLangSystemClassName)
-	if err != nil {
-		return nil, err
-	}
-	var propsObjectId core.Identifier
-	for _, field := range javaLangSystemClass.StaticFields {
-		if field.Name == "props" {
-			id, err := field.Value.ToObject()
-			if err != nil {
-				return nil, err
-			}
-			propsObjectId = id
-			break
-		}
-	}
-	props, err := heap.ParseNormalObject(propsObjectId)
-	if err != nil {
-		return nil, err
-	}
-	propsMapField, err := props.GetFieldValueByName("map")
-	if err != nil {
-		return nil, err
-	}
-	propsMapId, err := propsMapField.Value.ToObject()
-	if err != nil {
-		return nil, err
-	}
-	propsMap, err := heap.ParseNormalObject(propsMapId)
-	if err != nil {
-		return nil, err
-	}
-	tableField, err := propsMap.GetFieldValueByName("table")
-	if err != nil {
-		return nil, err
-	}
-	tableId, err := tableField.Value.ToObject()
-	if err != nil {
-		return nil, err
-	}
-	table, err := heap.ParseObjectArrayFull(tableId)
-	if err != nil {
-		return nil, err
-	}
-	resultProps := make(map[string]string)
-	for _, nodeObjectId := range table.Elements {
-		if nodeObjectId != 0 {
-			node, err := heap.ParseNormalObject(nodeObjectId)
-			if err != nil {
-				return nil, err
-			}
-			key, err := node.GetFieldValueByName("key")
-			if err != nil {
-				return nil, err
-			}
-			val, err := node.GetFieldValueByName("val")
-			if err != nil {
-				return nil, err
-			}
-			keyString, err := heap.ParseJavaString(key.Value)
-			if err != nil {
-				return nil, err
-			}
-			valString, err := heap.ParseJavaString(val.Value)
-			if err != nil {
-				return nil, err
-			}
-			resultProps[keyString] = valString
-		}
-	}
-	return Properties(resultProps), nil
-}
-
 func getEnv(props Properties) EnvProperties {
 	return EnvProperties{
 		System:       props["os.name"],
@@ -178,48 +120,82 @@ func getEnv(props Properties) EnvProperties {
 	}
 }
 
-func getHeap(parsedAccessor *dump.ParsedAccessor) (HeapProperties, error) {
+func getHeap(parsedAccessor *dump.ParsedAccessor, javaHeap *java.Heap, size *core.SizeInfo) (HeapProperties, error) {
 	classes := parsedAccessor.ListHprofLoadClass()
-	gcRootsCount := len(parsedAccessor.HprofGcRootJavaFrame) + len(parsedAccessor.HprofGcRootJniGlobal) +
-		len(parsedAccessor.HprofGcRootJniLocal) + len(parsedAccessor.ListHprofGcRootStickyClass()) +
-		len(parsedAccessor.ListHprofGcRootThreadObj())
+	gcRoots := javaHeap.GcRoots()
 	classSet := make(map[core.Identifier]any)
 	var void any
 	for _, c := range classes {
 		classSet[c.ClassObjectId] = void
 	}
-	meta := parsedAccessor.MetaStorage
-	var totalSize int
-	sizeInfo := core.NewSizeInfo(parsedAccessor.IdSize)
-	for tpe, num := range meta.Counters.PrimArrayElementsCount {
-		totalSize += sizeInfo.OfType(tpe) * num
-	}
-	for _, num := range meta.Counters.ObjArrayElementsCount {
-		totalSize += sizeInfo.OfType(core.Object) * num
+	totalCount, totalSize, err := countInstances(javaHeap, size, parsedAccessor.Counters)
+	if err != nil {
+		return HeapProperties{}, err
 	}
-	for classId, num := range meta.Counters.InstancesCount {
-		class, err := parsedAccessor.GetHprofGcClassDump(classId)
+	var heaps []HeapSize
+	for _, heap := range parsedAccessor.ListHeaps() {
+		count, heapSize, err := countInstances(javaHeap, size, heap.Counters)
 		if err != nil {
 			return HeapProperties{}, err
 		}
-		totalSize += int(class.InstanceSize) * num
+		heaps = append(heaps, HeapSize{Name: heap.Name, Instances: count, HeapSize: heapSize})
 	}
-	var totalCount int
-	for _, num := range meta.Counters.PrimArraysCount {
+	return HeapProperties{
+		Layout:        size.Layout().String(),
+		Classes:       len(classSet),
+		GcRoots:       len(gcRoots),
+		GcRootsByKind: countGcRoots(gcRoots),
+		Instances:     totalCount,
+		HeapSize:      totalSize,
+		Heaps:         heaps,
+	}, nil
+}
+
+func countInstances(javaHeap *java.Heap, size *core.SizeInfo, counters storage.Counters) (totalCount, totalSize int, err error) {
+	for tpe, num := range counters.PrimArraysCount {
+		totalSize += size.OfArrays(tpe, num, counters.PrimArrayElementsCount[tpe], counters.PrimArrayLengths[tpe])
+	}
+	for classId, num := range counters.ObjArraysCount {
+		totalSize += size.OfArrays(core.Object, num, counters.ObjArrayElementsCount[classId], counters.ObjArrayLengths[classId])
+	}
+	for classId, num := range counters.InstancesCount {
+		instanceSize, err := javaHeap.InstanceSize(classId, size)
+		if err != nil {
+			return 0, 0, err
+		}
+		totalSize += instanceSize * num
+	}
+	for _, num := range counters.PrimArraysCount {
 		totalCount += num
 	}
-	for _, num := range meta.Counters.ObjArraysCount {
+	for _, num := range counters.ObjArraysCount {
 		totalCount += num
 	}
-	for _, num := range meta.Counters.InstancesCount {
+	for _, num := range counters.InstancesCount {
 		totalCount += num
 	}
-	return HeapProperties{
-		Classes:   len(classSet),
-		GcRoots:   gcRootsCount,
-		Instances: totalCount,
-		HeapSize:  totalSize,
-	}, nil
+	return totalCount, totalSize, nil
+}
+
+func countGcRoots(gcRoots []java.GcRoot) []Kv {
+	counts := make(map[java.GcRootKind]int)
+	for _, root := range gcRoots {
+		counts[root.Kind]++
+	}
+	kinds := make([]java.GcRootKind, 0, len(counts))
+	for kind := range counts {
+		kinds = append(kinds, kind)
+	}
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i] < kinds[j] })
+	var result []Kv
+	for _, kind := range kinds {
+		name := kind.String()
+		result = append(result, Kv{
+			Key: strings.ToUpper(name[:1]) + name[1:],
+			Val: strconv.Itoa(counts[kind]),
+		})
+	}
+	return result
 }
 
 func getSystem(parsedAccessor *dump.ParsedAccessor) (SystemProperties, error) {
@@ -0,0 +1,202 @@
+// testdump builds synthetic .hprof files for tests, so they do not have
+// to spell out every byte of the dump by hand. Dumps use 8-byte identifiers
+// and JAVA PROFILE 1.0.2 header. The package is imported only by tests.
+package testdump
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/danielleontiev/neojhat/internal/core"
+	"github.com/danielleontiev/neojhat/internal/dump"
+	"github.com/danielleontiev/neojhat/internal/storage"
+)
+
+var FileHeader = []byte{
+	0x4a, 0x41, 0x56, 0x41, 0x20, 0x50, 0x52, 0x4f, 0x46, 0x49, 0x4c, 0x45, 0x20, 0x31, 0x2e, 0x30, 0x2e, 0x32, 0x00, // header, 0-terminated
+	0x00, 0x00, 0x00, 0x08, // identifier size
+	0x00, 0x00, 0x01, 0x7b, // timestamp, low word
+	0x7f, 0x28, 0xa8, 0x27, // timestamp, high word
+}
+
+func U2(v uint16) []byte {
+	return binary.BigEndian.AppendUint16(nil, v)
+}
+
+func U4(v uint32) []byte {
+	return binary.BigEndian.AppendUint32(nil, v)
+}
+
+func Id(v uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, v)
+}
+
+// Concat never modifies its arguments.
+func Concat(parts ...[]byte) []byte {
+	var res []byte
+	for _, p := range parts {
+		res = append(res, p...)
+	}
+	return res
+}
+
+func RecordHeader(tag core.Tag, remaining uint32) []byte {
+	return Concat([]byte{byte(tag)}, U4(0), U4(remaining))
+}
+
+func Utf8(id uint64, s string) []byte {
+	return Concat(RecordHeader(core.HprofUtf8Tag, uint32(8+len(s))), Id(id), []byte(s))
+}
+
+func LoadClass(serial uint32, classId, nameId uint64) []byte {
+	return Concat(RecordHeader(core.HprofLoadClassTag, 24), U4(serial), Id(classId), U4(1), Id(nameId))
+}
+
+type Field struct {
+	NameId uint64
+	Type   core.JavaType
+	Value  []byte // only used by static fields
+}
+
+func ClassDump(classId, superId uint64, instanceSize uint32, statics, fields []Field) []byte {
+	return LoadedClassDump(classId, superId, 0, instanceSize, statics, fields)
+}
+
+// LoadedClassDump is ClassDump of the class loaded by the given loader.
+func LoadedClassDump(classId, superId, loaderId uint64, instanceSize uint32, statics, fields []Field) []byte {
+	record := Concat(
+		[]byte{byte(core.HprofGcClassDumpType)},
+		Id(classId), U4(1), Id(superId),
+		Id(loaderId), Id(0), Id(0), Id(0), Id(0), // loader, signers, protection domain, reserved
+		U4(instanceSize),
+		U2(0), // constant pool
+		U2(uint16(len(statics))),
+	)
+	for _, f := range statics {
+		record = Concat(record, Id(f.NameId), []byte{byte(f.Type)}, f.Value)
+	}
+	record = Concat(record, U2(uint16(len(fields))))
+	for _, f := range fields {
+		record = Concat(record, Id(f.NameId), []byte{byte(f.Type)})
+	}
+	return record
+}
+
+func InstanceDump(objectId, classId uint64, values ...[]byte) []byte {
+	payload := Concat(values...)
+	return Concat(
+		[]byte{byte(core.HprofGcInstanceDumpType)},
+		Id(objectId), U4(1), Id(classId), U4(uint32(len(payload))),
+		payload,
+	)
+}
+
+func ObjArrayDump(arrayId, classId uint64, elements ...uint64) []byte {
+	record := Concat(
+		[]byte{byte(core.HprofGcObjArrayDumpType)},
+		Id(arrayId), U4(1), U4(uint32(len(elements))), Id(classId),
+	)
+	for _, e := range elements {
+		record = Concat(record, Id(e))
+	}
+	return record
+}
+
+func PrimArrayDump(arrayId uint64, ty core.JavaType, elements uint32, payload []byte) []byte {
+	return Concat(
+		[]byte{byte(core.HprofGcPrimArrayDumpType)},
+		Id(arrayId), U4(1), U4(elements), []byte{byte(ty)},
+		payload,
+	)
+}
+
+func RootJniGlobal(objectId uint64) []byte {
+	return Concat([]byte{byte(core.HprofGcRootJniGlobalType)}, Id(objectId), Id(0))
+}
+
+func RootJavaFrame(objectId uint64, threadSerial, frame uint32) []byte {
+	return Concat([]byte{byte(core.HprofGcRootJavaFrameType)}, Id(objectId), U4(threadSerial), U4(frame))
+}
+
+func RootStickyClass(objectId uint64) []byte {
+	return Concat([]byte{byte(core.HprofGcRootStickyClassType)}, Id(objectId))
+}
+
+func RootThreadObj(objectId uint64, threadSerial, traceSerial uint32) []byte {
+	return Concat([]byte{byte(core.HprofGcRootThreadObjType)}, Id(objectId), U4(threadSerial), U4(traceSerial))
+}
+
+// Dump puts given top-level records first and wraps sub-records
+// into a single heap dump segment.
+func Dump(records [][]byte, subRecords ...[]byte) []byte {
+	result := Concat(FileHeader)
+	result = Concat(result, Concat(records...))
+	result = Concat(result, RecordHeader(core.HprofHeapDumpSegmentTag, 0))
+	result = Concat(result, Concat(subRecords...))
+	return Concat(result, RecordHeader(core.HprofHeapDumpEndTag, 0))
+}
+
+// NewParsedAccessor parses the dump to in-memory storages. If withReferences
+// is set, the references index is built as well.
+func NewParsedAccessor(in []byte, withReferences bool) (*dump.ParsedAccessor, error) {
+	heapDump := bytes.NewReader(in)
+	smallWriter := storage.NewSmallRecordsWriteStorage()
+	instanceDumpWriteVolume := storage.NewRamWriteVolume()
+	objArrayDumpWriteVolume := storage.NewRamWriteVolume()
+	primArrayDumpWriteVolume := storage.NewRamWriteVolume()
+	bigWriter := storage.NewBigRecordsWriteStorage(
+		instanceDumpWriteVolume, objArrayDumpWriteVolume, primArrayDumpWriteVolume)
+	metaWriter := storage.NewMetaWriteStorage()
+	parser := dump.NewParser(heapDump, smallWriter, bigWriter, metaWriter)
+	referencesWriteVolume := storage.NewRamWriteVolume()
+	if withReferences {
+		// tiny batches to exercise external sorting
+		parser.WithReferences(storage.NewReferencesWriteStorage(referencesWriteVolume, NewRun, 2))
+	}
+	if err := parser.ParseHeapDump(); err != nil {
+		return nil, fmt.Errorf("error indexing sample input: %w", err)
+	}
+	bigReader, err := storage.NewBigRecordsReadStorage(
+		storage.NewRamReadVolume(instanceDumpWriteVolume.Bytes()), instanceDumpWriteVolume.Len(),
+		storage.NewRamReadVolume(objArrayDumpWriteVolume.Bytes()), objArrayDumpWriteVolume.Len(),
+		storage.NewRamReadVolume(primArrayDumpWriteVolume.Bytes()), primArrayDumpWriteVolume.Len(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error creating bigreader: %w", err)
+	}
+	smallReader := storage.NewSmallRecordsReadStorage()
+	metaReader := storage.NewMetaReadStorage()
+	smallBuf := bytes.NewBuffer(nil)
+	metaBuf := bytes.NewBuffer(nil)
+	if err := smallWriter.SerializeTo(smallBuf); err != nil {
+		return nil, fmt.Errorf("error serializing smallwriter: %w", err)
+	}
+	if err := metaWriter.SerializeTo(metaBuf); err != nil {
+		return nil, fmt.Errorf("error serializing meta writer: %w", err)
+	}
+	if err := smallReader.RestoreFrom(smallBuf); err != nil {
+		return nil, fmt.Errorf("error creating smallreader: %w", err)
+	}
+	if err := metaReader.RestoreFrom(metaBuf); err != nil {
+		return nil, fmt.Errorf("error creating meta reader: %w", err)
+	}
+	accessor := dump.NewParsedAccessor(heapDump, bigReader, smallReader, metaReader)
+	if withReferences {
+		referencesReader, err := storage.NewReferencesReadStorage(
+			storage.NewRamReadVolume(referencesWriteVolume.Bytes()), referencesWriteVolume.Len())
+		if err != nil {
+			return nil, fmt.Errorf("error creating references reader: %w", err)
+		}
+		accessor.WithReferences(referencesReader)
+	}
+	return accessor, nil
+}
+
+func NewRun() (storage.RunVolume, error) {
+	return storage.NewRamRunVolume(), nil
+}
+
+func NewArray() (storage.ArrayVolume, error) {
+	return storage.NewRamArrayVolume(), nil
+}
@@ -1,41 +1,54 @@
 package threads
 
+import "github.com/danielleontiev/neojhat/internal/core"
+
 type ThreadDump struct {
-	StackTraces []StackTrace
+	StackTraces []StackTrace `json:"stackTraces"`
 }
 
 type StackTrace struct {
-	ThreadName     string
-	ThreadId       int
-	ThreadDaemon   bool
-	ThreadPriority int
-	ThreadStatus   ThreadStatus
-	NumberOfFrames uint32
-	Frames         []StackFrame
+	ThreadName     string       `json:"threadName"`
+	ThreadId       int          `json:"threadId"`
+	ThreadDaemon   bool         `json:"threadDaemon"`
+	ThreadPriority int          `json:"threadPriority"`
+	ThreadStatus   ThreadStatus `json:"threadStatus"`
+	NumberOfFrames uint32       `json:"numberOfFrames"`
+	Frames         []StackFrame `json:"frames"`
 }
 
 type StackFrame struct {
-	MethodName      string
-	MethodSignature string
-	FileName        string
-	ClassName       string
-	LineNumber      string
-	LocalFrames     []LocalFrame
+	MethodName      string       `json:"methodName"`
+	MethodSignature string       `json:"methodSignature"`
+	FileName        string       `json:"fileName"`
+	ClassName       string       `json:"className"`
+	LineNumber      string       `json:"lineNumber"`
+	LocalFrames     []LocalFrame `json:"localFrames,omitempty"`
 }
 
 type LocalFrame struct {
-	ObjectId            int
-	ObjectTypeSignature string
-	Type                FrameType
+	ObjectId            core.Identifier `json:"objectId"`
+	ObjectTypeSignature string          `json:"objectTypeSignature"`
+	Type                FrameType       `json:"type"`
 }
 
 type FrameType int
 
 const (
-	JniLocal = iota
+	JniLocal FrameType = iota
 	Frame
 )
 
+func (t FrameType) String() string {
+	if t == JniLocal {
+		return "jni-local"
+	}
+	return "frame"
+}
+
+func (t FrameType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
 const (
 	ThreadStateAlive                 = 0x0001
 	ThreadStateTerminated            = 0x0002
@@ -64,3 +77,7 @@ func (ts ThreadStatus) String() string {
 		return "RUNNABLE"
 	}
 }
+
+func (ts ThreadStatus) MarshalText() ([]byte, error) {
+	return []byte(ts.String()), nil
+}
@@ -84,7 +84,7 @@ func GetThreadDump(parsedAccessor *dump.ParsedAccessor) (ThreadDump, error) {
 		if err != nil {
 			return ThreadDump{}, err
 		}
-		frame := LocalFrame{ObjectId: int(jniLocal.ObjectId), ObjectTypeSignature: objectName, Type: JniLocal}
+		frame := LocalFrame{ObjectId: jniLocal.ObjectId, ObjectTypeSignature: objectName, Type: JniLocal}
 		tn := threadSerialNumber(jniLocal.ThreadSerialNumber)
 		pos := positionInStack(jniLocal.FrameNumberInStackTrace)
 		initNestedMap(tn)
@@ -97,7 +97,7 @@ func GetThreadDump(parsedAccessor *dump.ParsedAccessor) (ThreadDump, error) {
 		if err != nil {
 			return ThreadDump{}, err
 		}
-		frame := LocalFrame{ObjectId: int(javaFrame.ObjectId), ObjectTypeSignature: objectName, Type: Frame}
+		frame := LocalFrame{ObjectId: javaFrame.ObjectId, ObjectTypeSignature: objectName, Type: Frame}
 		tn := threadSerialNumber(javaFrame.ThreadSerialNumber)
 		pos := positionInStack(javaFrame.FrameNumberInStackTrace)
 		initNestedMap(tn)